@@ -1259,6 +1259,9 @@ func TestFileSystemFind(t *testing.T) {
 		for _, match := range findResp.Matches {
 			assert.True(t, strings.HasSuffix(match.Path, ".go"), "Expected .go file, got: %s", match.Path)
 			assert.Equal(t, "file", match.Type)
+			assert.Greater(t, match.Size, int64(0))
+			assert.NotEmpty(t, match.Permissions)
+			assert.False(t, match.ModTime.IsZero())
 		}
 	})
 
@@ -1286,6 +1289,9 @@ func TestFileSystemFind(t *testing.T) {
 		// Verify all matches are directories
 		for _, match := range findResp.Matches {
 			assert.Equal(t, "directory", match.Type)
+			assert.Equal(t, int64(0), match.Size)
+			assert.NotEmpty(t, match.Permissions)
+			assert.False(t, match.ModTime.IsZero())
 		}
 	})
 
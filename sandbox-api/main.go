@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -19,7 +20,9 @@ import (
 
 	"github.com/blaxel-ai/sandbox-api/src/handler"
 	"github.com/blaxel-ai/sandbox-api/src/handler/process"
+	"github.com/blaxel-ai/sandbox-api/src/lib/auth"
 	"github.com/blaxel-ai/sandbox-api/src/lib/blaxel"
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
 	"github.com/blaxel-ai/sandbox-api/src/lib/networking"
 	"github.com/blaxel-ai/sandbox-api/src/lib/proxy"
 	"github.com/blaxel-ai/sandbox-api/src/lib/sentrylib"
@@ -51,8 +54,20 @@ func main() {
 	command := flag.String("command", "", "Command to execute")
 	shortCommand := flag.String("c", "", "Command to execute (shorthand)")
 	disableTelemetry := flag.Bool("disable-telemetry", false, "Disable anonymous error reporting")
+	issueToken := flag.String("issue-token", "", "Print a token granting these comma-separated scopes, signed with SANDBOX_TOKEN_SECRET, and exit")
+	tokenTTL := flag.Duration("token-ttl", 0, "Validity of the token printed by -issue-token (0 never expires)")
 	flag.Parse()
 
+	if *issueToken != "" {
+		token, err := auth.Issue(auth.Secret, strings.Split(*issueToken, ","), *tokenTTL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to issue token: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(token)
+		return
+	}
+
 	sentrylib.Version = handler.Version
 	sentryFlush := sentrylib.Init(*disableTelemetry)
 	defer sentryFlush()
@@ -131,6 +146,10 @@ func main() {
 		docs.SwaggerInfo.BasePath = "/"
 		docs.SwaggerInfo.Schemes = []string{"http"}
 	}
+	if api.RoutePrefix != "" {
+		docs.SwaggerInfo.BasePath = strings.TrimSuffix(docs.SwaggerInfo.BasePath, "/") + api.RoutePrefix
+		logrus.Infof("Route prefix: %s", api.RoutePrefix)
+	}
 
 	gin.SetMode(gin.ReleaseMode)
 	disableRequestLogging := os.Getenv("DISABLE_REQUEST_LOGGING") == "true"
@@ -164,7 +183,7 @@ func main() {
 	router := api.SetupRouter(disableRequestLogging, enableProcessingTime)
 
 	// Route registration happens inside the NewServer constructor
-	if _, err := mcp.NewServer(router); err != nil {
+	if _, err := mcp.NewServer(router.Group(api.RoutePrefix)); err != nil {
 		logrus.Fatalf("Failed to create MCP server: %v", err)
 	}
 
@@ -172,23 +191,53 @@ func main() {
 	serverAddr := fmt.Sprintf(":%d", portValue)
 	logrus.Infof("Starting Sandbox API server on %s", serverAddr)
 
+	// Timeouts can be tuned with SERVER_READ_TIMEOUT, SERVER_WRITE_TIMEOUT and SERVER_IDLE_TIMEOUT
+	// (seconds or Go durations, 0 disables). Streaming endpoints lift the write deadline themselves.
 	server := &http.Server{
 		Addr:              serverAddr,
 		Handler:           router,
-		ReadTimeout:       10 * time.Minute, // Allow up to 10 minutes for reading large uploads
-		WriteTimeout:      10 * time.Minute, // Allow up to 10 minutes for writing large downloads
-		ReadHeaderTimeout: 30 * time.Second, // Headers should be quick
-		IdleTimeout:       2 * time.Minute,  // Keep-alive connections timeout
-		MaxHeaderBytes:    1 << 20,          // 1 MB max header size
+		ReadTimeout:       parseServerTimeout("SERVER_READ_TIMEOUT", 10*time.Minute),  // Allow up to 10 minutes for reading large uploads
+		WriteTimeout:      parseServerTimeout("SERVER_WRITE_TIMEOUT", 10*time.Minute), // Allow up to 10 minutes for writing large downloads
+		ReadHeaderTimeout: 30 * time.Second,                                           // Headers should be quick
+		IdleTimeout:       parseServerTimeout("SERVER_IDLE_TIMEOUT", 2*time.Minute),   // Keep-alive connections timeout
+		MaxHeaderBytes:    1 << 20,                                                    // 1 MB max header size
 	}
 
+	// ENABLE_HTTP2 serves HTTP/2 without TLS (h2c, prior knowledge) next to HTTP/1.1, so a client
+	// can multiplex several long-lived streams (watch, logs) over one connection
+	if os.Getenv("ENABLE_HTTP2") == "true" || os.Getenv("ENABLE_HTTP2") == "1" {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		server.Protocols = protocols
+		logrus.Info("HTTP/2 (h2c) enabled")
+	}
+	logrus.Infof("Server timeouts: read=%s write=%s idle=%s", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+
 	// Set up signal handling for graceful shutdown
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	// Stop the sandbox after a period of inactivity if IDLE_TIMEOUT is set
+	idleCh := make(chan struct{})
+	if idleTimeout := parseIdleTimeout(os.Getenv("IDLE_TIMEOUT")); idleTimeout > 0 {
+		logrus.Infof("Idle timeout: %s", idleTimeout)
+		go watchIdle(ctx, idleTimeout, pm, idleCh)
+	}
+
 	go func() {
-		sig := <-sigCh
-		logrus.Infof("Received signal %v, shutting down...", sig)
+		select {
+		case sig := <-sigCh:
+			logrus.Infof("Received signal %v, shutting down...", sig)
+		case <-idleCh:
+			logrus.Info("Sandbox is idle, shutting down...")
+			if err := pm.SaveState(); err != nil {
+				logrus.WithError(err).Warn("Failed to save process state before idle shutdown")
+			}
+		}
+
+		// Streams never end on their own: ask them to send a reconnect event first
+		drain.Drain(process.StreamDrainTimeout)
 
 		// Shutdown HTTP server gracefully with a timeout
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -213,6 +262,70 @@ func main() {
 	logrus.Info("Server stopped")
 }
 
+// parseIdleTimeout parses IDLE_TIMEOUT as a Go duration ("15m") or a number
+// of seconds. Zero disables the idle timeout.
+func parseIdleTimeout(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		logrus.Warnf("Invalid IDLE_TIMEOUT %q, idle shutdown disabled", value)
+		return 0
+	}
+	return d
+}
+
+// parseServerTimeout reads a server timeout from the environment as a Go duration
+// ("90s") or a number of seconds, falling back to def when unset or invalid.
+// Zero means no timeout.
+func parseServerTimeout(name string, def time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return def
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		logrus.Warnf("Invalid %s %q, using default of %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// watchIdle closes idleCh once no request has been seen for the given timeout
+// and no process is running. Any request or running process resets the timer.
+func watchIdle(ctx context.Context, timeout time.Duration, pm *process.ProcessManager, idleCh chan<- struct{}) {
+	interval := timeout / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if pm.HasRunningProcesses() {
+				api.Activity.Touch()
+				continue
+			}
+			if api.Activity.IdleFor() >= timeout {
+				close(idleCh)
+				return
+			}
+		}
+	}
+}
+
 // startBackgroundCommand runs the given command string in a goroutine using the
 // configured SHELL and SHELL_ARGS environment variables.
 func startBackgroundCommand(ctx context.Context, command string) {
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityTracker records when the API last saw a request and how many
+// requests (including long-lived streams) are still in flight
+type ActivityTracker struct {
+	mu           sync.Mutex
+	lastActivity time.Time
+	active       int
+}
+
+// NewActivityTracker creates a tracker whose idle period starts now
+func NewActivityTracker() *ActivityTracker {
+	return &ActivityTracker{lastActivity: time.Now()}
+}
+
+// Touch resets the idle timer
+func (t *ActivityTracker) Touch() {
+	t.mu.Lock()
+	t.lastActivity = time.Now()
+	t.mu.Unlock()
+}
+
+// IdleFor returns how long the API has been idle. A tracker with in-flight
+// requests is never idle.
+func (t *ActivityTracker) IdleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active > 0 {
+		return 0
+	}
+	return time.Since(t.lastActivity)
+}
+
+// ActiveRequests returns the number of requests currently being served
+func (t *ActivityTracker) ActiveRequests() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active
+}
+
+func (t *ActivityTracker) begin() {
+	t.mu.Lock()
+	t.active++
+	t.lastActivity = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *ActivityTracker) end() {
+	t.mu.Lock()
+	t.active--
+	t.lastActivity = time.Now()
+	t.mu.Unlock()
+}
+
+// Activity is the tracker fed by the router's activity middleware
+var Activity = NewActivityTracker()
+
+// activityMiddleware marks the API as busy for the whole lifetime of a request,
+// so streaming endpoints keep the sandbox awake until the client disconnects
+func activityMiddleware(tracker *ActivityTracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tracker.begin()
+		defer tracker.end()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler"
+	"github.com/blaxel-ai/sandbox-api/src/lib/auth"
+)
+
+// TokenHeader carries a scoped token when Authorization is already used by a gateway in front of the API
+const TokenHeader = "X-Sandbox-Token"
+
+// scopeAreas maps the first segment of a route to the area of its scope
+var scopeAreas = map[string]string{
+	"filesystem":                "filesystem",
+	"filesystem-multipart":      "filesystem",
+	"filesystem-find":           "filesystem",
+	"filesystem-search":         "filesystem",
+	"filesystem-content-search": "filesystem",
+	"filesystem-resolve":        "filesystem",
+	"filesystem-xattr":          "filesystem",
+	"filesystem-snapshots":      "filesystem",
+	"filesystem-restore":        "filesystem",
+	"filesystem-follow":         "filesystem",
+	"filesystem-untar":          "filesystem",
+	"filesystem-unpack":         "filesystem",
+	"filesystem-dedupe":         "filesystem",
+	"filesystem-render":         "filesystem",
+	"filesystem-swap":           "filesystem",
+	"watch":                     "filesystem",
+	"process":                   "process",
+	"network":                   "network",
+	"codegen":                   "codegen",
+	"terminal":                  "terminal",
+	"mcp":                       "mcp",
+	"drives":                    "drive",
+}
+
+// writeOnlyAreas give full control whatever the method, so they always need the write action
+var writeOnlyAreas = map[string]bool{
+	"terminal": true,
+	"mcp":      true,
+}
+
+// readOnlyPosts are the POST routes registered with readOnlyPOST by SetupRouter: they only take
+// a body to carry their query, so they need the read action
+var readOnlyPosts = map[string]bool{}
+
+// publicRoutes never need a token, like the root path itself
+var publicRoutes = map[string]bool{
+	"health":       true,
+	"swagger":      true,
+	"openapi.json": true,
+}
+
+// requiredScope returns the area and action needed for a request path (without RoutePrefix).
+// GET, HEAD and the readOnlyPosts need the read action, other methods the write one. Routes
+// outside the known areas (upgrade, restart, config...) belong to the system area.
+func requiredScope(method, path string) (string, string, bool) {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if path == "" || path == "/" || publicRoutes[segment] || method == http.MethodOptions {
+		return "", "", false
+	}
+	area, ok := scopeAreas[segment]
+	if !ok {
+		area = "system"
+	}
+	action := auth.ActionWrite
+	readOnly := method == http.MethodGet || method == http.MethodHead || (method == http.MethodPost && readOnlyPosts[path])
+	if readOnly && !writeOnlyAreas[area] {
+		action = auth.ActionRead
+	}
+	return area, action, true
+}
+
+// requestToken returns the token of a request: the X-Sandbox-Token header, the Authorization
+// bearer, or the token query parameter for clients that cannot set headers (WebSockets)
+func requestToken(c *gin.Context) string {
+	if token := c.GetHeader(TokenHeader); token != "" {
+		return token
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return c.Query("token")
+}
+
+// scopeMiddleware rejects requests whose token does not grant the scope of the route, with 401
+// when the token is missing or invalid and 403 when its scopes are insufficient.
+// It does nothing unless SANDBOX_TOKEN_SECRET is set.
+func scopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.Enabled() {
+			c.Next()
+			return
+		}
+		path, ok := strings.CutPrefix(c.Request.URL.Path, RoutePrefix)
+		if !ok {
+			c.Next()
+			return
+		}
+		area, action, needed := requiredScope(c.Request.Method, path)
+		if !needed {
+			c.Next()
+			return
+		}
+
+		token := requestToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, handler.ErrorResponse{Error: "a token is required"})
+			return
+		}
+		claims, err := auth.Parse(auth.Secret, token)
+		if err != nil {
+			message := "invalid token"
+			if errors.Is(err, auth.ErrTokenExpired) {
+				message = "token expired"
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, handler.ErrorResponse{Error: message})
+			return
+		}
+		if !claims.Allows(area, action) {
+			c.AbortWithStatusJSON(http.StatusForbidden, handler.ErrorResponse{Error: "token lacks the " + area + ":" + action + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/blaxel-ai/sandbox-api/docs"
+	"github.com/gin-gonic/gin"
+)
+
+// forwardedValue returns the first value of a X-Forwarded-* header, set by the closest client of the chain
+func forwardedValue(header string) string {
+	value, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(value)
+}
+
+// handleOpenAPI serves the API spec with the host, scheme and base path the request reached
+// the API with. X-Forwarded-Host, X-Forwarded-Proto and X-Forwarded-Prefix set by a reverse
+// proxy take precedence over the request itself; without X-Forwarded-Prefix the base path
+// configured at startup is kept.
+func handleOpenAPI(c *gin.Context) {
+	spec := *docs.SwaggerInfo
+
+	spec.Host = c.Request.Host
+	if host := forwardedValue(c.GetHeader("X-Forwarded-Host")); host != "" {
+		spec.Host = host
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := forwardedValue(c.GetHeader("X-Forwarded-Proto")); proto != "" {
+		scheme = proto
+	}
+	spec.Schemes = []string{scheme}
+
+	if prefix := forwardedValue(c.GetHeader("X-Forwarded-Prefix")); prefix != "" {
+		spec.BasePath = normalizeRoutePrefix(prefix) + RoutePrefix
+	} else if spec.BasePath == "" {
+		spec.BasePath = RoutePrefix
+	}
+	if spec.BasePath == "" {
+		spec.BasePath = "/"
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(spec.ReadDoc()))
+}
@@ -21,6 +21,25 @@ import (
 	"github.com/blaxel-ai/sandbox-api/src/lib/audit"
 )
 
+// RoutePrefix is prepended to every route, so the API can be mounted under a subpath
+// (e.g. /sandbox) behind a reverse proxy that doesn't rewrite paths.
+// Can be configured via ROUTE_PREFIX environment variable, empty by default.
+var RoutePrefix = ""
+
+func init() {
+	RoutePrefix = normalizeRoutePrefix(os.Getenv("ROUTE_PREFIX"))
+}
+
+// normalizeRoutePrefix returns prefix with a single leading slash and no trailing slash,
+// or an empty string when it only contains slashes
+func normalizeRoutePrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
 // SetupRouter configures all the routes for the Sandbox API
 // If disableRequestLogging is true, the logrus middleware will be skipped
 // If enableProcessingTime is true, the Server-Timing header middleware will be added
@@ -41,6 +60,9 @@ func SetupRouter(disableRequestLogging bool, enableProcessingTime bool) *gin.Eng
 	// Add middleware to prevent caching
 	r.Use(noCacheMiddleware())
 
+	// Add middleware to track request activity for the idle timeout
+	r.Use(activityMiddleware(Activity))
+
 	// Add processing time middleware if enabled
 	if enableProcessingTime {
 		r.Use(processingTimeMiddleware())
@@ -51,11 +73,8 @@ func SetupRouter(disableRequestLogging bool, enableProcessingTime bool) *gin.Eng
 		r.Use(logrusMiddleware())
 	}
 
-	// Swagger documentation route
-	r.GET("/swagger", func(c *gin.Context) {
-		c.Redirect(301, "/swagger/index.html")
-	})
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Add middleware checking capability-scoped tokens (only when SANDBOX_TOKEN_SECRET is set)
+	r.Use(scopeMiddleware())
 
 	// Initialize handlers
 	baseHandler := handler.NewBaseHandler()
@@ -65,13 +84,18 @@ func SetupRouter(disableRequestLogging bool, enableProcessingTime bool) *gin.Eng
 	codegenHandler := handler.NewCodegenHandler(fsHandler)
 	systemHandler := handler.NewSystemHandler()
 	driveHandler := handler.NewDriveHandler()
+	operationsHandler := handler.NewOperationsHandler()
 
 	// Check if terminal is disabled via environment variable
 	disableTerminal := os.Getenv("DISABLE_TERMINAL") == "true" || os.Getenv("DISABLE_TERMINAL") == "1"
 
 	// Custom filesystem tree router middleware to handle tree-specific routes
 	r.Use(func(c *gin.Context) {
-		path := c.Request.URL.Path
+		path, ok := strings.CutPrefix(c.Request.URL.Path, RoutePrefix)
+		if !ok {
+			c.Next()
+			return
+		}
 		method := c.Request.Method
 
 		// Check if this is a tree request
@@ -118,95 +142,176 @@ func SetupRouter(disableRequestLogging bool, enableProcessingTime bool) *gin.Eng
 				return
 			}
 		}
+
 		c.Next()
 	})
 
+	// All routes are registered under RoutePrefix (empty by default). The group must be created
+	// after the last middleware, as it copies the middleware chain.
+	routes := r.Group(RoutePrefix)
+
+	// Swagger documentation route
+	routes.GET("/swagger", func(c *gin.Context) {
+		c.Redirect(301, RoutePrefix+"/swagger/index.html")
+	})
+	routes.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	routes.GET("/openapi.json", handleOpenAPI)
+
 	// HEAD handler for checking endpoint existence
 	head := headHandler()
 
+	// readOnlyPOST registers a POST route that only reads, so that it needs the read scope
+	readOnlyPOST := func(path string, handler gin.HandlerFunc) {
+		routes.POST(path, handler)
+		readOnlyPosts[path] = true
+	}
+
 	// Multipart upload routes (separate endpoint to avoid wildcard conflicts)
-	r.GET("/filesystem-multipart", fsHandler.HandleListMultipartUploads)
-	r.HEAD("/filesystem-multipart", head)
-	r.POST("/filesystem-multipart/initiate/*path", fsHandler.HandleInitiateMultipartUpload)
-	r.PUT("/filesystem-multipart/:uploadId/part", fsHandler.HandleUploadPart)
-	r.POST("/filesystem-multipart/:uploadId/complete", fsHandler.HandleCompleteMultipartUpload)
-	r.DELETE("/filesystem-multipart/:uploadId/abort", fsHandler.HandleAbortMultipartUpload)
-	r.GET("/filesystem-multipart/:uploadId/parts", fsHandler.HandleListParts)
-	r.HEAD("/filesystem-multipart/:uploadId/parts", head)
+	routes.GET("/filesystem-multipart", fsHandler.HandleListMultipartUploads)
+	routes.HEAD("/filesystem-multipart", head)
+	routes.POST("/filesystem-multipart/initiate/*path", fsHandler.HandleInitiateMultipartUpload)
+	routes.PUT("/filesystem-multipart/:uploadId/part", fsHandler.HandleUploadPart)
+	routes.POST("/filesystem-multipart/:uploadId/complete", fsHandler.HandleCompleteMultipartUpload)
+	routes.DELETE("/filesystem-multipart/:uploadId/abort", fsHandler.HandleAbortMultipartUpload)
+	routes.GET("/filesystem-multipart/:uploadId/parts", fsHandler.HandleListParts)
+	routes.HEAD("/filesystem-multipart/:uploadId/parts", head)
 
 	// Filesystem routes
-	r.GET("/filesystem-find/*path", fsHandler.HandleFind)
-	r.HEAD("/filesystem-find/*path", head)
-	r.GET("/filesystem-search/*path", fsHandler.HandleFuzzySearch)
-	r.HEAD("/filesystem-search/*path", head)
-	r.GET("/filesystem-content-search/*path", fsHandler.HandleContentSearch)
-	r.HEAD("/filesystem-content-search/*path", head)
-	r.GET("/watch/filesystem/*path", fsHandler.HandleWatchDirectory)
-	r.HEAD("/watch/filesystem/*path", head)
-	r.GET("/filesystem/*path", fsHandler.HandleGetFile)
-	r.HEAD("/filesystem/*path", head)
-	r.PUT("/filesystem/*path", fsHandler.HandleCreateOrUpdateFile)
-	r.DELETE("/filesystem/*path", fsHandler.HandleDeleteFile)
+	routes.GET("/filesystem-resolve", fsHandler.HandleResolvePath)
+	routes.HEAD("/filesystem-resolve", head)
+	routes.GET("/filesystem-resolve/*path", fsHandler.HandleResolvePath)
+	routes.HEAD("/filesystem-resolve/*path", head)
+	routes.GET("/filesystem-find/*path", fsHandler.HandleFind)
+	routes.HEAD("/filesystem-find/*path", head)
+	routes.GET("/filesystem-search/*path", fsHandler.HandleFuzzySearch)
+	routes.HEAD("/filesystem-search/*path", head)
+	routes.GET("/filesystem-content-search/*path", fsHandler.HandleContentSearch)
+	routes.HEAD("/filesystem-content-search/*path", head)
+	routes.GET("/filesystem-xattr/*path", fsHandler.HandleGetXattrs)
+	routes.HEAD("/filesystem-xattr/*path", head)
+	routes.PUT("/filesystem-xattr/*path", fsHandler.HandleSetXattr)
+	routes.GET("/filesystem-snapshots/*path", fsHandler.HandleListSnapshots)
+	routes.HEAD("/filesystem-snapshots/*path", head)
+	routes.POST("/filesystem-snapshots/*path", fsHandler.HandleCreateSnapshot)
+	routes.POST("/filesystem-restore/*path", fsHandler.HandleRestoreSnapshot)
+	routes.GET("/filesystem-follow/*path", fsHandler.HandleFollowFile)
+	routes.HEAD("/filesystem-follow/*path", head)
+	routes.POST("/filesystem-untar/*path", fsHandler.HandleUntar)
+	routes.POST("/filesystem-unpack/*path", fsHandler.HandleUnpack)
+	routes.POST("/filesystem-dedupe/*path", fsHandler.HandleDedupe)
+	routes.POST("/filesystem-render/*path", fsHandler.HandleRender)
+	routes.POST("/filesystem-swap/*path", fsHandler.HandleSwap)
+	routes.GET("/watch/filesystem/*path", fsHandler.HandleWatchDirectory)
+	routes.HEAD("/watch/filesystem/*path", head)
+	routes.GET("/watch/filesystem-multi", fsHandler.HandleWatchDirectories)
+	routes.HEAD("/watch/filesystem-multi", head)
+	routes.GET("/filesystem/*path", fsHandler.HandleGetFile)
+	routes.HEAD("/filesystem/*path", head)
+	routes.PUT("/filesystem/*path", fsHandler.HandleCreateOrUpdateFile)
+	routes.DELETE("/filesystem/*path", fsHandler.HandleDeleteFile)
+	routes.PATCH("/filesystem/*path", fsHandler.HandlePatchFile)
+	readOnlyPOST("/filesystem/stat-batch", fsHandler.HandleStatBatch)
+	routes.POST("/filesystem/replace", fsHandler.HandleReplace)
+	readOnlyPOST("/filesystem/compare", fsHandler.HandleCompare)
+	readOnlyPOST("/filesystem/match", fsHandler.HandleMatchPath)
 
 	// Process routes
-	r.GET("/process", processHandler.HandleListProcesses)
-	r.HEAD("/process", head)
-	r.POST("/process", processHandler.HandleExecuteCommand)
-	r.GET("/process/:identifier/logs", processHandler.HandleGetProcessLogs)
-	r.HEAD("/process/:identifier/logs", head)
-	r.GET("/process/:identifier/logs/stream", processHandler.HandleGetProcessLogsStream)
-	r.HEAD("/process/:identifier/logs/stream", head)
-	r.DELETE("/process/:identifier", processHandler.HandleStopProcess)
-	r.DELETE("/process/:identifier/kill", processHandler.HandleKillProcess)
-	r.GET("/process/:identifier", processHandler.HandleGetProcess)
-	r.HEAD("/process/:identifier", head)
+	routes.GET("/process", processHandler.HandleListProcesses)
+	routes.HEAD("/process", head)
+	routes.POST("/process", processHandler.HandleExecuteCommand)
+	routes.POST("/process/batch", processHandler.HandleExecuteBatch)
+	readOnlyPOST("/process/validate", processHandler.HandleValidateCommand)
+	routes.POST("/process/reap", processHandler.HandleReapZombies)
+	readOnlyPOST("/process/env/preview", processHandler.HandlePreviewEnv)
+	routes.GET("/process/logs/stream", processHandler.HandleGetMultiProcessLogsStream)
+	routes.HEAD("/process/logs/stream", head)
+	routes.GET("/process/logs/export", processHandler.HandleExportProcessLogs)
+	routes.HEAD("/process/logs/export", head)
+	routes.GET("/process/:identifier/logs", processHandler.HandleGetProcessLogs)
+	routes.HEAD("/process/:identifier/logs", head)
+	routes.GET("/process/:identifier/logs/stream", processHandler.HandleGetProcessLogsStream)
+	routes.HEAD("/process/:identifier/logs/stream", head)
+	routes.POST("/process/:identifier/logs/mirror", processHandler.HandleMirrorProcessLogs)
+	routes.POST("/process/:identifier/logs/reattach", processHandler.HandleReattachProcessLogs)
+	routes.GET("/process/:identifier/status/stream", processHandler.HandleGetProcessStatusStream)
+	routes.HEAD("/process/:identifier/status/stream", head)
+	routes.GET("/process/:identifier/exit-code", processHandler.HandleGetProcessExitCode)
+	routes.HEAD("/process/:identifier/exit-code", head)
+	routes.GET("/process/:identifier/port-ready", processHandler.HandleProcessPortReady)
+	routes.HEAD("/process/:identifier/port-ready", head)
+	routes.GET("/process/:identifier/spec", processHandler.HandleGetProcessSpec)
+	routes.HEAD("/process/:identifier/spec", head)
+	routes.DELETE("/process/:identifier", processHandler.HandleStopProcess)
+	routes.DELETE("/process/:identifier/kill", processHandler.HandleKillProcess)
+	routes.POST("/process/:identifier/pause", processHandler.HandlePauseProcess)
+	routes.POST("/process/:identifier/resume", processHandler.HandleResumeProcess)
+	routes.POST("/process/:identifier/refresh", processHandler.HandleRefreshProcess)
+	routes.GET("/process/:identifier", processHandler.HandleGetProcess)
+	routes.PATCH("/process/:identifier", processHandler.HandleUpdateProcess)
+	routes.HEAD("/process/:identifier", head)
 
 	// Network routes
-	r.GET("/network/process/:pid/ports", networkHandler.HandleGetPorts)
-	r.HEAD("/network/process/:pid/ports", head)
-	r.POST("/network/process/:pid/monitor", networkHandler.HandleMonitorPorts)
-	r.DELETE("/network/process/:pid/monitor", networkHandler.HandleStopMonitoringPorts)
+	routes.GET("/network/process/:pid/ports", networkHandler.HandleGetPorts)
+	routes.HEAD("/network/process/:pid/ports", head)
+	routes.POST("/network/process/:pid/monitor", networkHandler.HandleMonitorPorts)
+	routes.DELETE("/network/process/:pid/monitor", networkHandler.HandleStopMonitoringPorts)
 
 	// Tunnel routes (write-only, no GET to prevent config/key leakage)
-	r.PUT("/network/tunnel/config", networkHandler.HandleUpdateTunnelConfig)
-	r.DELETE("/network/tunnel", networkHandler.HandleDisconnectTunnel)
+	routes.PUT("/network/tunnel/config", networkHandler.HandleUpdateTunnelConfig)
+	routes.DELETE("/network/tunnel", networkHandler.HandleDisconnectTunnel)
 
 	// Codegen routes
-	r.PUT("/codegen/fastapply/*path", codegenHandler.HandleFastApply)
-	r.GET("/codegen/reranking/*path", codegenHandler.HandleReranking)
-	r.HEAD("/codegen/reranking/*path", head)
+	routes.PUT("/codegen/fastapply/*path", codegenHandler.HandleFastApply)
+	routes.GET("/codegen/reranking/*path", codegenHandler.HandleReranking)
+	routes.HEAD("/codegen/reranking/*path", head)
 
 	// Terminal routes (web-based terminal with PTY)
 	// Can be disabled with DISABLE_TERMINAL=true environment variable
 	if !disableTerminal {
 		terminalHandler := handler.NewTerminalHandler()
-		r.GET("/terminal", terminalHandler.HandleTerminalPage)
-		r.HEAD("/terminal", head)
-		r.GET("/terminal/ws", terminalHandler.HandleTerminalWS)
-		r.HEAD("/terminal/ws", head)
+		routes.GET("/terminal", terminalHandler.HandleTerminalPage)
+		routes.HEAD("/terminal", head)
+		routes.GET("/terminal/ws", terminalHandler.HandleTerminalWS)
+		routes.HEAD("/terminal/ws", head)
 	} else {
 		logrus.Info("Terminal endpoint disabled via DISABLE_TERMINAL environment variable")
 	}
 
 	// System routes
-	r.POST("/upgrade", systemHandler.HandleUpgrade)
-	r.HEAD("/upgrade", head)
-	r.GET("/health", systemHandler.HandleHealth)
-	r.HEAD("/health", head)
+	routes.POST("/upgrade", systemHandler.HandleUpgrade)
+	routes.HEAD("/upgrade", head)
+	routes.POST("/restart", systemHandler.HandleRestart)
+	routes.GET("/health", systemHandler.HandleHealth)
+	routes.HEAD("/health", head)
+	routes.GET("/system/tools", systemHandler.HandleListTools)
+	routes.HEAD("/system/tools", head)
+	routes.GET("/system/resources", systemHandler.HandleGetResources)
+	routes.HEAD("/system/resources", head)
+	routes.GET("/config/timezone", systemHandler.HandleGetTimezone)
+	routes.HEAD("/config/timezone", head)
+	routes.PUT("/config/timezone", systemHandler.HandleSetTimezone)
+	routes.GET("/config/ulimits", systemHandler.HandleGetUlimits)
+	routes.HEAD("/config/ulimits", head)
+	routes.PUT("/config/ulimits", systemHandler.HandleSetUlimits)
+
+	// Long operations in flight (searches, archive extraction)
+	routes.GET("/operations", operationsHandler.HandleListOperations)
+	routes.HEAD("/operations", head)
+	routes.POST("/operations/:id/cancel", operationsHandler.HandleCancelOperation)
 
 	// Debug routes (dev environment only)
 	if os.Getenv("BL_ENV") == "dev" {
-		r.GET("/debug/panic", func(c *gin.Context) {
+		routes.GET("/debug/panic", func(c *gin.Context) {
 			panic("test panic for sentry verification")
 		})
 	}
 
 	// Drive routes (for mounting/unmounting agent drives)
 	// GET /drives/mount list, POST /drives/mount attach, DELETE /drives/mount/*mountPath detach
-	r.GET("/drives/mount", driveHandler.ListMounts)
-	r.POST("/drives/mount", driveHandler.AttachDrive)
-	r.HEAD("/drives/mount", head)
-	r.DELETE("/drives/mount/*mountPath", func(c *gin.Context) {
+	routes.GET("/drives/mount", driveHandler.ListMounts)
+	routes.POST("/drives/mount", driveHandler.AttachDrive)
+	routes.HEAD("/drives/mount", head)
+	routes.DELETE("/drives/mount/*mountPath", func(c *gin.Context) {
 		mountPath := c.Param("mountPath")
 		if mountPath == "" || mountPath == "/" {
 			c.JSON(http.StatusBadRequest, handler.ErrorResponse{Error: "Mount path is required (must start with /)"})
@@ -220,12 +325,12 @@ func SetupRouter(disableRequestLogging bool, enableProcessingTime bool) *gin.Eng
 	})
 
 	// Root welcome endpoint - handles all HTTP methods
-	r.GET("/", baseHandler.HandleWelcome)
-	r.POST("/", baseHandler.HandleWelcome)
-	r.PUT("/", baseHandler.HandleWelcome)
-	r.DELETE("/", baseHandler.HandleWelcome)
-	r.PATCH("/", baseHandler.HandleWelcome)
-	r.OPTIONS("/", baseHandler.HandleWelcome)
+	routes.GET("/", baseHandler.HandleWelcome)
+	routes.POST("/", baseHandler.HandleWelcome)
+	routes.PUT("/", baseHandler.HandleWelcome)
+	routes.DELETE("/", baseHandler.HandleWelcome)
+	routes.PATCH("/", baseHandler.HandleWelcome)
+	routes.OPTIONS("/", baseHandler.HandleWelcome)
 
 	return r
 }
@@ -236,7 +341,7 @@ func SetupRouter(disableRequestLogging bool, enableProcessingTime bool) *gin.Eng
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, HEAD, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if c.Request.Method == "OPTIONS" {
@@ -1,7 +1,26 @@
 package api
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler"
+	"github.com/blaxel-ai/sandbox-api/src/handler/filesystem"
+	"github.com/blaxel-ai/sandbox-api/src/lib/auth"
+	"github.com/blaxel-ai/sandbox-api/src/lib/operations"
 )
 
 func TestRedactSecrets(t *testing.T) {
@@ -217,3 +236,677 @@ func TestRedactSecretsHandlesSpecialCharacters(t *testing.T) {
 		})
 	}
 }
+
+func TestActivityTrackerInFlightRequestsAreNeverIdle(t *testing.T) {
+	tracker := NewActivityTracker()
+	tracker.lastActivity = tracker.lastActivity.Add(-time.Hour)
+	if tracker.IdleFor() < time.Hour {
+		t.Fatalf("Expected tracker to be idle for at least an hour, got %s", tracker.IdleFor())
+	}
+
+	tracker.begin()
+	if tracker.IdleFor() != 0 {
+		t.Errorf("Expected in-flight request to keep tracker busy, got idle for %s", tracker.IdleFor())
+	}
+	tracker.end()
+
+	if tracker.ActiveRequests() != 0 {
+		t.Errorf("Expected no active requests, got %d", tracker.ActiveRequests())
+	}
+	if tracker.IdleFor() > time.Second {
+		t.Errorf("Expected idle timer to be reset after request, got %s", tracker.IdleFor())
+	}
+}
+
+func TestNormalizeRoutePrefix(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"/":           "",
+		"sandbox":     "/sandbox",
+		"/sandbox/":   "/sandbox",
+		" /a/b ":      "/a/b",
+		"//sandbox//": "/sandbox",
+	}
+	for input, want := range tests {
+		if got := normalizeRoutePrefix(input); got != want {
+			t.Errorf("normalizeRoutePrefix(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRoutePrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := RoutePrefix
+	RoutePrefix = "/sandbox"
+	defer func() { RoutePrefix = previous }()
+
+	r := SetupRouter(true, false)
+	dir := url.PathEscape(t.TempDir())
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/sandbox/health", http.StatusOK},
+		{http.MethodGet, "/health", http.StatusNotFound},
+		{http.MethodGet, "/sandbox/filesystem/tree/" + dir, http.StatusOK},
+		{http.MethodGet, "/filesystem/tree/" + dir, http.StatusNotFound},
+		{http.MethodGet, "/sandbox/filesystem/" + dir, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d (%s)", tt.method, tt.path, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := RoutePrefix
+	RoutePrefix = "/sandbox"
+	defer func() { RoutePrefix = previous }()
+
+	r := SetupRouter(true, false)
+	tests := []struct {
+		headers                map[string]string
+		host, scheme, basePath string
+	}{
+		{nil, "example.com", "http", "/sandbox"},
+		{map[string]string{
+			"X-Forwarded-Host":   "run.example.com, proxy.internal",
+			"X-Forwarded-Proto":  "https",
+			"X-Forwarded-Prefix": "/ws/sandboxes/my-sandbox/",
+		}, "run.example.com", "https", "/ws/sandboxes/my-sandbox/sandbox"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/sandbox/openapi.json", nil)
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d (%s)", w.Code, w.Body.String())
+		}
+
+		var spec struct {
+			Host     string         `json:"host"`
+			BasePath string         `json:"basePath"`
+			Schemes  []string       `json:"schemes"`
+			Paths    map[string]any `json:"paths"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+			t.Fatalf("Failed to decode spec: %v", err)
+		}
+		if spec.Host != tt.host || spec.BasePath != tt.basePath || len(spec.Schemes) != 1 || spec.Schemes[0] != tt.scheme {
+			t.Errorf("Expected %s://%s%s, got %+v %s %s", tt.scheme, tt.host, tt.basePath, spec.Schemes, spec.Host, spec.BasePath)
+		}
+		if len(spec.Paths) == 0 {
+			t.Error("Expected the spec to list paths")
+		}
+	}
+}
+
+func TestScopeMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := auth.Secret
+	auth.Secret = "test-secret"
+	defer func() { auth.Secret = previous }()
+
+	readOnly, err := auth.Issue(auth.Secret, []string{"filesystem:read"}, 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	forged, _ := auth.Issue("other-secret", []string{"*"}, 0)
+
+	r := SetupRouter(true, false)
+	dir := url.PathEscape(t.TempDir())
+	tests := []struct {
+		method string
+		path   string
+		header string
+		token  string
+		want   int
+	}{
+		{http.MethodGet, "/health", "", "", http.StatusOK},
+		{http.MethodGet, "/filesystem/" + dir, "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/filesystem/" + dir, "Authorization", "Bearer " + forged, http.StatusUnauthorized},
+		{http.MethodGet, "/filesystem/" + dir, "Authorization", "Bearer " + readOnly, http.StatusOK},
+		{http.MethodGet, "/filesystem/" + dir, TokenHeader, readOnly, http.StatusOK},
+		{http.MethodGet, "/filesystem/tree/" + dir, TokenHeader, readOnly, http.StatusOK},
+		{http.MethodDelete, "/filesystem/" + dir, TokenHeader, readOnly, http.StatusForbidden},
+		{http.MethodGet, "/process", TokenHeader, readOnly, http.StatusForbidden},
+		{http.MethodPost, "/upgrade", TokenHeader, readOnly, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.token)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s (%s): expected %d, got %d (%s)", tt.method, tt.path, tt.header, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupRouter(true, false) // Registers the read-only POST routes
+
+	tests := []struct {
+		method, path, area, action string
+		needed                     bool
+	}{
+		{http.MethodGet, "/", "", "", false},
+		{http.MethodGet, "/swagger/index.html", "", "", false},
+		{http.MethodOptions, "/process", "", "", false},
+		{http.MethodHead, "/watch/filesystem/tmp", "filesystem", auth.ActionRead, true},
+		{http.MethodGet, "/filesystem-resolve/src", "filesystem", auth.ActionRead, true},
+		{http.MethodPut, "/filesystem-xattr/src", "filesystem", auth.ActionWrite, true},
+		{http.MethodGet, "/filesystem-snapshots/src", "filesystem", auth.ActionRead, true},
+		{http.MethodGet, "/filesystem-follow/app.log", "filesystem", auth.ActionRead, true},
+		{http.MethodPost, "/filesystem-untar/src", "filesystem", auth.ActionWrite, true},
+		{http.MethodPost, "/filesystem-restore/src", "filesystem", auth.ActionWrite, true},
+		{http.MethodPost, "/process", "process", auth.ActionWrite, true},
+		{http.MethodPost, "/filesystem/stat-batch", "filesystem", auth.ActionRead, true},
+		{http.MethodPost, "/filesystem/compare", "filesystem", auth.ActionRead, true},
+		{http.MethodPost, "/filesystem/match", "filesystem", auth.ActionRead, true},
+		{http.MethodPost, "/process/validate", "process", auth.ActionRead, true},
+		{http.MethodPost, "/process/env/preview", "process", auth.ActionRead, true},
+		{http.MethodPost, "/process/reap", "process", auth.ActionWrite, true},
+		{http.MethodGet, "//health", "system", auth.ActionRead, true},
+		{http.MethodPost, "//process", "system", auth.ActionWrite, true},
+		{http.MethodPost, "/filesystem/tmp/match", "filesystem", auth.ActionWrite, true},
+		{http.MethodPut, "/filesystem/compare", "filesystem", auth.ActionWrite, true},
+		{http.MethodGet, "/terminal/ws", "terminal", auth.ActionWrite, true},
+		{http.MethodGet, "/config/timezone", "system", auth.ActionRead, true},
+	}
+	for _, tt := range tests {
+		area, action, needed := requiredScope(tt.method, tt.path)
+		if area != tt.area || action != tt.action || needed != tt.needed {
+			t.Errorf("requiredScope(%s, %s) = %s, %s, %v", tt.method, tt.path, area, action, needed)
+		}
+	}
+}
+
+func TestXattrRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRouter(true, false)
+	dir := t.TempDir()
+	file := url.PathEscape(dir + "/data.txt")
+	if err := os.WriteFile(dir+"/data.txt", []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/filesystem-xattr/"+file, strings.NewReader(`{"name":"user.comment","value":"ok"}`)))
+	if w.Code == http.StatusBadRequest && strings.Contains(w.Body.String(), "not supported") {
+		t.Skip("extended attributes are not supported here")
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the attribute to be set, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/filesystem-xattr/"+file, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"user.comment":"ok"`) {
+		t.Errorf("Expected the attribute to be listed, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	// Files named xattr are regular files
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/filesystem/"+url.PathEscape(dir)+"/xattr", strings.NewReader(`{"content":"file"}`)))
+	if content, err := os.ReadFile(dir + "/xattr"); err != nil || string(content) != "file" {
+		t.Errorf("Expected a file named xattr to be written, got %d (%s), %q (%v)", w.Code, w.Body.String(), content, err)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/filesystem/"+url.PathEscape(dir)+"/xattr", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "file") {
+		t.Errorf("Expected the file named xattr to be read, got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestSnapshotRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(dir string) { filesystem.SnapshotDir = dir }(filesystem.SnapshotDir)
+	filesystem.SnapshotDir = t.TempDir()
+	r := SetupRouter(true, false)
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/data.txt", []byte("v1"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/filesystem-snapshots/"+url.PathEscape(dir)+"?name=v1", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"v1"`) {
+		t.Fatalf("Expected the snapshot to be created, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	if err := os.WriteFile(dir+"/data.txt", []byte("v2"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/filesystem-restore/"+url.PathEscape(dir)+"?snapshot=v1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the snapshot to be restored, got %d (%s)", w.Code, w.Body.String())
+	}
+	if content, err := os.ReadFile(dir + "/data.txt"); err != nil || string(content) != "v1" {
+		t.Errorf("Expected the file to be rolled back, got %q (%v)", content, err)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/filesystem-snapshots/"+url.PathEscape(dir), nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"v1"`) {
+		t.Errorf("Expected the snapshot to be listed, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/filesystem-restore/"+url.PathEscape(dir)+"?snapshot=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown snapshot, got %d (%s)", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/filesystem-snapshots/"+url.PathEscape(dir+"/data.txt"), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 when snapshotting a file, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	// Files named snapshots are regular files
+	if err := os.WriteFile(dir+"/snapshots", []byte("file"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/filesystem/"+url.PathEscape(dir)+"/snapshots", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"content":"file"`) {
+		t.Errorf("Expected the file named snapshots to be read, got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestFilesNamedLikeActions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRouter(true, false)
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/follow", []byte("followed"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/filesystem/"+url.PathEscape(dir)+"/follow", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"content":"followed"`) {
+		t.Errorf("Expected the file named follow to be read, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	for _, name := range []string{"render", "untar", "swap"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/filesystem/"+url.PathEscape(dir)+"/"+name, strings.NewReader(`{"content":"file"}`)))
+		if content, err := os.ReadFile(dir + "/" + name); err != nil || string(content) != "file" {
+			t.Errorf("Expected a file named %s to be written, got %d (%s), %q (%v)", name, w.Code, w.Body.String(), content, err)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/filesystem-follow/"+url.PathEscape(dir), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 when following a directory, got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestDedupeRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRouter(true, false)
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(dir+"/"+name, []byte("same"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/filesystem-dedupe/"+url.PathEscape(dir), nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"bytesReclaimed":4`) {
+		t.Fatalf("Expected one duplicate to be linked, got %d (%s)", w.Code, w.Body.String())
+	}
+	if w.Header().Get(handler.OperationIDHeader) == "" {
+		t.Error("Expected the operation ID in the response headers")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/filesystem-dedupe/"+url.PathEscape(dir+"/missing"), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing directory, got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestRenderRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRouter(true, false)
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/config.tmpl", []byte("port: {{.port}}"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	render := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/filesystem-render/"+url.PathEscape(dir+"/config.tmpl"), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := render(`{"variables": {"port": 3000}}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"content":"port: 3000"`) {
+		t.Fatalf("Expected the rendered content, got %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = render(`{"variables": {"port": 3000}, "destination": "` + dir + `/config.yaml"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d (%s)", w.Code, w.Body.String())
+	}
+	if content, err := os.ReadFile(dir + "/config.yaml"); err != nil || string(content) != "port: 3000" {
+		t.Errorf("Expected the rendered destination, got %q (%v)", content, err)
+	}
+
+	if w = render(`{"strict": true}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a missing variable in strict mode, got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestUnpackRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRouter(true, false)
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "hello.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5})
+	_, _ = tw.Write([]byte("hello"))
+	_ = tw.Close()
+	if err := os.WriteFile(dir+"/release.bin", buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	unpack := func(target string, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/filesystem-unpack/"+url.PathEscape(target)+query, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := unpack(dir+"/release.bin", "?destination="+url.QueryEscape(dir+"/out"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d (%s)", w.Code, w.Body.String())
+	}
+	var response handler.UnpackResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Format != "tar" || response.Files != 1 {
+		t.Errorf("Expected one file unpacked from a tar, got %s (%v)", w.Body.String(), err)
+	}
+	if content, err := os.ReadFile(dir + "/out/hello.txt"); err != nil || string(content) != "hello" {
+		t.Errorf("Expected the extracted file, got %q (%v)", content, err)
+	}
+
+	if w = unpack(dir+"/missing.zip", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing archive, got %d (%s)", w.Code, w.Body.String())
+	}
+	if err := os.WriteFile(dir+"/notes.txt", []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if w = unpack(dir+"/notes.txt", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d (%s)", w.Code, w.Body.String())
+	}
+}
+
+func TestWatchSettle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(SetupRouter(true, false))
+	defer server.Close()
+	dir := t.TempDir()
+
+	// Headers are only sent with the first event, so the stream is read in the background.
+	// It is canceled before the server is closed, which waits for open streams.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := make(chan string, 10)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch/filesystem/"+url.PathEscape(dir)+"?settleMs=200", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			close(lines)
+			return
+		}
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(dir+"/"+name, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, `"op":"SETTLED"`) {
+			t.Fatalf("Expected a SETTLED event first, got %s", line)
+		}
+		for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+			if !strings.Contains(line, name) {
+				t.Errorf("Expected %s in the settled paths, got %s", name, line)
+			}
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for the SETTLED event")
+	}
+
+	resp, err := http.Get(server.URL + "/watch/filesystem/" + url.PathEscape(dir) + "?settleMs=-1")
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid settleMs, got %d", resp.StatusCode)
+	}
+}
+
+func TestWatchBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(SetupRouter(true, false))
+	defer server.Close()
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := make(chan string, 10)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch/filesystem/"+url.PathEscape(dir)+"?batch=true&flushIntervalMs=300", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			close(lines)
+			return
+		}
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(dir+"/"+name, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	select {
+	case line := <-lines:
+		var batch handler.FileEventBatch
+		if err := json.Unmarshal([]byte(line), &batch); err != nil {
+			t.Fatalf("Expected a batch, got %s (%v)", line, err)
+		}
+		if len(batch.Events) < 2 || batch.LastSeq != uint64(len(batch.Events)) {
+			t.Fatalf("Expected the events of both files with lastSeq, got %s", line)
+		}
+		for i, event := range batch.Events {
+			if event.Seq != uint64(i+1) {
+				t.Errorf("Expected consecutive seqs starting at 1, got %s", line)
+				break
+			}
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for the batch")
+	}
+}
+
+func TestWatchMultipleDirectories(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(SetupRouter(true, false))
+	defer server.Close()
+	src, tests := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(src+"/nested", 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	query := "?path=" + url.QueryEscape(src+"/**") + "&path=" + url.QueryEscape(tests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := make(chan string, 10)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch/filesystem-multi"+query, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			close(lines)
+			return
+		}
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	if err := os.WriteFile(src+"/nested/a.txt", []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(tests+"/b.txt", []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	roots := map[string]string{}
+	deadline := time.After(3 * time.Second)
+	for len(roots) < 2 {
+		select {
+		case line := <-lines:
+			var event handler.FileEvent
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				continue
+			}
+			roots[event.Name] = event.Root
+		case <-deadline:
+			t.Fatalf("Timeout waiting for the events of both directories, got %v", roots)
+		}
+	}
+	if roots["a.txt"] != src+"/**" || roots["b.txt"] != tests {
+		t.Errorf("Expected events tagged with their root, got %v", roots)
+	}
+
+	resp, err := http.Get(server.URL + "/watch/filesystem-multi?path=" + url.QueryEscape(src) + "&path=" + url.QueryEscape(src+"/missing"))
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a missing directory, got %d", resp.StatusCode)
+	}
+}
+
+func TestOperationRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(SetupRouter(true, false))
+	defer server.Close()
+	dir := t.TempDir()
+
+	// An archive whose only file is still being uploaded keeps the extraction running
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	if err := tw.WriteHeader(&tar.Header{Name: "big.bin", Mode: 0644, Size: 1 << 20, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	if _, err := tw.Write(make([]byte, 1024)); err != nil {
+		t.Fatalf("Failed to write content: %v", err)
+	}
+
+	body, upload := io.Pipe()
+	defer upload.Close()
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(server.URL+"/filesystem-untar/"+url.PathEscape(dir), "application/x-tar", body)
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	if _, err := upload.Write(archive.Bytes()); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	var op operations.Operation
+	for deadline := time.Now().Add(5 * time.Second); op.ID == "" && time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		resp, err := http.Get(server.URL + "/operations")
+		if err != nil {
+			t.Fatalf("Failed to list operations: %v", err)
+		}
+		var list handler.OperationListResponse
+		_ = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		for _, listed := range list.Operations {
+			if listed.Type == "untar" && listed.Target == dir && listed.Progress > 0 {
+				op = listed
+			}
+		}
+	}
+	if op.ID == "" {
+		t.Fatal("Expected the extraction to be listed")
+	}
+	if op.Unit != "bytes" {
+		t.Errorf("Expected progress in bytes, got %+v", op)
+	}
+
+	resp, err := http.Post(server.URL+"/operations/"+op.ID+"/cancel", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to cancel: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 cancelling the operation, got %d", resp.StatusCode)
+	}
+
+	// The extraction stops at its next read
+	_, _ = upload.Write(make([]byte, 1024))
+	_ = upload.Close()
+	select {
+	case code := <-status:
+		if code != http.StatusConflict {
+			t.Errorf("Expected 409 for the cancelled extraction, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the cancelled extraction")
+	}
+
+	resp, err = http.Post(server.URL+"/operations/"+op.ID+"/cancel", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to cancel: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a finished operation, got %d", resp.StatusCode)
+	}
+}
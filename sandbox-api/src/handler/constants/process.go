@@ -9,4 +9,5 @@ const (
 	ProcessStatusStopped   ProcessStatus = "stopped"
 	ProcessStatusRunning   ProcessStatus = "running"
 	ProcessStatusCompleted ProcessStatus = "completed"
+	ProcessStatusQueued    ProcessStatus = "queued"
 )
@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -22,6 +27,8 @@ import (
 
 	"github.com/blaxel-ai/sandbox-api/src/handler/filesystem"
 	"github.com/blaxel-ai/sandbox-api/src/lib"
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
+	"github.com/blaxel-ai/sandbox-api/src/lib/throttle"
 )
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -31,23 +38,69 @@ type FileSystemHandler struct {
 	*BaseHandler
 	fs               *filesystem.Filesystem
 	multipartManager *filesystem.MultipartManager
+	maxInlineSize    int64
+	maxTransferRate  int64 // Server-side cap of maxBytesPerSec, 0 when transfers are not capped
 }
 
 // FileEvent represents a file event
 type FileEvent struct {
-	Op    string  `json:"op"`
-	Name  string  `json:"name"`
-	Path  string  `json:"path"`
-	Error *string `json:"error"`
+	Op      string     `json:"op"`
+	Name    string     `json:"name"`
+	Path    string     `json:"path"`
+	Error   *string    `json:"error"`
+	Size    *int64     `json:"size,omitempty"`    // Only with details=true, on CREATE and WRITE events
+	ModTime *time.Time `json:"modTime,omitempty"` // Only with details=true, on CREATE and WRITE events
+	Paths   []string   `json:"paths,omitempty"`   // Only on SETTLED events, the paths changed during the burst
+	Root    string     `json:"root,omitempty"`    // Only on multi-directory watches, the watched path the event comes from
+	Seq     uint64     `json:"seq"`               // Position of the event in the stream, starting at 1 and increased by one for each event, so that gaps show missed events. KEEPALIVE events carry the seq of the last event
+	Time    *time.Time `json:"time,omitempty"`    // Only on KEEPALIVE events, when the keepalive was sent
 } // @name FileEvent
 
+// FileEventBatch wraps the watch events of a flush interval when streaming with batch=true.
+// Keepalives are empty batches, so that lastSeq tells clients which events they should have received.
+type FileEventBatch struct {
+	Events  []FileEvent `json:"events" binding:"required"`
+	LastSeq uint64      `json:"lastSeq" binding:"required" example:"42"` // seq of the last event streamed so far, 0 before the first one
+} // @name FileEventBatch
+
+// FileEventSettled is the op of the event emitted by settled watches once a burst of changes is over
+const FileEventSettled = "SETTLED"
+
+// FileEventKeepalive is the op of the events sent when a watch stream is idle, so that
+// proxies don't close it. They don't count as events: their seq is the one of the last event.
+const FileEventKeepalive = "KEEPALIVE"
+
+// FileEventWatchError is the op of the event emitted when a subdirectory of a recursive watch
+// can't be watched: its changes are missed, error tells why
+const FileEventWatchError = "WATCH_ERROR"
+
 // FileRequest represents the request body for creating or updating a file
 type FileRequest struct {
-	Content     string `json:"content" example:"file contents here"`
-	IsDirectory bool   `json:"isDirectory" example:"false"`
-	Permissions string `json:"permissions" example:"0644"`
+	Content         string `json:"content" example:"file contents here"`
+	ContentEncoding string `json:"contentEncoding,omitempty" example:"utf8" enums:"utf8,base64"` // Encoding of content, base64 allows writing arbitrary bytes (default utf8)
+	IsDirectory     bool   `json:"isDirectory" example:"false"`
+	Type            string `json:"type,omitempty" example:"file" enums:"file,directory,fifo"` // Entry to create, fifo creates a named pipe (default file, or directory with isDirectory)
+	Permissions     string `json:"permissions" example:"0644"`
+	Owner           string `json:"owner,omitempty" example:"app"` // Overrides the default owner (name or uid)
+	Group           string `json:"group,omitempty" example:"app"` // Overrides the default group (name or gid)
 } // @name FileRequest
 
+// decodeFileContent decodes the content of a file request according to its encoding
+func decodeFileContent(content, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "utf8", "utf-8":
+		return []byte(content), nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("invalid contentEncoding '%s', must be utf8 or base64", encoding)
+	}
+}
+
 // MultipartInitiateRequest represents the request body for initiating a multipart upload
 type MultipartInitiateRequest struct {
 	Permissions string `json:"permissions" example:"0644"`
@@ -128,8 +181,11 @@ type ContentSearchResponse struct {
 
 // FindMatch represents a single find result
 type FindMatch struct {
-	Path string `json:"path" binding:"required" example:"src/main.go"`
-	Type string `json:"type" binding:"required" example:"file"` // "file" or "directory"
+	Path        string    `json:"path" binding:"required" example:"src/main.go"`
+	Type        string    `json:"type" binding:"required" example:"file"` // "file" or "directory"
+	Size        int64     `json:"size" binding:"required" example:"1024"` // 0 for directories
+	ModTime     time.Time `json:"modTime" binding:"required"`
+	Permissions string    `json:"permissions" binding:"required" example:"644"`
 } // @name FindMatch
 
 // FindResponse represents the response from find
@@ -138,8 +194,270 @@ type FindResponse struct {
 	Total   int         `json:"total" binding:"required" example:"5"`
 } // @name FindResponse
 
+// MaxStatBatchPaths is the maximum number of paths accepted by a single stat-batch request
+const MaxStatBatchPaths = 1000
+
+// StatBatchRequest represents the request body for retrieving metadata of several paths
+type StatBatchRequest struct {
+	Paths []string `json:"paths" binding:"required" example:"src/main.go,/etc/hosts"`
+} // @name StatBatchRequest
+
+// StatBatchEntry represents the metadata of a single path, or the error preventing it from being read
+type StatBatchEntry struct {
+	Path         string     `json:"path" binding:"required" example:"src/main.go"`
+	Type         string     `json:"type,omitempty" example:"file"` // "file" or "directory"
+	Size         int64      `json:"size,omitempty" example:"1024"`
+	Permissions  string     `json:"permissions,omitempty" example:"644"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Error        string     `json:"error,omitempty" example:"no such file or directory"`
+} // @name StatBatchEntry
+
+// MaxUntarSize is the maximum total size of the files extracted by a single untar request
+const MaxUntarSize = 10 * 1024 * 1024 * 1024
+
+// UntarResponse represents the result of extracting an archive
+type UntarResponse struct {
+	Path        string `json:"path" binding:"required" example:"/app"`
+	Files       int    `json:"files" binding:"required" example:"120"`
+	Directories int    `json:"directories" binding:"required" example:"14"`
+	Bytes       int64  `json:"bytes" binding:"required" example:"1048576"`
+} // @name UntarResponse
+
+// UnpackResponse represents the result of unpacking an archive of the sandbox filesystem
+type UnpackResponse struct {
+	Path        string `json:"path" binding:"required" example:"/tmp/release.tar.gz"`
+	Destination string `json:"destination" binding:"required" example:"/app"`
+	Format      string `json:"format" binding:"required" example:"tar.gz" enums:"zip,tar,tar.gz,tar.bz2"`
+	Files       int    `json:"files" binding:"required" example:"120"`
+	Directories int    `json:"directories" binding:"required" example:"14"`
+	Bytes       int64  `json:"bytes" binding:"required" example:"1048576"`
+} // @name UnpackResponse
+
+// SwapRequest is the JSON body accepted by the swap endpoint
+type SwapRequest struct {
+	Content     string `json:"content" example:"{\"version\": 2}"`
+	Permissions string `json:"permissions,omitempty" example:"0644"` // Only used when the file does not exist yet
+} // @name SwapRequest
+
+// RenderRequest is the request body for rendering a template file
+type RenderRequest struct {
+	Variables   map[string]any `json:"variables" example:"{\"port\": 3000}"`             // Data of the template: {{.port}} is replaced with variables.port
+	Destination string         `json:"destination,omitempty" example:"/app/config.yaml"` // File the result is written to (atomically), returned in content when empty
+	Strict      bool           `json:"strict,omitempty" example:"true"`                  // Fail when the template references a missing variable instead of rendering <no value>
+	Permissions string         `json:"permissions,omitempty" example:"0644"`             // Mode of the destination when it does not exist yet
+} // @name RenderRequest
+
+// RenderResponse is the result of a template render
+type RenderResponse struct {
+	Path        string `json:"path" binding:"required" example:"/app/config.yaml.tmpl"`
+	Destination string `json:"destination,omitempty" example:"/app/config.yaml"` // Set when the result was written to a file
+	Content     string `json:"content,omitempty" example:"port: 3000"`           // Rendered content, set without destination
+	Size        int    `json:"size" binding:"required" example:"10"`             // Size of the rendered content in bytes
+} // @name RenderResponse
+
+// XattrRequest is the body of the extended attribute update endpoint
+type XattrRequest struct {
+	Name     string `json:"name" binding:"required" example:"user.comment"`
+	Value    string `json:"value" example:"reviewed"`
+	Encoding string `json:"encoding,omitempty" example:"utf8" enums:"utf8,base64"` // Encoding of value, base64 allows arbitrary bytes (default utf8)
+} // @name XattrRequest
+
+// XattrResponse lists extended attributes of a path
+type XattrResponse struct {
+	Path       string            `json:"path" binding:"required" example:"/app/data.bin"`
+	Attributes map[string]string `json:"attributes" binding:"required"`
+	Encoding   string            `json:"encoding" binding:"required" example:"utf8"` // Encoding of the values
+} // @name XattrResponse
+
+// SnapshotListResponse lists the snapshots of a directory
+type SnapshotListResponse struct {
+	Path      string                `json:"path" binding:"required" example:"/app/src"`
+	Snapshots []filesystem.Snapshot `json:"snapshots" binding:"required"`
+} // @name SnapshotListResponse
+
+// MaxCompareEntries is the maximum number of differences listed by a directory comparison
+const MaxCompareEntries = 10000
+
+// CompareRequest represents the request body for comparing two directories
+type CompareRequest struct {
+	PathA      string   `json:"pathA" binding:"required" example:"/app"`
+	PathB      string   `json:"pathB" binding:"required" example:"/backup/app"`
+	Exclude    []string `json:"exclude,omitempty" example:"node_modules,*.pyc"` // Glob patterns matched against file and directory names, matching directories are skipped entirely
+	MaxEntries int      `json:"maxEntries,omitempty" example:"1000"`            // Maximum number of differences listed (default and max 10000)
+} // @name CompareRequest
+
+// CompareResponse lists the differences between two directories
+type CompareResponse struct {
+	PathA     string   `json:"pathA" binding:"required" example:"/app"`
+	PathB     string   `json:"pathB" binding:"required" example:"/backup/app"`
+	OnlyInA   []string `json:"onlyInA" binding:"required"`                   // Files only present in pathA, relative to it
+	OnlyInB   []string `json:"onlyInB" binding:"required"`                   // Files only present in pathB, relative to it
+	Different []string `json:"different" binding:"required"`                 // Files present in both whose content differs
+	Identical int      `json:"identical" binding:"required" example:"120"`   // Number of files present in both with the same content
+	Truncated bool     `json:"truncated" binding:"required" example:"false"` // Whether some differences were left out because of maxEntries
+} // @name CompareResponse
+
+// PathMatchRequest is the request body for testing a path against watch and find patterns
+type PathMatchRequest struct {
+	Path    string   `json:"path" binding:"required" example:"/app/src/index.ts"`
+	Ignore  []string `json:"ignore,omitempty" example:"node_modules,.git"` // Watch ignore patterns, matched when contained in the path
+	Include []string `json:"include,omitempty" example:"*.ts,*.js"`        // Find patterns, matched against the file name
+	Glob    string   `json:"glob,omitempty" example:"/app/**/*.ts"`        // Recursive watch path (/dir/** or /dir/**/<glob>)
+} // @name PathMatchRequest
+
+// PathMatchResponse tells which of the patterns match a path
+type PathMatchResponse struct {
+	Path        string `json:"path" binding:"required" example:"/app/src/index.ts"`
+	Matches     bool   `json:"matches" binding:"required" example:"true"`     // Whether the path is reported: not ignored, matching one of the include patterns and the glob when set
+	Ignored     bool   `json:"ignored" binding:"required" example:"false"`    // Whether an ignore pattern matches
+	IgnoredBy   string `json:"ignoredBy,omitempty" example:"node_modules"`    // First ignore pattern that matches
+	Included    bool   `json:"included" binding:"required" example:"true"`    // Whether an include pattern matches, true without include patterns
+	IncludedBy  string `json:"includedBy,omitempty" example:"*.ts"`           // First include pattern that matches
+	GlobMatched bool   `json:"globMatched" binding:"required" example:"true"` // Whether the glob matches, true without glob
+} // @name PathMatchResponse
+
+// MaxDeleteDryRunEntries is the maximum number of paths listed by a dry-run delete
+const MaxDeleteDryRunEntries = 10000
+
+// DeleteDryRunResponse describes what a delete would remove
+type DeleteDryRunResponse struct {
+	Path        string   `json:"path" binding:"required" example:"/app/build"`
+	Entries     []string `json:"entries" binding:"required"`                   // Paths that would be removed, the target included
+	Files       int      `json:"files" binding:"required" example:"42"`        // Number of files and symlinks that would be removed
+	Directories int      `json:"directories" binding:"required" example:"5"`   // Number of directories that would be removed, the target included
+	TotalSize   int64    `json:"totalSize" binding:"required" example:"1024"`  // Total size in bytes of the regular files that would be removed
+	Truncated   bool     `json:"truncated" binding:"required" example:"false"` // Whether entries was cut at MaxDeleteDryRunEntries (counts and size are always complete)
+} // @name DeleteDryRunResponse
+
+// DirectoryCountResponse represents the number of entries in a directory
+type DirectoryCountResponse struct {
+	Path  string `json:"path" binding:"required" example:"/tmp/build"`
+	Count int    `json:"count" binding:"required" example:"12"`
+} // @name DirectoryCountResponse
+
+// DefaultMaxInlineFileSize is the largest file returned inline (base64 in JSON) by GET /filesystem/{path}
+// unless SANDBOX_FS_MAX_INLINE_SIZE overrides it. Larger files must be downloaded.
+const DefaultMaxInlineFileSize = 100 * 1024 * 1024
+
+// transferRate returns the rate in bytes per second a file download or upload is throttled to:
+// the maxBytesPerSec query parameter, capped at the server-side limit, which also applies when
+// the parameter is not set. 0 means no throttling.
+func (h *FileSystemHandler) transferRate(c *gin.Context) (int64, error) {
+	rate := h.maxTransferRate
+	if value := c.Query("maxBytesPerSec"); value != "" {
+		requested, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || requested <= 0 {
+			return 0, fmt.Errorf("invalid maxBytesPerSec %q: must be a positive integer", value)
+		}
+		if rate == 0 || requested < rate {
+			rate = requested
+		}
+	}
+	return rate, nil
+}
+
+// throttledResponseWriter paces the body written to a response. It hides the ReaderFrom of
+// the underlying writer so that file downloads cannot bypass it with sendfile.
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	body io.Writer
+}
+
+func (w *throttledResponseWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+// MaxHeadTailLines is the maximum number of lines returned by a head or tail read
+const MaxHeadTailLines = 10000
+
+// FileLinesResponse represents the first or last lines of a file
+type FileLinesResponse struct {
+	Path  string   `json:"path" binding:"required" example:"/var/log/app.log"`
+	Lines []string `json:"lines" binding:"required"`
+	Count int      `json:"count" binding:"required" example:"20"`
+} // @name FileLinesResponse
+
+// StatBatchResponse represents the response from stat-batch
+type StatBatchResponse struct {
+	Entries []StatBatchEntry `json:"entries" binding:"required"`
+} // @name StatBatchResponse
+
+// MaxReplaceFiles is the maximum number of files a single replace request may touch
+const MaxReplaceFiles = 1000
+
+// MaxReplaceFileSize is the maximum size of a file that replace will rewrite
+const MaxReplaceFileSize = 10 * 1024 * 1024
+
+// ReplaceRequest represents the request body for a search and replace across files
+type ReplaceRequest struct {
+	Path          string   `json:"path" binding:"required" example:"src"`
+	FilePattern   string   `json:"filePattern,omitempty" example:"*.go"`
+	Search        string   `json:"search" binding:"required" example:"oldName"`
+	Replace       string   `json:"replace" example:"newName"`
+	CaseSensitive *bool    `json:"caseSensitive,omitempty" example:"true"`
+	DryRun        bool     `json:"dryRun,omitempty" example:"false"`
+	ExcludeDirs   []string `json:"excludeDirs,omitempty" example:"node_modules,.git"`
+} // @name ReplaceRequest
+
+// ReplaceFileResult represents the outcome of the replacement in a single file
+type ReplaceFileResult struct {
+	Path         string `json:"path" binding:"required" example:"handler/main.go"`
+	Replacements int    `json:"replacements" binding:"required" example:"3"`
+	Error        string `json:"error,omitempty" example:"file appears to be binary"`
+} // @name ReplaceFileResult
+
+// ReplaceResponse represents the response from a search and replace across files
+type ReplaceResponse struct {
+	Files             []ReplaceFileResult `json:"files" binding:"required"`
+	FilesChanged      int                 `json:"filesChanged" binding:"required" example:"2"`
+	TotalReplacements int                 `json:"totalReplacements" binding:"required" example:"5"`
+	DryRun            bool                `json:"dryRun" binding:"required" example:"false"`
+} // @name ReplaceResponse
+
 // NewFileSystemHandler creates a new filesystem handler
 func NewFileSystemHandler() *FileSystemHandler {
+	// Setup multipart uploads directory
+	uploadsDir := filepath.Join(os.TempDir(), "multipart-uploads")
+	multipartManager := filesystem.NewMultipartManager(uploadsDir)
+
+	// Load existing uploads from disk
+	if multipartManager != nil {
+		_ = multipartManager.LoadUploads()
+	}
+
+	fs := newFilesystem()
+
+	// SANDBOX_FS_MAX_INLINE_SIZE (bytes) caps the size of files read in JSON mode, 0 disables the limit
+	maxInlineSize := int64(DefaultMaxInlineFileSize)
+	if value := os.Getenv("SANDBOX_FS_MAX_INLINE_SIZE"); value != "" {
+		if size, err := strconv.ParseInt(value, 10, 64); err == nil && size >= 0 {
+			maxInlineSize = size
+		} else {
+			logrus.Warnf("Invalid SANDBOX_FS_MAX_INLINE_SIZE %q, using default of %d bytes", value, maxInlineSize)
+		}
+	}
+
+	// SANDBOX_FS_MAX_TRANSFER_RATE (bytes per second) throttles every file download and upload
+	var maxTransferRate int64
+	if value := os.Getenv("SANDBOX_FS_MAX_TRANSFER_RATE"); value != "" {
+		if rate, err := strconv.ParseInt(value, 10, 64); err == nil && rate >= 0 {
+			maxTransferRate = rate
+		} else {
+			logrus.Warnf("Invalid SANDBOX_FS_MAX_TRANSFER_RATE %q, transfers are not throttled", value)
+		}
+	}
+
+	return &FileSystemHandler{
+		BaseHandler:      NewBaseHandler(),
+		fs:               fs,
+		multipartManager: multipartManager,
+		maxInlineSize:    maxInlineSize,
+		maxTransferRate:  maxTransferRate,
+	}
+}
+
+// newFilesystem returns the filesystem the API works on, configured from the environment
+func newFilesystem() *filesystem.Filesystem {
 	// Get working directory from environment or use default
 	workingDir := os.Getenv("WORKDIR")
 	if workingDir == "" {
@@ -152,20 +470,43 @@ func NewFileSystemHandler() *FileSystemHandler {
 		}
 	}
 
-	// Setup multipart uploads directory
-	uploadsDir := filepath.Join(os.TempDir(), "multipart-uploads")
-	multipartManager := filesystem.NewMultipartManager(uploadsDir)
+	fs := filesystem.NewFilesystemWithWorkingDir("/", workingDir)
+	// SANDBOX_FS_CONFINE rejects any path that resolves (after symlinks) outside the working directory
+	if confine := os.Getenv("SANDBOX_FS_CONFINE"); confine == "true" || confine == "1" {
+		fs.Confine = true
+	}
+	// SANDBOX_FS_DEFAULT_OWNER / SANDBOX_FS_DEFAULT_GROUP own files created through the API,
+	// so that a root-run API doesn't leave root-owned files behind for a non-root app
+	fs.DefaultOwner = os.Getenv("SANDBOX_FS_DEFAULT_OWNER")
+	fs.DefaultGroup = os.Getenv("SANDBOX_FS_DEFAULT_GROUP")
+	return fs
+}
 
-	// Load existing uploads from disk
-	if multipartManager != nil {
-		_ = multipartManager.LoadUploads()
+// formatPath formats the requested path and, when confinement is enabled, rejects it with 403
+// if it escapes the working directory. It returns false once an error response has been sent.
+func (h *FileSystemHandler) formatPath(c *gin.Context, path string) (string, bool) {
+	path, err := lib.FormatPath(path)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return "", false
 	}
 
-	return &FileSystemHandler{
-		BaseHandler:      NewBaseHandler(),
-		fs:               filesystem.NewFilesystemWithWorkingDir("/", workingDir),
-		multipartManager: multipartManager,
+	if h.fs.Confine {
+		if _, err := h.fs.GetAbsolutePath(path); err != nil {
+			h.SendError(c, pathErrorStatus(err, http.StatusBadRequest), err)
+			return "", false
+		}
 	}
+
+	return path, true
+}
+
+// pathErrorStatus maps confinement violations to 403 and everything else to the fallback status
+func pathErrorStatus(err error, fallback int) int {
+	if errors.Is(err, filesystem.ErrPathOutsideRoot) {
+		return http.StatusForbidden
+	}
+	return fallback
 }
 
 // extractPathFromRequest extracts the path from the request and determines if it's relative or absolute
@@ -255,19 +596,30 @@ func (h *FileSystemHandler) DeleteFile(path string) error {
 // @Produce json,octet-stream
 // @Param path path string true "File or directory path"
 // @Param download query boolean false "Force download mode for files"
+// @Param count query boolean false "Only return the number of entries of a directory"
+// @Param head query integer false "Only return the first N lines of a file"
+// @Param tail query integer false "Only return the last N lines of a file"
+// @Param maxBytesPerSec query integer false "Throttle the download to this many bytes per second (capped by SANDBOX_FS_MAX_TRANSFER_RATE)"
+// @Param sort query string false "Sort directory entries by name, size or mtime, ascending. Sorted listings also return files and subdirectories together in entries. Unsorted by default"
+// @Param dirsFirst query boolean false "List subdirectories before files in directory listings"
+// @Param compress query string false "Return the file gzip-compressed: with Content-Encoding: gzip in download mode (range requests are not supported), or as base64 of the gzipped bytes with contentEncoding=gzip in JSON mode" Enums(gzip)
+// @Param withHash query string false "Also return the digest of the file content with this algorithm, computed from the returned bytes (JSON mode only)" Enums(md5, sha1, sha256, sha512)
 // @Success 200 {file} file "File content (download mode)"
 // @Success 200 {object} filesystem.FileWithContent "File content (JSON mode)"
 // @Success 200 {object} filesystem.Directory "Directory listing"
+// @Success 200 {object} DirectoryCountResponse "Directory entry count (count mode)"
+// @Success 200 {object} FileLinesResponse "First or last lines of a file (head/tail mode)"
+// @Failure 400 {object} ErrorResponse "Count requested on a file, invalid head/tail, invalid sort, invalid compress or invalid withHash"
 // @Failure 404 {object} ErrorResponse "File or directory not found"
+// @Failure 413 {object} ErrorResponse "File too large to be returned as JSON, use download mode"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /filesystem/{path} [get]
 func (h *FileSystemHandler) HandleGetFile(c *gin.Context) {
 	path := h.extractPathFromRequest(c)
 
-	path, err := lib.FormatPath(path)
-	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+	path, ok := h.formatPath(c, path)
+	if !ok {
 		return
 	}
 
@@ -282,6 +634,29 @@ func (h *FileSystemHandler) HandleGetFile(c *gin.Context) {
 		return
 	}
 
+	if c.Query("count") == "true" {
+		if !info.IsDir() {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("count is only supported for directories"))
+			return
+		}
+		count, err := h.fs.CountEntries(path)
+		if err != nil {
+			h.SendError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		h.SendJSON(c, http.StatusOK, DirectoryCountResponse{Path: path, Count: count})
+		return
+	}
+
+	if c.Query("head") != "" || c.Query("tail") != "" {
+		if info.IsDir() {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("head and tail are only supported for files"))
+			return
+		}
+		h.handleReadLines(c, path)
+		return
+	}
+
 	if info.IsDir() {
 		h.handleListDirectory(c, path)
 		return
@@ -295,6 +670,42 @@ func (h *FileSystemHandler) HandleGetFile(c *gin.Context) {
 	h.SendError(c, http.StatusNotFound, fmt.Errorf("file or directory not found"))
 }
 
+// handleReadLines returns the first (head) or last (tail) lines of a file
+func (h *FileSystemHandler) handleReadLines(c *gin.Context, path string) {
+	head, tail := c.Query("head"), c.Query("tail")
+	if head != "" && tail != "" {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("head and tail cannot be used together"))
+		return
+	}
+
+	param, value := "head", head
+	if tail != "" {
+		param, value = "tail", tail
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("%s must be a positive integer", param))
+		return
+	}
+	if n > MaxHeadTailLines {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("%s cannot exceed %d lines", param, MaxHeadTailLines))
+		return
+	}
+
+	var lines []string
+	if param == "tail" {
+		lines, err = h.fs.TailLines(path, n)
+	} else {
+		lines, err = h.fs.HeadLines(path, n)
+	}
+	if err != nil {
+		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error reading file: %w", err))
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, FileLinesResponse{Path: path, Lines: lines, Count: len(lines)})
+}
+
 // handleReadFile handles requests to read a file
 func (h *FileSystemHandler) handleReadFile(c *gin.Context, path string) {
 	// Check if client wants to download the file content directly
@@ -323,7 +734,30 @@ func (h *FileSystemHandler) handleReadFile(c *gin.Context, path string) {
 		wantsDownload = true
 	}
 
+	compress := c.Query("compress")
+	if err := filesystem.ValidateCompress(compress); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	withHash := c.Query("withHash")
+	if err := filesystem.ValidateHash(withHash); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	if wantsDownload {
+		if withHash != "" {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("withHash is only supported in JSON mode"))
+			return
+		}
+
+		rate, err := h.transferRate(c)
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+
 		// Stream binary content directly from disk (no memory buffering)
 		absPath, err := h.fs.GetAbsolutePath(path)
 		if err != nil {
@@ -393,24 +827,80 @@ func (h *FileSystemHandler) handleReadFile(c *gin.Context, path string) {
 
 		// Use http.ServeContent for zero-copy transfer via sendfile() syscall
 		// This transfers data directly from file descriptor to socket without user-space copying
-		http.ServeContent(c.Writer, c.Request, filename, info.ModTime(), file)
+		var w http.ResponseWriter = c.Writer
+		if rate > 0 {
+			w = &throttledResponseWriter{ResponseWriter: c.Writer, body: throttle.NewWriter(c.Writer, rate)}
+		}
+
+		// The compressed length is unknown upfront, so the gzip stream is sent without
+		// Content-Length or range support. Content-Encoding is set so that no response
+		// compression applied further down compresses it again.
+		if compress == filesystem.CompressGzip {
+			c.Header("Content-Encoding", "gzip")
+			c.Header("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+			c.Header("Vary", "Accept-Encoding")
+			c.Status(http.StatusOK)
+			if c.Request.Method == http.MethodHead {
+				return
+			}
+			if err := filesystem.CopyGzip(w, file); err != nil {
+				logrus.WithError(err).WithField("path", absPath).Warn("Failed to stream gzip-compressed file")
+			}
+			return
+		}
+
+		http.ServeContent(w, c.Request, filename, info.ModTime(), file)
 		return
 	}
 
 	// JSON mode: read entire file into memory for serialization
+	if h.maxInlineSize > 0 {
+		info, err := h.fs.Infos(path)
+		if err != nil {
+			h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error reading file: %w", err))
+			return
+		}
+		if info.Size() > h.maxInlineSize {
+			h.SendError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("file size %d exceeds the maximum of %d bytes for a JSON response, download it with Accept: application/octet-stream or ?download=true instead", info.Size(), h.maxInlineSize))
+			return
+		}
+	}
+
 	file, err := h.ReadFile(path)
 	if err != nil {
 		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error reading file: %w", err))
 		return
 	}
 
+	// Hashed from the bytes read for the response, so that the digest always matches them
+	if withHash != "" {
+		if file.Hash, err = filesystem.HashBytes(withHash, file.Content); err != nil {
+			h.SendError(c, http.StatusInternalServerError, fmt.Errorf("error hashing file: %w", err))
+			return
+		}
+		file.HashAlgorithm = withHash
+	}
+
+	if compress == filesystem.CompressGzip {
+		if file.Content, err = filesystem.GzipBytes(file.Content); err != nil {
+			h.SendError(c, http.StatusInternalServerError, fmt.Errorf("error compressing file: %w", err))
+			return
+		}
+		file.ContentEncoding = filesystem.CompressGzip
+	}
+
 	// Default behavior: return JSON response
 	h.SendJSON(c, http.StatusOK, file)
 }
 
 // handleListDirectory handles requests to list a directory
 func (h *FileSystemHandler) handleListDirectory(c *gin.Context, path string) {
-	dir, err := h.ListDirectory(path)
+	opts := filesystem.ListOptions{Sort: c.Query("sort"), DirsFirst: c.Query("dirsFirst") == "true"}
+	dir, err := h.fs.ListDirectoryWithOptions(path, opts)
+	if errors.Is(err, filesystem.ErrInvalidListSort) {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
 		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error listing directory: %w", err))
 		return
@@ -421,18 +911,28 @@ func (h *FileSystemHandler) handleListDirectory(c *gin.Context, path string) {
 
 // HandleCreateOrUpdateFile handles PUT requests to /filesystem/:path
 // @Summary Create or update a file or directory
-// @Description Create or update a file or directory
+// @Description Create or update a file or directory. New files and directories are owned by SANDBOX_FS_DEFAULT_OWNER/SANDBOX_FS_DEFAULT_GROUP when set; owner and group in the request override it. Set contentEncoding to base64 to write binary content, or type to fifo to create a named pipe (400 on platforms without named pipes). Multipart uploads (a file part, with optional permissions, owner and group fields sent before it) replace the file atomically, already carrying its final permissions and ownership; ownership changes are skipped when the API is not privileged to make them.
 // @Tags filesystem
 // @Accept json
 // @Produce json
 // @Param path path string true "File or directory path"
 // @Param request body FileRequest true "File or directory details"
+// @Param maxBytesPerSec query integer false "Throttle the upload to this many bytes per second (capped by SANDBOX_FS_MAX_TRANSFER_RATE)"
+// @Param failIfExists query boolean false "When creating a directory, fail with a 409 if the path already exists instead of succeeding. Only one of concurrent requests for the same path succeeds"
 // @Success 200 {object} SuccessResponse "Success message"
 // @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 409 {object} ErrorResponse "Directory already exists with failIfExists"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /filesystem/{path} [put]
 func (h *FileSystemHandler) HandleCreateOrUpdateFile(c *gin.Context) {
+	rate, err := h.transferRate(c)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.Request.Body = throttle.NewReadCloser(c.Request.Body, rate)
+
 	contentType := c.GetHeader("Content-Type")
 	if strings.HasPrefix(contentType, "multipart/form-data") {
 		h.HandleCreateOrUpdateBinary(c)
@@ -444,16 +944,19 @@ func (h *FileSystemHandler) HandleCreateOrUpdateFile(c *gin.Context) {
 func (h *FileSystemHandler) HandleCreateOrUpdateFileJSON(c *gin.Context) {
 	path := h.extractPathFromRequest(c)
 
-	path, err := lib.FormatPath(path)
-	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+	path, ok := h.formatPath(c, path)
+	if !ok {
 		return
 	}
 
 	var request struct {
-		Content     string `json:"content"`
-		IsDirectory bool   `json:"isDirectory"`
-		Permissions string `json:"permissions"`
+		Content         string `json:"content"`
+		ContentEncoding string `json:"contentEncoding"`
+		IsDirectory     bool   `json:"isDirectory"`
+		Type            string `json:"type"`
+		Permissions     string `json:"permissions"`
+		Owner           string `json:"owner"`
+		Group           string `json:"group"`
 	}
 
 	if err := h.BindJSON(c, &request); err != nil {
@@ -461,6 +964,32 @@ func (h *FileSystemHandler) HandleCreateOrUpdateFileJSON(c *gin.Context) {
 		return
 	}
 
+	switch request.Type {
+	case "", filesystem.FileTypeFile:
+	case "directory":
+		request.IsDirectory = true
+	case filesystem.FileTypeFIFO:
+		if request.IsDirectory {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("type fifo cannot be used with isDirectory"))
+			return
+		}
+	default:
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid type '%s', must be file, directory or fifo", request.Type))
+		return
+	}
+
+	failIfExists := c.Query("failIfExists") == "true"
+	if failIfExists && !request.IsDirectory {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("failIfExists only applies to directory creation"))
+		return
+	}
+
+	content, err := decodeFileContent(request.Content, request.ContentEncoding)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	// Parse permissions or use appropriate defaults
 	var permissions os.FileMode
 	if request.Permissions != "" {
@@ -485,30 +1014,67 @@ func (h *FileSystemHandler) HandleCreateOrUpdateFileJSON(c *gin.Context) {
 		if request.Permissions == "" {
 			permissions = 0755
 		}
-		if err := h.CreateDirectory(path, permissions); err != nil {
+		if failIfExists {
+			err = h.fs.CreateDirectoryExclusive(path, permissions)
+		} else {
+			err = h.CreateDirectory(path, permissions)
+		}
+		if errors.Is(err, filesystem.ErrDirectoryExists) {
+			h.SendError(c, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
 			h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error creating directory: %w", err))
 			return
 		}
+		if err := h.fs.Chown(path, request.Owner, request.Group); err != nil {
+			h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error setting ownership: %w", err))
+			return
+		}
 		h.SendSuccessWithPath(c, path, "Directory created successfully")
 		return
 	}
 
+	// Handle named pipe creation
+	if request.Type == filesystem.FileTypeFIFO {
+		if err := h.fs.CreateFIFO(path, permissions); err != nil {
+			if errors.Is(err, filesystem.ErrFIFOsNotSupported) {
+				h.SendError(c, http.StatusBadRequest, err)
+				return
+			}
+			h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error creating named pipe: %w", err))
+			return
+		}
+		if err := h.fs.Chown(path, request.Owner, request.Group); err != nil {
+			h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error setting ownership: %w", err))
+			return
+		}
+		h.SendSuccessWithPath(c, path, "Named pipe created successfully")
+		return
+	}
+
 	// Handle file creation/update
-	if err := h.WriteFile(path, []byte(request.Content), permissions); err != nil {
+	if err := h.WriteFile(path, content, permissions); err != nil {
 		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error writing file: %w", err))
 		return
 	}
+	if err := h.fs.Chown(path, request.Owner, request.Group); err != nil {
+		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error setting ownership: %w", err))
+		return
+	}
 
 	h.SendSuccessWithPath(c, path, "File created/updated successfully")
 }
 
+// HandleCreateOrUpdateBinary handles multipart PUT requests to /filesystem/:path. The file is
+// written atomically with the permissions, owner and group fields sent before the file part,
+// so it never appears with other ones. Owner and group sent after it are applied once written.
 func (h *FileSystemHandler) HandleCreateOrUpdateBinary(c *gin.Context) {
 	// Get path from form data
 	path := h.extractPathFromRequest(c)
 
-	path, err := lib.FormatPath(path)
-	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+	path, ok := h.formatPath(c, path)
+	if !ok {
 		return
 	}
 
@@ -519,7 +1085,7 @@ func (h *FileSystemHandler) HandleCreateOrUpdateBinary(c *gin.Context) {
 		return
 	}
 
-	var permissions os.FileMode = 0644
+	opts := filesystem.WriteOptions{Perm: 0644}
 	var wroteFile bool
 
 	for {
@@ -544,20 +1110,33 @@ func (h *FileSystemHandler) HandleCreateOrUpdateBinary(c *gin.Context) {
 					h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid permissions format '%s': %w", strings.TrimSpace(string(data)), perr))
 					return
 				}
-				permissions = os.FileMode(permInt)
+				opts.Perm = os.FileMode(permInt)
+				opts.ForcePerm = true
+			}
+			_ = part.Close()
+			continue
+		}
+
+		if (name == "owner" || name == "group") && filename == "" {
+			data, _ := io.ReadAll(part)
+			if name == "owner" {
+				opts.Owner = strings.TrimSpace(string(data))
+			} else {
+				opts.Group = strings.TrimSpace(string(data))
 			}
 			_ = part.Close()
 			continue
 		}
 
 		if name == "file" && filename != "" && !wroteFile {
-			// Stream directly to disk with requested permissions
-			if err := h.fs.WriteFileFromReader(path, part, permissions); err != nil {
+			// Stream to a temporary file given its final permissions and ownership, then rename it
+			if err := h.fs.WriteFileAtomicWithOptions(path, part, opts); err != nil {
 				_ = part.Close()
 				h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error writing binary file: %w", err))
 				return
 			}
 			wroteFile = true
+			opts = filesystem.WriteOptions{}
 			_ = part.Close()
 			continue
 		}
@@ -571,32 +1150,52 @@ func (h *FileSystemHandler) HandleCreateOrUpdateBinary(c *gin.Context) {
 		return
 	}
 
+	// Ownership fields sent after the file part
+	if err := h.fs.Chown(path, opts.Owner, opts.Group); err != nil {
+		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error setting ownership: %w", err))
+		return
+	}
+
 	h.SendSuccessWithPath(c, path, "Binary file uploaded successfully")
 }
 
 // HandleDeleteFileOrDirectory handles DELETE requests to /filesystem/:path
 // @Summary Delete file or directory
-// @Description Delete a file or directory
+// @Description Delete a file or directory. With dryRun=true, nothing is deleted and the response lists what would be removed.
 // @Tags filesystem
 // @Accept json
 // @Produce json
 // @Param path path string true "File or directory path"
 // @Param recursive query boolean false "Delete directory recursively"
+// @Param emptyOnly query boolean false "Only delete the directory if it is empty (cannot be combined with recursive)"
+// @Param dryRun query boolean false "Report what would be removed (paths, counts and size) without deleting anything"
 // @Success 200 {object} SuccessResponse "Success message"
+// @Success 200 {object} DeleteDryRunResponse "What would be removed (dry run)"
+// @Failure 400 {object} ErrorResponse "Invalid combination of parameters"
 // @Failure 404 {object} ErrorResponse "File or directory not found"
+// @Failure 409 {object} ErrorResponse "Directory is not empty"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /filesystem/{path} [delete]
 func (h *FileSystemHandler) HandleDeleteFile(c *gin.Context) {
 	path := h.extractPathFromRequest(c)
 
-	path, err := lib.FormatPath(path)
-	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+	path, ok := h.formatPath(c, path)
+	if !ok {
 		return
 	}
 
 	recursive := c.Query("recursive")
+	emptyOnly := c.Query("emptyOnly") == "true"
+	if emptyOnly && recursive == "true" {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("emptyOnly cannot be combined with recursive"))
+		return
+	}
+
+	if c.Query("dryRun") == "true" {
+		h.handleDeleteDryRun(c, path, recursive == "true", emptyOnly)
+		return
+	}
 
 	// Check if it's a directory
 	isDir, err := h.DirectoryExists(path)
@@ -608,6 +1207,10 @@ func (h *FileSystemHandler) HandleDeleteFile(c *gin.Context) {
 	if isDir {
 		// Delete directory
 		err := h.DeleteDirectory(path, recursive == "true")
+		if errors.Is(err, filesystem.ErrDirectoryNotEmpty) {
+			h.SendError(c, http.StatusConflict, err)
+			return
+		}
 		if err != nil {
 			h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("error deleting directory: %w", err))
 			return
@@ -624,6 +1227,11 @@ func (h *FileSystemHandler) HandleDeleteFile(c *gin.Context) {
 	}
 
 	if isFile {
+		if emptyOnly {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("emptyOnly only applies to directories"))
+			return
+		}
+
 		// Delete file
 		err := h.DeleteFile(path)
 		if err != nil {
@@ -637,38 +1245,146 @@ func (h *FileSystemHandler) HandleDeleteFile(c *gin.Context) {
 	h.SendError(c, http.StatusNotFound, fmt.Errorf("file or directory not found"))
 }
 
-// HandleGetTree handles GET requests for directory trees
-// @Summary Get directory tree
-// @Description Get a recursive directory tree structure starting from the specified path
+// handleDeleteDryRun reports what a delete would remove, without deleting anything
+func (h *FileSystemHandler) handleDeleteDryRun(c *gin.Context, path string, recursive bool, emptyOnly bool) {
+	if emptyOnly {
+		isDir, err := h.DirectoryExists(path)
+		if err != nil {
+			h.SendError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if !isDir {
+			if _, err := h.fs.Infos(path); os.IsNotExist(err) {
+				h.SendError(c, http.StatusNotFound, fmt.Errorf("file or directory not found"))
+				return
+			}
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("emptyOnly only applies to directories"))
+			return
+		}
+	}
+
+	plan, err := h.fs.PlanDelete(path, recursive, MaxDeleteDryRunEntries)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.SendError(c, http.StatusNotFound, fmt.Errorf("file or directory not found"))
+			return
+		}
+		if errors.Is(err, filesystem.ErrDirectoryNotEmpty) {
+			h.SendError(c, http.StatusConflict, err)
+			return
+		}
+		h.SendError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, DeleteDryRunResponse{
+		Path:        path,
+		Entries:     plan.Entries,
+		Files:       plan.Files,
+		Directories: plan.Directories,
+		TotalSize:   plan.Size,
+		Truncated:   plan.Truncated,
+	})
+}
+
+// HandlePatchFile handles PATCH requests to apply a JSON patch to a file
+// @Summary Apply a JSON patch to a file
+// @Description Apply an RFC 6902 JSON patch to a JSON file and write it back indented with two spaces. Object keys keep their original order. The request Content-Type must be application/json-patch+json.
 // @Tags filesystem
-// @Accept json
+// @Accept application/json-patch+json
 // @Produce json
-// @Param path path string true "Root directory path"
-// @Success 200 {object} filesystem.Directory "Directory tree"
-// @Failure 400 {object} ErrorResponse "Bad request"
-// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Param path path string true "File path"
+// @Param patch body []object true "RFC 6902 patch operations"
+// @Success 200 {object} SuccessResponse "Success message"
+// @Failure 400 {object} ErrorResponse "Invalid patch document"
+// @Failure 404 {object} ErrorResponse "File not found"
+// @Failure 409 {object} ErrorResponse "Patch cannot be applied to the file"
+// @Failure 413 {object} ErrorResponse "Patch larger than 1 MiB"
+// @Failure 415 {object} ErrorResponse "Unsupported content type"
+// @Failure 422 {object} ErrorResponse "File is not valid JSON"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /filesystem/tree/{path} [get]
-func (h *FileSystemHandler) HandleGetTree(c *gin.Context) {
-	rootPath, exists := c.Get("rootPath")
-	if !exists {
-		// Fallback to path param if not set in context
-		rootPath = c.Param("path")
+// @Router /filesystem/{path} [patch]
+func (h *FileSystemHandler) HandlePatchFile(c *gin.Context) {
+	if c.ContentType() != "application/json-patch+json" {
+		h.SendError(c, http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/json-patch+json"))
+		return
 	}
 
-	// Convert to string
-	rootPathStr, ok := rootPath.(string)
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
 	if !ok {
-		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("invalid path parameter"))
 		return
 	}
 
-	rootPathStr, err := lib.FormatPath(rootPathStr)
+	isFile, err := h.FileExists(path)
 	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+		h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
 		return
 	}
-
+	if !isFile {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("file not found"))
+		return
+	}
+
+	patch, err := io.ReadAll(io.LimitReader(c.Request.Body, filesystem.MaxJSONPatchSize+1))
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("error reading patch: %w", err))
+		return
+	}
+	if len(patch) > filesystem.MaxJSONPatchSize {
+		h.SendError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("patch exceeds the maximum of %d bytes", filesystem.MaxJSONPatchSize))
+		return
+	}
+
+	if err := h.fs.PatchJSONFile(path, patch); err != nil {
+		switch {
+		case errors.Is(err, filesystem.ErrInvalidJSONPatch):
+			h.SendError(c, http.StatusBadRequest, err)
+		case errors.Is(err, filesystem.ErrInvalidJSONDocument):
+			h.SendError(c, http.StatusUnprocessableEntity, err)
+		case errors.Is(err, filesystem.ErrJSONPatchConflict):
+			h.SendError(c, http.StatusConflict, err)
+		default:
+			h.SendError(c, http.StatusInternalServerError, fmt.Errorf("error patching file: %w", err))
+		}
+		return
+	}
+
+	h.SendSuccessWithPath(c, path, "File patched successfully")
+}
+
+// HandleGetTree handles GET requests for directory trees
+// @Summary Get directory tree
+// @Description Get a recursive directory tree structure starting from the specified path
+// @Tags filesystem
+// @Accept json
+// @Produce json
+// @Param path path string true "Root directory path"
+// @Success 200 {object} filesystem.Directory "Directory tree"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /filesystem/tree/{path} [get]
+func (h *FileSystemHandler) HandleGetTree(c *gin.Context) {
+	rootPath, exists := c.Get("rootPath")
+	if !exists {
+		// Fallback to path param if not set in context
+		rootPath = c.Param("path")
+	}
+
+	// Convert to string
+	rootPathStr, ok := rootPath.(string)
+	if !ok {
+		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("invalid path parameter"))
+		return
+	}
+
+	rootPathStr, ok = h.formatPath(c, rootPathStr)
+	if !ok {
+		return
+	}
+
 	// Check if path exists and is a directory
 	isDir, err := h.DirectoryExists(rootPathStr)
 	if err != nil {
@@ -722,9 +1438,8 @@ func (h *FileSystemHandler) HandleCreateOrUpdateTree(c *gin.Context) {
 		h.SendError(c, http.StatusUnprocessableEntity, fmt.Errorf("invalid path parameter"))
 		return
 	}
-	rootPathStr, err := lib.FormatPath(rootPathStr)
-	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+	rootPathStr, ok = h.formatPath(c, rootPathStr)
+	if !ok {
 		return
 	}
 
@@ -845,9 +1560,8 @@ func (h *FileSystemHandler) HandleInitiateMultipartUpload(c *gin.Context) {
 	}
 
 	path := h.extractPathFromRequest(c)
-	path, err := lib.FormatPath(path)
-	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+	path, ok := h.formatPath(c, path)
+	if !ok {
 		return
 	}
 
@@ -894,6 +1608,7 @@ func (h *FileSystemHandler) HandleInitiateMultipartUpload(c *gin.Context) {
 // @Param uploadId path string true "Upload ID"
 // @Param partNumber query int true "Part number (1-10000)"
 // @Param file formData file true "Part data"
+// @Param maxBytesPerSec query integer false "Throttle the upload to this many bytes per second (capped by SANDBOX_FS_MAX_TRANSFER_RATE)"
 // @Success 200 {object} MultipartUploadPartResponse "Part uploaded"
 // @Failure 400 {object} ErrorResponse "Bad request"
 // @Failure 404 {object} ErrorResponse "Upload not found"
@@ -923,6 +1638,13 @@ func (h *FileSystemHandler) HandleUploadPart(c *gin.Context) {
 		return
 	}
 
+	rate, err := h.transferRate(c)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.Request.Body = throttle.NewReadCloser(c.Request.Body, rate)
+
 	// Use streaming multipart reader
 	mr, err := c.Request.MultipartReader()
 	if err != nil {
@@ -1121,26 +1843,155 @@ func (h *FileSystemHandler) HandleListMultipartUploads(c *gin.Context) {
 	h.SendJSON(c, http.StatusOK, response)
 }
 
+// newFileEvent maps an fsnotify event to the FileEvent streamed to watchers.
+// Every op is reported, including CHMOD for permission and metadata changes.
+func newFileEvent(event fsnotify.Event, details bool) FileEvent {
+	segments := strings.Split(event.Name, "/")
+	msg := FileEvent{
+		Op:    event.Op.String(),
+		Name:  segments[len(segments)-1],
+		Path:  strings.Join(segments[:len(segments)-1], "/"),
+		Error: nil,
+	}
+	if details {
+		addFileEventDetails(&msg, event)
+	}
+	return msg
+}
+
+// addFileEventDetails stats the file of a CREATE or WRITE event to report its
+// size and modification time. Nothing is added if the file is already gone.
+func addFileEventDetails(msg *FileEvent, event fsnotify.Event) {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return
+	}
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return
+	}
+	size := info.Size()
+	modTime := info.ModTime()
+	msg.Size = &size
+	msg.ModTime = &modTime
+}
+
 // HandleWatchDirectory streams file modification events for a directory
 // @Summary Stream file modification events in a directory
-// @Description Streams the path of modified files (one per line) in the given directory. Closes when the client disconnects.
+// @Description Streams the path of modified files (one per line) in the given directory, with the op that modified them (CREATE, WRITE, REMOVE, RENAME or CHMOD). Closes when the client disconnects, or after a [reconnect] line when the API shuts down or upgrades. A path ending with /** watches subdirectories too; it can be followed by a glob (e.g. /src/**/*.ts) to only stream events whose path matches it, at any depth. A subdirectory that can't be watched (permissions, inotify watch limit) is reported with a WATCH_ERROR event holding the error: changes below it are missed. With settleMs, events are not streamed one by one: a single SETTLED event listing the changed paths is sent once no event happened for settleMs, e.g. to rebuild once a compiler is done writing. Every event has a seq, increased by one for each event of the stream, so that clients can tell they missed some; with batch=true, events are grouped in batches whose lastSeq is also sent by keepalives. Keepalives are sent every 30 seconds, or every keepaliveSec: KEEPALIVE events holding the time they were sent and the seq of the last event, or empty batches with batch=true.
 // @Tags filesystem
 // @Produce plain
 // @Param ignore query string false "Ignore patterns (comma-separated)"
-// @Param path path string true "Directory path to watch"
+// @Param details query boolean false "Include size and modTime in CREATE and WRITE events"
+// @Param flushIntervalMs query integer false "Batch events and flush at most once per interval (max 10000), 0 flushes every event"
+// @Param settleMs query integer false "Emit a single SETTLED event with the changed paths once no event happened for this long (max 60000), 0 streams every event"
+// @Param batch query boolean false "Stream FileEventBatch lines wrapping the events of each flush interval (100ms when flushIntervalMs is 0), keepalives being empty batches with the last seq"
+// @Param keepaliveSec query integer false "Send a keepalive every this many seconds (default 30, min 5, max 600)"
+// @Param path path string true "Directory path to watch, optionally followed by /** or /**/<glob>"
 // @Success 200 {string} string "Stream of modified file paths, one per line"
 // @Failure 400 {object} ErrorResponse "Invalid path"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Too many concurrent streaming connections, retry after Retry-After seconds"
 // @Router /watch/filesystem/{path} [get]
 func (h *FileSystemHandler) HandleWatchDirectory(c *gin.Context) {
 	path := h.extractPathFromRequest(c)
 
-	path, err := lib.FormatPath(path)
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	root, status, err := h.resolveWatchRoot(path)
 	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+		h.SendError(c, status, err)
+		return
+	}
+
+	h.streamWatch(c, []*watchRoot{root}, false)
+}
+
+// MaxWatchRoots is the maximum number of directories watched by a single multi-watch stream
+const MaxWatchRoots = 20
+
+// HandleWatchDirectories streams file modification events for several directories
+// @Summary Stream file modification events in several directories
+// @Description Streams the events of several directories in a single connection, like the single directory watch, keepalives included. Each path query parameter is a directory, optionally followed by /** or /**/<glob>; every event has the root it came from, as given in path. Up to 20 paths.
+// @Tags filesystem
+// @Produce plain
+// @Param path query []string true "Directory paths to watch, optionally followed by /** or /**/<glob> (repeat the parameter)" collectionFormat(multi)
+// @Param ignore query string false "Ignore patterns (comma-separated)"
+// @Param details query boolean false "Include size and modTime in CREATE and WRITE events"
+// @Param flushIntervalMs query integer false "Batch events and flush at most once per interval (max 10000), 0 flushes every event"
+// @Param settleMs query integer false "Emit a single SETTLED event with the changed paths of every root once no event happened for this long (max 60000), 0 streams every event"
+// @Param batch query boolean false "Stream FileEventBatch lines wrapping the events of each flush interval (100ms when flushIntervalMs is 0), keepalives being empty batches with the last seq"
+// @Param keepaliveSec query integer false "Send a keepalive every this many seconds (default 30, min 5, max 600)"
+// @Success 200 {string} string "Stream of modified file paths, one per line"
+// @Failure 400 {object} ErrorResponse "Invalid path"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Too many concurrent streaming connections, retry after Retry-After seconds"
+// @Router /watch/filesystem-multi [get]
+func (h *FileSystemHandler) HandleWatchDirectories(c *gin.Context) {
+	paths := c.QueryArray("path")
+	if len(paths) == 0 {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("at least one path is required"))
 		return
 	}
+	if len(paths) > MaxWatchRoots {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("too many paths: %d (max %d)", len(paths), MaxWatchRoots))
+		return
+	}
+
+	roots := make([]*watchRoot, 0, len(paths))
+	for _, path := range paths {
+		formatted, ok := h.formatPath(c, path)
+		if !ok {
+			return
+		}
+		root, status, err := h.resolveWatchRoot(formatted)
+		if err != nil {
+			h.SendError(c, status, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		root.name = path
+		roots = append(roots, root)
+	}
+
+	h.streamWatch(c, roots, true)
+}
+
+// watchRoot is a directory watched by a watch stream
+type watchRoot struct {
+	name      string // Path as requested, reported in the root field of multi-watch events
+	dir       string
+	glob      *filesystem.WatchGlob // Filters the events of /dir/**/<glob> watches
+	recursive bool
+}
+
+// resolveWatchRoot parses a formatted watch path, optionally ending with /** or /**/<glob>,
+// and checks that its directory exists. It returns the status to respond with on error.
+func (h *FileSystemHandler) resolveWatchRoot(path string) (*watchRoot, int, error) {
+	dir, globPattern, recursive := filesystem.SplitRecursiveWatchPath(path)
+	root := &watchRoot{name: path, dir: dir, recursive: recursive}
+	if globPattern != "" {
+		glob, err := h.fs.NewWatchGlob(dir, globPattern)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		root.glob = glob
+	}
+
+	isDir, err := h.DirectoryExists(dir)
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	if !isDir {
+		return nil, http.StatusBadRequest, fmt.Errorf("path is not a directory")
+	}
+	return root, 0, nil
+}
 
+// streamWatch watches roots and streams their events until the client disconnects or the API
+// drains. With tagRoots, each event has the root it came from.
+func (h *FileSystemHandler) streamWatch(c *gin.Context, roots []*watchRoot, tagRoots bool) {
 	// Parse ignore patterns from query param
 	ignoreParam := c.Query("ignore")
 	var ignorePatterns []string
@@ -1148,103 +1999,170 @@ func (h *FileSystemHandler) HandleWatchDirectory(c *gin.Context) {
 		ignorePatterns = strings.Split(ignoreParam, ",")
 	}
 	shouldIgnore := func(eventPath string) bool {
-		for _, pattern := range ignorePatterns {
-			if pattern != "" && strings.Contains(eventPath, pattern) {
-				return true
-			}
-		}
-		return false
+		_, ignored := filesystem.MatchIgnorePattern(eventPath, ignorePatterns)
+		return ignored
 	}
 
-	recursive := false
-	if strings.HasSuffix(path, "/**") {
-		recursive = true
-		path = strings.TrimSuffix(path, "/**")
-		if path == "" {
-			path = "/"
-		}
-	}
+	details := c.Query("details") == "true"
+	batch := c.Query("batch") == "true"
 
-	isDir, err := h.DirectoryExists(path)
+	flushInterval, err := parseFlushInterval(c)
 	if err != nil {
-		h.SendError(c, http.StatusUnprocessableEntity, err)
+		h.SendError(c, http.StatusBadRequest, err)
 		return
 	}
-
-	if !isDir {
-		h.SendError(c, http.StatusBadRequest, fmt.Errorf("path is not a directory"))
+	settleWindow, err := parseSettleWindow(c)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	keepaliveInterval, err := parseKeepaliveInterval(c, 30*time.Second)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	c.Writer.Header().Set("Transfer-Encoding", "chunked")
-	c.Writer.Header().Set("X-Accel-Buffering", "no")
-	c.Writer.WriteHeader(http.StatusOK)
-	flusher, ok := c.Writer.(http.Flusher)
+	release, ok := h.acquireStream(c)
 	if !ok {
+		return
+	}
+	defer release()
+
+	if _, ok := c.Writer.(http.Flusher); !ok {
 		h.SendError(c, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
 		return
 	}
 
+	// Every root is watched before the response starts, so that a root that can't be watched is
+	// still answered with an error status. Until then events wait for startMu, and they are
+	// dropped if the response never starts.
+	var out *streamOutput
+	var startMu sync.RWMutex
+	startMu.Lock()
+
 	ctx := c.Request.Context()
 	done := make(chan struct{})
 
-	var stop func()
-	if recursive {
-		stop, err = h.fs.WatchDirectoryRecursive(path, func(event fsnotify.Event) {
+	writeLine := func(value any) {
+		defer func() { _ = recover() }()
+		startMu.RLock()
+		defer startMu.RUnlock()
+		if out == nil {
+			return
+		}
+		json, err := json.Marshal(value)
+		if err != nil {
+			logrus.Error("Error marshalling file event:", err)
+			h.SendError(c, http.StatusInternalServerError, err)
+			return
+		}
+		_, _ = out.Write([]byte(string(json) + "\n"))
+	}
+
+	// Events are numbered as they are written (or batched), under a lock so that seq follows the stream order
+	var batcher *eventBatcher
+	if batch {
+		batchInterval := flushInterval
+		if batchInterval == 0 {
+			batchInterval = DefaultBatchInterval
+		}
+		batcher = newEventBatcher(batchInterval, func(b FileEventBatch) { writeLine(b) })
+		defer batcher.Stop()
+	}
+	var seqMu sync.Mutex
+	var seq uint64
+	writeEvent := func(msg FileEvent) {
+		if batcher != nil {
+			batcher.Add(msg)
+			return
+		}
+		seqMu.Lock()
+		defer seqMu.Unlock()
+		seq++
+		msg.Seq = seq
+		writeLine(msg)
+	}
+
+	// Keepalives go through the seq lock so that they carry the seq of the last written event
+	writeKeepalive := func() error {
+		seqMu.Lock()
+		defer seqMu.Unlock()
+		now := time.Now()
+		data, err := json.Marshal(FileEvent{Op: FileEventKeepalive, Seq: seq, Time: &now})
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(append(data, '\n'))
+		return err
+	}
+
+	// With a settle window, events only feed the list of changed paths of the current burst
+	var settler *eventSettler
+	if settleWindow > 0 {
+		settledPath := ""
+		if !tagRoots {
+			settledPath = roots[0].dir
+		}
+		settler = newEventSettler(settleWindow, func(paths []string) {
+			writeEvent(FileEvent{Op: FileEventSettled, Path: settledPath, Paths: paths})
+		})
+		defer settler.Stop()
+	}
+
+	for _, root := range roots {
+		onEvent := func(event fsnotify.Event) {
 			defer func() { _ = recover() }()
 			if shouldIgnore(event.Name) {
 				return
 			}
-			msg := FileEvent{
-				Op:    event.Op.String(),
-				Name:  strings.Split(event.Name, "/")[len(strings.Split(event.Name, "/"))-1],
-				Path:  strings.Join(strings.Split(event.Name, "/")[:len(strings.Split(event.Name, "/"))-1], "/"),
-				Error: nil,
-			}
-			json, err := json.Marshal(msg)
-			if err != nil {
-				logrus.Error("Error marshalling file event:", err)
-				h.SendError(c, http.StatusInternalServerError, err)
-				return
-			}
-			if _, err := c.Writer.Write([]byte(string(json) + "\n")); err != nil {
+			if root.glob != nil && !root.glob.Match(event.Name) {
 				return
 			}
-			flusher.Flush()
-		})
-	} else {
-		stop, err = h.fs.WatchDirectory(path, func(event fsnotify.Event) {
-			defer func() { _ = recover() }()
-			if shouldIgnore(event.Name) {
+			if settler != nil {
+				settler.Add(event.Name)
 				return
 			}
-			msg := FileEvent{
-				Op:    event.Op.String(),
-				Name:  strings.Split(event.Name, "/")[len(strings.Split(event.Name, "/"))-1],
-				Path:  strings.Join(strings.Split(event.Name, "/")[:len(strings.Split(event.Name, "/"))-1], "/"),
-				Error: nil,
-			}
-			json, err := json.Marshal(msg)
-			if err != nil {
-				logrus.Error("Error marshalling file event:", err)
-				h.SendError(c, http.StatusInternalServerError, err)
-				return
+			msg := newFileEvent(event, details)
+			if tagRoots {
+				msg.Root = root.name
 			}
-			if _, err := c.Writer.Write([]byte(string(json) + "\n")); err != nil {
-				return
+			writeEvent(msg)
+		}
+
+		// Sent as they happen, even with a settle window, so clients know coverage is incomplete
+		onError := func(dir string, err error) {
+			message := err.Error()
+			msg := FileEvent{Op: FileEventWatchError, Name: filepath.Base(dir), Path: filepath.Dir(dir), Error: &message}
+			if tagRoots {
+				msg.Root = root.name
 			}
-			flusher.Flush()
-		})
-	}
-	if err != nil {
-		h.SendError(c, http.StatusInternalServerError, err)
-		return
+			writeEvent(msg)
+		}
+
+		var stop func()
+		if root.recursive {
+			stop, err = h.fs.WatchDirectoryRecursiveWithErrors(root.dir, onEvent, onError)
+		} else {
+			stop, err = h.fs.WatchDirectory(root.dir, onEvent)
+		}
+		if err != nil {
+			startMu.Unlock()
+			h.SendError(c, http.StatusInternalServerError, fmt.Errorf("%s: %w", root.name, err))
+			return
+		}
+		defer stop() // Ensures watcher is removed when handler exits
 	}
-	defer stop() // Ensures watcher is removed when handler exits
+
+	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.Writer.Header().Set("Transfer-Encoding", "chunked")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+	out = newStreamOutput(c.Writer, flushInterval)
+	defer out.Close()
+	startMu.Unlock()
 
 	// Keepalive ticker to prevent idle timeouts while watching
-	keepaliveTicker := time.NewTicker(30 * time.Second)
+	keepaliveTicker := time.NewTicker(keepaliveInterval)
 	defer keepaliveTicker.Stop()
 
 	go func() {
@@ -1253,13 +2171,22 @@ func (h *FileSystemHandler) HandleWatchDirectory(c *gin.Context) {
 			case <-ctx.Done():
 				close(done)
 				return
+			case <-drain.Signal():
+				if batcher != nil {
+					batcher.Flush()
+				}
+				_, _ = out.Write([]byte(reconnectMessage))
+				close(done)
+				return
 			case <-keepaliveTicker.C:
-				// Send a keepalive line
-				if _, err := c.Writer.Write([]byte("[keepalive]\n")); err != nil {
+				if batcher != nil {
+					batcher.Keepalive()
+					continue
+				}
+				if err := writeKeepalive(); err != nil {
 					close(done)
 					return
 				}
-				flusher.Flush()
 			}
 		}
 	}()
@@ -1267,9 +2194,52 @@ func (h *FileSystemHandler) HandleWatchDirectory(c *gin.Context) {
 	<-done
 }
 
+// ResolvedPath is the path the filesystem handlers would operate on for a request path
+type ResolvedPath struct {
+	Path         string `json:"path" binding:"required" example:"src/app.ts"`              // Path once extracted from the URL and formatted (~ expanded, double slashes removed)
+	AbsolutePath string `json:"absolutePath" binding:"required" example:"/app/src/app.ts"` // Cleaned absolute path, symlinks are not resolved
+	Relative     bool   `json:"relative" binding:"required" example:"true"`                // Whether path was resolved from the working directory
+	WorkingDir   string `json:"workingDir" binding:"required" example:"/app"`
+} // @name ResolvedPath
+
+// HandleResolvePath handles GET requests to /filesystem-resolve
+// @Summary Resolve a path
+// @Description Returns the absolute path the filesystem endpoints would use for a path, without touching the filesystem, to debug how paths are encoded. The path after /filesystem-resolve follows the same rules as the one after /filesystem: it is relative to the working directory unless its slashes are encoded as %2F (e.g. /filesystem-resolve/%2Ftmp%2Fa for /tmp/a), and /filesystem-resolve/ is the working directory itself. The path query parameter takes precedence and is used as is, absolute when it starts with /. Symlinks are not resolved and confinement (SANDBOX_FS_CONFINE) is not checked.
+// @Tags filesystem
+// @Produce json
+// @Param path path string false "Path, encoded as in /filesystem/{path}"
+// @Param path query string false "Path, used as is"
+// @Success 200 {object} ResolvedPath "Resolved path"
+// @Failure 400 {object} ErrorResponse "Invalid path, or a relative path outside of the root directory"
+// @Router /filesystem-resolve/{path} [get]
+func (h *FileSystemHandler) HandleResolvePath(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+	if query, ok := c.GetQuery("path"); ok {
+		path = query
+	}
+
+	path, err := lib.FormatPath(path)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	absPath, err := h.fs.ResolvePath(path)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, ResolvedPath{
+		Path:         path,
+		AbsolutePath: absPath,
+		Relative:     !filepath.IsAbs(path),
+		WorkingDir:   h.fs.WorkingDir,
+	})
+}
+
 // HandleFind
 // @Summary Find files and directories
-// @Description Finds files and directories using the find command.
+// @Description Finds files and directories using the find command. Every match has its type, size (0 for directories), modification time and permissions.
 // @Tags filesystem
 // @Accept json
 // @Produce json
@@ -1283,6 +2253,7 @@ func (h *FileSystemHandler) HandleWatchDirectory(c *gin.Context) {
 // @Failure 400 {object} ErrorResponse "Bad request"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 409 {object} ErrorResponse "Operation cancelled through /operations"
 // @Router /filesystem-find/{path} [get]
 func (h *FileSystemHandler) HandleFind(c *gin.Context) {
 	// Parse maxResults (default: 20)
@@ -1359,18 +2330,24 @@ func (h *FileSystemHandler) HandleFind(c *gin.Context) {
 	// Get absolute path for searching
 	absSearchDir, err := h.fs.GetAbsolutePath(searchDir)
 	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+		h.SendError(c, pathErrorStatus(err, http.StatusBadRequest), err)
 		return
 	}
 
-	// Collect all candidate paths by walking directory
-	candidates := []string{}
-	candidateTypes := make(map[string]string)
+	ctx, op := startOperation(c, "find", absSearchDir, "entries")
+	defer op.Done()
+
+	// Collect all candidates by walking directory, with their absolute path until made relative
+	candidates := []FindMatch{}
 
 	err = filepath.WalkDir(absSearchDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		op.Add(1)
 
 		base := filepath.Base(path)
 
@@ -1395,28 +2372,39 @@ func (h *FileSystemHandler) HandleFind(c *gin.Context) {
 		}
 
 		if !d.IsDir() && len(patterns) > 0 {
-			matched := false
-			for _, pattern := range patterns {
-				if match, _ := filepath.Match(pattern, base); match {
-					matched = true
-					break
-				}
-			}
-			if !matched {
+			if _, matched := filesystem.MatchIncludePattern(path, patterns); !matched {
 				return nil
 			}
 		}
 
-		candidates = append(candidates, path)
+		info, err := d.Info()
+		if err != nil {
+			// The entry vanished since it was listed
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		match := FindMatch{
+			Path:        path,
+			Type:        "file",
+			ModTime:     info.ModTime(),
+			Permissions: fmt.Sprintf("%o", info.Mode().Perm()),
+		}
 		if d.IsDir() {
-			candidateTypes[path] = "directory"
+			match.Type = "directory"
 		} else {
-			candidateTypes[path] = "file"
+			match.Size = info.Size()
 		}
+		candidates = append(candidates, match)
 
 		return nil
 	})
 
+	if ctx.Err() != nil {
+		h.SendError(c, http.StatusConflict, errOperationCancelled(op))
+		return
+	}
 	if err != nil {
 		h.SendError(c, http.StatusInternalServerError, fmt.Errorf("error walking directory: %w", err))
 		return
@@ -1424,21 +2412,17 @@ func (h *FileSystemHandler) HandleFind(c *gin.Context) {
 
 	// Convert to response format
 	results := make([]FindMatch, 0, len(candidates))
-	for i, absPath := range candidates {
+	for i, match := range candidates {
 		if maxResults >= 0 && i >= maxResults { // -1 means all results
 			break
 		}
 
 		// Make path relative to search directory
-		relPath, err := filepath.Rel(absSearchDir, absPath)
-		if err != nil {
-			relPath = absPath
+		if relPath, err := filepath.Rel(absSearchDir, match.Path); err == nil {
+			match.Path = relPath
 		}
 
-		results = append(results, FindMatch{
-			Path: relPath,
-			Type: candidateTypes[absPath],
-		})
+		results = append(results, match)
 	}
 
 	// Return results
@@ -1464,6 +2448,7 @@ func (h *FileSystemHandler) HandleFind(c *gin.Context) {
 // @Failure 400 {object} ErrorResponse "Bad request"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 409 {object} ErrorResponse "Operation cancelled through /operations"
 // @Router /filesystem-search/{path} [get]
 func (h *FileSystemHandler) HandleFuzzySearch(c *gin.Context) {
 	// Parse maxResults (default: 20)
@@ -1530,7 +2515,7 @@ func (h *FileSystemHandler) HandleFuzzySearch(c *gin.Context) {
 	// Get absolute path for searching
 	absSearchDir, err := h.fs.GetAbsolutePath(searchDir)
 	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+		h.SendError(c, pathErrorStatus(err, http.StatusBadRequest), err)
 		return
 	}
 
@@ -1541,14 +2526,21 @@ func (h *FileSystemHandler) HandleFuzzySearch(c *gin.Context) {
 		return
 	}
 
+	ctx, op := startOperation(c, "fuzzy-search", absSearchDir, "entries")
+	defer op.Done()
+
 	// Collect candidates
 	candidates := []string{}
 	candidateTypes := make(map[string]string)
 
 	err = filepath.Walk(absSearchDir, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err != nil {
 			return nil
 		}
+		op.Add(1)
 
 		base := filepath.Base(path)
 
@@ -1579,6 +2571,10 @@ func (h *FileSystemHandler) HandleFuzzySearch(c *gin.Context) {
 		return nil
 	})
 
+	if ctx.Err() != nil {
+		h.SendError(c, http.StatusConflict, errOperationCancelled(op))
+		return
+	}
 	if err != nil {
 		h.SendError(c, http.StatusInternalServerError, fmt.Errorf("error walking directory: %w", err))
 		return
@@ -1646,6 +2642,7 @@ func (h *FileSystemHandler) HandleFuzzySearch(c *gin.Context) {
 // @Failure 400 {object} ErrorResponse "Bad request"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 409 {object} ErrorResponse "Operation cancelled through /operations"
 // @Router /filesystem-content-search/{path} [get]
 func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 	// Get search query
@@ -1659,9 +2656,8 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 	searchDir := h.extractPathFromRequest(c)
 
 	if searchDir != "" && searchDir != "/" && searchDir != "." {
-		searchDir, err := lib.FormatPath(searchDir)
-		if err != nil {
-			h.SendError(c, http.StatusBadRequest, err)
+		searchDir, ok := h.formatPath(c, searchDir)
+		if !ok {
 			return
 		}
 		// Verify directory exists
@@ -1723,7 +2719,7 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 	// Get absolute path for searching
 	absSearchDir, err := h.fs.GetAbsolutePath(searchDir)
 	if err != nil {
-		h.SendError(c, http.StatusBadRequest, err)
+		h.SendError(c, pathErrorStatus(err, http.StatusBadRequest), err)
 		return
 	}
 
@@ -1741,9 +2737,15 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 		searchQuery = strings.ToLower(query)
 	}
 
+	ctx, op := startOperation(c, "content-search", absSearchDir, "files")
+	defer op.Done()
+
 	// Collect files to search
 	var filesToSearch []string
 	err = filepath.WalkDir(absSearchDir, func(path string, d fs.DirEntry, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err != nil {
 			return nil
 		}
@@ -1772,6 +2774,10 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 		return nil
 	})
 
+	if ctx.Err() != nil {
+		h.SendError(c, http.StatusConflict, errOperationCancelled(op))
+		return
+	}
 	if err != nil {
 		h.SendError(c, http.StatusInternalServerError, fmt.Errorf("error walking directory: %w", err))
 		return
@@ -1819,6 +2825,11 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 		go func() {
 			defer wg.Done()
 			for filePath := range filesChan {
+				if ctx.Err() != nil {
+					continue
+				}
+				op.Add(1)
+
 				// Read file
 				content, err := os.ReadFile(filePath)
 				if err != nil {
@@ -1834,11 +2845,15 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 					}
 
 					if col := strings.Index(searchLine, searchQuery); col >= 0 {
-						resultsChan <- searchResult{
+						select {
+						case resultsChan <- searchResult{
 							path:   filePath,
 							line:   lineNum + 1,
 							column: col + 1,
 							text:   line,
+						}:
+						case <-ctx.Done():
+							return
 						}
 					}
 				}
@@ -1850,6 +2865,11 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 	close(resultsChan)
 	<-done
 
+	if ctx.Err() != nil {
+		h.SendError(c, http.StatusConflict, errOperationCancelled(op))
+		return
+	}
+
 	response := ContentSearchResponse{
 		Query:   query,
 		Matches: matches,
@@ -1858,3 +2878,857 @@ func (h *FileSystemHandler) HandleContentSearch(c *gin.Context) {
 
 	h.SendJSON(c, http.StatusOK, response)
 }
+
+// HandleStatBatch handles POST requests to /filesystem/stat-batch
+// @Summary Get metadata for several paths
+// @Description Return size, type, permissions and modification time for each requested path, or the error that prevented reading it. Symlinks are followed.
+// @Tags filesystem
+// @Accept json
+// @Produce json
+// @Param request body StatBatchRequest true "Paths to stat"
+// @Success 200 {object} StatBatchResponse "Metadata for each path, in request order"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Router /filesystem/stat-batch [post]
+func (h *FileSystemHandler) HandleStatBatch(c *gin.Context) {
+	var request StatBatchRequest
+	if err := h.BindJSON(c, &request); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(request.Paths) > MaxStatBatchPaths {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("too many paths: %d (max %d)", len(request.Paths), MaxStatBatchPaths))
+		return
+	}
+
+	entries := make([]StatBatchEntry, 0, len(request.Paths))
+	for _, requestedPath := range request.Paths {
+		entry := StatBatchEntry{Path: requestedPath}
+
+		path, err := lib.FormatPath(requestedPath)
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+
+		info, err := h.fs.Infos(path)
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+
+		modTime := info.ModTime()
+		entry.Type = "file"
+		if info.IsDir() {
+			entry.Type = "directory"
+		}
+		entry.Size = info.Size()
+		entry.Permissions = fmt.Sprintf("%o", info.Mode().Perm())
+		entry.LastModified = &modTime
+		entries = append(entries, entry)
+	}
+
+	h.SendJSON(c, http.StatusOK, StatBatchResponse{Entries: entries})
+}
+
+// HandleReplace handles POST requests to /filesystem/replace
+// @Summary Search and replace across files
+// @Description Apply a regular expression substitution to a file, or to every file under a directory matching filePattern. The replacement may reference capture groups ($1, ${name}). Binary files and files larger than 10MB are reported and skipped. With dryRun, matches are counted but nothing is written.
+// @Tags filesystem
+// @Accept json
+// @Produce json
+// @Param request body ReplaceRequest true "Replace parameters"
+// @Success 200 {object} ReplaceResponse "Per-file replacement counts"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 403 {object} ErrorResponse "Path outside of the working directory"
+// @Failure 404 {object} ErrorResponse "Path not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /filesystem/replace [post]
+func (h *FileSystemHandler) HandleReplace(c *gin.Context) {
+	var request ReplaceRequest
+	if err := h.BindJSON(c, &request); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	pattern := request.Search
+	if request.CaseSensitive != nil && !*request.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid search expression: %w", err))
+		return
+	}
+
+	path, ok := h.formatPath(c, request.Path)
+	if !ok {
+		return
+	}
+	absPath, err := h.fs.GetAbsolutePath(path)
+	if err != nil {
+		h.SendError(c, pathErrorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.SendError(c, http.StatusNotFound, fmt.Errorf("path not found: %s", request.Path))
+			return
+		}
+		h.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Collect the files to rewrite
+	var files []string
+	if !info.IsDir() {
+		files = append(files, absPath)
+	} else {
+		excludeDirs := request.ExcludeDirs
+		if excludeDirs == nil {
+			excludeDirs = []string{
+				"node_modules", "vendor", ".git", "dist", "build",
+				"target", "__pycache__", ".venv", ".next", "coverage",
+			}
+		}
+		excludeDirsMap := make(map[string]bool)
+		for _, dir := range excludeDirs {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				excludeDirsMap[dir] = true
+			}
+		}
+
+		err = filepath.WalkDir(absPath, func(walkPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if walkPath != absPath && excludeDirsMap[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if request.FilePattern != "" {
+				if matched, _ := filepath.Match(request.FilePattern, d.Name()); !matched {
+					return nil
+				}
+			}
+			files = append(files, walkPath)
+			if len(files) > MaxReplaceFiles {
+				return fmt.Errorf("too many files match: more than %d", MaxReplaceFiles)
+			}
+			return nil
+		})
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	response := ReplaceResponse{Files: []ReplaceFileResult{}, DryRun: request.DryRun}
+	for _, file := range files {
+		displayPath := file
+		if info.IsDir() {
+			displayPath, _ = filepath.Rel(absPath, file)
+		}
+
+		count, err := h.fs.ReplaceInFile(file, re, request.Replace, MaxReplaceFileSize, request.DryRun)
+		if err != nil {
+			response.Files = append(response.Files, ReplaceFileResult{Path: displayPath, Error: err.Error()})
+			continue
+		}
+		if count == 0 {
+			continue
+		}
+		response.Files = append(response.Files, ReplaceFileResult{Path: displayPath, Replacements: count})
+		response.FilesChanged++
+		response.TotalReplacements += count
+	}
+
+	h.SendJSON(c, http.StatusOK, response)
+}
+
+// HandleUntar handles POST requests to /filesystem-untar/{path}
+// @Summary Upload and extract a tar archive
+// @Description Stream a tar or tar.gz archive in the request body (not multipart) and extract it into the target directory as it is received, without buffering the archive. Compression is detected automatically. Entries escaping the target directory are rejected and extraction stops once 10GB of file data has been written; entries extracted before an error are kept.
+// @Tags filesystem
+// @Accept application/x-tar,application/gzip
+// @Produce json
+// @Param path path string true "Target directory path"
+// @Param archive body string true "tar or tar.gz archive"
+// @Success 200 {object} UntarResponse "Extraction summary"
+// @Failure 400 {object} ErrorResponse "Invalid or unsafe archive"
+// @Failure 413 {object} ErrorResponse "Archive too large"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Failure 409 {object} ErrorResponse "Operation cancelled through /operations"
+// @Router /filesystem-untar/{path} [post]
+func (h *FileSystemHandler) HandleUntar(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	if info, err := h.fs.Infos(path); err == nil && !info.IsDir() {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("path points to a file, not a directory"))
+		return
+	}
+
+	ctx, op := startOperation(c, "untar", path, "bytes")
+	defer op.Done()
+
+	result, err := h.fs.ExtractTar(path, &operationReader{ctx: ctx, r: c.Request.Body, op: op}, MaxUntarSize)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			h.SendError(c, http.StatusConflict, errOperationCancelled(op))
+		case errors.Is(err, filesystem.ErrArchiveTooLarge):
+			h.SendError(c, http.StatusRequestEntityTooLarge, err)
+		case errors.Is(err, filesystem.ErrUnsafeArchiveEntry), errors.Is(err, filesystem.ErrInvalidArchive):
+			h.SendError(c, http.StatusBadRequest, err)
+		default:
+			h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		}
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, UntarResponse{
+		Path:        path,
+		Files:       result.Files,
+		Directories: result.Directories,
+		Bytes:       result.Bytes,
+	})
+}
+
+// HandleUnpack handles POST requests to /filesystem-unpack/{path}
+// @Summary Extract an archive of the sandbox filesystem
+// @Description Extract the zip, tar, tar.gz or tar.bz2 archive at path into destination (the directory of the archive by default). The format is detected from the first bytes of the archive, or from its extension, and returned. Entries escaping the destination are rejected and extraction stops once 10GB of file data has been written; entries extracted before an error are kept.
+// @Tags filesystem
+// @Produce json
+// @Param path path string true "Archive path"
+// @Param destination query string false "Directory to extract into, created if needed (default: the directory of the archive)"
+// @Success 200 {object} UnpackResponse "Extraction summary"
+// @Failure 400 {object} ErrorResponse "Unknown format, invalid or unsafe archive"
+// @Failure 404 {object} ErrorResponse "Archive not found"
+// @Failure 413 {object} ErrorResponse "Archive too large"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-unpack/{path} [post]
+func (h *FileSystemHandler) HandleUnpack(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	destination := filepath.Dir(path)
+	if c.Query("destination") != "" {
+		if destination, ok = h.formatPath(c, c.Query("destination")); !ok {
+			return
+		}
+	}
+
+	format, result, err := h.fs.Unpack(path, destination, MaxUntarSize)
+	if err != nil {
+		switch {
+		case os.IsNotExist(err) && format == "":
+			h.SendError(c, http.StatusNotFound, err)
+		case errors.Is(err, filesystem.ErrArchiveTooLarge):
+			h.SendError(c, http.StatusRequestEntityTooLarge, err)
+		case errors.Is(err, filesystem.ErrUnknownArchiveFormat), errors.Is(err, filesystem.ErrUnsafeArchiveEntry), errors.Is(err, filesystem.ErrInvalidArchive):
+			h.SendError(c, http.StatusBadRequest, err)
+		default:
+			h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		}
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, UnpackResponse{
+		Path:        path,
+		Destination: destination,
+		Format:      format,
+		Files:       result.Files,
+		Directories: result.Directories,
+		Bytes:       result.Bytes,
+	})
+}
+
+// HandleRender handles POST requests to /filesystem-render/{path}
+// @Summary Render a template file
+// @Description Render a file as a Go text/template (https://pkg.go.dev/text/template) with the given variables, and write the result to destination or return it. A variable missing from variables renders as <no value>, or fails the render with strict. Templates are limited to 10MB.
+// @Tags filesystem
+// @Accept json
+// @Produce json
+// @Param path path string true "Template file path"
+// @Param request body RenderRequest true "Variables and destination"
+// @Success 200 {object} RenderResponse "Rendered template"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 404 {object} ErrorResponse "Template not found"
+// @Failure 413 {object} ErrorResponse "Template too large"
+// @Failure 422 {object} ErrorResponse "Invalid template or destination could not be written"
+// @Router /filesystem-render/{path} [post]
+func (h *FileSystemHandler) HandleRender(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	var request RenderRequest
+	if err := h.BindJSON(c, &request); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	permissions := os.FileMode(0644)
+	if request.Permissions != "" {
+		permInt, err := strconv.ParseUint(request.Permissions, 8, 32)
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid permissions format '%s': %w", request.Permissions, err))
+			return
+		}
+		permissions = os.FileMode(permInt)
+	}
+
+	var destination string
+	if request.Destination != "" {
+		if destination, ok = h.formatPath(c, request.Destination); !ok {
+			return
+		}
+	}
+
+	content, err := h.fs.RenderTemplate(path, request.Variables, request.Strict)
+	if err != nil {
+		switch {
+		case os.IsNotExist(err):
+			h.SendError(c, http.StatusNotFound, err)
+		case errors.Is(err, filesystem.ErrTemplateTooLarge):
+			h.SendError(c, http.StatusRequestEntityTooLarge, err)
+		default:
+			h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		}
+		return
+	}
+
+	response := RenderResponse{Path: path, Size: len(content)}
+	if destination == "" {
+		response.Content = string(content)
+		h.SendJSON(c, http.StatusOK, response)
+		return
+	}
+
+	if err := h.fs.WriteFileAtomic(destination, bytes.NewReader(content), permissions); err != nil {
+		h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), fmt.Errorf("error writing rendered template: %w", err))
+		return
+	}
+
+	response.Destination = destination
+	h.SendJSON(c, http.StatusOK, response)
+}
+
+// HandleSwap handles POST requests to /filesystem-swap/{path}
+// @Summary Atomically replace a file
+// @Description Write new content to a temporary file next to the target, fsync it, then rename it over the target. The response is sent only once the rename succeeded, so readers see either the old or the new file, never a partial one. The content is the JSON content field, or the raw request body for any other content type. An existing file keeps its permissions and ownership.
+// @Tags filesystem
+// @Accept json,application/octet-stream
+// @Produce json
+// @Param path path string true "File path"
+// @Param request body SwapRequest true "New content"
+// @Success 200 {object} SuccessResponse "File replaced"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-swap/{path} [post]
+func (h *FileSystemHandler) HandleSwap(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	permissions := os.FileMode(0644)
+	content := io.Reader(c.Request.Body)
+	if strings.HasPrefix(c.GetHeader("Content-Type"), "application/json") {
+		var request SwapRequest
+		if err := h.BindJSON(c, &request); err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+		if request.Permissions != "" {
+			permInt, err := strconv.ParseUint(request.Permissions, 8, 32)
+			if err != nil {
+				h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid permissions format '%s': %w", request.Permissions, err))
+				return
+			}
+			permissions = os.FileMode(permInt)
+		}
+		content = strings.NewReader(request.Content)
+	}
+
+	if info, err := h.fs.Infos(path); err == nil && info.IsDir() {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("path points to a directory, not a file"))
+		return
+	}
+
+	if err := h.fs.WriteFileAtomic(path, content, permissions); err != nil {
+		h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), fmt.Errorf("error swapping file: %w", err))
+		return
+	}
+
+	h.SendSuccessWithPath(c, path, "File swapped successfully")
+}
+
+// HandleGetXattrs handles GET requests to /filesystem-xattr/{path}
+// @Summary List extended attributes
+// @Description List the extended attributes (xattrs) of a file or directory with their values, or a single one with name. Values are returned as is, or base64-encoded with encoding=base64 (use it for binary values).
+// @Tags filesystem
+// @Produce json
+// @Param path path string true "File or directory path"
+// @Param name query string false "Only return this attribute (e.g. user.comment)"
+// @Param encoding query string false "Encoding of the values: utf8 (default) or base64"
+// @Success 200 {object} XattrResponse "Extended attributes"
+// @Failure 400 {object} ErrorResponse "Invalid parameters or extended attributes not supported by the filesystem"
+// @Failure 404 {object} ErrorResponse "Path or attribute not found"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-xattr/{path} [get]
+func (h *FileSystemHandler) HandleGetXattrs(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	encoding := c.DefaultQuery("encoding", "utf8")
+	if encoding != "utf8" && encoding != "base64" {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid encoding '%s', must be utf8 or base64", encoding))
+		return
+	}
+
+	var attrs map[string][]byte
+	var err error
+	if name := c.Query("name"); name != "" {
+		var value []byte
+		if value, err = h.fs.GetXattr(path, name); err == nil {
+			attrs = map[string][]byte{name: value}
+		}
+	} else {
+		attrs, err = h.fs.ListXattrs(path)
+	}
+	if err != nil {
+		h.SendError(c, xattrErrorStatus(err), fmt.Errorf("error reading extended attributes: %w", err))
+		return
+	}
+
+	response := XattrResponse{Path: path, Attributes: make(map[string]string, len(attrs)), Encoding: encoding}
+	for name, value := range attrs {
+		if encoding == "base64" {
+			response.Attributes[name] = base64.StdEncoding.EncodeToString(value)
+		} else {
+			response.Attributes[name] = string(value)
+		}
+	}
+	h.SendJSON(c, http.StatusOK, response)
+}
+
+// HandleSetXattr handles PUT requests to /filesystem-xattr/{path}
+// @Summary Set an extended attribute
+// @Description Create or replace an extended attribute (xattr) of a file or directory. Names are namespaced, e.g. user.comment; other namespaces than user usually need privileges.
+// @Tags filesystem
+// @Accept json
+// @Produce json
+// @Param path path string true "File or directory path"
+// @Param request body XattrRequest true "Attribute to set"
+// @Success 200 {object} SuccessResponse "Attribute set"
+// @Failure 400 {object} ErrorResponse "Invalid request or extended attributes not supported by the filesystem"
+// @Failure 404 {object} ErrorResponse "Path not found"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-xattr/{path} [put]
+func (h *FileSystemHandler) HandleSetXattr(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	var request XattrRequest
+	if err := h.BindJSON(c, &request); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	value, err := decodeFileContent(request.Value, request.Encoding)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.fs.SetXattr(path, request.Name, value); err != nil {
+		h.SendError(c, xattrErrorStatus(err), fmt.Errorf("error setting extended attribute: %w", err))
+		return
+	}
+
+	h.SendSuccessWithPath(c, path, "Extended attribute set successfully")
+}
+
+// xattrErrorStatus returns the HTTP status of an extended attribute error
+func xattrErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, filesystem.ErrXattrsNotSupported), errors.Is(err, filesystem.ErrInvalidXattrName):
+		return http.StatusBadRequest
+	case errors.Is(err, filesystem.ErrXattrNotFound), errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	}
+	return pathErrorStatus(err, http.StatusUnprocessableEntity)
+}
+
+// HandleCreateSnapshot handles POST requests to /filesystem-snapshots/{path}
+// @Summary Snapshot a directory
+// @Description Store the content of a directory (files, directories and symlinks) as a named checkpoint that it can later be rolled back to with the restore endpoint. Snapshots are kept by the sandbox API, outside of the directory.
+// @Tags filesystem
+// @Produce json
+// @Param path path string true "Directory path"
+// @Param name query string false "Snapshot name (letters, digits, '.', '_' and '-'), defaults to the creation time"
+// @Success 200 {object} filesystem.Snapshot "Snapshot created"
+// @Failure 400 {object} ErrorResponse "Invalid name or path is not a directory"
+// @Failure 404 {object} ErrorResponse "Directory not found"
+// @Failure 409 {object} ErrorResponse "A snapshot with this name already exists"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-snapshots/{path} [post]
+func (h *FileSystemHandler) HandleCreateSnapshot(c *gin.Context) {
+	path, ok := h.snapshotPath(c)
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.fs.CreateSnapshot(path, c.Query("name"))
+	if err != nil {
+		h.SendError(c, snapshotErrorStatus(err), err)
+		return
+	}
+	h.SendJSON(c, http.StatusOK, snapshot)
+}
+
+// HandleListSnapshots handles GET requests to /filesystem-snapshots/{path}
+// @Summary List the snapshots of a directory
+// @Description List the snapshots taken of a directory, oldest first. A directory that was never snapshotted has an empty list.
+// @Tags filesystem
+// @Produce json
+// @Param path path string true "Directory path"
+// @Success 200 {object} SnapshotListResponse "Snapshots of the directory"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-snapshots/{path} [get]
+func (h *FileSystemHandler) HandleListSnapshots(c *gin.Context) {
+	path, ok := h.formatPath(c, h.extractPathFromRequest(c))
+	if !ok {
+		return
+	}
+
+	snapshots, err := h.fs.ListSnapshots(path)
+	if err != nil {
+		h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		return
+	}
+	h.SendJSON(c, http.StatusOK, SnapshotListResponse{Path: path, Snapshots: snapshots})
+}
+
+// HandleRestoreSnapshot handles POST requests to /filesystem-restore/{path}
+// @Summary Roll a directory back to a snapshot
+// @Description Replace the content of a directory with a snapshot taken by the snapshot endpoint. Entries created since the snapshot are removed. The snapshot is extracted before anything is removed, so the directory is left untouched when it cannot be read. The snapshot is kept and can be restored again.
+// @Tags filesystem
+// @Produce json
+// @Param path path string true "Directory path"
+// @Param snapshot query string true "Name of the snapshot to restore"
+// @Success 200 {object} filesystem.Snapshot "Restored snapshot"
+// @Failure 400 {object} ErrorResponse "Missing or invalid snapshot name"
+// @Failure 404 {object} ErrorResponse "Snapshot not found"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-restore/{path} [post]
+func (h *FileSystemHandler) HandleRestoreSnapshot(c *gin.Context) {
+	name := c.Query("snapshot")
+	if name == "" {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("snapshot query parameter is required"))
+		return
+	}
+	path, ok := h.snapshotPath(c)
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.fs.RestoreSnapshot(path, name)
+	if err != nil {
+		h.SendError(c, snapshotErrorStatus(err), err)
+		return
+	}
+	h.SendJSON(c, http.StatusOK, snapshot)
+}
+
+// snapshotPath returns the formatted directory path of a snapshot request. A missing directory
+// is left to the snapshot functions; any other path is rejected unless it is a directory. It
+// returns false once an error response has been sent.
+func (h *FileSystemHandler) snapshotPath(c *gin.Context) (string, bool) {
+	path, ok := h.formatPath(c, h.extractPathFromRequest(c))
+	if !ok {
+		return "", false
+	}
+	if info, err := h.fs.Infos(path); err == nil && !info.IsDir() {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("path is not a directory"))
+		return "", false
+	}
+	return path, true
+}
+
+func snapshotErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, filesystem.ErrInvalidSnapshotName):
+		return http.StatusBadRequest
+	case errors.Is(err, filesystem.ErrSnapshotExists):
+		return http.StatusConflict
+	case errors.Is(err, filesystem.ErrSnapshotNotFound), errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	}
+	return pathErrorStatus(err, http.StatusUnprocessableEntity)
+}
+
+// HandleDedupe handles POST requests to /filesystem-dedupe/{path}
+// @Summary Deduplicate the files of a directory
+// @Description Scan a directory recursively and replace identical files (same content, permissions and owner, on the same filesystem) by hardlinks to a single copy, returning the disk space reclaimed. Symlinks, empty files and files that already have several links are skipped. Linked files share their content: writing one in place changes them all, while tools that replace files (editors, package managers) are unaffected. The operation is listed in /operations and can be cancelled there.
+// @Tags filesystem
+// @Produce json
+// @Param path path string true "Directory path"
+// @Param dryRun query boolean false "Only report the duplicates and the space that would be reclaimed"
+// @Success 200 {object} filesystem.DedupeResult "Deduplication summary"
+// @Failure 400 {object} ErrorResponse "Path is not a directory"
+// @Failure 404 {object} ErrorResponse "Directory not found"
+// @Failure 409 {object} ErrorResponse "Operation cancelled through /operations"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem-dedupe/{path} [post]
+func (h *FileSystemHandler) HandleDedupe(c *gin.Context) {
+	path, ok := h.snapshotPath(c)
+	if !ok {
+		return
+	}
+
+	ctx, op := startOperation(c, "dedupe", path, "files")
+	defer op.Done()
+
+	result, err := h.fs.Dedupe(ctx, path, filesystem.DedupeOptions{
+		DryRun:   c.Query("dryRun") == "true",
+		Progress: op.Add,
+	})
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			h.SendError(c, http.StatusConflict, errOperationCancelled(op))
+		case os.IsNotExist(err):
+			h.SendError(c, http.StatusNotFound, err)
+		default:
+			h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		}
+		return
+	}
+	h.SendJSON(c, http.StatusOK, result)
+}
+
+// HandleFollowFile handles GET requests to /filesystem-follow/{path}
+// @Summary Follow a file as it grows
+// @Description Streams the content appended to a file, like tail -F, until the client disconnects. When the API shuts down or upgrades, the stream ends with the trailer X-Stream-End: reconnect. The file can be written by any process. Only new content is streamed unless fromStart is true. When the file is truncated it is streamed again from its start; when it is rotated (renamed or removed, then recreated) the new file is followed.
+// @Tags filesystem
+// @Produce plain
+// @Param path path string true "File path"
+// @Param fromStart query boolean false "Stream the existing content before following"
+// @Param flushIntervalMs query integer false "Batch writes and flush at most once per interval (max 10000), 0 flushes every write"
+// @Success 200 {string} string "Stream of appended content"
+// @Failure 400 {object} ErrorResponse "Invalid request or path is a directory"
+// @Failure 404 {object} ErrorResponse "File not found"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Failure 503 {object} ErrorResponse "Too many concurrent streaming connections, retry after Retry-After seconds"
+// @Router /filesystem-follow/{path} [get]
+func (h *FileSystemHandler) HandleFollowFile(c *gin.Context) {
+	path := h.extractPathFromRequest(c)
+
+	path, ok := h.formatPath(c, path)
+	if !ok {
+		return
+	}
+
+	info, err := h.fs.Infos(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.SendError(c, http.StatusNotFound, err)
+			return
+		}
+		h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		return
+	}
+	if info.IsDir() {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("path is a directory: %s", path))
+		return
+	}
+
+	flushInterval, err := parseFlushInterval(c)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	follower, err := h.fs.OpenFollower(path, c.Query("fromStart") == "true")
+	if err != nil {
+		h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		return
+	}
+	defer follower.Close()
+
+	release, ok := h.acquireStream(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	// The content is streamed as is, a drained stream is flagged in a trailer instead of a reconnect line
+	c.Writer.Header().Set("Trailer", "X-Stream-End")
+	c.Writer.WriteHeader(http.StatusOK)
+	out := newStreamOutput(c.Writer, flushInterval)
+	defer out.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-drain.Signal():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := follower.Follow(ctx, out); err != nil {
+		logrus.WithField("path", path).WithError(err).Debug("Stopped following file")
+	}
+	select {
+	case <-drain.Signal():
+		c.Writer.Header().Set("X-Stream-End", "reconnect")
+	default:
+	}
+}
+
+// HandleMatchPath handles POST requests to /filesystem/match
+// @Summary Test a path against patterns
+// @Description Tell whether a path matches watch ignore patterns, find include patterns and a recursive watch glob, and which pattern matched. Patterns are evaluated with the same matchers as the watch and find endpoints, to debug why a file was or wasn't reported. The path doesn't need to exist.
+// @Tags filesystem
+// @Accept json
+// @Produce json
+// @Param request body PathMatchRequest true "Path and patterns"
+// @Success 200 {object} PathMatchResponse "Match result"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Router /filesystem/match [post]
+func (h *FileSystemHandler) HandleMatchPath(c *gin.Context) {
+	var request PathMatchRequest
+	if err := h.BindJSON(c, &request); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, pattern := range request.Include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid include pattern %q: %w", pattern, err))
+			return
+		}
+	}
+
+	path, ok := h.formatPath(c, request.Path)
+	if !ok {
+		return
+	}
+
+	response := PathMatchResponse{Path: path, Included: true, GlobMatched: true}
+	response.IgnoredBy, response.Ignored = filesystem.MatchIgnorePattern(path, request.Ignore)
+	if len(request.Include) > 0 {
+		response.IncludedBy, response.Included = filesystem.MatchIncludePattern(path, request.Include)
+	}
+	if request.Glob != "" {
+		glob, ok := h.formatPath(c, request.Glob)
+		if !ok {
+			return
+		}
+		matched, err := h.fs.MatchWatchPath(glob, path)
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+		response.GlobMatched = matched
+	}
+	response.Matches = !response.Ignored && response.Included && response.GlobMatched
+
+	h.SendJSON(c, http.StatusOK, response)
+}
+
+// HandleCompare handles POST requests to /filesystem/compare
+// @Summary Compare two directories
+// @Description Compare the files of two directory trees and list the files only present in one of them and the files whose content differs (compared by size, then SHA-256). Symlinks are compared by target. Useful to check that a copy is complete and correct without downloading both trees.
+// @Tags filesystem
+// @Accept json
+// @Produce json
+// @Param request body CompareRequest true "Directories to compare"
+// @Success 200 {object} CompareResponse "Differences between the directories"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 404 {object} ErrorResponse "Directory not found"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Router /filesystem/compare [post]
+func (h *FileSystemHandler) HandleCompare(c *gin.Context) {
+	var request CompareRequest
+	if err := h.BindJSON(c, &request); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, pattern := range request.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err))
+			return
+		}
+	}
+
+	maxEntries := request.MaxEntries
+	if maxEntries <= 0 || maxEntries > MaxCompareEntries {
+		maxEntries = MaxCompareEntries
+	}
+
+	pathA, ok := h.formatPath(c, request.PathA)
+	if !ok {
+		return
+	}
+	pathB, ok := h.formatPath(c, request.PathB)
+	if !ok {
+		return
+	}
+
+	result, err := h.fs.CompareDirectories(pathA, pathB, request.Exclude, maxEntries)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.SendError(c, http.StatusNotFound, err)
+			return
+		}
+		h.SendError(c, pathErrorStatus(err, http.StatusUnprocessableEntity), err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, CompareResponse{
+		PathA:     pathA,
+		PathB:     pathB,
+		OnlyInA:   result.OnlyInA,
+		OnlyInB:   result.OnlyInB,
+		Different: result.Different,
+		Identical: result.Identical,
+		Truncated: result.Truncated,
+	})
+}
@@ -0,0 +1,126 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// WriteOptions sets the permissions and ownership of a file written by WriteFileAtomicWithOptions
+type WriteOptions struct {
+	Perm      os.FileMode // Mode of a new file, and of an existing one when ForcePerm is set
+	ForcePerm bool
+	Owner     string // Name or id, empty keeps the existing owner (or the default one for a new file)
+	Group     string // Name or id, empty keeps the existing group (or the default one for a new file)
+}
+
+// WriteFileAtomic replaces a file with the content of r without readers ever seeing
+// a partial file: the content is written to a temporary file in the same directory,
+// fsynced, then renamed over the target. It returns once the rename is durable.
+// An existing file keeps its permissions and ownership, perm only applies to new files.
+// A symlink target is resolved so the link keeps pointing to the swapped file.
+func (fs *Filesystem) WriteFileAtomic(path string, r io.Reader, perm os.FileMode) error {
+	return fs.WriteFileAtomicWithOptions(path, r, WriteOptions{Perm: perm})
+}
+
+// WriteFileAtomicWithOptions is WriteFileAtomic with the final permissions and ownership
+// set on the temporary file, before the rename, so the file never appears with other ones.
+// Ownership changes are skipped when the API is not privileged to make them.
+func (fs *Filesystem) WriteFileAtomicWithOptions(path string, r io.Reader, opts WriteOptions) error {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return err
+	}
+
+	// Resolved first, so that an unknown user fails the write before anything is written
+	uid, gid, err := lookupOwnership(opts.Owner, opts.Group)
+	if err != nil {
+		return err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	}
+
+	dir := filepath.Dir(absPath)
+	if err := fs.mkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	perm := opts.Perm
+	existing, statErr := os.Stat(absPath)
+	if statErr == nil {
+		if existing.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		if !opts.ForcePerm {
+			perm = existing.Mode().Perm()
+		}
+	} else if !os.IsNotExist(statErr) {
+		return statErr
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(absPath)+".swap-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return err
+	}
+	// CreateTemp always uses 0600, set the final mode before the file becomes visible
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if existing != nil {
+		if st, ok := existing.Sys().(*syscall.Stat_t); ok {
+			if err := lchownIfPermitted(tmpPath, int(st.Uid), int(st.Gid)); err != nil {
+				return err
+			}
+		}
+	} else if err := fs.applyDefaultOwnership(tmpPath); err != nil {
+		return err
+	}
+	if uid != -1 || gid != -1 {
+		if err := lchownIfPermitted(tmpPath, uid, gid); err != nil {
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, absPath); err != nil {
+		return err
+	}
+	committed = true
+
+	// Persist the rename itself
+	return syncDir(dir)
+}
+
+// syncDir fsyncs a directory so that entries created or renamed in it survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	target := filepath.Join(tempDir, "config.json")
+	if err := os.WriteFile(target, []byte(`{"v":1}`), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	link := filepath.Join(tempDir, "current.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if err := fs.WriteFileAtomic("current.json", strings.NewReader(`{"v":2}`), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil || string(content) != `{"v":2}` {
+		t.Errorf("Expected swapped content, got %q (%v)", content, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected existing permissions to be kept, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if linkInfo, err := os.Lstat(link); err != nil || linkInfo.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the symlink to be kept")
+	}
+
+	// New files use the given permissions and no temporary file is left behind
+	if err := fs.WriteFileAtomic("sub/new.txt", strings.NewReader("hello"), 0640); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+	info, err = os.Stat(filepath.Join(tempDir, "sub", "new.txt"))
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Expected new file with 0640, got %v (%v)", info, err)
+	}
+	entries, _ := os.ReadDir(tempDir)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".swap-") {
+			t.Errorf("Temporary file left behind: %s", entry.Name())
+		}
+	}
+
+	if err := fs.WriteFileAtomic("sub", strings.NewReader("x"), 0644); err == nil {
+		t.Errorf("Expected an error when swapping a directory")
+	}
+}
+
+func TestWriteFileAtomicWithOptions(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	target := filepath.Join(tempDir, "run.sh")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// Owning the file as the current user is always permitted
+	opts := WriteOptions{Perm: 0755, ForcePerm: true, Owner: strconv.Itoa(os.Getuid()), Group: strconv.Itoa(os.Getgid())}
+	if err := fs.WriteFileAtomicWithOptions("run.sh", strings.NewReader("#!/bin/sh\n"), opts); err != nil {
+		t.Fatalf("WriteFileAtomicWithOptions failed: %v", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the forced permissions on the existing file, got %v (%v)", info, err)
+	}
+
+	err = fs.WriteFileAtomicWithOptions("run.sh", strings.NewReader("replaced"), WriteOptions{Perm: 0644, Owner: "no-such-user-for-tests"})
+	if err == nil || !strings.Contains(err.Error(), "unknown owner") {
+		t.Errorf("Expected an unknown owner error, got %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "#!/bin/sh\n" {
+		t.Errorf("Expected the file to be untouched after a failed write, got %q", content)
+	}
+}
@@ -0,0 +1,189 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DirectoryComparison lists the differences between two directory trees.
+// Paths are relative to the compared directories.
+type DirectoryComparison struct {
+	OnlyInA   []string
+	OnlyInB   []string
+	Different []string
+	Identical int
+	Truncated bool
+}
+
+// compareEntry is a file or symlink found while walking a compared tree
+type compareEntry struct {
+	absPath string
+	info    os.FileInfo
+}
+
+// CompareDirectories compares the files of two directory trees: files present in only
+// one of them, and files present in both whose content differs (by size, then SHA-256).
+// Symlinks are compared by target and never followed; directories themselves are not
+// reported, only the files they contain. Files and directories whose name matches one
+// of the exclude glob patterns are skipped. At most maxEntries differences are listed
+// (0 lists them all), Truncated is set when some were left out.
+func (fs *Filesystem) CompareDirectories(pathA string, pathB string, exclude []string, maxEntries int) (*DirectoryComparison, error) {
+	for _, pattern := range exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	filesA, err := fs.collectCompareEntries(pathA, exclude)
+	if err != nil {
+		return nil, err
+	}
+	filesB, err := fs.collectCompareEntries(pathB, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DirectoryComparison{OnlyInA: []string{}, OnlyInB: []string{}, Different: []string{}}
+	count := 0
+	add := func(list *[]string, rel string) {
+		if maxEntries > 0 && count >= maxEntries {
+			result.Truncated = true
+			return
+		}
+		count++
+		*list = append(*list, rel)
+	}
+
+	for _, rel := range sortedKeys(filesA) {
+		b, ok := filesB[rel]
+		if !ok {
+			add(&result.OnlyInA, rel)
+			continue
+		}
+		same, err := sameFileContent(filesA[rel], b)
+		if err != nil {
+			return nil, err
+		}
+		if same {
+			result.Identical++
+		} else {
+			add(&result.Different, rel)
+		}
+	}
+	for _, rel := range sortedKeys(filesB) {
+		if _, ok := filesA[rel]; !ok {
+			add(&result.OnlyInB, rel)
+		}
+	}
+	return result, nil
+}
+
+// collectCompareEntries walks a directory and returns its files and symlinks by relative path
+func (fs *Filesystem) collectCompareEntries(path string, exclude []string) (map[string]compareEntry, error) {
+	root, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+
+	entries := make(map[string]compareEntry)
+	err = filepath.WalkDir(root, func(entryPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entryPath == root {
+			return nil
+		}
+		for _, pattern := range exclude {
+			if matched, _ := filepath.Match(pattern, d.Name()); matched {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		entryInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, entryPath)
+		if err != nil {
+			return err
+		}
+		entries[filepath.ToSlash(rel)] = compareEntry{absPath: entryPath, info: entryInfo}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// sameFileContent reports whether two entries have the same type and content
+func sameFileContent(a compareEntry, b compareEntry) (bool, error) {
+	if a.info.Mode().Type() != b.info.Mode().Type() {
+		return false, nil
+	}
+	if a.info.Mode()&os.ModeSymlink != 0 {
+		targetA, err := os.Readlink(a.absPath)
+		if err != nil {
+			return false, err
+		}
+		targetB, err := os.Readlink(b.absPath)
+		if err != nil {
+			return false, err
+		}
+		return targetA == targetB, nil
+	}
+	if !a.info.Mode().IsRegular() {
+		return true, nil // Devices, sockets and pipes have no content to compare
+	}
+	if a.info.Size() != b.info.Size() {
+		return false, nil
+	}
+	hashA, err := hashFile(a.absPath)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(b.absPath)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(hashA, hashB), nil
+}
+
+func hashFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+func sortedKeys(entries map[string]compareEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompareDirectories(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"a/same.txt":            "hello",
+		"b/same.txt":            "hello",
+		"a/sub/changed.txt":     "one",
+		"b/sub/changed.txt":     "two",
+		"a/resized.txt":         "short",
+		"b/resized.txt":         "much longer",
+		"a/only-a.txt":          "x",
+		"b/sub/deep/only-b.txt": "y",
+		"a/node_modules/m.js":   "ignored",
+		"a/cache.pyc":           "ignored",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.Symlink("same.txt", filepath.Join(tempDir, "a", "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink("only-b.txt", filepath.Join(tempDir, "b", "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	result, err := fs.CompareDirectories("a", "b", []string{"node_modules", "*.pyc"}, 0)
+	if err != nil {
+		t.Fatalf("CompareDirectories failed: %v", err)
+	}
+	if want := []string{"only-a.txt"}; !reflect.DeepEqual(result.OnlyInA, want) {
+		t.Errorf("OnlyInA = %v, want %v", result.OnlyInA, want)
+	}
+	if want := []string{"sub/deep/only-b.txt"}; !reflect.DeepEqual(result.OnlyInB, want) {
+		t.Errorf("OnlyInB = %v, want %v", result.OnlyInB, want)
+	}
+	if want := []string{"link", "resized.txt", "sub/changed.txt"}; !reflect.DeepEqual(result.Different, want) {
+		t.Errorf("Different = %v, want %v", result.Different, want)
+	}
+	if result.Identical != 1 || result.Truncated {
+		t.Errorf("Expected 1 identical file and no truncation, got %+v", result)
+	}
+
+	// Without excludes, the node_modules and .pyc files only exist in a
+	result, err = fs.CompareDirectories("a", "b", nil, 2)
+	if err != nil {
+		t.Fatalf("CompareDirectories failed: %v", err)
+	}
+	if !result.Truncated || len(result.OnlyInA)+len(result.OnlyInB)+len(result.Different) != 2 {
+		t.Errorf("Expected 2 entries and truncation, got %+v", result)
+	}
+
+	if _, err := fs.CompareDirectories("a", "a/same.txt", nil, 0); err == nil {
+		t.Error("Expected an error when comparing a file")
+	}
+	if _, err := fs.CompareDirectories("a", "b", []string{"["}, 0); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
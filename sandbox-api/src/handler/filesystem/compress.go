@@ -0,0 +1,40 @@
+package filesystem
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// CompressGzip is the only compression supported for file content
+const CompressGzip = "gzip"
+
+// ValidateCompress checks the compression requested for file content, empty meaning none
+func ValidateCompress(compress string) error {
+	switch compress {
+	case "", CompressGzip:
+		return nil
+	default:
+		return fmt.Errorf("invalid compress '%s', must be gzip", compress)
+	}
+}
+
+// CopyGzip writes the gzip-compressed content of r to w
+func CopyGzip(w io.Writer, r io.Reader) error {
+	gz := gzip.NewWriter(w)
+	if _, err := io.Copy(gz, r); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// GzipBytes returns data gzip-compressed
+func GzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := CopyGzip(&buf, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,32 @@
+package filesystem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGzipContentRoundTrip(t *testing.T) {
+	if err := ValidateCompress("br"); err == nil {
+		t.Error("Expected an error for an unsupported compression")
+	}
+
+	content := bytes.Repeat([]byte("hello world\n"), 50)
+	gzipped, err := GzipBytes(content)
+	if err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+
+	file := FileWithContentByte{Content: gzipped, ContentEncoding: CompressGzip}
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var decoded FileWithContentByte
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if decoded.ContentEncoding != CompressGzip || !bytes.Equal(decoded.Content, gzipped) {
+		t.Errorf("Expected the gzipped bytes to survive the JSON round trip, got %q", decoded.ContentEncoding)
+	}
+}
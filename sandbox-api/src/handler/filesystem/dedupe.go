@@ -0,0 +1,138 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// DedupeResult summarizes a deduplication of a directory
+type DedupeResult struct {
+	Path           string `json:"path" binding:"required" example:"/app/node_modules"`
+	Files          int    `json:"files" binding:"required" example:"1200"`              // Regular files scanned
+	Duplicates     int    `json:"duplicates" binding:"required" example:"340"`          // Files replaced (or that would be) by a hardlink
+	BytesReclaimed int64  `json:"bytesReclaimed" binding:"required" example:"52428800"` // Disk space freed (or that would be)
+	DryRun         bool   `json:"dryRun" binding:"required" example:"false"`
+} // @name DedupeResult
+
+// dedupeKey groups the files that can share an inode: same size, device, permissions and owner.
+// Linking files with different metadata would change the permissions or owner of one of them.
+type dedupeKey struct {
+	size int64
+	dev  uint64
+	mode os.FileMode
+	uid  uint32
+	gid  uint32
+}
+
+// DedupeOptions configures a deduplication
+type DedupeOptions struct {
+	// DryRun only reports what would be reclaimed
+	DryRun bool
+	// Progress, when set, is called with the number of files scanned or hashed
+	Progress func(n int64)
+}
+
+// Dedupe replaces the identical regular files under the directory at path by hardlinks to a
+// single copy. Files are identical when their content hashes match and they have the same
+// permissions and owner. Symlinks, empty files and files that already have several links are
+// skipped. Each duplicate is replaced atomically, so it is never missing.
+func (fs *Filesystem) Dedupe(ctx context.Context, path string, opts DedupeOptions) (DedupeResult, error) {
+	result := DedupeResult{DryRun: opts.DryRun}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(int64) {}
+	}
+
+	root, err := fs.snapshotDirectory(path)
+	if err != nil {
+		return result, err
+	}
+	result.Path = root
+
+	// Group candidates by size first, so only files that can be identical are hashed
+	candidates := map[dedupeKey][]string{}
+	err = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() && isWithin(SnapshotDir, p) {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		result.Files++
+		progress(1)
+
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok || info.Size() == 0 || stat.Nlink > 1 {
+			return nil
+		}
+		key := dedupeKey{size: info.Size(), dev: uint64(stat.Dev), mode: info.Mode(), uid: stat.Uid, gid: stat.Gid}
+		candidates[key] = append(candidates[key], p)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for key, paths := range candidates {
+		if len(paths) < 2 {
+			continue
+		}
+		originals := map[string]string{}
+		for _, p := range paths {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			sum, err := hashFile(p)
+			progress(1)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return result, err
+			}
+			original, exists := originals[string(sum)]
+			if !exists {
+				originals[string(sum)] = p
+				continue
+			}
+			if !opts.DryRun {
+				if err := replaceWithLink(original, p); err != nil {
+					return result, fmt.Errorf("failed to link %s to %s: %w", p, original, err)
+				}
+			}
+			result.Duplicates++
+			result.BytesReclaimed += key.size
+		}
+	}
+	return result, nil
+}
+
+// replaceWithLink atomically replaces duplicate with a hardlink to original, by linking under
+// a temporary name next to duplicate then renaming it over
+func replaceWithLink(original string, duplicate string) error {
+	tmp := filepath.Join(filepath.Dir(duplicate), fmt.Sprintf(".%s.dedupe-%d", filepath.Base(duplicate), os.Getpid()))
+	if err := os.Link(original, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, duplicate); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
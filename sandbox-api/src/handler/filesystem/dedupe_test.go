@@ -0,0 +1,97 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	root := t.TempDir()
+	content := strings.Repeat("same content\n", 100)
+	write := func(name string, data string, perm os.FileMode) string {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(data), perm); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatalf("Failed to chmod %s: %v", name, err)
+		}
+		return path
+	}
+	original := write("a/index.js", content, 0644)
+	duplicate := write("b/index.js", content, 0644)
+	other := write("b/other.js", strings.Repeat("other content\n", 100), 0644)
+	private := write("c/index.js", content, 0600) // Different permissions
+	write("empty1", "", 0644)
+	write("empty2", "", 0644)
+	linked := write("d/linked.js", content, 0644)
+	if err := os.Link(linked, filepath.Join(root, "d/linked2.js")); err != nil {
+		t.Fatalf("Failed to link: %v", err)
+	}
+	if err := os.Symlink(original, filepath.Join(root, "link.js")); err != nil {
+		t.Fatalf("Failed to symlink: %v", err)
+	}
+
+	fs := NewFilesystem("/")
+	result, err := fs.Dedupe(context.Background(), root, DedupeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if result.Files != 8 || result.Duplicates != 1 || result.BytesReclaimed != int64(len(content)) || !result.DryRun {
+		t.Errorf("Unexpected dry run result: %+v", result)
+	}
+	if sameFile(t, original, duplicate) {
+		t.Fatal("Expected a dry run to leave files untouched")
+	}
+
+	var progress int64
+	result, err = fs.Dedupe(context.Background(), root, DedupeOptions{Progress: func(n int64) { progress += n }})
+	if err != nil {
+		t.Fatalf("Dedupe failed: %v", err)
+	}
+	if result.Duplicates != 1 || result.BytesReclaimed != int64(len(content)) || progress == 0 {
+		t.Errorf("Unexpected result: %+v (progress %d)", result, progress)
+	}
+	if !sameFile(t, original, duplicate) {
+		t.Error("Expected the duplicate to be a hardlink to the original")
+	}
+	if data, _ := os.ReadFile(duplicate); string(data) != content {
+		t.Error("Expected the duplicate to keep its content")
+	}
+	for _, path := range []string{other, private, linked} {
+		if sameFile(t, original, path) {
+			t.Errorf("Expected %s not to be linked", path)
+		}
+	}
+
+	// Nothing left to reclaim
+	if result, err := fs.Dedupe(context.Background(), root, DedupeOptions{}); err != nil || result.Duplicates != 0 {
+		t.Errorf("Expected no duplicates left, got %+v (%v)", result, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fs.Dedupe(ctx, root, DedupeOptions{}); err == nil {
+		t.Error("Expected a cancelled dedupe to fail")
+	}
+	if _, err := fs.Dedupe(context.Background(), original, DedupeOptions{}); err == nil {
+		t.Error("Expected an error for a file")
+	}
+}
+
+func sameFile(t *testing.T, a, b string) bool {
+	t.Helper()
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		t.Fatalf("Failed to stat: %v %v", errA, errB)
+	}
+	return infoA.Sys().(*syscall.Stat_t).Ino == infoB.Sys().(*syscall.Stat_t).Ino
+}
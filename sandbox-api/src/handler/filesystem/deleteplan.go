@@ -0,0 +1,83 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DeletePlan describes what a delete would remove
+type DeletePlan struct {
+	Entries     []string
+	Files       int
+	Directories int
+	Size        int64
+	Truncated   bool
+}
+
+// PlanDelete reports what deleting path would remove, without removing anything.
+// Directories are walked only when recursive is set; a non-recursive delete of a
+// non-empty directory fails with ErrDirectoryNotEmpty, like DeleteDirectory.
+// At most maxEntries paths are listed (0 lists them all), counts and size always cover everything.
+// Symlinks are reported as files and never followed, as RemoveAll would remove the link only.
+func (fs *Filesystem) PlanDelete(path string, recursive bool, maxEntries int) (*DeletePlan, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &DeletePlan{Entries: []string{}}
+	add := func(entryPath string, info os.FileInfo) {
+		if info.IsDir() {
+			plan.Directories++
+		} else {
+			plan.Files++
+			if info.Mode().IsRegular() {
+				plan.Size += info.Size()
+			}
+		}
+		if maxEntries > 0 && len(plan.Entries) >= maxEntries {
+			plan.Truncated = true
+			return
+		}
+		plan.Entries = append(plan.Entries, entryPath)
+	}
+
+	if !info.IsDir() {
+		add(absPath, info)
+		return plan, nil
+	}
+
+	if !recursive {
+		count, err := fs.CountEntries(absPath)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrDirectoryNotEmpty, path)
+		}
+		add(absPath, info)
+		return plan, nil
+	}
+
+	err = filepath.WalkDir(absPath, func(entryPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		entryInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		add(entryPath, entryInfo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
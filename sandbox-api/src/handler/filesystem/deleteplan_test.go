@@ -0,0 +1,56 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanDelete(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	root := filepath.Join(tempDir, "build")
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("world!"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// The symlink is counted, its target is not walked
+	if err := os.Symlink(tempDir, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	plan, err := fs.PlanDelete("build", true, 0)
+	if err != nil {
+		t.Fatalf("PlanDelete failed: %v", err)
+	}
+	if plan.Files != 3 || plan.Directories != 2 || plan.Size != 11 {
+		t.Errorf("Expected 3 files, 2 directories and 11 bytes, got %+v", plan)
+	}
+	if len(plan.Entries) != 5 || plan.Truncated {
+		t.Errorf("Expected 5 entries, got %v (truncated=%v)", plan.Entries, plan.Truncated)
+	}
+
+	plan, err = fs.PlanDelete("build", true, 2)
+	if err != nil {
+		t.Fatalf("PlanDelete failed: %v", err)
+	}
+	if len(plan.Entries) != 2 || !plan.Truncated || plan.Files != 3 {
+		t.Errorf("Expected 2 listed entries with complete counts, got %+v", plan)
+	}
+
+	if _, err := fs.PlanDelete("build", false, 0); !errors.Is(err, ErrDirectoryNotEmpty) {
+		t.Errorf("Expected ErrDirectoryNotEmpty, got %v", err)
+	}
+
+	// Nothing was removed
+	if _, err := os.Stat(filepath.Join(root, "sub", "b.txt")); err != nil {
+		t.Errorf("Dry run removed files: %v", err)
+	}
+}
@@ -17,12 +17,19 @@ type Subdirectory struct {
 
 // Directory represents a directory in the filesystem
 type Directory struct {
-	Path           string          `json:"path" binding:"required"`
-	Name           string          `json:"name" binding:"required"`
-	Files          []*File         `json:"files" binding:"required"`
-	Subdirectories []*Subdirectory `json:"subdirectories" binding:"required"` // @name Subdirectories
+	Path           string            `json:"path" binding:"required"`
+	Name           string            `json:"name" binding:"required"`
+	Files          []*File           `json:"files" binding:"required"`
+	Subdirectories []*Subdirectory   `json:"subdirectories" binding:"required"` // @name Subdirectories
+	Entries        []*DirectoryEntry `json:"entries,omitempty"`                 // Files and subdirectories together in the requested order, only for sorted listings
 } // @name Directory
 
+// DirectoryEntry is an entry of a sorted directory listing
+type DirectoryEntry struct {
+	Name string `json:"name" binding:"required" example:"src"`
+	Type string `json:"type" binding:"required" example:"directory" enums:"directory,file,symlink,fifo,socket,device"`
+} // @name DirectoryEntry
+
 func NewDirectory(path string) *Directory {
 	return &Directory{
 		Path:           path,
@@ -164,11 +171,25 @@ func (fs *Filesystem) WatchDirectory(path string, callback func(event fsnotify.E
 	return stop, nil
 }
 
+// addWatch adds a directory to a watcher, replaced in tests to simulate failures
+var addWatch = func(watcher *fsnotify.Watcher, dir string) error {
+	return watcher.Add(dir)
+}
+
 // WatchDirectoryRecursive watches a directory and all its subdirectories for changes.
 // The callback is called with the event when a change occurs.
 // This handles the race condition where files may be created in a new directory
 // before the watch is established on that directory.
 func (fs *Filesystem) WatchDirectoryRecursive(path string, callback func(event fsnotify.Event)) (func(), error) {
+	return fs.WatchDirectoryRecursiveWithErrors(path, callback, nil)
+}
+
+// WatchDirectoryRecursiveWithErrors is WatchDirectoryRecursive reporting the subdirectories
+// that can't be watched (permissions, inotify watch limit...) to onError instead of failing:
+// their events, and those of their descendants, are missed. Failures of the initial walk are
+// reported before it returns, those of directories created later as they happen. Only a
+// failure to watch path itself is an error.
+func (fs *Filesystem) WatchDirectoryRecursiveWithErrors(path string, callback func(event fsnotify.Event), onError func(dir string, err error)) (func(), error) {
 	absPath, err := fs.GetAbsolutePath(path)
 	if err != nil {
 		return nil, err
@@ -179,19 +200,41 @@ func (fs *Filesystem) WatchDirectoryRecursive(path string, callback func(event f
 		return nil, err
 	}
 
-	// Helper to add a directory to the watcher
+	if onError == nil {
+		onError = func(dir string, err error) {
+			logrus.WithField("path", dir).WithError(err).Warn("Failed to watch directory")
+		}
+	}
+
+	// Helper to add a directory to the watcher, reporting subdirectories that can't be watched
 	addDir := func(dir string) error {
-		return watcher.Add(dir)
+		err := addWatch(watcher, dir)
+		if err != nil && dir != absPath {
+			onError(dir, err)
+		}
+		return err
 	}
 
-	// Helper to add all subdirectories
+	// Helper to add all subdirectories, skipping the ones that can't be read or watched
 	addDirs := func(root string) error {
 		return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
 			if err != nil {
-				return err
+				if p == root {
+					return err
+				}
+				onError(p, err)
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
 			}
 			if info.IsDir() {
-				return addDir(p)
+				if err := addDir(p); err != nil {
+					if p == root {
+						return err
+					}
+					return filepath.SkipDir
+				}
 			}
 			return nil
 		})
@@ -0,0 +1,63 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrFIFOsNotSupported is returned when named pipes cannot be created on this platform
+var ErrFIFOsNotSupported = errors.New("named pipes are not supported on this platform")
+
+// File types reported in directory listings
+const (
+	FileTypeFile    = "file"
+	FileTypeSymlink = "symlink"
+	FileTypeFIFO    = "fifo"
+	FileTypeSocket  = "socket"
+	FileTypeDevice  = "device"
+)
+
+// FileType returns the type of a non-directory entry from its mode
+func FileType(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return FileTypeSymlink
+	case mode&os.ModeNamedPipe != 0:
+		return FileTypeFIFO
+	case mode&os.ModeSocket != 0:
+		return FileTypeSocket
+	case mode&os.ModeDevice != 0:
+		return FileTypeDevice
+	default:
+		return FileTypeFile
+	}
+}
+
+// CreateFIFO creates a named pipe at the given path, creating its parent directories.
+// An existing named pipe is kept as is, any other existing entry is an error.
+func (fs *Filesystem) CreateFIFO(path string, perm os.FileMode) error {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(absPath); err == nil {
+		if info.Mode()&os.ModeNamedPipe != 0 {
+			return nil
+		}
+		return &os.PathError{Op: "mkfifo", Path: path, Err: os.ErrExist}
+	}
+
+	if err := fs.mkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return err
+	}
+	if err := mkfifo(absPath, perm); err != nil {
+		return err
+	}
+	// mkfifo applies the umask, set the requested permissions explicitly
+	if err := os.Chmod(absPath, perm); err != nil {
+		return err
+	}
+	return fs.applyDefaultOwnership(absPath)
+}
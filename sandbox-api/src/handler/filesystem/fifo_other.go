@@ -0,0 +1,10 @@
+//go:build !unix
+
+package filesystem
+
+import "os"
+
+// mkfifo returns an error on platforms without named pipes
+func mkfifo(absPath string, perm os.FileMode) error {
+	return ErrFIFOsNotSupported
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateFIFO(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := fs.CreateFIFO("pipes/events", 0600); err != nil {
+		t.Fatalf("CreateFIFO failed: %v", err)
+	}
+	info, err := os.Lstat(filepath.Join(tempDir, "pipes", "events"))
+	if err != nil {
+		t.Fatalf("Failed to stat the named pipe: %v", err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a named pipe with 0600, got %v", info.Mode())
+	}
+
+	// Creating it again is a no-op, replacing a regular file is an error
+	if err := fs.CreateFIFO("pipes/events", 0600); err != nil {
+		t.Errorf("Expected an existing named pipe to be kept, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "pipes", "data.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := fs.CreateFIFO("pipes/data.txt", 0600); err == nil {
+		t.Errorf("Expected an error when a regular file exists")
+	}
+
+	dir, err := fs.ListDirectory("pipes")
+	if err != nil {
+		t.Fatalf("ListDirectory failed: %v", err)
+	}
+	types := map[string]string{}
+	for _, file := range dir.Files {
+		types[file.Name] = file.Type
+	}
+	if types["events"] != FileTypeFIFO || types["data.txt"] != FileTypeFile {
+		t.Errorf("Unexpected types in listing: %v", types)
+	}
+}
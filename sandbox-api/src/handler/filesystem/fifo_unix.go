@@ -0,0 +1,16 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+)
+
+// mkfifo creates a named pipe
+func mkfifo(absPath string, perm os.FileMode) error {
+	if err := syscall.Mkfifo(absPath, uint32(perm.Perm())); err != nil {
+		return &os.PathError{Op: "mkfifo", Path: absPath, Err: err}
+	}
+	return nil
+}
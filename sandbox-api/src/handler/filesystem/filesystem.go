@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -17,10 +18,24 @@ import (
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
+// ErrDirectoryNotEmpty is returned when a non-recursive delete targets a directory that still has entries
+var ErrDirectoryNotEmpty = errors.New("directory is not empty")
+
+// ErrDirectoryExists is returned when an exclusive directory creation targets an existing path
+var ErrDirectoryExists = errors.New("directory already exists")
+
+// ErrPathOutsideRoot is returned when confinement is enabled and a path resolves outside the working directory
+var ErrPathOutsideRoot = errors.New("path resolves outside of the working directory")
+
 // Filesystem represents the root directory of the filesystem
 type Filesystem struct {
 	Root       string `json:"root"`
 	WorkingDir string `json:"workingDir"`
+	// Confine rejects every path that, after symlink resolution, escapes WorkingDir
+	Confine bool `json:"-"`
+	// DefaultOwner and DefaultGroup (names or ids) own files and directories created through the API
+	DefaultOwner string `json:"-"`
+	DefaultGroup string `json:"-"`
 } // @name Filesystem
 
 // FileByte represents a file in the filesystem
@@ -42,6 +57,7 @@ type File struct {
 	LastModified time.Time `json:"lastModified" binding:"required"`
 	Owner        string    `json:"owner" binding:"required"`
 	Group        string    `json:"group" binding:"required"`
+	Type         string    `json:"type,omitempty" example:"file" enums:"file,symlink,fifo,socket,device"` // Set in directory listings
 } // @name File
 
 // MarshalJSON implements json.Marshaler for custom JSON marshaling
@@ -129,12 +145,20 @@ func (f *FileByte) UnmarshalJSON(data []byte) error {
 type FileWithContentByte struct {
 	FileByte
 	Content []byte `json:"-"`
+	// ContentEncoding is CompressGzip when Content holds the gzip-compressed file
+	ContentEncoding string `json:"-"`
+	// Hash is the hex digest of the file content with HashAlgorithm, when requested
+	Hash          string `json:"-"`
+	HashAlgorithm string `json:"-"`
 }
 
 // FileWithContent is a data transfer object for FileWithContent with encoded content
 type FileWithContent struct {
 	File
-	Content string `json:"content" binding:"required"`
+	Content         string `json:"content" binding:"required"`
+	ContentEncoding string `json:"contentEncoding,omitempty" example:"gzip"`                                                  // Set to gzip when content is the base64 of the gzip-compressed file (compress=gzip)
+	Hash            string `json:"hash,omitempty" example:"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"` // Hex digest of the file content, before compression (withHash)
+	HashAlgorithm   string `json:"hashAlgorithm,omitempty" example:"sha256"`
 } // @name FileWithContent
 
 // MarshalJSON implements json.Marshaler for custom JSON marshaling
@@ -148,9 +172,17 @@ func (f FileWithContentByte) MarshalJSON() ([]byte, error) {
 		Group:        f.Group,
 	}
 
+	content := string(f.Content)
+	if f.ContentEncoding == CompressGzip {
+		content = base64.StdEncoding.EncodeToString(f.Content)
+	}
+
 	return json.Marshal(FileWithContent{
-		File:    fileDTO,
-		Content: string(f.Content),
+		File:            fileDTO,
+		Content:         content,
+		ContentEncoding: f.ContentEncoding,
+		Hash:            f.Hash,
+		HashAlgorithm:   f.HashAlgorithm,
 	})
 }
 
@@ -174,6 +206,14 @@ func (f *FileWithContentByte) UnmarshalJSON(data []byte) error {
 
 	f.FileByte = file
 	f.Content = []byte(dto.Content)
+	f.ContentEncoding = dto.ContentEncoding
+	f.Hash = dto.Hash
+	f.HashAlgorithm = dto.HashAlgorithm
+	if dto.ContentEncoding == CompressGzip {
+		if f.Content, err = base64.StdEncoding.DecodeString(dto.Content); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -196,6 +236,24 @@ func (fs *Filesystem) ResolveDisplayPath(path string) string {
 
 // GetAbsolutePath gets the absolute path, ensuring it's within the root
 func (fs *Filesystem) GetAbsolutePath(path string) (string, error) {
+	absPath, err := fs.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if fs.Confine {
+		if err := fs.checkConfined(absPath); err != nil {
+			return "", err
+		}
+	}
+
+	return absPath, nil
+}
+
+// ResolvePath returns the cleaned absolute path of path, relative paths being resolved from
+// the working directory. Unlike GetAbsolutePath, it never touches the filesystem: symlinks
+// are not resolved and confinement is not checked.
+func (fs *Filesystem) ResolvePath(path string) (string, error) {
 	var absPath string
 
 	// If path is absolute (starts with /), use it directly
@@ -222,6 +280,48 @@ func (fs *Filesystem) GetAbsolutePath(path string) (string, error) {
 	return absPath, nil
 }
 
+// checkConfined resolves symlinks in absPath and ensures the result stays within the working directory.
+// Paths that don't exist yet are checked through their deepest existing ancestor.
+func (fs *Filesystem) checkConfined(absPath string) error {
+	root, err := filepath.EvalSymlinks(fs.WorkingDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	resolved, err := resolveExistingPrefix(absPath)
+	if err != nil {
+		return err
+	}
+
+	relPath, err := filepath.Rel(root, resolved)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return ErrPathOutsideRoot
+	}
+	return nil
+}
+
+// resolveExistingPrefix evaluates symlinks on the deepest existing ancestor of path
+// and re-appends the remaining, not yet created, components
+func resolveExistingPrefix(path string) (string, error) {
+	remaining := ""
+	current := path
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(resolved, remaining), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path, nil
+		}
+		remaining = filepath.Join(filepath.Base(current), remaining)
+		current = parent
+	}
+}
+
 // FileExists checks if a file exists at the given path
 func (fs *Filesystem) FileExists(path string) (bool, error) {
 	absPath, err := fs.GetAbsolutePath(path)
@@ -322,11 +422,18 @@ func (fs *Filesystem) WriteFile(path string, content []byte, perm os.FileMode) e
 
 	// Ensure parent directory exists
 	dir := filepath.Dir(absPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := fs.mkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(absPath, content, perm)
+	_, statErr := os.Lstat(absPath)
+	if err := os.WriteFile(absPath, content, perm); err != nil {
+		return err
+	}
+	if os.IsNotExist(statErr) {
+		return fs.applyDefaultOwnership(absPath)
+	}
+	return nil
 }
 
 // WriteFileFromReader streams content from a reader to a file on disk
@@ -338,10 +445,11 @@ func (fs *Filesystem) WriteFileFromReader(path string, r io.Reader, perm os.File
 
 	// Ensure parent directory exists
 	dir := filepath.Dir(absPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := fs.mkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
+	_, statErr := os.Lstat(absPath)
 	f, err := os.OpenFile(absPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
 	if err != nil {
 		return err
@@ -354,6 +462,9 @@ func (fs *Filesystem) WriteFileFromReader(path string, r io.Reader, perm os.File
 		_ = f.Close() // Close file before attempting to remove
 		return err
 	}
+	if os.IsNotExist(statErr) {
+		return fs.applyDefaultOwnership(absPath)
+	}
 	return nil
 }
 
@@ -364,11 +475,41 @@ func (fs *Filesystem) CreateDirectory(path string, perm os.FileMode) error {
 		return err
 	}
 
-	return os.MkdirAll(absPath, perm)
+	return fs.mkdirAll(absPath, perm)
 }
 
-// ListDirectory lists files and directories in the given path
+// CreateDirectoryExclusive creates a directory like CreateDirectory, but fails with
+// ErrDirectoryExists when something already exists at path. The last directory is created
+// with a single mkdir, so only one of concurrent calls succeeds.
+func (fs *Filesystem) CreateDirectoryExclusive(path string, perm os.FileMode) error {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := fs.mkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(absPath, perm); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%w: %s", ErrDirectoryExists, path)
+		}
+		return err
+	}
+	return fs.applyDefaultOwnership(absPath)
+}
+
+// ListDirectory lists files and directories in the given path, in OS order
 func (fs *Filesystem) ListDirectory(path string) (*Directory, error) {
+	return fs.ListDirectoryWithOptions(path, ListOptions{})
+}
+
+// ListDirectoryWithOptions is ListDirectory with the entries ordered according to opts
+func (fs *Filesystem) ListDirectoryWithOptions(path string, opts ListOptions) (*Directory, error) {
+	if !validListSorts[opts.Sort] {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidListSort, opts.Sort)
+	}
+
 	absPath, err := fs.GetAbsolutePath(path)
 	if err != nil {
 		return nil, err
@@ -383,28 +524,44 @@ func (fs *Filesystem) ListDirectory(path string) (*Directory, error) {
 		return nil, err
 	}
 
+	// Use os.Lstat to get info about the symlink itself, not its target
+	// This prevents errors when symlinks point to non-existent targets
+	infos := make([]os.FileInfo, 0, len(entries))
 	for _, entry := range entries {
-		// Use displayPath for the entry paths too
-		entryPath := filepath.Join(displayPath, entry.Name())
-		absEntryPath := filepath.Join(absPath, entry.Name())
-
-		// Use os.Lstat to get info about the symlink itself, not its target
-		// This prevents errors when symlinks point to non-existent targets
-		info, err := os.Lstat(absEntryPath)
+		info, err := os.Lstat(filepath.Join(absPath, entry.Name()))
 		if err != nil {
 			return nil, err
 		}
+		infos = append(infos, info)
+	}
+	if opts.ordered() {
+		sortListing(infos, opts)
+		dir.Entries = make([]*DirectoryEntry, 0, len(infos))
+	}
+
+	for _, info := range infos {
+		// Use displayPath for the entry paths too
+		entryPath := filepath.Join(displayPath, info.Name())
+		absEntryPath := filepath.Join(absPath, info.Name())
+
+		if dir.Entries != nil {
+			entryType := FileTypeDirectory
+			if !info.IsDir() {
+				entryType = FileType(info.Mode())
+			}
+			dir.Entries = append(dir.Entries, &DirectoryEntry{Name: info.Name(), Type: entryType})
+		}
 
 		if info.IsDir() {
-			dir.AddSubdirectory(&Subdirectory{Path: entryPath, Name: entry.Name()})
+			dir.AddSubdirectory(&Subdirectory{Path: entryPath, Name: info.Name()})
 		} else {
-			// It's a file or symlink
+			// It's a file, symlink or special file (named pipe, socket, device)
 			owner, group, err := fs.getFileOwnerAndGroup(absEntryPath)
 			if err != nil {
 				return nil, err
 			}
 
-			file := &File{Path: entryPath, Name: entry.Name(), Permissions: fmt.Sprintf("%o", info.Mode()), Size: info.Size(), LastModified: info.ModTime(), Owner: owner, Group: group}
+			file := &File{Path: entryPath, Name: info.Name(), Permissions: fmt.Sprintf("%o", info.Mode()), Size: info.Size(), LastModified: info.ModTime(), Owner: owner, Group: group, Type: FileType(info.Mode())}
 			dir.AddFile(file)
 		}
 	}
@@ -450,7 +607,41 @@ func (fs *Filesystem) DeleteDirectory(path string, recursive bool) error {
 	if recursive {
 		return os.RemoveAll(absPath)
 	}
-	return os.Remove(absPath) // This will fail if directory is not empty
+	// This will fail if directory is not empty
+	if err := os.Remove(absPath); err != nil {
+		if errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST) {
+			return fmt.Errorf("%w: %s", ErrDirectoryNotEmpty, path)
+		}
+		return err
+	}
+	return nil
+}
+
+// CountEntries returns the number of entries directly inside a directory
+func (fs *Filesystem) CountEntries(path string) (int, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	dir, err := os.Open(absPath)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = dir.Close() }()
+
+	// Read names in batches so huge directories don't need to be held in memory
+	count := 0
+	for {
+		names, err := dir.Readdirnames(1024)
+		count += len(names)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
 }
 
 // CopyFile copies a file from src to dst
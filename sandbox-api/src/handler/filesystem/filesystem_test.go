@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -74,6 +75,56 @@ func TestGetAbsolutePath(t *testing.T) {
 	}
 }
 
+// TestGetAbsolutePathConfined tests that confinement rejects symlinks escaping the working directory
+func TestGetAbsolutePathConfined(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	outsideDir, err := os.MkdirTemp("", "filesystem-outside-*")
+	if err != nil {
+		t.Fatalf("Failed to create outside directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(outsideDir) }()
+
+	if err := os.Mkdir(filepath.Join(tempDir, "inside"), 0755); err != nil {
+		t.Fatalf("Failed to create inside directory: %v", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(tempDir, "escape")); err != nil {
+		t.Fatalf("Failed to create escaping symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(tempDir, "inside"), filepath.Join(tempDir, "internal")); err != nil {
+		t.Fatalf("Failed to create internal symlink: %v", err)
+	}
+
+	fs.Confine = true
+
+	testCases := []struct {
+		path      string
+		shouldErr bool
+	}{
+		{"file.txt", false},
+		{"inside/new/file.txt", false},
+		{"internal/file.txt", false},
+		{"escape", true},
+		{"escape/file.txt", true},
+		{"escape/missing/file.txt", true},
+		{filepath.Join(outsideDir, "file.txt"), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			_, err := fs.GetAbsolutePath(tc.path)
+			if tc.shouldErr {
+				if !errors.Is(err, ErrPathOutsideRoot) {
+					t.Errorf("Expected ErrPathOutsideRoot for path %s, got %v", tc.path, err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error for path %s: %v", tc.path, err)
+			}
+		})
+	}
+}
+
 // TestFileOperations tests basic file operations
 func TestFileOperations(t *testing.T) {
 	_, fs, cleanup := setupTestEnvironment(t)
@@ -347,3 +398,62 @@ func TestErrorHandling(t *testing.T) {
 		t.Errorf("Expected error when getting file info for directory, got none")
 	}
 }
+
+func TestDeleteDirectoryNotEmpty(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	dir := filepath.Join(tempDir, "build")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	count, err := fs.CountEntries(dir)
+	if err != nil {
+		t.Fatalf("CountEntries failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 entries, got %d", count)
+	}
+
+	if err := fs.DeleteDirectory(dir, false); !errors.Is(err, ErrDirectoryNotEmpty) {
+		t.Errorf("Expected ErrDirectoryNotEmpty, got %v", err)
+	}
+
+	if err := fs.DeleteDirectory(filepath.Join(dir, "sub"), false); err != nil {
+		t.Errorf("Expected empty directory to be deleted, got %v", err)
+	}
+}
+
+func TestCreateDirectoryExclusive(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	dir := filepath.Join(tempDir, "projects", "app")
+	if err := fs.CreateDirectoryExclusive(dir, 0750); err != nil {
+		t.Fatalf("CreateDirectoryExclusive failed: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		t.Fatalf("Expected %s to be a directory (%v)", dir, err)
+	}
+
+	if err := fs.CreateDirectoryExclusive(dir, 0750); !errors.Is(err, ErrDirectoryExists) {
+		t.Errorf("Expected ErrDirectoryExists, got %v", err)
+	}
+	file := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := fs.CreateDirectoryExclusive(file, 0755); !errors.Is(err, ErrDirectoryExists) {
+		t.Errorf("Expected ErrDirectoryExists over a file, got %v", err)
+	}
+
+	// CreateDirectory stays idempotent
+	if err := fs.CreateDirectory(dir, 0755); err != nil {
+		t.Errorf("Expected CreateDirectory to succeed on an existing directory, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// followPollInterval is how often a followed file is checked even without fsnotify events,
+// which can be missed (e.g. on network filesystems)
+var followPollInterval = time.Second
+
+// Follower streams the content appended to a file, like tail -F
+type Follower struct {
+	path    string
+	file    *os.File
+	offset  int64
+	watcher *fsnotify.Watcher
+}
+
+// OpenFollower opens a file to follow. Only content written after the call is streamed,
+// unless fromStart is set. The parent directory is watched so that a file replaced by
+// rotation is reopened.
+func (fs *Filesystem) OpenFollower(path string, fromStart bool) (*Follower, error) {
+	file, err := fs.openRegularFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Follower{path: file.Name(), file: file}
+	if !fromStart {
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		f.offset = info.Size()
+	}
+
+	f.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := f.watcher.Add(filepath.Dir(f.path)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Follow writes new content to w as it is appended, until ctx is done or a write fails.
+// A truncated file is read again from its start; a rotated file (renamed or removed, then
+// recreated) is drained, then the new file is followed from its start.
+func (f *Follower) Follow(ctx context.Context, w io.Writer) error {
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+
+	if err := f.readNew(w); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-f.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Name != f.path {
+				continue
+			}
+		case err, ok := <-f.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logrus.WithField("path", f.path).WithError(err).Warn("Error watching followed file")
+			continue
+		case <-ticker.C:
+		}
+		if err := f.readNew(w); err != nil {
+			return err
+		}
+	}
+}
+
+// readNew copies the content written since the last read, handling truncation and rotation
+func (f *Follower) readNew(w io.Writer) error {
+	current, statErr := os.Stat(f.path)
+
+	if f.file != nil {
+		opened, err := f.file.Stat()
+		if err != nil {
+			return err
+		}
+		rotated := statErr != nil || !os.SameFile(opened, current)
+		if !rotated && opened.Size() < f.offset {
+			f.offset = 0
+		}
+		// Writers may still append to a rotated file until they reopen the path, drain it first
+		if err := f.copyFrom(w); err != nil {
+			return err
+		}
+		if !rotated {
+			return nil
+		}
+		_ = f.file.Close()
+		f.file = nil
+		f.offset = 0
+	}
+
+	if statErr != nil || current.IsDir() {
+		return nil // Wait for the file to be recreated
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil
+	}
+	f.file = file
+	return f.copyFrom(w)
+}
+
+func (f *Follower) copyFrom(w io.Writer) error {
+	n, err := io.Copy(w, io.NewSectionReader(f.file, f.offset, 1<<62))
+	f.offset += n
+	return err
+}
+
+// Close releases the file and the watcher
+func (f *Follower) Close() {
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	if f.watcher != nil {
+		_ = f.watcher.Close()
+	}
+}
@@ -0,0 +1,125 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for a concurrent writer and reader
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitForContent(t *testing.T, out *syncBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %q, got %q", want, out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func appendFile(t *testing.T, path string, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	path := filepath.Join(tempDir, "app.log")
+	appendFile(t, path, "old\n")
+
+	follower, err := fs.OpenFollower("app.log", false)
+	if err != nil {
+		t.Fatalf("OpenFollower failed: %v", err)
+	}
+	defer follower.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- follower.Follow(ctx, out) }()
+
+	// Existing content is skipped, appended content is streamed
+	appendFile(t, path, "one\n")
+	waitForContent(t, out, "one\n")
+
+	// Truncation restarts from the beginning of the file
+	if err := os.WriteFile(path, []byte("t\n"), 0644); err != nil {
+		t.Fatalf("Failed to truncate file: %v", err)
+	}
+	waitForContent(t, out, "one\nt\n")
+
+	// Rotation: the file is renamed and a new one is created at the same path
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	appendFile(t, path, "new\n")
+	waitForContent(t, out, "one\nt\nnew\n")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Follow returned an error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follow did not stop after cancellation")
+	}
+
+	if _, err := fs.OpenFollower(".", false); err == nil {
+		t.Error("Expected an error when following a directory")
+	}
+}
+
+func TestFollowerFromStart(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	path := filepath.Join(tempDir, "app.log")
+	appendFile(t, path, "existing\n")
+
+	follower, err := fs.OpenFollower("app.log", true)
+	if err != nil {
+		t.Fatalf("OpenFollower failed: %v", err)
+	}
+	defer follower.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &syncBuffer{}
+	go func() { _ = follower.Follow(ctx, out) }()
+
+	waitForContent(t, out, "existing\n")
+	appendFile(t, path, "more\n")
+	waitForContent(t, out, "existing\nmore\n")
+}
@@ -0,0 +1,54 @@
+package filesystem
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// Hash algorithms supported for file content
+const (
+	HashMD5    = "md5"
+	HashSHA1   = "sha1"
+	HashSHA256 = "sha256"
+	HashSHA512 = "sha512"
+)
+
+// NewHash returns a hash for one of the supported algorithms
+func NewHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("invalid hash algorithm '%s', must be md5, sha1, sha256 or sha512", algorithm)
+	}
+}
+
+// ValidateHash checks the hash algorithm requested for file content, empty meaning none
+func ValidateHash(algorithm string) error {
+	if algorithm == "" {
+		return nil
+	}
+	_, err := NewHash(algorithm)
+	return err
+}
+
+// HashBytes returns the hex-encoded digest of data
+func HashBytes(algorithm string, data []byte) (string, error) {
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,23 @@
+package filesystem
+
+import "testing"
+
+func TestHashBytes(t *testing.T) {
+	for algorithm, want := range map[string]string{
+		HashMD5:    "5d41402abc4b2a76b9719d911017c592",
+		HashSHA1:   "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		HashSHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	} {
+		got, err := HashBytes(algorithm, []byte("hello"))
+		if err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s (%v)", algorithm, want, got, err)
+		}
+	}
+
+	if err := ValidateHash(""); err != nil {
+		t.Errorf("Expected no hash to be valid, got %v", err)
+	}
+	if err := ValidateHash("crc32"); err == nil {
+		t.Error("Expected an error for an unsupported algorithm")
+	}
+}
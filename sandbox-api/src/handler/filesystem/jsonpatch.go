@@ -0,0 +1,523 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var (
+	// ErrInvalidJSONDocument is returned when the file to patch is not valid JSON
+	ErrInvalidJSONDocument = errors.New("file is not a valid JSON document")
+	// ErrInvalidJSONPatch is returned when the patch is not a valid RFC 6902 document
+	ErrInvalidJSONPatch = errors.New("invalid JSON patch")
+	// ErrJSONPatchConflict is returned when a patch operation cannot be applied to the document
+	ErrJSONPatchConflict = errors.New("JSON patch cannot be applied")
+)
+
+// MaxJSONPatchSize is the largest patch document accepted by PATCH /filesystem/{path}
+const MaxJSONPatchSize = 1 << 20
+
+// jsonStringEncoder encodes strings without escaping HTML characters, so that
+// patched files only differ from the original where the patch changed them
+var jsonStringEncoder = jsoniter.Config{EscapeHTML: false}.Froze()
+
+// jsonObject is a JSON object that remembers the order of its keys
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *jsonObject) set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *jsonObject) remove(key string) {
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// jsonArray is a JSON array that can be modified in place
+type jsonArray struct {
+	items []interface{}
+}
+
+// patchOperation is a single RFC 6902 operation
+type patchOperation struct {
+	op    string
+	path  string
+	from  string
+	value interface{}
+}
+
+// ApplyJSONPatch applies an RFC 6902 patch to a JSON document and returns the
+// result indented with two spaces. Object keys keep their original order.
+func ApplyJSONPatch(document []byte, patch []byte) ([]byte, error) {
+	doc, err := parseOrderedJSON(document)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSONDocument, err)
+	}
+
+	operations, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, operation := range operations {
+		doc, err = operation.apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d (%s %s): %v", ErrJSONPatchConflict, i, operation.op, operation.path, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeIndentedJSON(&buf, doc, ""); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// PatchJSONFile applies an RFC 6902 patch to a JSON file and writes it back pretty-printed
+func (fs *Filesystem) PatchJSONFile(path string, patch []byte) error {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New("path points to a directory, not a file")
+	}
+
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return err
+	}
+
+	patched, err := ApplyJSONPatch(content, patch)
+	if err != nil {
+		return err
+	}
+
+	// Written atomically, so readers never see a half-written JSON document
+	return fs.WriteFileAtomic(absPath, bytes.NewReader(patched), info.Mode().Perm())
+}
+
+func parsePatch(patch []byte) ([]patchOperation, error) {
+	parsed, err := parseOrderedJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSONPatch, err)
+	}
+	list, ok := parsed.(*jsonArray)
+	if !ok {
+		return nil, fmt.Errorf("%w: patch must be an array of operations", ErrInvalidJSONPatch)
+	}
+
+	operations := make([]patchOperation, 0, len(list.items))
+	for i, item := range list.items {
+		obj, ok := item.(*jsonObject)
+		if !ok {
+			return nil, fmt.Errorf("%w: operation %d is not an object", ErrInvalidJSONPatch, i)
+		}
+
+		operation := patchOperation{}
+		op, _ := obj.values["op"].(string)
+		path, hasPath := obj.values["path"].(string)
+		if !hasPath {
+			return nil, fmt.Errorf("%w: operation %d is missing \"path\"", ErrInvalidJSONPatch, i)
+		}
+		operation.op = op
+		operation.path = path
+
+		switch op {
+		case "add", "replace", "test":
+			value, hasValue := obj.values["value"]
+			if !hasValue {
+				return nil, fmt.Errorf("%w: operation %d (%s) is missing \"value\"", ErrInvalidJSONPatch, i, op)
+			}
+			operation.value = value
+		case "move", "copy":
+			from, hasFrom := obj.values["from"].(string)
+			if !hasFrom {
+				return nil, fmt.Errorf("%w: operation %d (%s) is missing \"from\"", ErrInvalidJSONPatch, i, op)
+			}
+			operation.from = from
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalidJSONPatch, i, op)
+		}
+		operations = append(operations, operation)
+	}
+	return operations, nil
+}
+
+func (p patchOperation) apply(doc interface{}) (interface{}, error) {
+	path, err := parseJSONPointer(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.op {
+	case "add":
+		return addValue(doc, path, p.value)
+	case "remove":
+		_, doc, err = removeValue(doc, path)
+		return doc, err
+	case "replace":
+		return replaceValue(doc, path, p.value)
+	case "move":
+		from, err := parseJSONPointer(p.from)
+		if err != nil {
+			return nil, err
+		}
+		if p.path == p.from {
+			return doc, nil
+		}
+		if strings.HasPrefix(p.path, p.from+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		value, doc, err := removeValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	case "copy":
+		from, err := parseJSONPointer(p.from)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, deepCopyJSON(value))
+	case "test":
+		value, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(value, p.value) {
+			return nil, errors.New("test failed: value does not match")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", p.op)
+}
+
+// parseJSONPointer splits an RFC 6901 pointer into unescaped reference tokens
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, token := range path {
+		switch container := current.(type) {
+		case *jsonObject:
+			value, ok := container.values[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			current = value
+		case *jsonArray:
+			index, err := arrayIndex(token, len(container.items), false)
+			if err != nil {
+				return nil, err
+			}
+			current = container.items[index]
+		default:
+			return nil, fmt.Errorf("cannot traverse into a scalar value at %q", token)
+		}
+	}
+	return current, nil
+}
+
+func addValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case *jsonObject:
+		container.set(last, value)
+	case *jsonArray:
+		index, err := arrayIndex(last, len(container.items), true)
+		if err != nil {
+			return nil, err
+		}
+		container.items = append(container.items, nil)
+		copy(container.items[index+1:], container.items[index:])
+		container.items[index] = value
+	default:
+		return nil, errors.New("parent is not an object or array")
+	}
+	return doc, nil
+}
+
+func replaceValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case *jsonObject:
+		if _, ok := container.values[last]; !ok {
+			return nil, fmt.Errorf("member %q not found", last)
+		}
+		container.set(last, value)
+	case *jsonArray:
+		index, err := arrayIndex(last, len(container.items), false)
+		if err != nil {
+			return nil, err
+		}
+		container.items[index] = value
+	default:
+		return nil, errors.New("parent is not an object or array")
+	}
+	return doc, nil
+}
+
+func removeValue(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the document root")
+	}
+	parent, err := getValue(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case *jsonObject:
+		value, ok := container.values[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q not found", last)
+		}
+		container.remove(last)
+		return value, doc, nil
+	case *jsonArray:
+		index, err := arrayIndex(last, len(container.items), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := container.items[index]
+		container.items = append(container.items[:index], container.items[index+1:]...)
+		return value, doc, nil
+	}
+	return nil, nil, errors.New("parent is not an object or array")
+}
+
+func deepCopyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *jsonObject:
+		cp := &jsonObject{keys: append([]string(nil), v.keys...), values: make(map[string]interface{}, len(v.values))}
+		for k, val := range v.values {
+			cp.values[k] = deepCopyJSON(val)
+		}
+		return cp
+	case *jsonArray:
+		cp := &jsonArray{items: make([]interface{}, len(v.items))}
+		for i, item := range v.items {
+			cp.items[i] = deepCopyJSON(item)
+		}
+		return cp
+	}
+	return value
+}
+
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case *jsonObject:
+		bv, ok := b.(*jsonObject)
+		if !ok || len(av.values) != len(bv.values) {
+			return false
+		}
+		for k, val := range av.values {
+			other, ok := bv.values[k]
+			if !ok || !jsonEqual(val, other) {
+				return false
+			}
+		}
+		return true
+	case *jsonArray:
+		bv, ok := b.(*jsonArray)
+		if !ok || len(av.items) != len(bv.items) {
+			return false
+		}
+		for i := range av.items {
+			if !jsonEqual(av.items[i], bv.items[i]) {
+				return false
+			}
+		}
+		return true
+	case jsoniter.Number:
+		bv, ok := b.(jsoniter.Number)
+		if !ok {
+			return false
+		}
+		af, aErr := av.Float64()
+		bf, bErr := bv.Float64()
+		if aErr != nil || bErr != nil {
+			return av == bv
+		}
+		return af == bf
+	}
+	return a == b
+}
+
+// parseOrderedJSON decodes a JSON document into jsonObject, jsonArray and scalar
+// values, keeping object keys in document order and numbers as written
+func parseOrderedJSON(data []byte) (interface{}, error) {
+	iter := jsoniter.ParseBytes(jsoniter.ConfigDefault, data)
+	value := readOrderedValue(iter)
+	if iter.Error != nil && iter.Error != io.EOF {
+		return nil, iter.Error
+	}
+	if iter.WhatIsNext() != jsoniter.InvalidValue {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+func readOrderedValue(iter *jsoniter.Iterator) interface{} {
+	switch iter.WhatIsNext() {
+	case jsoniter.ObjectValue:
+		obj := &jsonObject{values: make(map[string]interface{})}
+		iter.ReadObjectCB(func(iter *jsoniter.Iterator, key string) bool {
+			obj.set(key, readOrderedValue(iter))
+			return iter.Error == nil
+		})
+		return obj
+	case jsoniter.ArrayValue:
+		arr := &jsonArray{items: []interface{}{}}
+		iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
+			arr.items = append(arr.items, readOrderedValue(iter))
+			return iter.Error == nil
+		})
+		return arr
+	case jsoniter.StringValue:
+		return iter.ReadString()
+	case jsoniter.NumberValue:
+		return jsoniter.Number(iter.ReadNumber())
+	case jsoniter.BoolValue:
+		return iter.ReadBool()
+	case jsoniter.NilValue:
+		iter.ReadNil()
+		return nil
+	}
+	iter.ReportError("readOrderedValue", "unexpected token")
+	return nil
+}
+
+func writeIndentedJSON(buf *bytes.Buffer, value interface{}, indent string) error {
+	const step = "  "
+
+	switch v := value.(type) {
+	case *jsonObject:
+		if len(v.keys) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, key := range v.keys {
+			buf.WriteString(indent + step)
+			if err := writeIndentedJSON(buf, key, ""); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			if err := writeIndentedJSON(buf, v.values[key], indent+step); err != nil {
+				return err
+			}
+			if i < len(v.keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case *jsonArray:
+		if len(v.items) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range v.items {
+			buf.WriteString(indent + step)
+			if err := writeIndentedJSON(buf, item, indent+step); err != nil {
+				return err
+			}
+			if i < len(v.items)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	case jsoniter.Number:
+		buf.WriteString(string(v))
+	default:
+		encoded, err := jsonStringEncoder.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
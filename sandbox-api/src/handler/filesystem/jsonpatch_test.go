@@ -0,0 +1,116 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	document := `{"name": "app", "version": "1.0.0", "scripts": {"build": "tsc"}, "files": ["dist"], "private": true, "port": 3000}`
+
+	testCases := []struct {
+		name     string
+		patch    string
+		expected string
+		err      error
+	}{
+		{
+			name:  "add replace remove keep key order",
+			patch: `[{"op":"replace","path":"/version","value":"1.1.0"},{"op":"add","path":"/scripts/test","value":"jest"},{"op":"remove","path":"/private"}]`,
+			expected: `{
+  "name": "app",
+  "version": "1.1.0",
+  "scripts": {
+    "build": "tsc",
+    "test": "jest"
+  },
+  "files": [
+    "dist"
+  ],
+  "port": 3000
+}
+`,
+		},
+		{
+			name:  "array append move copy and test",
+			patch: `[{"op":"test","path":"/port","value":3000.0},{"op":"add","path":"/files/-","value":"<src>"},{"op":"add","path":"/files/0","value":"lib"},{"op":"move","from":"/port","path":"/config"},{"op":"copy","from":"/name","path":"/scripts/name"}]`,
+			expected: `{
+  "name": "app",
+  "version": "1.0.0",
+  "scripts": {
+    "build": "tsc",
+    "name": "app"
+  },
+  "files": [
+    "lib",
+    "dist",
+    "<src>"
+  ],
+  "private": true,
+  "config": 3000
+}
+`,
+		},
+		{name: "failed test", patch: `[{"op":"test","path":"/name","value":"other"}]`, err: ErrJSONPatchConflict},
+		{name: "missing member", patch: `[{"op":"remove","path":"/missing"}]`, err: ErrJSONPatchConflict},
+		{name: "array index out of range", patch: `[{"op":"add","path":"/files/5","value":"x"}]`, err: ErrJSONPatchConflict},
+		{name: "unknown op", patch: `[{"op":"merge","path":"/name"}]`, err: ErrInvalidJSONPatch},
+		{name: "patch not an array", patch: `{"op":"remove","path":"/name"}`, err: ErrInvalidJSONPatch},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ApplyJSONPatch([]byte(document), []byte(tc.patch))
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("Expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(result) != tc.expected {
+				t.Errorf("Unexpected result:\ngot:\n%s\nwant:\n%s", result, tc.expected)
+			}
+		})
+	}
+
+	if _, err := ApplyJSONPatch([]byte(`{"a": 1,}`), []byte(`[]`)); !errors.Is(err, ErrInvalidJSONDocument) {
+		t.Errorf("Expected ErrInvalidJSONDocument, got %v", err)
+	}
+	if _, err := ApplyJSONPatch([]byte(`{"a": 1} {"b": 2}`), []byte(`[]`)); !errors.Is(err, ErrInvalidJSONDocument) {
+		t.Errorf("Expected ErrInvalidJSONDocument for trailing data, got %v", err)
+	}
+}
+
+func TestPatchJSONFile(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	path := filepath.Join(tempDir, "package.json")
+	if err := os.WriteFile(path, []byte(`{"name":"app"}`), 0600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	before, _ := os.Stat(path)
+	if err := fs.PatchJSONFile("package.json", []byte(`[{"op":"add","path":"/version","value":"2.0.0"}]`)); err != nil {
+		t.Fatalf("Failed to patch file: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	expected := "{\n  \"name\": \"app\",\n  \"version\": \"2.0.0\"\n}\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions to be preserved, got %o", info.Mode().Perm())
+	}
+	// The file is swapped with a rename, not rewritten in place
+	if os.SameFile(before, info) {
+		t.Error("Expected the file to be replaced atomically")
+	}
+}
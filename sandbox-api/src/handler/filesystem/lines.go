@@ -0,0 +1,106 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// tailChunkSize is the size of the blocks read backwards from the end of a file by TailLines
+const tailChunkSize = 64 * 1024
+
+// HeadLines returns the first n lines of a file, without their line terminators
+func (fs *Filesystem) HeadLines(path string, n int) ([]string, error) {
+	file, err := fs.openRegularFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := make([]string, 0)
+	reader := bufio.NewReader(file)
+	for len(lines) < n {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lines = append(lines, trimLineEnding(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+// TailLines returns the last n lines of a file, without their line terminators.
+// The file is read backwards in blocks so only the end of large files is loaded.
+func (fs *Filesystem) TailLines(path string, n int) ([]string, error) {
+	file, err := fs.openRegularFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size()
+	var data []byte
+	// A trailing newline terminates the last line rather than starting an empty one,
+	// so n lines need n+1 separators in the buffer (or the start of the file).
+	for offset > 0 && bytes.Count(data, []byte{'\n'}) <= n {
+		size := int64(tailChunkSize)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data = append(chunk, data...)
+	}
+
+	if len(data) == 0 {
+		return []string{}, nil
+	}
+	all := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	// When the read stopped mid-file, the first element may be a partial line
+	if offset > 0 {
+		all = all[1:]
+	}
+	if len(all) > n {
+		all = all[len(all)-n:]
+	}
+	lines := make([]string, len(all))
+	for i, line := range all {
+		lines[i] = trimLineEnding(line)
+	}
+	return lines, nil
+}
+
+func (fs *Filesystem) openRegularFile(path string) (*os.File, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New("path points to a directory, not a file")
+	}
+	return os.Open(absPath)
+}
+
+func trimLineEnding(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
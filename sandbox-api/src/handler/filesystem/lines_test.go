@@ -0,0 +1,72 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeadAndTailLines(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "small.log"), []byte("one\r\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "open.log"), []byte("one\ntwo"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Build a file spanning several tail chunks
+	var large strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&large, "line %d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "large.log"), []byte(large.String()), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		tail     bool
+		n        int
+		expected []string
+	}{
+		{"head", "small.log", false, 2, []string{"one", "two"}},
+		{"head beyond end", "small.log", false, 10, []string{"one", "two", "three"}},
+		{"tail", "small.log", true, 2, []string{"two", "three"}},
+		{"tail beyond start", "small.log", true, 10, []string{"one", "two", "three"}},
+		{"tail without trailing newline", "open.log", true, 1, []string{"two"}},
+		{"tail across chunks", "large.log", true, 3, []string{"line 19997", "line 19998", "line 19999"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lines []string
+			var err error
+			if tc.tail {
+				lines, err = fs.TailLines(tc.path, tc.n)
+			} else {
+				lines, err = fs.HeadLines(tc.path, tc.n)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(lines, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, lines)
+			}
+		})
+	}
+
+	lines, err := fs.TailLines("large.log", 20000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lines) != 20000 || lines[0] != "line 0" {
+		t.Errorf("Expected the whole file, got %d lines starting with %q", len(lines), lines[0])
+	}
+}
@@ -0,0 +1,75 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FileTypeDirectory is the type of subdirectories in sorted listings
+const FileTypeDirectory = "directory"
+
+// Sort orders of directory listings
+const (
+	ListSortName  = "name"
+	ListSortSize  = "size"
+	ListSortMtime = "mtime"
+)
+
+var validListSorts = map[string]bool{"": true, ListSortName: true, ListSortSize: true, ListSortMtime: true}
+
+// ErrInvalidListSort is returned for an unknown sort order
+var ErrInvalidListSort = errors.New("invalid sort: must be name, size or mtime")
+
+// ListOptions orders the entries of a directory listing. The zero value keeps the OS order.
+type ListOptions struct {
+	// Sort orders entries by name (case-insensitive), size or modification time, ascending.
+	// Subdirectories count as empty when sorting by size.
+	Sort string
+	// DirsFirst lists subdirectories before files
+	DirsFirst bool
+}
+
+func (o ListOptions) ordered() bool {
+	return o.Sort != "" || o.DirsFirst
+}
+
+// sortListing orders the entries of a directory according to opts, ties broken by name
+func sortListing(infos []os.FileInfo, opts ListOptions) {
+	size := func(info os.FileInfo) int64 {
+		if info.IsDir() {
+			return 0
+		}
+		return info.Size()
+	}
+	byName := func(a, b os.FileInfo) bool {
+		if la, lb := strings.ToLower(a.Name()), strings.ToLower(b.Name()); la != lb {
+			return la < lb
+		}
+		return a.Name() < b.Name()
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if opts.DirsFirst && a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		switch opts.Sort {
+		case ListSortName:
+			return byName(a, b)
+		case ListSortSize:
+			if size(a) != size(b) {
+				return size(a) < size(b)
+			}
+			return byName(a, b)
+		case ListSortMtime:
+			if !a.ModTime().Equal(b.ModTime()) {
+				return a.ModTime().Before(b.ModTime())
+			}
+			return byName(a, b)
+		}
+		// dirsFirst alone keeps the OS order within each group
+		return false
+	})
+}
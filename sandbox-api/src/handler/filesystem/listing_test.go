@@ -0,0 +1,83 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListDirectoryWithOptions(t *testing.T) {
+	root := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	for i, entry := range []struct {
+		name string
+		size int
+		dir  bool
+	}{
+		{"beta.txt", 30, false},
+		{"Alpha.txt", 10, false},
+		{"zeta", 0, true},
+		{"gamma.txt", 20, false},
+		{"delta", 0, true},
+	} {
+		path := filepath.Join(root, entry.name)
+		var err error
+		if entry.dir {
+			err = os.Mkdir(path, 0755)
+		} else {
+			err = os.WriteFile(path, make([]byte, entry.size), 0644)
+		}
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", entry.name, err)
+		}
+		modTime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set times: %v", err)
+		}
+	}
+
+	fs := NewFilesystem("/")
+	for _, tc := range []struct {
+		opts     ListOptions
+		expected string
+	}{
+		{ListOptions{Sort: ListSortName}, "Alpha.txt,beta.txt,delta,gamma.txt,zeta"},
+		{ListOptions{Sort: ListSortName, DirsFirst: true}, "delta,zeta,Alpha.txt,beta.txt,gamma.txt"},
+		{ListOptions{Sort: ListSortSize}, "delta,zeta,Alpha.txt,gamma.txt,beta.txt"},
+		{ListOptions{Sort: ListSortMtime}, "beta.txt,Alpha.txt,zeta,gamma.txt,delta"},
+		{ListOptions{Sort: ListSortMtime, DirsFirst: true}, "zeta,delta,beta.txt,Alpha.txt,gamma.txt"},
+	} {
+		dir, err := fs.ListDirectoryWithOptions(root, tc.opts)
+		if err != nil {
+			t.Fatalf("%+v: failed to list: %v", tc.opts, err)
+		}
+		names := make([]string, 0, len(dir.Entries))
+		for _, entry := range dir.Entries {
+			names = append(names, entry.Name)
+		}
+		if got := strings.Join(names, ","); got != tc.expected {
+			t.Errorf("%+v: expected %s, got %s", tc.opts, tc.expected, got)
+		}
+		if len(dir.Files) != 3 || len(dir.Subdirectories) != 2 {
+			t.Errorf("%+v: expected 3 files and 2 subdirectories, got %d and %d", tc.opts, len(dir.Files), len(dir.Subdirectories))
+		}
+	}
+
+	// Files and subdirectories keep the same relative order
+	dir, _ := fs.ListDirectoryWithOptions(root, ListOptions{Sort: ListSortSize})
+	if dir.Files[0].Name != "Alpha.txt" || dir.Files[2].Name != "beta.txt" || dir.Entries[0].Type != FileTypeDirectory || dir.Entries[2].Type != FileTypeFile {
+		t.Errorf("Unexpected sorted listing: %+v", dir)
+	}
+
+	// Unsorted listings don't return entries
+	if dir, _ := fs.ListDirectory(root); dir.Entries != nil {
+		t.Errorf("Expected no entries for an unsorted listing, got %+v", dir.Entries)
+	}
+
+	if _, err := fs.ListDirectoryWithOptions(root, ListOptions{Sort: "color"}); !errors.Is(err, ErrInvalidListSort) {
+		t.Errorf("Expected ErrInvalidListSort, got %v", err)
+	}
+}
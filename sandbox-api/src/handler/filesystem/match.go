@@ -0,0 +1,56 @@
+package filesystem
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// MatchIgnorePattern returns the first watch ignore pattern contained in path. Empty patterns are skipped.
+func MatchIgnorePattern(path string, patterns []string) (string, bool) {
+	for _, pattern := range patterns {
+		if pattern != "" && strings.Contains(path, pattern) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// MatchIncludePattern returns the first find pattern matching the file name of path
+func MatchIncludePattern(path string, patterns []string) (string, bool) {
+	base := filepath.Base(path)
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, base); match {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// MatchWatchPath reports whether the events of path are streamed by a recursive watch
+// on watchPath, such as /src/** or /src/**/*.ts
+func (fs *Filesystem) MatchWatchPath(watchPath string, path string) (bool, error) {
+	dir, pattern, recursive := SplitRecursiveWatchPath(watchPath)
+	if !recursive {
+		return false, fmt.Errorf("invalid watch path %q: expected /** or /**/<glob>", watchPath)
+	}
+
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return false, err
+	}
+	if pattern != "" {
+		glob, err := fs.NewWatchGlob(dir, pattern)
+		if err != nil {
+			return false, err
+		}
+		return glob.Match(absPath), nil
+	}
+
+	root, err := fs.GetAbsolutePath(dir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(root, absPath)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, ".."), nil
+}
@@ -0,0 +1,54 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchPatterns(t *testing.T) {
+	if pattern, ok := MatchIgnorePattern("/app/node_modules/x/index.js", []string{"", ".git", "node_modules"}); !ok || pattern != "node_modules" {
+		t.Errorf("Expected node_modules to match, got %q, %v", pattern, ok)
+	}
+	if _, ok := MatchIgnorePattern("/app/src/index.js", []string{".git", "node_modules"}); ok {
+		t.Error("Expected no ignore pattern to match")
+	}
+
+	if pattern, ok := MatchIncludePattern("/app/src/index.ts", []string{"*.js", "*.ts"}); !ok || pattern != "*.ts" {
+		t.Errorf("Expected *.ts to match, got %q, %v", pattern, ok)
+	}
+	// Only the file name is matched
+	if _, ok := MatchIncludePattern("/app/src.ts/index.js", []string{"*.ts"}); ok {
+		t.Error("Expected no include pattern to match")
+	}
+}
+
+func TestMatchWatchPath(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	src := filepath.Join(tempDir, "src")
+	tests := []struct {
+		watchPath, path string
+		want            bool
+	}{
+		{src + "/**", filepath.Join(src, "a", "b.js"), true},
+		{src + "/**", src, false},
+		{src + "/**", filepath.Join(tempDir, "other.js"), false},
+		{src + "/**/*.ts", filepath.Join(src, "a", "b.ts"), true},
+		{src + "/**/*.ts", filepath.Join(src, "a", "b.js"), false},
+		{src + "/**/test/*.ts", filepath.Join(src, "test", "b.ts"), true},
+	}
+	for _, tt := range tests {
+		got, err := fs.MatchWatchPath(tt.watchPath, tt.path)
+		if err != nil {
+			t.Fatalf("MatchWatchPath(%q, %q) failed: %v", tt.watchPath, tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("MatchWatchPath(%q, %q) = %v, want %v", tt.watchPath, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := fs.MatchWatchPath(src, filepath.Join(src, "a.ts")); err == nil {
+		t.Error("Expected an error for a non-recursive watch path")
+	}
+}
@@ -0,0 +1,116 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// lookupOwnership resolves a user and group, given as names or numeric ids, to
+// a uid and gid. An empty value resolves to -1, which leaves it unchanged.
+func lookupOwnership(owner string, group string) (int, int, error) {
+	uid, gid := -1, -1
+
+	if owner != "" {
+		if id, err := strconv.Atoi(owner); err == nil {
+			uid = id
+		} else {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return -1, -1, fmt.Errorf("unknown owner %q", owner)
+			}
+			uid, _ = strconv.Atoi(u.Uid)
+		}
+	}
+
+	if group != "" {
+		if id, err := strconv.Atoi(group); err == nil {
+			gid = id
+		} else {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return -1, -1, fmt.Errorf("unknown group %q", group)
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
+
+// Chown sets the owner and group of path, given as names or numeric ids. Either
+// may be empty to keep the current value. Ownership changes are skipped, not
+// reported as errors, when the API is not privileged to make them.
+func (fs *Filesystem) Chown(path string, owner string, group string) error {
+	if owner == "" && group == "" {
+		return nil
+	}
+
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return err
+	}
+
+	uid, gid, err := lookupOwnership(owner, group)
+	if err != nil {
+		return err
+	}
+
+	return lchownIfPermitted(absPath, uid, gid)
+}
+
+func lchownIfPermitted(absPath string, uid int, gid int) error {
+	if err := os.Lchown(absPath, uid, gid); err != nil {
+		if errors.Is(err, syscall.EPERM) {
+			logrus.WithField("path", absPath).Debug("Skipping chown: not permitted")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// applyDefaultOwnership chowns the given paths to DefaultOwner/DefaultGroup, if configured
+func (fs *Filesystem) applyDefaultOwnership(absPaths ...string) error {
+	if fs.DefaultOwner == "" && fs.DefaultGroup == "" {
+		return nil
+	}
+
+	uid, gid, err := lookupOwnership(fs.DefaultOwner, fs.DefaultGroup)
+	if err != nil {
+		return err
+	}
+
+	for _, absPath := range absPaths {
+		if err := lchownIfPermitted(absPath, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mkdirAll behaves like os.MkdirAll and applies the default ownership to every
+// directory it had to create
+func (fs *Filesystem) mkdirAll(absPath string, perm os.FileMode) error {
+	var created []string
+	for dir := absPath; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		created = append(created, dir)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(absPath, perm); err != nil {
+		return err
+	}
+	return fs.applyDefaultOwnership(created...)
+}
@@ -0,0 +1,59 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func fileOwnership(t *testing.T, path string) (uint32, uint32) {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", path, err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	return stat.Uid, stat.Gid
+}
+
+func TestDefaultOwnership(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	fs.DefaultOwner = "4242"
+	fs.DefaultGroup = "4343"
+
+	if err := fs.WriteFile(filepath.Join(tempDir, "nested/dir/file.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if os.Geteuid() != 0 {
+		// Without privileges the chown is skipped rather than failing the write
+		return
+	}
+
+	for _, path := range []string{"nested", "nested/dir", "nested/dir/file.txt"} {
+		uid, gid := fileOwnership(t, filepath.Join(tempDir, path))
+		if uid != 4242 || gid != 4343 {
+			t.Errorf("Expected %s to be owned by 4242:4343, got %d:%d", path, uid, gid)
+		}
+	}
+
+	// The temp dir already existed and must keep its owner
+	if uid, _ := fileOwnership(t, tempDir); uid == 4242 {
+		t.Error("Expected existing parent directory to keep its owner")
+	}
+
+	// A per-request override wins over the default
+	if err := fs.Chown(filepath.Join(tempDir, "nested/dir/file.txt"), "4444", ""); err != nil {
+		t.Fatalf("Chown failed: %v", err)
+	}
+	if uid, gid := fileOwnership(t, filepath.Join(tempDir, "nested/dir/file.txt")); uid != 4444 || gid != 4343 {
+		t.Errorf("Expected override owner 4444:4343, got %d:%d", uid, gid)
+	}
+
+	if err := fs.Chown(filepath.Join(tempDir, "nested"), "no-such-user-xyz", ""); err == nil {
+		t.Error("Expected error for unknown owner")
+	}
+}
@@ -0,0 +1,67 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+)
+
+// MaxTemplateSize is the largest template file rendered by RenderTemplate
+const MaxTemplateSize = 10 * 1024 * 1024
+
+// ErrInvalidTemplate is returned when a template cannot be parsed or rendered,
+// including when it references a missing variable in strict mode
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// ErrTemplateTooLarge is returned when a template file exceeds MaxTemplateSize
+var ErrTemplateTooLarge = errors.New("template exceeds the maximum size")
+
+// RenderTemplate renders the file at path as a Go text/template, with vars as its data:
+// {{.name}} is replaced with vars["name"]. A variable missing from vars renders as
+// "<no value>", or fails the render with strict.
+func (fs *Filesystem) RenderTemplate(path string, vars map[string]any, strict bool) ([]byte, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	source, err := io.ReadAll(io.LimitReader(file, MaxTemplateSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(source) > MaxTemplateSize {
+		return nil, fmt.Errorf("%w of %d bytes", ErrTemplateTooLarge, MaxTemplateSize)
+	}
+
+	tmpl := template.New(info.Name())
+	if strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+	if tmpl, err = tmpl.Parse(string(source)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return out.Bytes(), nil
+}
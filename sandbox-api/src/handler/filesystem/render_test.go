@@ -0,0 +1,50 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	path := filepath.Join(tempDir, "config.tmpl")
+	template := "port: {{.port}}\nhosts:{{range .hosts}} {{.}}{{end}}\nname: {{.name}}\n"
+	if err := os.WriteFile(path, []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]any{"port": 3000, "hosts": []any{"a", "b"}}
+
+	out, err := fs.RenderTemplate(path, vars, false)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if want := "port: 3000\nhosts: a b\nname: <no value>\n"; string(out) != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	if _, err := fs.RenderTemplate(path, vars, true); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected ErrInvalidTemplate for a missing variable in strict mode, got %v", err)
+	}
+	vars["name"] = "api"
+	out, err = fs.RenderTemplate(path, vars, true)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if want := "port: 3000\nhosts: a b\nname: api\n"; string(out) != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	if err := os.WriteFile(path, []byte("{{.port"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.RenderTemplate(path, vars, false); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected ErrInvalidTemplate for an unparsable template, got %v", err)
+	}
+	if _, err := fs.RenderTemplate(filepath.Join(tempDir, "missing.tmpl"), vars, false); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+}
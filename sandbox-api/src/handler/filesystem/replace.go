@@ -0,0 +1,54 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// ErrBinaryFile is returned when a replacement is attempted on a file that looks binary
+var ErrBinaryFile = errors.New("file appears to be binary")
+
+// ReplaceInFile replaces every match of re in the file with replacement, which may
+// reference capture groups ($1, ${name}). It returns the number of matches replaced.
+// Files larger than maxSize bytes are rejected; a maxSize of 0 disables the check.
+// When dryRun is true the matches are counted but the file is left untouched.
+func (fs *Filesystem) ReplaceInFile(path string, re *regexp.Regexp, replacement string, maxSize int64, dryRun bool) (int, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir() {
+		return 0, errors.New("path points to a directory, not a file")
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		return 0, fmt.Errorf("file size %d exceeds the maximum of %d bytes", info.Size(), maxSize)
+	}
+
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return 0, err
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return 0, ErrBinaryFile
+	}
+
+	count := len(re.FindAllIndex(content, -1))
+	if count == 0 || dryRun {
+		return count, nil
+	}
+
+	// Replaced atomically, so readers never see a half-written file
+	updated := re.ReplaceAll(content, []byte(replacement))
+	if err := fs.WriteFileAtomic(absPath, bytes.NewReader(updated), info.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,64 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestReplaceInFile(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	path := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(path, []byte("foo := oldName()\nbar := oldName(1)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	re := regexp.MustCompile(`old(Name)\(`)
+
+	count, err := fs.ReplaceInFile("main.go", re, "new$1(", 0, true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 matches, got %d", count)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "foo := oldName()\nbar := oldName(1)\n" {
+		t.Errorf("Dry run modified the file: %q", content)
+	}
+
+	before, _ := os.Stat(path)
+	count, err = fs.ReplaceInFile("main.go", re, "new$1(", 0, false)
+	if err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 replacements, got %d", count)
+	}
+	content, _ = os.ReadFile(path)
+	if string(content) != "foo := newName()\nbar := newName(1)\n" {
+		t.Errorf("Unexpected content after replace: %q", content)
+	}
+	// The file is swapped with a rename, not rewritten in place
+	if after, _ := os.Stat(path); os.SameFile(before, after) {
+		t.Error("Expected the file to be replaced atomically")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(tempDir, ".main.go.swap-*")); len(matches) != 0 {
+		t.Errorf("Expected no temporary file left, got %v", matches)
+	}
+
+	if _, err := fs.ReplaceInFile("main.go", re, "x", 4, false); err == nil {
+		t.Error("Expected an error for a file above the size limit")
+	}
+
+	binPath := filepath.Join(tempDir, "blob.bin")
+	if err := os.WriteFile(binPath, []byte("oldName(\x00"), 0644); err != nil {
+		t.Fatalf("Failed to write binary file: %v", err)
+	}
+	if _, err := fs.ReplaceInFile("blob.bin", re, "x", 0, false); !errors.Is(err, ErrBinaryFile) {
+		t.Errorf("Expected ErrBinaryFile, got %v", err)
+	}
+}
@@ -0,0 +1,391 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotDir is where directory snapshots are stored, in one subdirectory per snapshotted directory.
+// Can be configured via SANDBOX_SNAPSHOT_DIR environment variable
+var SnapshotDir = "/var/lib/sandbox-api/snapshots"
+
+func init() {
+	if dir := os.Getenv("SANDBOX_SNAPSHOT_DIR"); dir != "" {
+		SnapshotDir = dir
+	}
+}
+
+// ErrSnapshotNotFound is returned when a directory has no snapshot with the requested name
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// ErrSnapshotExists is returned when a directory already has a snapshot with the requested name
+var ErrSnapshotExists = errors.New("snapshot already exists")
+
+// ErrInvalidSnapshotName is returned for snapshot names that are not safe file names
+var ErrInvalidSnapshotName = errors.New("invalid snapshot name: use up to 64 letters, digits, '.', '_' or '-'")
+
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// restoreStagingPrefix names the temporary directory a snapshot is extracted to before it replaces
+// the directory content
+const restoreStagingPrefix = ".sandbox-restore-"
+
+// Snapshot describes a stored snapshot of a directory
+type Snapshot struct {
+	Name        string    `json:"name" binding:"required" example:"before-refactor"`
+	Path        string    `json:"path" binding:"required" example:"/app/src"`
+	CreatedAt   time.Time `json:"createdAt" binding:"required"`
+	Files       int       `json:"files" binding:"required" example:"42"`
+	Directories int       `json:"directories" binding:"required" example:"7"`
+	Size        int64     `json:"size" binding:"required" example:"20480"` // Size of the compressed archive
+} // @name Snapshot
+
+// snapshotStore returns the directory holding the snapshots of the directory at absPath
+func snapshotStore(absPath string) string {
+	sum := sha256.Sum256([]byte(absPath))
+	return filepath.Join(SnapshotDir, hex.EncodeToString(sum[:8]))
+}
+
+// snapshotDirectory returns the absolute path of path, checking that it is a directory
+func (fs *Filesystem) snapshotDirectory(path string) (string, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("path is not a directory: %s", path)
+	}
+	return absPath, nil
+}
+
+// CreateSnapshot stores the content of the directory at path as a tar.gz archive that
+// RestoreSnapshot can roll the directory back to. Regular files, directories and symlinks
+// are kept; other entries are skipped. An empty name uses the creation time.
+func (fs *Filesystem) CreateSnapshot(path string, name string) (Snapshot, error) {
+	if name == "" {
+		name = time.Now().UTC().Format("20060102-150405.000")
+	}
+	if !snapshotNamePattern.MatchString(name) {
+		return Snapshot{}, ErrInvalidSnapshotName
+	}
+	absPath, err := fs.snapshotDirectory(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	store := snapshotStore(absPath)
+	archivePath := filepath.Join(store, name+".tar.gz")
+	if _, err := os.Stat(archivePath); err == nil {
+		return Snapshot{}, fmt.Errorf("%w: %s", ErrSnapshotExists, name)
+	}
+	if err := os.MkdirAll(store, 0700); err != nil {
+		return Snapshot{}, err
+	}
+
+	// Write to a temporary file so an interrupted snapshot is never listed
+	tmp, err := os.CreateTemp(store, ".tmp-"+name+"-*")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	snapshot := Snapshot{Name: name, Path: absPath, CreatedAt: time.Now()}
+	err = writeSnapshotArchive(tmp, absPath, &snapshot)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if info, err := os.Stat(tmp.Name()); err == nil {
+		snapshot.Size = info.Size()
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.WriteFile(filepath.Join(store, name+".json"), data, 0600); err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		_ = os.Remove(filepath.Join(store, name+".json"))
+		return Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// writeSnapshotArchive writes the content of root to w as a tar.gz archive, counting the
+// entries in snapshot. The snapshot store itself is skipped when it lives under root.
+func writeSnapshotArchive(w io.Writer, root string, snapshot *Snapshot) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() && isWithin(SnapshotDir, path) {
+			return filepath.SkipDir
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		var link string
+		switch {
+		case info.Mode().IsRegular(), info.IsDir():
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			snapshot.Directories++
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, f)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+			snapshot.Files++
+		default:
+			snapshot.Files++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ListSnapshots returns the snapshots of the directory at path, oldest first
+func (fs *Filesystem) ListSnapshots(path string) ([]Snapshot, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	matches, err := filepath.Glob(filepath.Join(snapshotStore(absPath), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		snapshot, err := readSnapshot(match)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func readSnapshot(metadataPath string) (Snapshot, error) {
+	var snapshot Snapshot
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, err
+	}
+	if _, err := os.Stat(strings.TrimSuffix(metadataPath, ".json") + ".tar.gz"); err != nil {
+		return snapshot, err
+	}
+	return snapshot, nil
+}
+
+// RestoreSnapshot rolls the directory at path back to a snapshot: once the archive has been
+// extracted successfully, every current entry of the directory is replaced by the snapshot
+// content. The directory itself is kept, so processes working in it are not affected. If the
+// extraction fails the directory is left untouched.
+func (fs *Filesystem) RestoreSnapshot(path string, name string) (Snapshot, error) {
+	if !snapshotNamePattern.MatchString(name) {
+		return Snapshot{}, ErrInvalidSnapshotName
+	}
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	store := snapshotStore(absPath)
+	snapshot, err := readSnapshot(filepath.Join(store, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Snapshot{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+		}
+		return Snapshot{}, err
+	}
+	archive, err := os.Open(filepath.Join(store, name+".tar.gz"))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer func() { _ = archive.Close() }()
+
+	// The directory may have been deleted since the snapshot was taken
+	if err := fs.mkdirAll(absPath, 0755); err != nil {
+		return Snapshot{}, err
+	}
+	if _, err := fs.snapshotDirectory(absPath); err != nil {
+		return Snapshot{}, err
+	}
+
+	// Extract next to the current content, on the same filesystem, so entries can be renamed in place
+	staging, err := os.MkdirTemp(absPath, restoreStagingPrefix)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+	if err := extractSnapshotArchive(staging, archive); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to extract snapshot: %w", err)
+	}
+
+	entries, err := os.ReadDir(absPath)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(absPath, entry.Name())
+		if entryPath == staging {
+			continue
+		}
+		if err := os.RemoveAll(entryPath); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	restored, err := os.ReadDir(staging)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, entry := range restored {
+		if err := os.Rename(filepath.Join(staging, entry.Name()), filepath.Join(absPath, entry.Name())); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	return snapshot, nil
+}
+
+// extractSnapshotArchive extracts an archive written by writeSnapshotArchive into the empty
+// directory dest. Unlike ExtractTar, symlinks are restored as they were, wherever they point,
+// and modes and ownership are kept. Directory modes are applied last, so read-only directories
+// can still be filled.
+func extractSnapshotArchive(dest string, r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = gz.Close() }()
+
+	var dirs []*tar.Header
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		target, err := archiveTarget(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, header)
+			continue
+		case tar.TypeReg, tar.TypeRegA:
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(target, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		_ = os.Lchown(target, header.Uid, header.Gid)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		target := filepath.Join(dest, dirs[i].Name)
+		_ = os.Lchown(target, dirs[i].Uid, dirs[i].Gid)
+		if err := os.Chmod(target, os.FileMode(dirs[i].Mode).Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
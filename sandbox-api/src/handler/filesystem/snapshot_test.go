@@ -0,0 +1,93 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	defer func(dir string) { SnapshotDir = dir }(SnapshotDir)
+	SnapshotDir = t.TempDir()
+
+	dir := filepath.Join(tempDir, "project")
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// Symlinks pointing outside the directory are kept as they are
+	if err := os.Symlink("/etc/hostname", filepath.Join(dir, "host")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	snapshot, err := fs.CreateSnapshot("project", "before")
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if snapshot.Files != 3 || snapshot.Directories != 1 || snapshot.Size == 0 || snapshot.Path != dir {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+	if _, err := fs.CreateSnapshot("project", "before"); !errors.Is(err, ErrSnapshotExists) {
+		t.Errorf("Expected ErrSnapshotExists, got %v", err)
+	}
+	if _, err := fs.CreateSnapshot("project", "../escape"); !errors.Is(err, ErrInvalidSnapshotName) {
+		t.Errorf("Expected ErrInvalidSnapshotName, got %v", err)
+	}
+
+	// Risky edits: change, add and remove files
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("broken"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "run.sh")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	if _, err := fs.RestoreSnapshot("project", "before"); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "src", "main.go")); err != nil || string(content) != "package main\n" {
+		t.Errorf("Expected main.go to be restored, got %q (%v)", content, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected run.sh to be restored with its mode, got %v (%v)", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected new.txt to be removed, got %v", err)
+	}
+	if link, err := os.Readlink(filepath.Join(dir, "host")); err != nil || link != "/etc/hostname" {
+		t.Errorf("Expected the symlink to be restored, got %q (%v)", link, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("Expected exactly the snapshot entries, got %d", len(entries))
+	}
+
+	if _, err := fs.RestoreSnapshot("project", "missing"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("Expected ErrSnapshotNotFound, got %v", err)
+	}
+
+	if _, err := fs.CreateSnapshot("project", ""); err != nil {
+		t.Fatalf("CreateSnapshot without a name failed: %v", err)
+	}
+	snapshots, err := fs.ListSnapshots("project")
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != "before" {
+		t.Errorf("Expected two snapshots, oldest first, got %+v", snapshots)
+	}
+	if snapshots, err := fs.ListSnapshots("src"); err != nil || len(snapshots) != 0 {
+		t.Errorf("Expected no snapshots for another directory, got %+v (%v)", snapshots, err)
+	}
+}
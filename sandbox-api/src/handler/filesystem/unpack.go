@@ -0,0 +1,215 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Archive formats detected by Unpack
+const (
+	ArchiveFormatZip    = "zip"
+	ArchiveFormatTar    = "tar"
+	ArchiveFormatTarGz  = "tar.gz"
+	ArchiveFormatTarBz2 = "tar.bz2"
+)
+
+// ErrUnknownArchiveFormat is returned when the format of an archive can't be detected
+var ErrUnknownArchiveFormat = errors.New("unknown archive format, expected zip, tar, tar.gz or tar.bz2")
+
+// maxZipSymlinkSize bounds the target read from a zip symlink entry
+const maxZipSymlinkSize = 4096
+
+// DetectArchiveFormat returns the format of an archive from its first bytes (at least
+// 262 to recognize an uncompressed tar), falling back to the extension of its name
+func DetectArchiveFormat(name string, header []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ArchiveFormatZip, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return ArchiveFormatTarGz, nil
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return ArchiveFormatTarBz2, nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return ArchiveFormatTar, nil
+	}
+
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveFormatZip, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveFormatTarGz, nil
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"), strings.HasSuffix(lower, ".tbz"):
+		return ArchiveFormatTarBz2, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveFormatTar, nil
+	}
+	return "", ErrUnknownArchiveFormat
+}
+
+// Unpack extracts the archive at archivePath into the directory at destPath, creating it
+// if needed, and returns the detected format. Entries are checked like with ExtractTar,
+// and extraction stops once regular files add up to more than maxSize bytes (0 disables
+// the limit). Entries extracted before an error are kept.
+func (fs *Filesystem) Unpack(archivePath string, destPath string, maxSize int64) (string, ExtractResult, error) {
+	absArchive, err := fs.GetAbsolutePath(archivePath)
+	if err != nil {
+		return "", ExtractResult{}, err
+	}
+	file, err := os.Open(absArchive)
+	if err != nil {
+		return "", ExtractResult{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", ExtractResult{}, err
+	}
+	if info.IsDir() {
+		return "", ExtractResult{}, errors.New("path points to a directory, not a file")
+	}
+
+	header := make([]byte, 512)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return "", ExtractResult{}, err
+	}
+	format, err := DetectArchiveFormat(absArchive, header[:n])
+	if err != nil {
+		return "", ExtractResult{}, err
+	}
+
+	dest, realDest, err := fs.extractionDest(destPath)
+	if err != nil {
+		return format, ExtractResult{}, err
+	}
+
+	var result ExtractResult
+	switch format {
+	case ArchiveFormatZip:
+		result, err = fs.extractZip(dest, realDest, file, info.Size(), maxSize)
+	case ArchiveFormatTarGz:
+		gz, gzErr := gzip.NewReader(file)
+		if gzErr != nil {
+			return format, result, fmt.Errorf("%w: %v", ErrInvalidArchive, gzErr)
+		}
+		defer func() { _ = gz.Close() }()
+		result, err = fs.extractTarEntries(dest, realDest, tar.NewReader(gz), maxSize)
+	case ArchiveFormatTarBz2:
+		result, err = fs.extractTarEntries(dest, realDest, tar.NewReader(bzip2.NewReader(file)), maxSize)
+	default:
+		result, err = fs.extractTarEntries(dest, realDest, tar.NewReader(file), maxSize)
+	}
+	return format, result, err
+}
+
+// extractZip writes the entries of a zip archive into dest
+func (fs *Filesystem) extractZip(dest string, realDest string, r io.ReaderAt, size int64, maxSize int64) (ExtractResult, error) {
+	var result ExtractResult
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return result, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	for _, entry := range zr.File {
+		target, err := archiveTarget(dest, entry.Name)
+		if err != nil {
+			return result, err
+		}
+		if target == dest {
+			continue
+		}
+
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			perm := mode.Perm()
+			if perm == 0 {
+				perm = 0755
+			}
+			if err := fs.ensureParent(realDest, target); err != nil {
+				return result, err
+			}
+			if err := fs.mkdirAll(target, perm); err != nil {
+				return result, err
+			}
+			result.Directories++
+
+		case mode&os.ModeSymlink != 0:
+			linkname, err := readZipEntry(entry, maxZipSymlinkSize)
+			if err != nil {
+				return result, err
+			}
+			if err := fs.extractSymlink(dest, realDest, target, entry.Name, string(linkname)); err != nil {
+				return result, err
+			}
+			result.Files++
+
+		case mode.IsRegular():
+			// The uncompressed size of a zip entry can't be trusted, so the limit is
+			// also enforced on the bytes actually written
+			if maxSize > 0 && result.Bytes+int64(entry.UncompressedSize64) > maxSize {
+				return result, fmt.Errorf("%w (%d bytes)", ErrArchiveTooLarge, maxSize)
+			}
+			if err := fs.ensureParent(realDest, target); err != nil {
+				return result, err
+			}
+			perm := mode.Perm()
+			if perm == 0 {
+				perm = 0644
+			}
+			rc, err := entry.Open()
+			if err != nil {
+				return result, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			var src io.Reader = rc
+			if maxSize > 0 {
+				src = io.LimitReader(rc, maxSize-result.Bytes+1)
+			}
+			written, err := fs.extractFile(target, src, perm)
+			_ = rc.Close()
+			result.Bytes += written
+			if err != nil {
+				if errors.Is(err, zip.ErrChecksum) || errors.Is(err, zip.ErrFormat) {
+					return result, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+				}
+				return result, err
+			}
+			if maxSize > 0 && result.Bytes > maxSize {
+				return result, fmt.Errorf("%w (%d bytes)", ErrArchiveTooLarge, maxSize)
+			}
+			result.Files++
+
+		default:
+			// Devices, fifos and other special entries are skipped
+		}
+	}
+	return result, nil
+}
+
+// readZipEntry reads a small zip entry, failing when it is larger than limit
+func readZipEntry(entry *zip.File, limit int64) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: symlink %s target is too long", ErrInvalidArchive, entry.Name)
+	}
+	return data, nil
+}
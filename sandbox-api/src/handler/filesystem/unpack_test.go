@@ -0,0 +1,148 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+type zipEntry struct {
+	name string
+	mode os.FileMode
+	body string
+}
+
+func buildZip(t *testing.T, entries ...zipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		header.SetMode(e.mode)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatalf("Failed to write body: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	tarHeader := make([]byte, 512)
+	copy(tarHeader[257:], "ustar")
+
+	for _, tc := range []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"archive", []byte("PK\x03\x04rest"), ArchiveFormatZip},
+		{"archive", []byte{0x1f, 0x8b, 0x08}, ArchiveFormatTarGz},
+		{"archive", []byte("BZh91AY"), ArchiveFormatTarBz2},
+		{"archive", tarHeader, ArchiveFormatTar},
+		{"release.TGZ", []byte("??"), ArchiveFormatTarGz},
+		{"release.tar.bz2", nil, ArchiveFormatTarBz2},
+		{"release.tar", nil, ArchiveFormatTar},
+		{"release.zip", nil, ArchiveFormatZip},
+	} {
+		got, err := DetectArchiveFormat(tc.name, tc.header)
+		if err != nil || got != tc.want {
+			t.Errorf("DetectArchiveFormat(%s, %q) = %s, %v, want %s", tc.name, tc.header, got, err, tc.want)
+		}
+	}
+
+	if _, err := DetectArchiveFormat("notes.txt", []byte("hello")); !errors.Is(err, ErrUnknownArchiveFormat) {
+		t.Errorf("Expected ErrUnknownArchiveFormat, got %v", err)
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	entries := []tarEntry{
+		{name: "src/", typeflag: tar.TypeDir},
+		{name: "src/main.go", typeflag: tar.TypeReg, body: "package main\n"},
+		{name: "link", typeflag: tar.TypeSymlink, linkname: "src/main.go"},
+	}
+	archives := map[string][]byte{
+		// No extension, so that the format has to be detected from the content
+		ArchiveFormatTar:   buildTar(t, false, entries...).Bytes(),
+		ArchiveFormatTarGz: buildTar(t, true, entries...).Bytes(),
+		ArchiveFormatZip: buildZip(t,
+			zipEntry{name: "src/", mode: os.ModeDir | 0755},
+			zipEntry{name: "src/main.go", mode: 0644, body: "package main\n"},
+			zipEntry{name: "link", mode: os.ModeSymlink | 0777, body: "src/main.go"},
+		),
+	}
+	if _, err := exec.LookPath("bzip2"); err == nil {
+		cmd := exec.Command("bzip2", "-c")
+		cmd.Stdin = bytes.NewReader(archives[ArchiveFormatTar])
+		if out, err := cmd.Output(); err == nil {
+			archives[ArchiveFormatTarBz2] = out
+		}
+	}
+
+	for format, data := range archives {
+		archivePath := filepath.Join(tempDir, "archive-"+format)
+		if err := os.WriteFile(archivePath, data, 0644); err != nil {
+			t.Fatalf("Failed to write archive: %v", err)
+		}
+		dest := filepath.Join(tempDir, "out-"+format)
+
+		detected, result, err := fs.Unpack(archivePath, dest, 0)
+		if err != nil {
+			t.Fatalf("Unpack %s failed: %v", format, err)
+		}
+		if detected != format {
+			t.Errorf("Expected format %s, got %s", format, detected)
+		}
+		if result.Files != 2 || result.Directories != 1 || result.Bytes != 13 {
+			t.Errorf("Unexpected result for %s: %+v", format, result)
+		}
+		content, err := os.ReadFile(filepath.Join(dest, "link"))
+		if err != nil || string(content) != "package main\n" {
+			t.Errorf("Unexpected content through symlink for %s: %q (%v)", format, content, err)
+		}
+	}
+}
+
+func TestUnpackZipRejectsUnsafeEntries(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		name    string
+		entries []zipEntry
+		want    error
+	}{
+		{"parent traversal", []zipEntry{{name: "../evil.txt", mode: 0644, body: "x"}}, ErrUnsafeArchiveEntry},
+		{"symlink outside", []zipEntry{{name: "out", mode: os.ModeSymlink | 0777, body: "/etc"}}, ErrUnsafeArchiveEntry},
+		{"too large", []zipEntry{{name: "a.txt", mode: 0644, body: "0123456789"}, {name: "b.txt", mode: 0644, body: "0123456789"}}, ErrArchiveTooLarge},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			archivePath := filepath.Join(tempDir, "archive.zip")
+			if err := os.WriteFile(archivePath, buildZip(t, tc.entries...), 0644); err != nil {
+				t.Fatalf("Failed to write archive: %v", err)
+			}
+			if _, _, err := fs.Unpack(archivePath, filepath.Join(tempDir, "dest"), 15); !errors.Is(err, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "evil.txt")); !os.IsNotExist(err) {
+		t.Error("Expected no file to be written outside the destination")
+	}
+}
@@ -0,0 +1,235 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrArchiveTooLarge is returned when an archive expands beyond the allowed size
+var ErrArchiveTooLarge = errors.New("archive exceeds the maximum extracted size")
+
+// ErrInvalidArchive is returned when the stream is not a valid tar or tar.gz archive
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// ErrUnsafeArchiveEntry is returned for archive entries that would be written outside the destination
+var ErrUnsafeArchiveEntry = errors.New("archive entry escapes the destination directory")
+
+// ExtractResult summarizes an archive extraction
+type ExtractResult struct {
+	Files       int
+	Directories int
+	Bytes       int64
+}
+
+// ExtractTar extracts a tar or tar.gz stream (detected from its first bytes) into
+// the directory at path, creating it if needed. Entries are written as they are
+// read, so the archive is never held in memory. Entries resolving outside the
+// destination are rejected, and extraction stops once regular files add up to
+// more than maxSize bytes (0 disables the limit).
+func (fs *Filesystem) ExtractTar(path string, r io.Reader, maxSize int64) (ExtractResult, error) {
+	dest, realDest, err := fs.extractionDest(path)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+
+	br := bufio.NewReader(r)
+	var stream io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return ExtractResult{}, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		defer func() { _ = gz.Close() }()
+		stream = gz
+	}
+
+	return fs.extractTarEntries(dest, realDest, tar.NewReader(stream), maxSize)
+}
+
+// extractionDest creates the destination directory at path and returns it, along
+// with its location once symlinks are resolved
+func (fs *Filesystem) extractionDest(path string) (string, string, error) {
+	dest, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return "", "", err
+	}
+	if err := fs.mkdirAll(dest, 0755); err != nil {
+		return "", "", err
+	}
+	realDest, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return "", "", err
+	}
+	return dest, realDest, nil
+}
+
+// extractTarEntries writes the entries of a tar stream into dest
+func (fs *Filesystem) extractTarEntries(dest string, realDest string, tr *tar.Reader, maxSize int64) (ExtractResult, error) {
+	var result ExtractResult
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+
+		target, err := archiveTarget(dest, header.Name)
+		if err != nil {
+			return result, err
+		}
+		if target == dest {
+			continue
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if mode == 0 {
+				mode = 0755
+			}
+			if err := fs.ensureParent(realDest, target); err != nil {
+				return result, err
+			}
+			if err := fs.mkdirAll(target, mode); err != nil {
+				return result, err
+			}
+			result.Directories++
+
+		case tar.TypeReg, tar.TypeRegA:
+			if maxSize > 0 && result.Bytes+header.Size > maxSize {
+				return result, fmt.Errorf("%w (%d bytes)", ErrArchiveTooLarge, maxSize)
+			}
+			if err := fs.ensureParent(realDest, target); err != nil {
+				return result, err
+			}
+			if mode == 0 {
+				mode = 0644
+			}
+			written, err := fs.extractFile(target, tr, mode)
+			result.Bytes += written
+			if err != nil {
+				return result, err
+			}
+			result.Files++
+
+		case tar.TypeSymlink:
+			if err := fs.extractSymlink(dest, realDest, target, header.Name, header.Linkname); err != nil {
+				return result, err
+			}
+			result.Files++
+
+		case tar.TypeLink:
+			linkTarget, err := archiveTarget(dest, header.Linkname)
+			if err != nil {
+				return result, err
+			}
+			if err := fs.ensureParent(realDest, target); err != nil {
+				return result, err
+			}
+			_ = os.Remove(target)
+			if err := os.Link(linkTarget, target); err != nil {
+				return result, err
+			}
+			result.Files++
+
+		default:
+			// Devices, fifos and other special entries are skipped
+		}
+	}
+}
+
+// extractSymlink creates the symlink entry name at target, rejecting links that point outside the destination
+func (fs *Filesystem) extractSymlink(dest string, realDest string, target string, name string, linkname string) error {
+	if err := fs.ensureParent(realDest, target); err != nil {
+		return err
+	}
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	linkTarget := linkname
+	if !filepath.IsAbs(linkTarget) {
+		linkTarget = filepath.Join(realParent, linkTarget)
+	}
+	if !isWithin(realDest, filepath.Clean(linkTarget)) && !isWithin(dest, filepath.Clean(linkTarget)) {
+		return fmt.Errorf("%w: symlink %s -> %s", ErrUnsafeArchiveEntry, name, linkname)
+	}
+	_ = os.Remove(target)
+	return os.Symlink(linkname, target)
+}
+
+// ensureParent creates the parent directory of target and verifies that, once
+// symlinks created by earlier entries are resolved, it is still inside realDest
+func (fs *Filesystem) ensureParent(realDest string, target string) error {
+	parent := filepath.Dir(target)
+	if err := fs.mkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	realParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return err
+	}
+	if !isWithin(realDest, realParent) {
+		return fmt.Errorf("%w: %s", ErrUnsafeArchiveEntry, target)
+	}
+	return nil
+}
+
+// extractFile writes a single archive entry to target
+func (fs *Filesystem) extractFile(target string, r io.Reader, mode os.FileMode) (int64, error) {
+	// Never write through an existing symlink, which could point outside the destination
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return 0, err
+		}
+	}
+
+	_, statErr := os.Lstat(target)
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, err
+	}
+	if os.IsNotExist(statErr) {
+		return written, fs.applyDefaultOwnership(target)
+	}
+	return written, nil
+}
+
+// archiveTarget returns where an archive entry should be written, rejecting
+// absolute names and names that climb out of dest
+func archiveTarget(dest string, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchiveEntry, name)
+	}
+	target := filepath.Join(dest, name)
+	if !isWithin(dest, target) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchiveEntry, name)
+	}
+	return target, nil
+}
+
+// isWithin reports whether path is root or one of its descendants
+func isWithin(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
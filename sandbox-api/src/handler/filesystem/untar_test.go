@@ -0,0 +1,129 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func buildTar(t *testing.T, gzipped bool, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0644, Size: int64(len(e.body)), Linkname: e.linkname}
+		if e.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatalf("Failed to write body: %v", err)
+		}
+	}
+	_ = tw.Close()
+	if gz != nil {
+		_ = gz.Close()
+	}
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	for _, gzipped := range []bool{false, true} {
+		dest := filepath.Join(tempDir, "plain")
+		if gzipped {
+			dest = filepath.Join(tempDir, "gzipped")
+		}
+		archive := buildTar(t, gzipped,
+			tarEntry{name: "src/", typeflag: tar.TypeDir},
+			tarEntry{name: "src/main.go", typeflag: tar.TypeReg, body: "package main\n"},
+			tarEntry{name: "README.md", typeflag: tar.TypeReg, body: "hello"},
+			tarEntry{name: "link", typeflag: tar.TypeSymlink, linkname: "src/main.go"},
+		)
+
+		result, err := fs.ExtractTar(dest, archive, 0)
+		if err != nil {
+			t.Fatalf("ExtractTar (gzip=%v) failed: %v", gzipped, err)
+		}
+		if result.Files != 3 || result.Directories != 1 || result.Bytes != 18 {
+			t.Errorf("Unexpected result (gzip=%v): %+v", gzipped, result)
+		}
+		content, err := os.ReadFile(filepath.Join(dest, "link"))
+		if err != nil || string(content) != "package main\n" {
+			t.Errorf("Unexpected content through symlink: %q (%v)", content, err)
+		}
+	}
+}
+
+func TestExtractTarRejectsUnsafeEntries(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	dest := filepath.Join(tempDir, "dest")
+
+	testCases := []struct {
+		name    string
+		entries []tarEntry
+	}{
+		{"parent traversal", []tarEntry{{name: "../evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{"absolute path", []tarEntry{{name: "/tmp/evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{"symlink outside", []tarEntry{{name: "out", typeflag: tar.TypeSymlink, linkname: "/etc"}}},
+		{"symlink chain", []tarEntry{
+			{name: "a/", typeflag: tar.TypeDir},
+			{name: "a/up", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "a/up/up", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "a/up/up/evil.txt", typeflag: tar.TypeReg, body: "x"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := fs.ExtractTar(dest, buildTar(t, false, tc.entries...), 0)
+			if !errors.Is(err, ErrUnsafeArchiveEntry) {
+				t.Errorf("Expected ErrUnsafeArchiveEntry, got %v", err)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "evil.txt")); !os.IsNotExist(err) {
+		t.Error("Expected no file to be written outside the destination")
+	}
+}
+
+func TestExtractTarSizeLimit(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	archive := buildTar(t, true,
+		tarEntry{name: "a.txt", typeflag: tar.TypeReg, body: "0123456789"},
+		tarEntry{name: "b.txt", typeflag: tar.TypeReg, body: "0123456789"},
+	)
+	if _, err := fs.ExtractTar(filepath.Join(tempDir, "dest"), archive, 15); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Errorf("Expected ErrArchiveTooLarge, got %v", err)
+	}
+
+	if _, err := fs.ExtractTar(filepath.Join(tempDir, "dest"), bytes.NewBufferString("not an archive at all"), 0); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("Expected ErrInvalidArchive, got %v", err)
+	}
+}
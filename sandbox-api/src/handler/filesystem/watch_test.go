@@ -0,0 +1,84 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchDirectoryRecursiveReportsFailures(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	root := filepath.Join(tempDir, "watched")
+	for _, dir := range []string{"ok", "broken/nested", "later"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Simulate the inotify watch limit being hit on some directories
+	errLimit := errors.New("no space left on device")
+	defer func(original func(*fsnotify.Watcher, string) error) { addWatch = original }(addWatch)
+	addWatch = func(watcher *fsnotify.Watcher, dir string) error {
+		if filepath.Base(dir) == "broken" || filepath.Base(dir) == "new-broken" {
+			return errLimit
+		}
+		return watcher.Add(dir)
+	}
+
+	var mu sync.Mutex
+	failures := map[string]error{}
+	events := map[string]bool{}
+	stop, err := fs.WatchDirectoryRecursiveWithErrors(root, func(event fsnotify.Event) {
+		mu.Lock()
+		events[event.Name] = true
+		mu.Unlock()
+	}, func(dir string, err error) {
+		mu.Lock()
+		failures[dir] = err
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("Expected the watch to start despite the failure, got %v", err)
+	}
+	defer stop()
+
+	// The initial failure is reported before the watch returns, its subdirectories are skipped
+	mu.Lock()
+	if len(failures) != 1 || !errors.Is(failures[filepath.Join(root, "broken")], errLimit) {
+		t.Errorf("Expected a single failure for broken, got %v", failures)
+	}
+	mu.Unlock()
+
+	// Directories created later are reported too, other ones are still watched
+	if err := os.Mkdir(filepath.Join(root, "later", "new-broken"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "ok", "a.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		mu.Lock()
+		done := failures[filepath.Join(root, "later", "new-broken")] != nil && events[filepath.Join(root, "ok", "a.txt")]
+		mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the new failure and the event of a watched directory, got %v and %v", failures, events)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// The root failing is still an error
+	if _, err := fs.WatchDirectoryRecursiveWithErrors(filepath.Join(root, "broken"), func(fsnotify.Event) {}, nil); err == nil {
+		t.Error("Expected an error when the root can't be watched")
+	}
+}
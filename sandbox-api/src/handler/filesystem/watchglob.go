@@ -0,0 +1,68 @@
+package filesystem
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SplitRecursiveWatchPath splits a recursive watch path into the directory to watch
+// and an optional trailing glob: "/src/**" watches /src entirely, "/src/**/*.ts"
+// watches /src but only matches .ts files at any depth. recursive is false when the
+// path has no "/**" component.
+func SplitRecursiveWatchPath(watchPath string) (dir string, pattern string, recursive bool) {
+	if strings.HasSuffix(watchPath, "/**") {
+		dir = strings.TrimSuffix(watchPath, "/**")
+	} else if i := strings.Index(watchPath, "/**/"); i >= 0 {
+		dir, pattern = watchPath[:i], watchPath[i+len("/**/"):]
+	} else {
+		return watchPath, "", false
+	}
+	if dir == "" {
+		dir = "/"
+	}
+	return dir, pattern, true
+}
+
+// WatchGlob matches the paths of watch events against a glob relative to the watched directory
+type WatchGlob struct {
+	root     string
+	pattern  string
+	segments int
+}
+
+// NewWatchGlob compiles the trailing glob of a recursive watch on dir. The glob uses
+// path.Match syntax per segment and may span several segments ("test/*.ts"); it is
+// matched against the last segments of each event path, at any depth below dir.
+func (fs *Filesystem) NewWatchGlob(dir string, pattern string) (*WatchGlob, error) {
+	if pattern == "" || strings.HasPrefix(pattern, "/") || strings.HasSuffix(pattern, "/") {
+		return nil, fmt.Errorf("invalid watch glob %q", pattern)
+	}
+	if strings.Contains(pattern, "**") {
+		return nil, fmt.Errorf("invalid watch glob %q: ** is only supported once, before the glob", pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid watch glob %q: %w", pattern, err)
+	}
+
+	root, err := fs.GetAbsolutePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &WatchGlob{root: root, pattern: pattern, segments: strings.Count(pattern, "/") + 1}, nil
+}
+
+// Match reports whether an absolute event path below the watched directory matches the glob
+func (g *WatchGlob) Match(eventPath string) bool {
+	rel, err := filepath.Rel(g.root, eventPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	if len(segments) < g.segments {
+		return false
+	}
+	matched, _ := path.Match(g.pattern, strings.Join(segments[len(segments)-g.segments:], "/"))
+	return matched
+}
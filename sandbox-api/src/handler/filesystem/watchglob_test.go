@@ -0,0 +1,67 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitRecursiveWatchPath(t *testing.T) {
+	tests := []struct {
+		path, dir, pattern string
+		recursive          bool
+	}{
+		{"/src", "/src", "", false},
+		{"/src/**", "/src", "", true},
+		{"/**", "/", "", true},
+		{"/src/**/*.ts", "/src", "*.ts", true},
+		{"/**/test/*.go", "/", "test/*.go", true},
+	}
+	for _, tt := range tests {
+		dir, pattern, recursive := SplitRecursiveWatchPath(tt.path)
+		if dir != tt.dir || pattern != tt.pattern || recursive != tt.recursive {
+			t.Errorf("SplitRecursiveWatchPath(%q) = %q, %q, %v", tt.path, dir, pattern, recursive)
+		}
+	}
+}
+
+func TestWatchGlobMatch(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	glob, err := fs.NewWatchGlob("src", "*.ts")
+	if err != nil {
+		t.Fatalf("NewWatchGlob failed: %v", err)
+	}
+	src := filepath.Join(tempDir, "src")
+	for eventPath, want := range map[string]bool{
+		filepath.Join(src, "index.ts"):                    true,
+		filepath.Join(src, "a", "b", "c", "deep.ts"):      true,
+		filepath.Join(src, "index.js"):                    false,
+		filepath.Join(src, "types.ts", "nested.js"):       false,
+		filepath.Join(src, "a"):                           false,
+		filepath.Join(tempDir, "other", "outside.ts"):     false,
+		filepath.Join(src):                                false,
+		filepath.Join(src, "components", "button.ts.map"): false,
+	} {
+		if got := glob.Match(eventPath); got != want {
+			t.Errorf("Match(%q) = %v, want %v", eventPath, got, want)
+		}
+	}
+
+	glob, err = fs.NewWatchGlob("src", "test/*_test.go")
+	if err != nil {
+		t.Fatalf("NewWatchGlob failed: %v", err)
+	}
+	if !glob.Match(filepath.Join(src, "pkg", "test", "a_test.go")) {
+		t.Errorf("Expected a nested multi-segment match")
+	}
+	if glob.Match(filepath.Join(src, "pkg", "a_test.go")) || glob.Match(filepath.Join(src, "test", "sub", "a_test.go")) {
+		t.Errorf("Expected no match outside a test directory")
+	}
+
+	for _, pattern := range []string{"", "[", "a/**/b", "/abs"} {
+		if _, err := fs.NewWatchGlob("src", pattern); err == nil {
+			t.Errorf("Expected an error for pattern %q", pattern)
+		}
+	}
+}
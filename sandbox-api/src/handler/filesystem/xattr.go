@@ -0,0 +1,73 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrXattrsNotSupported is returned when the platform or the filesystem holding a path has no extended attributes
+	ErrXattrsNotSupported = errors.New("extended attributes are not supported on this filesystem")
+	// ErrXattrNotFound is returned when a path has no extended attribute with the requested name
+	ErrXattrNotFound = errors.New("extended attribute not found")
+	// ErrInvalidXattrName is returned for attribute names without a namespace
+	ErrInvalidXattrName = errors.New("invalid extended attribute name")
+)
+
+// ListXattrs returns the extended attributes of a path with their values
+func (fs *Filesystem) ListXattrs(path string) (map[string][]byte, error) {
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+	names, err := listXattrNames(absPath)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make(map[string][]byte, len(names))
+	for _, name := range names {
+		value, err := getXattr(absPath, name)
+		if errors.Is(err, ErrXattrNotFound) {
+			// Removed since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+// GetXattr returns the value of an extended attribute of a path
+func (fs *Filesystem) GetXattr(path, name string) ([]byte, error) {
+	if err := validateXattrName(name); err != nil {
+		return nil, err
+	}
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return getXattr(absPath, name)
+}
+
+// SetXattr creates or replaces an extended attribute of a path
+func (fs *Filesystem) SetXattr(path, name string, value []byte) error {
+	if err := validateXattrName(name); err != nil {
+		return err
+	}
+	absPath, err := fs.GetAbsolutePath(path)
+	if err != nil {
+		return err
+	}
+	return setXattr(absPath, name, value)
+}
+
+// validateXattrName checks an attribute name is namespaced (e.g. "user.comment")
+func validateXattrName(name string) error {
+	if name == "" || len(name) > 255 || !strings.Contains(name, ".") || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("%w %q: use a namespaced name such as user.comment", ErrInvalidXattrName, name)
+	}
+	return nil
+}
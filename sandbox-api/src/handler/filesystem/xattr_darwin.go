@@ -0,0 +1,8 @@
+//go:build darwin
+
+package filesystem
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr is returned by getxattr for a missing attribute
+var errNoXattr = unix.ENOATTR
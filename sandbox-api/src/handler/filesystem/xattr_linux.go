@@ -0,0 +1,8 @@
+//go:build linux
+
+package filesystem
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr is returned by getxattr for a missing attribute
+var errNoXattr = unix.ENODATA
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+// listXattrNames returns an error on platforms without extended attributes
+func listXattrNames(absPath string) ([]string, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+// getXattr returns an error on platforms without extended attributes
+func getXattr(absPath, name string) ([]byte, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+// setXattr returns an error on platforms without extended attributes
+func setXattr(absPath, name string, value []byte) error {
+	return ErrXattrsNotSupported
+}
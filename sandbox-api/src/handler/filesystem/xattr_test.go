@@ -0,0 +1,50 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestXattrs(t *testing.T) {
+	tempDir, fs, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "data.bin"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	err := fs.SetXattr("data.bin", "user.comment", []byte("reviewed"))
+	if errors.Is(err, ErrXattrsNotSupported) {
+		t.Skip("extended attributes are not supported here")
+	}
+	if err != nil {
+		t.Fatalf("SetXattr failed: %v", err)
+	}
+	if err := fs.SetXattr("data.bin", "user.raw", []byte{0, 1, 2}); err != nil {
+		t.Fatalf("SetXattr failed: %v", err)
+	}
+
+	value, err := fs.GetXattr("data.bin", "user.comment")
+	if err != nil || string(value) != "reviewed" {
+		t.Errorf("Expected reviewed, got %q (%v)", value, err)
+	}
+	attrs, err := fs.ListXattrs("data.bin")
+	if err != nil {
+		t.Fatalf("ListXattrs failed: %v", err)
+	}
+	if string(attrs["user.comment"]) != "reviewed" || string(attrs["user.raw"]) != "\x00\x01\x02" {
+		t.Errorf("Unexpected attributes: %q", attrs)
+	}
+
+	if _, err := fs.GetXattr("data.bin", "user.missing"); !errors.Is(err, ErrXattrNotFound) {
+		t.Errorf("Expected ErrXattrNotFound, got %v", err)
+	}
+	if err := fs.SetXattr("data.bin", "comment", []byte("x")); !errors.Is(err, ErrInvalidXattrName) {
+		t.Errorf("Expected ErrInvalidXattrName, got %v", err)
+	}
+	if _, err := fs.ListXattrs("missing.bin"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
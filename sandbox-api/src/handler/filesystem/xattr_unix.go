@@ -0,0 +1,83 @@
+//go:build linux || darwin
+
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// xattrError maps the errors of the xattr syscalls to the package errors
+func xattrError(op, absPath string, err error) error {
+	switch {
+	case errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP):
+		return ErrXattrsNotSupported
+	case errors.Is(err, errNoXattr):
+		return ErrXattrNotFound
+	default:
+		return &os.PathError{Op: op, Path: absPath, Err: err}
+	}
+}
+
+// listXattrNames returns the names of the extended attributes of a path
+func listXattrNames(absPath string) ([]string, error) {
+	for {
+		size, err := unix.Listxattr(absPath, nil)
+		if err != nil {
+			return nil, xattrError("listxattr", absPath, err)
+		}
+		if size == 0 {
+			return []string{}, nil
+		}
+		buf := make([]byte, size)
+		n, err := unix.Listxattr(absPath, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// Attributes were added in between, retry with the new size
+			continue
+		}
+		if err != nil {
+			return nil, xattrError("listxattr", absPath, err)
+		}
+		var names []string
+		for _, name := range strings.Split(string(buf[:n]), "\x00") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+}
+
+// getXattr returns the value of an extended attribute
+func getXattr(absPath, name string) ([]byte, error) {
+	for {
+		size, err := unix.Getxattr(absPath, name, nil)
+		if err != nil {
+			return nil, xattrError("getxattr", absPath, err)
+		}
+		buf := make([]byte, size)
+		if size == 0 {
+			return buf, nil
+		}
+		n, err := unix.Getxattr(absPath, name, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// The value grew in between, retry with the new size
+			continue
+		}
+		if err != nil {
+			return nil, xattrError("getxattr", absPath, err)
+		}
+		return buf[:n], nil
+	}
+}
+
+// setXattr creates or replaces an extended attribute
+func setXattr(absPath, name string, value []byte) error {
+	if err := unix.Setxattr(absPath, name, value, 0); err != nil {
+		return xattrError("setxattr", absPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,545 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler/filesystem"
+)
+
+func TestAddFileEventDetails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	msg := FileEvent{}
+	addFileEventDetails(&msg, fsnotify.Event{Name: path, Op: fsnotify.Write})
+	if msg.Size == nil || *msg.Size != 5 || msg.ModTime == nil {
+		t.Errorf("Expected size and modTime to be set, got %+v", msg)
+	}
+
+	msg = FileEvent{}
+	addFileEventDetails(&msg, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+	if msg.Size != nil || msg.ModTime != nil {
+		t.Error("Expected no details for REMOVE events")
+	}
+
+	// The file may be deleted before the stat: details are omitted
+	msg = FileEvent{}
+	addFileEventDetails(&msg, fsnotify.Event{Name: path + ".gone", Op: fsnotify.Create})
+	if msg.Size != nil || msg.ModTime != nil {
+		t.Error("Expected no details for a file that no longer exists")
+	}
+}
+
+func TestWatchChmodEvent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.sh")
+	if err := os.WriteFile(path, []byte("echo hi"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	h := NewFileSystemHandler()
+	events := make(chan FileEvent, 10)
+	stop, err := h.fs.WatchDirectory(dir, func(event fsnotify.Event) {
+		events <- newFileEvent(event, false)
+	})
+	if err != nil {
+		t.Fatalf("Failed to watch directory: %v", err)
+	}
+	defer stop()
+
+	if err := os.Chmod(path, 0755); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Op == "CHMOD" {
+				if event.Name != "script.sh" || event.Path != dir {
+					t.Errorf("Unexpected CHMOD event: %+v", event)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("Timeout waiting for the CHMOD event")
+		}
+	}
+}
+
+func TestStreamWatchRootFailure(t *testing.T) {
+	dir := t.TempDir()
+	h := NewFileSystemHandler()
+	roots := []*watchRoot{
+		{name: "ok", dir: dir},
+		{name: "gone", dir: filepath.Join(dir, "gone")}, // Removed after it was resolved
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/watch/filesystem-multi", nil)
+	h.streamWatch(c, roots, true)
+
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("Expected a 500 before the stream started, got %d (%s)", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "gone") || strings.Count(w.Body.String(), "\n") > 0 {
+		t.Errorf("Expected a single JSON error naming the root, got %q", w.Body.String())
+	}
+}
+
+func TestReadFileMaxInlineSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, make([]byte, 2048), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	h := NewFileSystemHandler()
+	h.maxInlineSize = 1024
+
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		url    string
+		status int
+	}{
+		{"/filesystem" + path, http.StatusRequestEntityTooLarge},
+		{"/filesystem" + path + "?download=true", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, tc.url, nil)
+		h.handleReadFile(c, path)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.url, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestReadFileCompressGzip(t *testing.T) {
+	content := strings.Repeat("package main\n", 100)
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	h := NewFileSystemHandler()
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/filesystem"+path+"?download=true&compress=gzip", nil)
+	h.handleReadFile(c, path)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a gzip download, got %d %v", w.Code, w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	if data, err := io.ReadAll(gz); err != nil || string(data) != content {
+		t.Errorf("Expected the file content once decompressed, got %d bytes (%v)", len(data), err)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/filesystem"+path+"?compress=gzip", nil)
+	h.handleReadFile(c, path)
+	var file filesystem.FileWithContentByte
+	if err := json.Unmarshal(w.Body.Bytes(), &file); err != nil {
+		t.Fatalf("Failed to decode response: %v (%s)", err, w.Body.String())
+	}
+	if file.ContentEncoding != "gzip" || file.Size != int64(len(content)) || len(file.Content) >= len(content) {
+		t.Fatalf("Expected smaller gzipped content with the original size, got encoding %q size %d content %d", file.ContentEncoding, file.Size, len(file.Content))
+	}
+	gz, err = gzip.NewReader(bytes.NewReader(file.Content))
+	if err != nil {
+		t.Fatalf("Failed to open gzip content: %v", err)
+	}
+	if data, err := io.ReadAll(gz); err != nil || string(data) != content {
+		t.Errorf("Expected the file content once decompressed, got %d bytes (%v)", len(data), err)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/filesystem"+path+"?compress=br", nil)
+	h.handleReadFile(c, path)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsupported compression, got %d", w.Code)
+	}
+}
+
+func TestReadFileWithHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	h := NewFileSystemHandler()
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		query  string
+		status int
+		hash   string
+	}{
+		{"?withHash=sha256", http.StatusOK, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"?withHash=md5", http.StatusOK, "5d41402abc4b2a76b9719d911017c592"},
+		{"?withHash=sha256&compress=gzip", http.StatusOK, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"?withHash=crc32", http.StatusBadRequest, ""},
+		{"?withHash=sha256&download=true", http.StatusBadRequest, ""},
+		{"", http.StatusOK, ""},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/filesystem"+path+tc.query, nil)
+		h.handleReadFile(c, path)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.query, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var file filesystem.FileWithContent
+		if err := json.Unmarshal(w.Body.Bytes(), &file); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tc.query, err)
+		}
+		if file.Hash != tc.hash {
+			t.Errorf("%s: expected hash %q, got %q", tc.query, tc.hash, file.Hash)
+		}
+		if (file.HashAlgorithm != "") != (tc.hash != "") {
+			t.Errorf("%s: unexpected hash algorithm %q", tc.query, file.HashAlgorithm)
+		}
+	}
+}
+
+func TestCreateFileContentEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	h := NewFileSystemHandler()
+
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		body    string
+		status  int
+		content string
+	}{
+		{`{"content":"AAEC/w==","contentEncoding":"base64"}`, http.StatusOK, "\x00\x01\x02\xff"},
+		{`{"content":"hello"}`, http.StatusOK, "hello"},
+		{`{"content":"not base64!","contentEncoding":"base64"}`, http.StatusBadRequest, ""},
+		{`{"content":"hello","contentEncoding":"hex"}`, http.StatusBadRequest, ""},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/filesystem/"+strings.ReplaceAll(path, "/", "%2F"), strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "path", Value: path}}
+		h.HandleCreateOrUpdateFile(c)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.body, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != tc.content {
+			t.Errorf("%s: expected content %q, got %q (%v)", tc.body, tc.content, data, err)
+		}
+	}
+}
+
+func TestCreateDirectoryFailIfExists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app")
+	h := NewFileSystemHandler()
+
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		query  string
+		body   string
+		status int
+	}{
+		{"?failIfExists=true", `{"isDirectory":true}`, http.StatusOK},
+		{"?failIfExists=true", `{"type":"directory"}`, http.StatusConflict},
+		{"", `{"isDirectory":true}`, http.StatusOK},
+		{"?failIfExists=true", `{"content":"hello"}`, http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/filesystem/"+strings.ReplaceAll(path, "/", "%2F")+tc.query, strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "path", Value: path}}
+		h.HandleCreateOrUpdateFile(c)
+		if w.Code != tc.status {
+			t.Errorf("%s %s: expected status %d, got %d (%s)", tc.query, tc.body, tc.status, w.Code, w.Body.String())
+		}
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		t.Errorf("Expected %s to be a directory (%v)", path, err)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	t.Setenv("WORKDIR", "/app")
+	h := NewFileSystemHandler()
+
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		url      string
+		param    string
+		path     string
+		absPath  string
+		relative bool
+	}{
+		{"/filesystem-resolve/src/app.ts", "/src/app.ts", "src/app.ts", "/app/src/app.ts", true},
+		{"/filesystem-resolve/%2Ftmp%2Fa", "/tmp/a", "/tmp/a", "/tmp/a", false},
+		{"/filesystem-resolve/", "/", ".", "/app", true},
+		{"/filesystem-resolve?path=tmp//x", "", "tmp/x", "/app/tmp/x", true},
+		{"/filesystem-resolve/ignored?path=/var/../etc", "/ignored", "/var/../etc", "/etc", false},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, tc.url, nil)
+		c.Params = gin.Params{{Key: "path", Value: tc.param}}
+		h.HandleResolvePath(c)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d (%s)", tc.url, w.Code, w.Body.String())
+			continue
+		}
+		var resolved ResolvedPath
+		if err := json.Unmarshal(w.Body.Bytes(), &resolved); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tc.url, err)
+		}
+		if resolved.Path != tc.path || resolved.AbsolutePath != tc.absPath || resolved.Relative != tc.relative || resolved.WorkingDir != "/app" {
+			t.Errorf("%s: unexpected resolved path %+v", tc.url, resolved)
+		}
+	}
+}
+
+func TestPatchFileTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "package.json")
+	if err := os.WriteFile(path, []byte(`{"name":"app"}`), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	h := NewFileSystemHandler()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	patch := `[{"op":"add","path":"/blob","value":"` + strings.Repeat("a", filesystem.MaxJSONPatchSize) + `"}]`
+	c.Request = httptest.NewRequest(http.MethodPatch, "/filesystem/"+strings.ReplaceAll(path, "/", "%2F"), strings.NewReader(patch))
+	c.Request.Header.Set("Content-Type", "application/json-patch+json")
+	c.Params = gin.Params{{Key: "path", Value: path}}
+	h.HandlePatchFile(c)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d (%s)", w.Code, w.Body.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"name":"app"}` {
+		t.Errorf("Expected the file to be left untouched, got %q", data)
+	}
+}
+
+func TestUploadBinaryPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.sh")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	h := NewFileSystemHandler()
+
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		fields map[string]string
+		status int
+		mode   os.FileMode
+	}{
+		{map[string]string{"permissions": "755", "owner": strconv.Itoa(os.Getuid()), "group": strconv.Itoa(os.Getgid())}, http.StatusOK, 0755},
+		{map[string]string{}, http.StatusOK, 0755}, // An existing file keeps its permissions
+		{map[string]string{"owner": "no-such-user-for-tests"}, http.StatusUnprocessableEntity, 0755},
+	} {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for key, value := range tc.fields {
+			_ = mw.WriteField(key, value)
+		}
+		part, _ := mw.CreateFormFile("file", "run.sh")
+		_, _ = part.Write([]byte("#!/bin/sh\n"))
+		_ = mw.Close()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/filesystem/"+strings.ReplaceAll(path, "/", "%2F"), &body)
+		c.Request.Header.Set("Content-Type", mw.FormDataContentType())
+		c.Params = gin.Params{{Key: "path", Value: path}}
+		h.HandleCreateOrUpdateFile(c)
+		if w.Code != tc.status {
+			t.Errorf("%v: expected status %d, got %d (%s)", tc.fields, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != tc.mode {
+			t.Errorf("%v: expected mode %v, got %v (%v)", tc.fields, tc.mode, info, err)
+		}
+	}
+}
+
+func TestTransferThrottling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+	content := make([]byte, 16*1024)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	h := NewFileSystemHandler()
+
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		name    string
+		url     string
+		cap     int64
+		status  int
+		minTime time.Duration
+		maxTime time.Duration
+	}{
+		{"requested rate", "?download=true&maxBytesPerSec=32768", 0, http.StatusOK, 450 * time.Millisecond, 900 * time.Millisecond},
+		{"server cap", "?download=true", 32768, http.StatusOK, 450 * time.Millisecond, 900 * time.Millisecond},
+		{"requested above the cap", "?download=true&maxBytesPerSec=1000000000", 32768, http.StatusOK, 450 * time.Millisecond, 900 * time.Millisecond},
+		{"unthrottled", "?download=true", 0, http.StatusOK, 0, 200 * time.Millisecond},
+		{"invalid rate", "?download=true&maxBytesPerSec=fast", 0, http.StatusBadRequest, 0, time.Second},
+	} {
+		h.maxTransferRate = tc.cap
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/filesystem"+path+tc.url, nil)
+		start := time.Now()
+		h.handleReadFile(c, path)
+		elapsed := time.Since(start)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.name, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status == http.StatusOK && w.Body.Len() != len(content) {
+			t.Errorf("%s: expected %d bytes, got %d", tc.name, len(content), w.Body.Len())
+		}
+		// 16KiB at 32KiB/s takes half a second
+		if elapsed < tc.minTime || elapsed > tc.maxTime {
+			t.Errorf("%s: expected between %s and %s, took %s", tc.name, tc.minTime, tc.maxTime, elapsed)
+		}
+	}
+
+	// Uploads are throttled the same way
+	h.maxTransferRate = 0
+	body := `{"content":"` + strings.Repeat("x", 16*1024) + `"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/filesystem/"+strings.ReplaceAll(path, "/", "%2F")+"?maxBytesPerSec=32768", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "path", Value: path}}
+	start := time.Now()
+	h.HandleCreateOrUpdateFile(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected upload to succeed, got %d (%s)", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond || elapsed > 900*time.Millisecond {
+		t.Errorf("Expected the upload to take about 500ms, took %s", elapsed)
+	}
+}
+
+func TestFindMatchFields(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0750); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0640); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	h := NewFileSystemHandler()
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		query, path, kind, permissions string
+		size                           int64
+	}{
+		{"", "src/main.go", "file", "640", int64(len("package main"))},
+		{"?type=directory", "src", "directory", "750", 0},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/filesystem-find/"+strings.ReplaceAll(dir, "/", "%2F")+tc.query, nil)
+		c.Params = gin.Params{{Key: "path", Value: dir}}
+		h.HandleFind(c)
+
+		var response FindResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v (%s)", err, w.Body.String())
+		}
+		var found *FindMatch
+		for i := range response.Matches {
+			if response.Matches[i].Path == tc.path {
+				found = &response.Matches[i]
+			}
+		}
+		if found == nil {
+			t.Errorf("%s not found in %+v", tc.path, response.Matches)
+			continue
+		}
+		if found.Type != tc.kind || found.Size != tc.size || found.Permissions != tc.permissions || found.ModTime.IsZero() {
+			t.Errorf("Unexpected match for %s: %+v", tc.path, found)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	h := NewFileSystemHandler()
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		body string
+		want PathMatchResponse
+	}{
+		{
+			`{"path": "/app/node_modules/x/index.ts", "ignore": [".git", "node_modules"], "include": ["*.ts"]}`,
+			PathMatchResponse{Path: "/app/node_modules/x/index.ts", Ignored: true, IgnoredBy: "node_modules", Included: true, IncludedBy: "*.ts", GlobMatched: true},
+		},
+		{
+			`{"path": "/app/src/index.ts", "ignore": ["node_modules"], "include": ["*.js", "*.ts"], "glob": "/app/**/*.ts"}`,
+			PathMatchResponse{Path: "/app/src/index.ts", Matches: true, Included: true, IncludedBy: "*.ts", GlobMatched: true},
+		},
+		{
+			`{"path": "/app/src/index.js", "glob": "/app/**/*.ts"}`,
+			PathMatchResponse{Path: "/app/src/index.js", Included: true},
+		},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/filesystem/match", strings.NewReader(tc.body))
+		h.HandleMatchPath(c)
+
+		var response PathMatchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v (%s)", err, w.Body.String())
+		}
+		if response != tc.want {
+			t.Errorf("For %s, expected %+v, got %+v", tc.body, tc.want, response)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/filesystem/match", strings.NewReader(`{"path": "/app/a.ts", "glob": "/app/*.ts"}`))
+	h.HandleMatchPath(c)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-recursive glob, got %d", w.Code)
+	}
+}
@@ -184,7 +184,7 @@ func (h *NetworkHandler) HandleStopMonitoringPorts(c *gin.Context) {
 
 // HandleUpdateTunnelConfig handles PUT requests to /network/tunnel/config
 // @Summary Update tunnel configuration
-// @Description Apply a new tunnel configuration on the fly. The existing tunnel is torn down and a new one is established. This endpoint is write-only; there is no corresponding GET to read the config back.
+// @Description Apply a new tunnel configuration on the fly. When only the peer (public key, endpoint, allowed IPs, keepalive) changes, it is updated in place without dropping the interface; otherwise the existing tunnel is torn down and a new one is established. This endpoint is write-only; there is no corresponding GET to read the config back.
 // @Tags network
 // @Accept json
 // @Produce json
@@ -2,6 +2,7 @@ package network
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
@@ -251,6 +252,30 @@ func isPortOpenByConnect(port int) bool {
 	return true
 }
 
+// WaitForPort retries a TCP connection to the given local port until it succeeds
+// or the context is done. It returns ctx.Err() if the port never accepted a connection.
+func WaitForPort(ctx context.Context, port int, interval time.Duration) error {
+	address := net.JoinHostPort("localhost", strconv.Itoa(port))
+	dialer := net.Dialer{Timeout: 500 * time.Millisecond}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 // getPortsUsingSS uses the 'ss' command to get port information for a specific PID
 func getPortsUsingSS(pid int) ([]*PortInfo, error) {
 	// Run ss command: ss -tunap | grep <pid>
@@ -2,6 +2,8 @@ package network
 
 import (
 	"bufio"
+	"context"
+	"net"
 	"os/exec"
 	"sync"
 	"testing"
@@ -139,6 +141,30 @@ func ExampleNetwork_GetPortsForPID() {
 	}
 }
 
+// TestWaitForPort verifies that WaitForPort returns once a listener accepts
+// connections and times out when nothing listens on the port.
+func TestWaitForPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := WaitForPort(ctx, port, 50*time.Millisecond); err != nil {
+		t.Errorf("Expected port %d to be ready, got %v", port, err)
+	}
+
+	// Once closed, the port should never become ready
+	listener.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := WaitForPort(ctx, port, 50*time.Millisecond); err == nil {
+		t.Errorf("Expected timeout waiting for closed port %d", port)
+	}
+}
+
 // ExampleNetwork_RegisterPortOpenCallback shows how to register a callback for when a process opens a port
 func ExampleNetwork_RegisterPortOpenCallback() {
 	network := GetNetwork()
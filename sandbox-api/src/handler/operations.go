@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/blaxel-ai/sandbox-api/src/lib/audit"
+	"github.com/blaxel-ai/sandbox-api/src/lib/operations"
+)
+
+// OperationIDHeader carries the ID of the long operation run by a request, to cancel it
+// through POST /operations/{id}/cancel
+const OperationIDHeader = "X-Operation-Id"
+
+// OperationsHandler lists and cancels the long operations in flight
+type OperationsHandler struct {
+	*BaseHandler
+}
+
+// NewOperationsHandler creates a new operations handler
+func NewOperationsHandler() *OperationsHandler {
+	return &OperationsHandler{
+		BaseHandler: NewBaseHandler(),
+	}
+}
+
+// OperationListResponse is the response body for GET /operations
+type OperationListResponse struct {
+	Operations []operations.Operation `json:"operations" binding:"required"`
+} // @name OperationListResponse
+
+// HandleListOperations handles GET requests to /operations
+// @Summary List long operations in flight
+// @Description List the long-running operations in flight across subsystems (find, searches, archive extraction), oldest first, with their progress.
+// @Tags system
+// @Produce json
+// @Success 200 {object} OperationListResponse "Operations in flight"
+// @Router /operations [get]
+func (h *OperationsHandler) HandleListOperations(c *gin.Context) {
+	h.SendJSON(c, http.StatusOK, OperationListResponse{Operations: operations.List()})
+}
+
+// HandleCancelOperation handles POST requests to /operations/{id}/cancel
+// @Summary Cancel a long operation
+// @Description Cancel a long-running operation in flight. The request running it fails with 409 once the operation has stopped.
+// @Tags system
+// @Produce json
+// @Param id path string true "Operation ID, as listed or returned in the X-Operation-Id header"
+// @Success 200 {object} SuccessResponse "Operation cancelled"
+// @Failure 404 {object} ErrorResponse "Operation not found"
+// @Router /operations/{id}/cancel [post]
+func (h *OperationsHandler) HandleCancelOperation(c *gin.Context) {
+	id, err := h.GetPathParam(c, "id")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := operations.Cancel(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, operations.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.SendError(c, status, err)
+		return
+	}
+
+	audit.LogEvent(c, "operation_cancel", logrus.Fields{"operation": id})
+	h.SendSuccess(c, "Operation cancelled")
+}
+
+// startOperation registers the long operation run by a request. Its context ends with the
+// request, and its ID is sent in the OperationIDHeader so the client can cancel it.
+func startOperation(c *gin.Context, kind string, target string, unit string) (context.Context, *operations.Handle) {
+	ctx, op := operations.Start(c.Request.Context(), kind, target, unit)
+	c.Header(OperationIDHeader, op.ID())
+	return ctx, op
+}
+
+// errOperationCancelled returns the error sent for an operation stopped by its context
+func errOperationCancelled(op *operations.Handle) error {
+	return fmt.Errorf("operation %s cancelled", op.ID())
+}
+
+// operationReader stops reading once the operation is cancelled, counting the bytes read
+type operationReader struct {
+	ctx context.Context
+	r   io.Reader
+	op  *operations.Handle
+}
+
+func (r *operationReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.op.Add(int64(n))
+	return n, err
+}
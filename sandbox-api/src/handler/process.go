@@ -1,21 +1,32 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/sirupsen/logrus"
 
 	"github.com/blaxel-ai/sandbox-api/src/handler/constants"
+	"github.com/blaxel-ai/sandbox-api/src/handler/filesystem"
+	"github.com/blaxel-ai/sandbox-api/src/handler/network"
 	"github.com/blaxel-ai/sandbox-api/src/handler/process"
 	"github.com/blaxel-ai/sandbox-api/src/lib"
 	"github.com/blaxel-ai/sandbox-api/src/lib/audit"
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
 )
 
 var (
@@ -35,6 +46,7 @@ func GetProcessHandler() *ProcessHandler {
 type ProcessHandler struct {
 	*BaseHandler
 	processManager *process.ProcessManager
+	fs             *filesystem.Filesystem // Confines the files processes read and write, like the filesystem handlers
 }
 
 // NewProcessHandler creates a new process handler
@@ -42,40 +54,89 @@ func NewProcessHandler() *ProcessHandler {
 	return &ProcessHandler{
 		BaseHandler:    NewBaseHandler(),
 		processManager: process.GetProcessManager(),
+		fs:             newFilesystem(),
 	}
 }
 
+// checkConfinedPath returns filesystem.ErrPathOutsideRoot when SANDBOX_FS_CONFINE is set and
+// path, once symlinks are resolved, is outside of the filesystem working directory. Relative
+// paths are resolved from dir, or from the API's working directory when dir is empty.
+func (h *ProcessHandler) checkConfinedPath(path string, dir string) error {
+	if !h.fs.Confine {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		if dir == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("could not resolve the working directory: %w", err)
+			}
+			dir = cwd
+		}
+		path = filepath.Join(dir, path)
+	}
+	_, err := h.fs.GetAbsolutePath(path)
+	return err
+}
+
 // ProcessRequest is the request body for executing a command
 type ProcessRequest struct {
-	Command           string            `json:"command" example:"ls -la" binding:"required"`
-	Name              string            `json:"name" example:"my-process"`
-	WorkingDir        string            `json:"workingDir" example:"/home/user"`
-	Env               map[string]string `json:"env" example:"{\"PORT\": \"3000\"}"`
-	WaitForCompletion bool              `json:"waitForCompletion" example:"false"`
-	Timeout           *int              `json:"timeout,omitempty" example:"30"` // Timeout in seconds. When keepAlive is true, defaults to 600s (10 minutes). Set to 0 for infinite (no auto-kill).
-	WaitForPorts      []int             `json:"waitForPorts" example:"3000,8080"`
-	RestartOnFailure  bool              `json:"restartOnFailure" example:"true"`
-	MaxRestarts       int               `json:"maxRestarts" example:"3"`   // Maximum number of restarts on failure. Set to a negative value (e.g. -1) for unlimited restarts.
-	KeepAlive         bool              `json:"keepAlive" example:"false"` // Disable scale-to-zero while process runs. Default timeout is 600s (10 minutes). Set timeout to 0 for infinite.
+	Command           string                `json:"command" example:"ls -la" binding:"required"`
+	Args              map[string]string     `json:"args,omitempty" example:"{\"file\": \"main.py\"}"` // Render command as a template: {{.file}} placeholders are replaced with these values, single-quoted for the shell (don't quote placeholders). Unknown variables are an error.
+	Name              string                `json:"name" example:"my-process"`
+	WorkingDir        string                `json:"workingDir" example:"/home/user"`
+	Env               map[string]string     `json:"env" example:"{\"PORT\": \"3000\"}"`
+	WaitForCompletion bool                  `json:"waitForCompletion" example:"false"`
+	Timeout           *int                  `json:"timeout,omitempty" example:"30"` // Timeout in seconds. When keepAlive is true, defaults to 600s (10 minutes). Set to 0 for infinite (no auto-kill).
+	WaitForPorts      []int                 `json:"waitForPorts" example:"3000,8080"`
+	RestartOnFailure  bool                  `json:"restartOnFailure" example:"true"`
+	MaxRestarts       int                   `json:"maxRestarts" example:"3"`                                    // Maximum number of restarts on failure. Set to a negative value (e.g. -1) for unlimited restarts.
+	KeepAlive         bool                  `json:"keepAlive" example:"false"`                                  // Disable scale-to-zero while process runs. Default timeout is 600s (10 minutes). Set timeout to 0 for infinite.
+	Labels            map[string]string     `json:"labels,omitempty" example:"{\"app\": \"web\"}"`              // Labels used to select and manage processes together
+	LogTag            string                `json:"logTag,omitempty" example:"api"`                             // Short tag prefixing the process's lines in multi-process log streams (e.g. [api]), defaults to the name
+	TailOutputBytes   *int                  `json:"tailOutputBytes,omitempty" example:"4096"`                   // With waitForCompletion, number of trailing bytes of output returned in tailOutput when the process fails (default 4096, 0 to disable)
+	CgroupLimits      *process.CgroupLimits `json:"cgroupLimits,omitempty"`                                     // Memory and CPU limits enforced with a dedicated cgroup v2 (Linux only). Without cgroup v2 the process runs unlimited and cgroupWarning explains why.
+	ID                string                `json:"id,omitempty" example:"build-42"`                            // Client-provided unique id used as the process pid instead of the generated one, so that a retried request can't start the process twice (409 if already used). Cannot be purely numeric.
+	EphemeralCwd      bool                  `json:"ephemeralCwd,omitempty" example:"false"`                     // Run in a new empty temp directory (returned in workingDir), removed once the process completes or is stopped. Cannot be used with workingDir.
+	ExpectExitCode    *int                  `json:"expectExitCode,omitempty" example:"0"`                       // With waitForCompletion, respond 417 (still with the process and its output) when the process exits with another code
+	SaveStateOnExit   bool                  `json:"saveStateOnExit,omitempty" example:"false"`                  // Save the process state, with its output, as soon as it ends so that it survives an API restart. Always done when a process exhausts its restarts on failure.
+	RetainLogs        *bool                 `json:"retainLogs,omitempty" example:"false"`                       // Set to false to remove the log files as soon as the process is done for good, its output then only being kept in memory and in the saved state. Log files are kept by default, and always for processes adopted after an API restart.
+	NewSession        bool                  `json:"newSession,omitempty" example:"false"`                       // Start the process in its own session (setsid), fully detached from the API, e.g. for long-lived background services. It still leads its process group, so stop and kill signal its children too.
+	MutexGroup        string                `json:"mutexGroup,omitempty" example:"npm-install-app"`             // Only run one process of this group at a time, e.g. to avoid two installs in the same directory
+	MutexPolicy       string                `json:"mutexPolicy,omitempty" example:"queue" enums:"queue,reject"` // When a process of mutexGroup is running: queue the process (default, listed with the queued status until it starts) or reject it with a 409. Streaming requests only support reject.
+	StdinFrom         *process.StdinSource  `json:"stdinFrom,omitempty"`                                        // Feed stdin from a file or from the stdout of another process (its output from the start, then followed until it exits). Stdin is empty otherwise.
 } // @name ProcessRequest
 
 // ProcessResponse is the response body for a process
 type ProcessResponse struct {
-	PID              string  `json:"pid" example:"1234" binding:"required"`
-	Name             string  `json:"name" example:"my-process" binding:"required"`
-	Command          string  `json:"command" example:"ls -la" binding:"required"`
-	Status           string  `json:"status" example:"running" enums:"failed,killed,stopped,running,completed" binding:"required"`
-	StartedAt        string  `json:"startedAt" example:"Wed, 01 Jan 2023 12:00:00 GMT" binding:"required"`
-	CompletedAt      *string `json:"completedAt" example:"Wed, 01 Jan 2023 12:01:00 GMT" binding:"required"`
-	ExitCode         int     `json:"exitCode" example:"0" binding:"required"`
-	WorkingDir       string  `json:"workingDir" example:"/home/user" binding:"required"`
-	Logs             *string `json:"logs" example:"logs output" binding:"required"`
-	Stdout           *string `json:"stdout" example:"stdout output" binding:"required"`
-	Stderr           *string `json:"stderr" example:"stderr output" binding:"required"`
-	RestartOnFailure bool    `json:"restartOnFailure" example:"true"`
-	MaxRestarts      int     `json:"maxRestarts" example:"3"`
-	RestartCount     int     `json:"restartCount" example:"2"`
-	KeepAlive        bool    `json:"keepAlive" example:"false"` // Whether scale-to-zero is disabled for this process
+	PID              string                `json:"pid" example:"1234" binding:"required"`
+	Name             string                `json:"name" example:"my-process" binding:"required"`
+	Command          string                `json:"command" example:"ls -la" binding:"required"`
+	Status           string                `json:"status" example:"running" enums:"failed,killed,stopped,running,completed,queued" binding:"required"`
+	StartedAt        string                `json:"startedAt" example:"Wed, 01 Jan 2023 12:00:00 GMT" binding:"required"`
+	CompletedAt      *string               `json:"completedAt" example:"Wed, 01 Jan 2023 12:01:00 GMT" binding:"required"`
+	ExitCode         int                   `json:"exitCode" example:"0" binding:"required"`
+	WorkingDir       string                `json:"workingDir" example:"/home/user" binding:"required"`
+	Logs             *string               `json:"logs" example:"logs output" binding:"required"`
+	Stdout           *string               `json:"stdout" example:"stdout output" binding:"required"`
+	Stderr           *string               `json:"stderr" example:"stderr output" binding:"required"`
+	RestartOnFailure bool                  `json:"restartOnFailure" example:"true"`
+	MaxRestarts      int                   `json:"maxRestarts" example:"3"`
+	RestartCount     int                   `json:"restartCount" example:"2"`
+	KeepAlive        bool                  `json:"keepAlive" example:"false"` // Whether scale-to-zero is disabled for this process
+	Paused           bool                  `json:"paused" example:"false"`    // Whether the process is frozen with SIGSTOP
+	Labels           map[string]string     `json:"labels,omitempty" example:"{\"app\": \"web\"}"`
+	LogTag           string                `json:"logTag,omitempty" example:"api"`
+	CgroupLimits     *process.CgroupLimits `json:"cgroupLimits,omitempty"`
+	CgroupWarning    string                `json:"cgroupWarning,omitempty" example:"cgroup limits were not applied: cgroups v2 is not available at /sys/fs/cgroup"` // Set when cgroupLimits could not be enforced
+	EphemeralCwd     bool                  `json:"ephemeralCwd,omitempty" example:"false"`                                                                          // Whether workingDir is a temp dir removed once the process is done
+	RetainLogs       *bool                 `json:"retainLogs,omitempty" example:"false"`                                                                            // Whether the log files are kept once the process is done, kept when not set
+	NewSession       bool                  `json:"newSession,omitempty" example:"false"`                                                                            // Whether the process was started in its own session
+	MutexGroup       string                `json:"mutexGroup,omitempty" example:"npm-install-app"`                                                                  // Only one process of the group runs at a time
+	TailOutput       *string               `json:"tailOutput,omitempty" example:"Error: module not found"`                                                          // Last bytes of combined output, set when a process run with waitForCompletion fails
+	TailLines        []string              `json:"tailLines,omitempty" example:"listening on :3000"`                                                                // Last lines of combined output, set when listing processes with includeTail
+	// Wall-clock duration, peak memory and CPU time (durationMs, maxRssBytes, cpuTimeMs), set once the process has completed
+	*process.ResourceUsage
 } // @name ProcessResponse
 
 type ProcessResponseWithLogs struct {
@@ -83,14 +144,96 @@ type ProcessResponseWithLogs struct {
 	Logs string `json:"logs" example:"logs output"`
 }
 
+// MaxIncludeTailLines is the maximum number of output lines attached to each process of a list
+const MaxIncludeTailLines = 1000
+
+// DefaultTailOutputBytes is the default size of tailOutput for failed processes
+const DefaultTailOutputBytes = 4096
+
+// MaxBatchProcesses is the maximum number of processes accepted by a single batch request
+const MaxBatchProcesses = 50
+
+// ProcessBatchRequest is the request body for starting several processes at once
+type ProcessBatchRequest struct {
+	Processes []ProcessRequest  `json:"processes" binding:"required"`
+	Parallel  bool              `json:"parallel" example:"false"`                        // Start all processes concurrently instead of in order
+	Labels    map[string]string `json:"labels,omitempty" example:"{\"stack\": \"dev\"}"` // Labels applied to every process of the batch (merged with per-process labels)
+} // @name ProcessBatchRequest
+
+// ProcessBatchResult is the outcome of a single entry of a batch request
+type ProcessBatchResult struct {
+	Index int    `json:"index" example:"0" binding:"required"`
+	PID   string `json:"pid,omitempty" example:"1234"`
+	Name  string `json:"name,omitempty" example:"my-process"`
+	Error string `json:"error,omitempty" example:"could not execute command"`
+} // @name ProcessBatchResult
+
+// ProcessBatchResponse is the response body for a batch of processes
+type ProcessBatchResponse struct {
+	Results []ProcessBatchResult `json:"results" binding:"required"`
+} // @name ProcessBatchResponse
+
+// ProcessValidateRequest is the request body for a shell syntax check
+type ProcessValidateRequest struct {
+	Command string `json:"command" example:"for f in *.log; do gzip \"$f\"; done" binding:"required"`
+	Shell   string `json:"shell,omitempty" example:"bash"` // Shell used to parse the command (sh, bash, dash, ash, ksh, mksh, zsh), defaults to $SHELL or sh
+} // @name ProcessValidateRequest
+
+// ProcessValidateResponse is the result of a shell syntax check
+type ProcessValidateResponse struct {
+	Valid bool   `json:"valid" example:"false" binding:"required"`
+	Shell string `json:"shell" example:"bash" binding:"required"`
+	Error string `json:"error,omitempty" example:"bash: line 1: syntax error: unexpected end of file"`
+} // @name ProcessValidateResponse
+
+// ProcessEnvPreviewRequest is the request body for previewing a process environment
+type ProcessEnvPreviewRequest struct {
+	Env map[string]string `json:"env" example:"{\"PORT\": \"3000\"}"`
+} // @name ProcessEnvPreviewRequest
+
+// ProcessEnvPreviewResponse is the environment a process would receive
+type ProcessEnvPreviewResponse struct {
+	Env        map[string]string `json:"env" binding:"required"`
+	Overridden []string          `json:"overridden" binding:"required" example:"PATH"` // System env vars replaced by the request env
+} // @name ProcessEnvPreviewResponse
+
+// ProcessLogMirrorRequest is the request body for mirroring a process's logs to a file
+type ProcessLogMirrorRequest struct {
+	Path string `json:"path" example:"/tmp/web.log" binding:"required"`
+} // @name ProcessLogMirrorRequest
+
+// PortReadyResponse is the response body for a port readiness probe
+type PortReadyResponse struct {
+	Port          int    `json:"port" example:"3000" binding:"required"`
+	Ready         bool   `json:"ready" example:"true" binding:"required"`
+	ElapsedMs     int64  `json:"elapsedMs" example:"1250" binding:"required"`
+	ProcessStatus string `json:"processStatus" example:"running" binding:"required"`
+} // @name PortReadyResponse
+
+// maxPortReadyTimeout caps how long a port readiness probe may block
+const maxPortReadyTimeout = 5 * time.Minute
+
+// ProcessExitCodeResponse is the outcome of a process, without its details
+type ProcessExitCodeResponse struct {
+	Status     string  `json:"status" example:"completed" enums:"failed,killed,stopped,running,completed" binding:"required"`
+	ExitCode   *int    `json:"exitCode" example:"0" binding:"required"`        // null while the process is running
+	ExitReason *string `json:"exitReason" example:"exited" binding:"required"` // exited, failed, signaled, stopped or killed; null while the process is running
+} // @name ProcessExitCodeResponse
+
+// Default and maximum time GET /process/{identifier}/exit-code blocks with wait=true
+const (
+	defaultExitCodeWait = 30 * time.Second
+	maxExitCodeWait     = 5 * time.Minute
+)
+
 // ProcessKillRequest is the request body for killing a process
 type ProcessKillRequest struct {
 	Signal string `json:"signal" example:"SIGTERM"`
 } // @name ProcessKillRequest
 
 // ExecuteProcess executes a process
-func (h *ProcessHandler) ExecuteProcess(command string, workingDir string, name string, env map[string]string, waitForCompletion bool, timeout int, waitForPorts []int, restartOnFailure bool, maxRestarts int, keepAlive bool) (ProcessResponse, error) {
-	processInfo, err := h.processManager.ExecuteProcess(command, workingDir, name, env, waitForCompletion, timeout, waitForPorts, restartOnFailure, maxRestarts, keepAlive)
+func (h *ProcessHandler) ExecuteProcess(command string, workingDir string, name string, env map[string]string, waitForCompletion bool, timeout int, waitForPorts []int, restartOnFailure bool, maxRestarts int, keepAlive bool, opts ...process.ProcessOption) (ProcessResponse, error) {
+	processInfo, err := h.processManager.ExecuteProcess(command, workingDir, name, env, waitForCompletion, timeout, waitForPorts, restartOnFailure, maxRestarts, keepAlive, opts...)
 
 	// If processInfo is nil (process failed to start), return empty response with error
 	if processInfo == nil {
@@ -120,12 +263,31 @@ func (h *ProcessHandler) ExecuteProcess(command string, workingDir string, name
 		MaxRestarts:      processInfo.MaxRestarts,
 		RestartCount:     processInfo.RestartCount,
 		KeepAlive:        processInfo.KeepAlive,
+		Paused:           processInfo.Paused,
+		Labels:           processInfo.Labels,
+		LogTag:           processInfo.LogTag,
+		CgroupLimits:     processInfo.CgroupLimits,
+		CgroupWarning:    processInfo.CgroupWarning,
+		EphemeralCwd:     processInfo.EphemeralCwd,
+		RetainLogs:       processInfo.RetainLogs,
+		NewSession:       processInfo.NewSession,
+		MutexGroup:       processInfo.MutexGroup,
+		ResourceUsage:    processInfo.Usage,
 	}, err
 }
 
 // ListProcesses lists all running processes
 func (h *ProcessHandler) ListProcesses() []ProcessResponse {
-	processes := h.processManager.ListProcesses()
+	return h.toProcessResponses(h.processManager.ListProcesses())
+}
+
+// ListProcessesByLabels lists the processes matching a label selector
+func (h *ProcessHandler) ListProcessesByLabels(selector map[string]string) []ProcessResponse {
+	return h.toProcessResponses(h.processManager.ListProcessesByLabels(selector))
+}
+
+// toProcessResponses converts process infos to responses, reading logs from files when available
+func (h *ProcessHandler) toProcessResponses(processes []*process.ProcessInfo) []ProcessResponse {
 	result := make([]ProcessResponse, 0, len(processes))
 	for _, p := range processes {
 		var completedAtPtr *string
@@ -162,6 +324,16 @@ func (h *ProcessHandler) ListProcesses() []ProcessResponse {
 			MaxRestarts:      p.MaxRestarts,
 			RestartCount:     p.RestartCount,
 			KeepAlive:        p.KeepAlive,
+			Paused:           p.Paused,
+			Labels:           p.Labels,
+			LogTag:           p.LogTag,
+			CgroupLimits:     p.CgroupLimits,
+			CgroupWarning:    p.CgroupWarning,
+			EphemeralCwd:     p.EphemeralCwd,
+			RetainLogs:       p.RetainLogs,
+			NewSession:       p.NewSession,
+			MutexGroup:       p.MutexGroup,
+			ResourceUsage:    p.Usage,
 		})
 	}
 	return result
@@ -207,6 +379,16 @@ func (h *ProcessHandler) GetProcess(identifier string) (ProcessResponse, error)
 		MaxRestarts:      processInfo.MaxRestarts,
 		RestartCount:     processInfo.RestartCount,
 		KeepAlive:        processInfo.KeepAlive,
+		Paused:           processInfo.Paused,
+		Labels:           processInfo.Labels,
+		LogTag:           processInfo.LogTag,
+		CgroupLimits:     processInfo.CgroupLimits,
+		CgroupWarning:    processInfo.CgroupWarning,
+		EphemeralCwd:     processInfo.EphemeralCwd,
+		RetainLogs:       processInfo.RetainLogs,
+		NewSession:       processInfo.NewSession,
+		MutexGroup:       processInfo.MutexGroup,
+		ResourceUsage:    processInfo.Usage,
 	}, nil
 }
 
@@ -225,11 +407,53 @@ func (h *ProcessHandler) KillProcess(identifier string) error {
 	return h.processManager.KillProcess(identifier)
 }
 
+// KillProcessExcept kills a process and its process group except excludePids and their descendants
+func (h *ProcessHandler) KillProcessExcept(identifier string, excludePids []int) error {
+	return h.processManager.KillProcessExcept(identifier, excludePids)
+}
+
+// PauseProcess freezes a running process
+func (h *ProcessHandler) PauseProcess(identifier string) error {
+	return h.processManager.PauseProcess(identifier)
+}
+
+// ResumeProcess continues a paused process
+func (h *ProcessHandler) ResumeProcess(identifier string) error {
+	return h.processManager.ResumeProcess(identifier)
+}
+
+// RefreshProcess re-evaluates right away whether a process is still running
+func (h *ProcessHandler) RefreshProcess(identifier string) error {
+	_, err := h.processManager.RefreshProcess(identifier)
+	return err
+}
+
+// UpdateRestartPolicy changes the restart policy of a running process
+func (h *ProcessHandler) UpdateRestartPolicy(identifier string, restartOnFailure *bool, maxRestarts *int) error {
+	_, err := h.processManager.UpdateRestartPolicy(identifier, restartOnFailure, maxRestarts)
+	return err
+}
+
+// MirrorLogsToFile appends the output of a running process to a file
+func (h *ProcessHandler) MirrorLogsToFile(identifier string, path string) error {
+	return h.processManager.MirrorLogsToFile(identifier, path)
+}
+
+// ReattachLogs restarts the log capture of a running process
+func (h *ProcessHandler) ReattachLogs(identifier string) error {
+	return h.processManager.ReattachLogs(identifier)
+}
+
 // StreamProcessOutput streams the output of a process
 func (h *ProcessHandler) StreamProcessOutput(identifier string, writer io.Writer) error {
 	return h.processManager.StreamProcessOutput(identifier, writer)
 }
 
+// StreamProcessOutputWithKeepalive streams the output of a process with keepalives every interval
+func (h *ProcessHandler) StreamProcessOutputWithKeepalive(identifier string, writer io.Writer, interval time.Duration) error {
+	return h.processManager.StreamProcessOutputWithKeepalive(identifier, writer, interval)
+}
+
 // RemoveLogWriter removes a log writer from a process
 func (h *ProcessHandler) RemoveLogWriter(identifier string, writer io.Writer) {
 	_ = h.processManager.RemoveLogWriter(identifier, writer)
@@ -237,29 +461,81 @@ func (h *ProcessHandler) RemoveLogWriter(identifier string, writer io.Writer) {
 
 // HandleListProcesses handles GET requests to /process/
 // @Summary List all processes
-// @Description Get a list of all running and completed processes
+// @Description Get a list of all running and completed processes, optionally filtered by label and by command. Filters combine: a process must match all of them.
 // @Tags process
 // @Accept json
 // @Produce json
+// @Param label query string false "Label selector (e.g. app=web,tier=api)"
+// @Param command query string false "Only list processes whose command contains this string"
+// @Param regex query boolean false "Match command as a regular expression instead of a substring"
+// @Param includeTail query integer false "Attach the last N lines of each process's combined output in tailLines (max 1000)"
 // @Success 200 {array} ProcessResponse "Process list"
+// @Failure 400 {object} ErrorResponse "Invalid label selector, command pattern or includeTail"
 // @Router /process [get]
 func (h *ProcessHandler) HandleListProcesses(c *gin.Context) {
-	processes := h.ListProcesses()
-	h.SendJSON(c, http.StatusOK, processes)
+	includeTail := 0
+	if value := c.Query("includeTail"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("includeTail must be a positive integer"))
+			return
+		}
+		if n > MaxIncludeTailLines {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("includeTail cannot exceed %d lines", MaxIncludeTailLines))
+			return
+		}
+		includeTail = n
+	}
+
+	var processes []*process.ProcessInfo
+	if label := c.Query("label"); label != "" {
+		selector, err := process.ParseLabelSelector(label)
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+		processes = h.processManager.ListProcessesByLabels(selector)
+	} else {
+		processes = h.processManager.ListProcesses()
+	}
+
+	if command := c.Query("command"); command != "" {
+		filter, err := process.ParseCommandFilter(command, c.Query("regex") == "true")
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+		processes = process.FilterProcessesByCommand(processes, filter)
+	}
+
+	responses := h.toProcessResponses(processes)
+	if includeTail > 0 {
+		for i := range responses {
+			if lines, err := h.processManager.GetOutputTailLines(responses[i].PID, includeTail); err == nil {
+				responses[i].TailLines = lines
+			}
+		}
+	}
+	h.SendJSON(c, http.StatusOK, responses)
 }
 
 // HandleExecuteCommand handles POST requests to /process/
 // @Summary Execute a command
-// @Description Execute a command and return process information. If Accept header is text/event-stream, streams logs in SSE format and returns the process response as a final event.
+// @Description Execute a command and return process information. If Accept header is text/event-stream, streams logs in SSE format and returns the process response as a final event. Once the process has completed, the response includes its wall-clock duration (durationMs), peak memory of the process and its children (maxRssBytes, sampled every 10ms) and CPU time (cpuTimeMs).
 // @Tags process
 // @Accept json
 // @Produce json
 // @Produce text/event-stream
 // @Param request body ProcessRequest true "Process execution request"
+// @Param keepaliveSec query integer false "Send a keepalive every this many seconds (with streaming, default 5, min 5, max 600)"
 // @Success 200 {object} ProcessResponse "Process information"
 // @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Working directory outside of SANDBOX_ALLOWED_CWD, or stdinFrom path outside of the working directory with SANDBOX_FS_CONFINE"
+// @Failure 409 {object} ErrorResponse "Process id already in use, or mutexGroup busy with mutexPolicy=reject"
+// @Failure 417 {object} ProcessResponse "Exit code differs from expectExitCode"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "With streaming, too many concurrent streaming connections, retry after Retry-After seconds"
 // @Router /process [post]
 func (h *ProcessHandler) HandleExecuteCommand(c *gin.Context) {
 	// Check if client wants SSE streaming
@@ -275,22 +551,20 @@ func (h *ProcessHandler) HandleExecuteCommand(c *gin.Context) {
 		return
 	}
 
-	if req.WorkingDir != "" {
-		formattedWorkingDir, err := lib.FormatPath(req.WorkingDir)
-		if err != nil {
-			h.SendError(c, http.StatusBadRequest, err)
-			return
-		}
-		req.WorkingDir = formattedWorkingDir
+	if status, err := h.validateProcessRequest(&req); err != nil {
+		h.SendError(c, status, err)
+		return
 	}
 
-	// If a name is provided, check if a process with that name already exists
-	if req.Name != "" {
-		alreadyExists, err := h.GetProcess(req.Name)
-		if err == nil && alreadyExists.Status == string(constants.ProcessStatusRunning) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("process with name '%s' already exists and is running", req.Name)})
-			return
-		}
+	if req.ExpectExitCode != nil && !req.WaitForCompletion {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("expectExitCode requires waitForCompletion"))
+		return
+	}
+
+	releaseName, err := h.processManager.ReserveName(req.Name)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
 	}
 
 	audit.LogEvent(c, "process_exec", logrus.Fields{
@@ -298,60 +572,252 @@ func (h *ProcessHandler) HandleExecuteCommand(c *gin.Context) {
 		"working-dir": req.WorkingDir,
 	})
 
-	// Timeout of 0 means infinite (no auto-kill)
-	// When keepAlive is true and timeout is not specified (nil) or negative, default to 600s
-	timeout := 0
-	if req.Timeout != nil {
-		timeout = *req.Timeout
+	// Execute the process
+	processInfo, err := h.ExecuteProcess(req.Command, req.WorkingDir, req.Name, req.Env, req.WaitForCompletion, req.timeout(), req.WaitForPorts, req.RestartOnFailure, req.MaxRestarts, req.KeepAlive, process.WithLabels(req.Labels), process.WithCgroupLimits(req.CgroupLimits), process.WithID(req.ID), process.WithEphemeralWorkingDir(req.EphemeralCwd), process.WithLogTag(req.LogTag), process.WithStdinFrom(req.StdinFrom), process.WithSaveStateOnExit(req.SaveStateOnExit), process.WithRetainLogs(req.RetainLogs), process.WithNewSession(req.NewSession), process.WithMutexGroup(req.MutexGroup, req.MutexPolicy))
+	releaseName()
+	if err != nil {
+		if errors.Is(err, process.ErrProcessIDExists) || errors.Is(err, process.ErrMutexGroupBusy) {
+			h.SendError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, process.ErrWorkingDirNotAllowed) {
+			h.SendError(c, http.StatusForbidden, err)
+			return
+		}
+		h.SendError(c, http.StatusUnprocessableEntity, err)
+		return
 	}
-	if req.KeepAlive && (req.Timeout == nil || timeout < 0) {
-		timeout = 600 // Default 10 minutes
+
+	unexpectedExit := req.ExpectExitCode != nil && (processInfo.Status == string(constants.ProcessStatusRunning) || processInfo.ExitCode != *req.ExpectExitCode)
+
+	// Surface the end of the output of failed runs so callers don't need a separate logs request
+	if req.WaitForCompletion && (processInfo.Status == string(constants.ProcessStatusFailed) || unexpectedExit) {
+		tailBytes := DefaultTailOutputBytes
+		if req.TailOutputBytes != nil {
+			tailBytes = *req.TailOutputBytes
+		}
+		if tailBytes > 0 {
+			if tail, err := h.processManager.GetOutputTail(processInfo.PID, tailBytes); err == nil {
+				processInfo.TailOutput = &tail
+			}
+		}
 	}
 
-	// Execute the process
-	processInfo, err := h.ExecuteProcess(req.Command, req.WorkingDir, req.Name, req.Env, req.WaitForCompletion, timeout, req.WaitForPorts, req.RestartOnFailure, req.MaxRestarts, req.KeepAlive)
+	if unexpectedExit {
+		h.SendJSON(c, http.StatusExpectationFailed, processInfo)
+		return
+	}
+	h.SendJSON(c, http.StatusOK, processInfo)
+}
+
+// HandleExecuteBatch handles POST requests to /process/batch
+// @Summary Execute several commands
+// @Description Start several processes in one request, in order or in parallel. Each entry reports its PID and name, or the error that prevented it from starting.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param request body ProcessBatchRequest true "Batch execution request"
+// @Success 200 {object} ProcessBatchResponse "Per-process results"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Router /process/batch [post]
+func (h *ProcessHandler) HandleExecuteBatch(c *gin.Context) {
+	var req ProcessBatchRequest
+	if err := h.BindJSON(c, &req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(req.Processes) == 0 {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("at least one process is required"))
+		return
+	}
+	if len(req.Processes) > MaxBatchProcesses {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("too many processes: %d (max %d)", len(req.Processes), MaxBatchProcesses))
+		return
+	}
+
+	audit.LogEvent(c, "process_exec_batch", logrus.Fields{
+		"count":    len(req.Processes),
+		"parallel": req.Parallel,
+	})
+
+	results := make([]ProcessBatchResult, len(req.Processes))
+	if req.Parallel {
+		var wg sync.WaitGroup
+		for i := range req.Processes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = h.executeBatchEntry(i, req.Processes[i], req.Labels)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range req.Processes {
+			results[i] = h.executeBatchEntry(i, req.Processes[i], req.Labels)
+		}
+	}
+
+	h.SendJSON(c, http.StatusOK, ProcessBatchResponse{Results: results})
+}
+
+// HandleValidateCommand handles POST requests to /process/validate
+// @Summary Check the syntax of a command
+// @Description Parse a command or script with the shell's no-exec mode (sh -n) and report whether it is syntactically valid. Nothing is executed.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param request body ProcessValidateRequest true "Command to check"
+// @Success 200 {object} ProcessValidateResponse "Syntax check result"
+// @Failure 400 {object} ErrorResponse "Invalid request or unsupported shell"
+// @Failure 422 {object} ErrorResponse "Syntax check could not run"
+// @Router /process/validate [post]
+func (h *ProcessHandler) HandleValidateCommand(c *gin.Context) {
+	var req ProcessValidateRequest
+	if err := h.BindJSON(c, &req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	audit.LogEvent(c, "process_validate", logrus.Fields{
+		"command": req.Command,
+		"shell":   req.Shell,
+	})
+
+	result, err := process.CheckSyntax(req.Command, req.Shell)
 	if err != nil {
+		if errors.Is(err, process.ErrUnsupportedShell) {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
 		h.SendError(c, http.StatusUnprocessableEntity, err)
 		return
 	}
 
-	h.SendJSON(c, http.StatusOK, processInfo)
+	response := ProcessValidateResponse{Valid: result.Valid, Shell: result.Shell}
+	if !result.Valid {
+		response.Error = result.Output
+	}
+	h.SendJSON(c, http.StatusOK, response)
 }
 
-// handleExecuteCommandStream handles streaming execution with JSON events
-// Events are streamed as newline-delimited JSON: {"type": "stdout|stderr|result|error|keepalive", "data": "..."}
-func (h *ProcessHandler) handleExecuteCommandStream(c *gin.Context) {
-	var req ProcessRequest
+// HandlePreviewEnv handles POST requests to /process/env/preview
+// @Summary Preview the environment of a process
+// @Description Returns the environment a process started with the same env would receive: the system environment and the identity env vars configured with SANDBOX_PROCESS_ENV, merged with the request env, which takes priority over all but forced identity env vars. Nothing is started. Values of env vars that look like secrets are replaced with [REDACTED] unless includeSecrets is true.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param request body ProcessEnvPreviewRequest true "Env vars to merge"
+// @Param includeSecrets query boolean false "Return secret-looking env values as is"
+// @Success 200 {object} ProcessEnvPreviewResponse "Merged environment"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Router /process/env/preview [post]
+func (h *ProcessHandler) HandlePreviewEnv(c *gin.Context) {
+	var req ProcessEnvPreviewRequest
 	if err := h.BindJSON(c, &req); err != nil {
 		h.SendError(c, http.StatusBadRequest, err)
 		return
 	}
 
+	env := process.PreviewEnv(req.Env)
+	overridden := []string{}
+	for key := range req.Env {
+		if _, ok := os.LookupEnv(key); ok {
+			overridden = append(overridden, key)
+		}
+	}
+	sort.Strings(overridden)
+
+	if c.Query("includeSecrets") != "true" {
+		env = process.RedactEnv(env)
+	}
+	h.SendJSON(c, http.StatusOK, ProcessEnvPreviewResponse{Env: env, Overridden: overridden})
+}
+
+// checkProcessID validates a client-provided process id and checks it isn't used yet.
+// It returns the HTTP status to respond with when the id can't be used.
+func (h *ProcessHandler) checkProcessID(id string) (int, error) {
+	if err := process.ValidateProcessID(id); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if h.processManager.ProcessIDExists(id) {
+		return http.StatusConflict, fmt.Errorf("%w: %s", process.ErrProcessIDExists, id)
+	}
+	return 0, nil
+}
+
+// renderCommand replaces the command with its rendering when args are provided
+func (req *ProcessRequest) renderCommand() error {
+	if req.Args == nil {
+		return nil
+	}
+	command, err := process.RenderCommand(req.Command, req.Args)
+	if err != nil {
+		return err
+	}
+	req.Command = command
+	return nil
+}
+
+// validateProcessRequest checks and normalizes the fields of a process request shared by
+// single, batch and streaming executions, and that its id is free. It returns the HTTP status
+// to respond with when the request is invalid. The name is reserved separately with ReserveName.
+func (h *ProcessHandler) validateProcessRequest(req *ProcessRequest) (int, error) {
+	if err := req.renderCommand(); err != nil {
+		return http.StatusBadRequest, err
+	}
+
 	if req.WorkingDir != "" {
 		formattedWorkingDir, err := lib.FormatPath(req.WorkingDir)
 		if err != nil {
-			h.SendError(c, http.StatusBadRequest, err)
-			return
+			return http.StatusBadRequest, err
 		}
 		req.WorkingDir = formattedWorkingDir
 	}
 
-	// If a name is provided, check if a process with that name already exists
-	if req.Name != "" {
-		alreadyExists, err := h.GetProcess(req.Name)
-		if err == nil && alreadyExists.Status == string(constants.ProcessStatusRunning) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("process with name '%s' already exists and is running", req.Name)})
-			return
+	if req.EphemeralCwd && req.WorkingDir != "" {
+		return http.StatusBadRequest, process.ErrEphemeralWorkingDir
+	}
+
+	if req.LogTag != "" {
+		if err := process.ValidateLogTag(req.LogTag); err != nil {
+			return http.StatusBadRequest, err
 		}
 	}
 
-	audit.LogEvent(c, "process_exec_stream", logrus.Fields{
-		"command":     req.Command,
-		"working-dir": req.WorkingDir,
-	})
+	if req.CgroupLimits != nil {
+		if err := req.CgroupLimits.Validate(); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	if req.StdinFrom != nil {
+		if err := req.StdinFrom.Validate(); err != nil {
+			return http.StatusBadRequest, err
+		}
+		if req.StdinFrom.Path != "" {
+			if err := h.checkConfinedPath(req.StdinFrom.Path, req.WorkingDir); err != nil {
+				return pathErrorStatus(err, http.StatusBadRequest), fmt.Errorf("stdinFrom: %w", err)
+			}
+		}
+	}
 
-	// Timeout of 0 means infinite (no auto-kill)
-	// When keepAlive is true and timeout is not specified (nil) or negative, default to 600s
+	if err := process.ValidateMutexPolicy(req.MutexPolicy); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Checked before the name, so that a retried request with the same id and name gets a 409
+	if req.ID != "" {
+		if status, err := h.checkProcessID(req.ID); err != nil {
+			return status, err
+		}
+	}
+	return 0, nil
+}
+
+// timeout returns the timeout of the process in seconds, 0 meaning infinite (no auto-kill).
+// When keepAlive is true and timeout is not specified (nil) or negative, it defaults to 600s.
+func (req *ProcessRequest) timeout() int {
 	timeout := 0
 	if req.Timeout != nil {
 		timeout = *req.Timeout
@@ -359,6 +825,101 @@ func (h *ProcessHandler) handleExecuteCommandStream(c *gin.Context) {
 	if req.KeepAlive && (req.Timeout == nil || timeout < 0) {
 		timeout = 600 // Default 10 minutes
 	}
+	return timeout
+}
+
+// executeBatchEntry starts a single process of a batch request
+func (h *ProcessHandler) executeBatchEntry(index int, req ProcessRequest, batchLabels map[string]string) ProcessBatchResult {
+	result := ProcessBatchResult{Index: index, Name: req.Name}
+
+	if req.Command == "" {
+		result.Error = "command is required"
+		return result
+	}
+
+	if _, err := h.validateProcessRequest(&req); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Reserved under the manager lock, so parallel entries can't start two processes with the same name
+	releaseName, err := h.processManager.ReserveName(req.Name)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Batch labels are shared by every entry; per-process labels take priority
+	labels := make(map[string]string, len(batchLabels)+len(req.Labels))
+	for k, v := range batchLabels {
+		labels[k] = v
+	}
+	for k, v := range req.Labels {
+		labels[k] = v
+	}
+
+	processInfo, err := h.ExecuteProcess(req.Command, req.WorkingDir, req.Name, req.Env, req.WaitForCompletion, req.timeout(), req.WaitForPorts, req.RestartOnFailure, req.MaxRestarts, req.KeepAlive, process.WithLabels(labels), process.WithCgroupLimits(req.CgroupLimits), process.WithID(req.ID), process.WithEphemeralWorkingDir(req.EphemeralCwd), process.WithLogTag(req.LogTag), process.WithStdinFrom(req.StdinFrom), process.WithSaveStateOnExit(req.SaveStateOnExit), process.WithRetainLogs(req.RetainLogs), process.WithNewSession(req.NewSession), process.WithMutexGroup(req.MutexGroup, req.MutexPolicy))
+	releaseName()
+	result.PID = processInfo.PID
+	if processInfo.Name != "" {
+		result.Name = processInfo.Name
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// handleExecuteCommandStream handles streaming execution with JSON events
+// Events are streamed as newline-delimited JSON: {"type": "stdout|stderr|result|error|keepalive|reconnect", "data": "..."}
+// A reconnect event, holding the process pid, ends the stream when the API shuts down or upgrades.
+func (h *ProcessHandler) handleExecuteCommandStream(c *gin.Context) {
+	release, ok := h.acquireStream(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var req ProcessRequest
+	if err := h.BindJSON(c, &req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if status, err := h.validateProcessRequest(&req); err != nil {
+		h.SendError(c, status, err)
+		return
+	}
+
+	// Checked before the stream starts, as errors can't change the status code afterwards
+	if !req.EphemeralCwd {
+		if err := process.CheckWorkingDir(req.WorkingDir); errors.Is(err, process.ErrWorkingDirNotAllowed) {
+			h.SendError(c, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	// The stream follows the process it started, a queued process has none yet
+	if req.MutexGroup != "" && req.MutexPolicy != process.MutexPolicyReject {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("streaming requests with a mutexGroup must use mutexPolicy=reject"))
+		return
+	}
+	keepaliveInterval, err := parseKeepaliveInterval(c, 5*time.Second)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	releaseName, err := h.processManager.ReserveName(req.Name)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	audit.LogEvent(c, "process_exec_stream", logrus.Fields{
+		"command":     req.Command,
+		"working-dir": req.WorkingDir,
+	})
 
 	// Set headers for streaming JSON events
 	c.Writer.Header().Set("Content-Type", "application/x-ndjson")
@@ -371,7 +932,8 @@ func (h *ProcessHandler) handleExecuteCommandStream(c *gin.Context) {
 	jw := &JSONStreamWriter{gin: c}
 
 	// Execute the process without waiting for completion (we'll handle waiting ourselves)
-	processInfo, err := h.ExecuteProcess(req.Command, req.WorkingDir, req.Name, req.Env, false, timeout, req.WaitForPorts, req.RestartOnFailure, req.MaxRestarts, req.KeepAlive)
+	processInfo, err := h.ExecuteProcess(req.Command, req.WorkingDir, req.Name, req.Env, false, req.timeout(), req.WaitForPorts, req.RestartOnFailure, req.MaxRestarts, req.KeepAlive, process.WithLabels(req.Labels), process.WithCgroupLimits(req.CgroupLimits), process.WithID(req.ID), process.WithEphemeralWorkingDir(req.EphemeralCwd), process.WithLogTag(req.LogTag), process.WithStdinFrom(req.StdinFrom), process.WithSaveStateOnExit(req.SaveStateOnExit), process.WithRetainLogs(req.RetainLogs), process.WithNewSession(req.NewSession), process.WithMutexGroup(req.MutexGroup, req.MutexPolicy))
+	releaseName()
 	if err != nil {
 		jw.WriteEvent("error", err.Error())
 		return
@@ -391,7 +953,7 @@ func (h *ProcessHandler) handleExecuteCommandStream(c *gin.Context) {
 	}
 
 	// Start keepalive ticker to prevent connection timeout
-	keepaliveTicker := time.NewTicker(5 * time.Second)
+	keepaliveTicker := time.NewTicker(keepaliveInterval)
 	defer keepaliveTicker.Stop()
 
 	for {
@@ -399,6 +961,11 @@ func (h *ProcessHandler) handleExecuteCommandStream(c *gin.Context) {
 		case <-c.Request.Context().Done():
 			h.RemoveLogWriter(processInfo.PID, jw)
 			return
+		case <-drain.Signal():
+			// The process keeps running, the client can follow it again after the restart
+			h.RemoveLogWriter(processInfo.PID, jw)
+			jw.WriteEvent("reconnect", processInfo.PID)
+			return
 		case <-proc.Done:
 			// Process completed
 			goto done
@@ -455,12 +1022,16 @@ done:
 
 // HandleGetProcessLogs handles GET requests to /process/{identifier}/logs
 // @Summary Get process logs
-// @Description Get the stdout and stderr output of a process
+// @Description Get the stdout and stderr output of a process. The response includes a cursor; polling clients pass it back as ?cursor= to only receive the output produced since the previous call. For processes restarted on failure, sinceLastRestart=true only returns the output of the current run.
 // @Tags process
 // @Accept json
 // @Produce json
 // @Param identifier path string true "Process identifier (PID or name)"
+// @Param stripAnsi query boolean false "Remove ANSI escape sequences (colors, cursor moves) from the output"
+// @Param cursor query integer false "Only return the output after this cursor, taken from a previous response"
+// @Param sinceLastRestart query boolean false "Only return the output of the current (most recent) run of the process. Cannot be used with cursor"
 // @Success 200 {object} process.ProcessLogs "Process logs"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
 // @Failure 404 {object} ErrorResponse "Process not found"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
@@ -472,27 +1043,62 @@ func (h *ProcessHandler) HandleGetProcessLogs(c *gin.Context) {
 		return
 	}
 
+	cursor := -1
+	if value := c.Query("cursor"); value != "" {
+		cursor, err = strconv.Atoi(value)
+		if err != nil || cursor < 0 {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid cursor %q: must be a non-negative integer", value))
+			return
+		}
+	}
+
+	sinceLastRestart := c.Query("sinceLastRestart") == "true"
+	if sinceLastRestart && cursor >= 0 {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("sinceLastRestart cannot be used with cursor"))
+		return
+	}
+
 	audit.LogEvent(c, "process_logs_access", logrus.Fields{})
 
-	logs, err := h.GetProcessOutput(identifier)
+	var logs process.ProcessLogs
+	switch {
+	case sinceLastRestart:
+		logs, err = h.processManager.GetProcessOutputSinceLastRestart(identifier)
+	case cursor >= 0:
+		logs, err = h.processManager.GetProcessOutputSince(identifier, cursor)
+	default:
+		logs, err = h.GetProcessOutput(identifier)
+	}
 	if err != nil {
 		h.SendError(c, http.StatusNotFound, err)
 		return
 	}
+	if c.Query("stripAnsi") == "true" {
+		logs.Stdout = process.StripANSI(logs.Stdout)
+		logs.Stderr = process.StripANSI(logs.Stderr)
+		logs.Logs = process.StripANSI(logs.Logs)
+	}
 
 	h.SendJSON(c, http.StatusOK, logs)
 }
 
 // HandleGetProcessLogsStream handles GET requests to /process/{identifier}/logs/stream
 // @Summary Stream process logs in real time
-// @Description Streams the stdout and stderr output of a process in real time, one line per log, prefixed with 'stdout:' or 'stderr:'. Closes when the process exits or the client disconnects.
+// @Description Streams the stdout and stderr output of a process in real time, one line per log, prefixed with 'stdout:' or 'stderr:'. The output produced before the client joined is sent first, so several clients can follow the same process. Closes when the process exits or the client disconnects, or after a [reconnect] line when the API shuts down or upgrades.
 // @Tags process
-// @Produce plain
+// @Produce plain,application/x-ndjson
 // @Param identifier path string true "Process identifier (PID or name)"
+// @Param flushIntervalMs query integer false "Batch output and flush at most once per interval (max 10000), 0 flushes every write"
+// @Param parseJsonLines query boolean false "Emit NDJSON events; stdout lines holding a JSON object or array are wrapped as {\"type\":\"stdout\",\"parsed\":...}, other lines as {\"type\":...,\"data\":...}"
+// @Param tag query boolean false "Prefix every line with the process log tag (or name), as in multi-process streams. Cannot be used with parseJsonLines"
+// @Param stripAnsi query boolean false "Remove ANSI escape sequences (colors, cursor moves) from the output"
+// @Param keepaliveSec query integer false "Send a keepalive every this many seconds (default 30, min 5, max 600)"
 // @Success 200 {string} string "Stream of process logs, one line per log (prefixed with stdout:/stderr:)"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
 // @Failure 404 {object} ErrorResponse "Process not found"
 // @Failure 422 {object} ErrorResponse "Unprocessable entity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Too many concurrent streaming connections, retry after Retry-After seconds"
 // @Router /process/{identifier}/logs/stream [get]
 func (h *ProcessHandler) HandleGetProcessLogsStream(c *gin.Context) {
 	identifier, err := h.GetPathParam(c, "identifier")
@@ -501,109 +1107,879 @@ func (h *ProcessHandler) HandleGetProcessLogsStream(c *gin.Context) {
 		return
 	}
 
-	audit.LogEvent(c, "process_logs_stream", logrus.Fields{})
-
-	// Set headers for streaming
-	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	c.Writer.Header().Set("Cache-Control", "no-cache")
-	c.Writer.Header().Set("Connection", "keep-alive")
-	c.Writer.Header().Set("X-Accel-Buffering", "no")
-	c.Writer.Flush()
-
-	// Use the custom ResponseWriter for flushing
-	rw := &ResponseWriter{gin: c}
-
-	err = h.StreamProcessOutput(identifier, rw)
+	parseJSONLines := c.Query("parseJsonLines") == "true"
+	flushInterval, err := parseFlushInterval(c)
 	if err != nil {
-		h.SendError(c, http.StatusUnprocessableEntity, err)
+		h.SendError(c, http.StatusBadRequest, err)
 		return
 	}
-
-	// Wait until the process is done or the client disconnects
-	proc, exists := h.processManager.GetProcessByIdentifier(identifier)
+	keepaliveInterval, err := parseKeepaliveInterval(c, process.DefaultKeepaliveInterval)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	stripANSI := c.Query("stripAnsi") == "true"
+	tagged := c.Query("tag") == "true"
+	if tagged && parseJSONLines {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("tag cannot be used with parseJsonLines"))
+		return
+	}
+	var logPrefix string
+	if tagged {
+		proc, exists := h.processManager.GetProcessByIdentifier(identifier)
+		if !exists {
+			h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+			return
+		}
+		logPrefix = proc.LogPrefix()
+	}
+
+	audit.LogEvent(c, "process_logs_stream", logrus.Fields{})
+
+	release, ok := h.acquireStream(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Set headers for streaming
+	if parseJSONLines {
+		c.Writer.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.Flush()
+
+	// Use the custom ResponseWriter for flushing
+	out := newStreamOutput(c.Writer, flushInterval)
+	defer out.Close()
+	rw := &ResponseWriter{gin: c, out: out}
+
+	var writer io.Writer = rw
+	var jw *JSONLinesLogWriter
+	var pw *PrefixedLogWriter
+	if parseJSONLines {
+		jw = NewJSONLinesLogWriter(rw)
+		writer = jw
+	} else if tagged {
+		pw = NewPrefixedLogWriter(rw, logPrefix)
+		writer = pw
+	}
+	var sw *ANSIStripWriter
+	if stripANSI {
+		sw = NewANSIStripWriter(writer)
+		writer = sw
+	}
+
+	err = h.StreamProcessOutputWithKeepalive(identifier, writer, keepaliveInterval)
+	if err != nil {
+		h.SendError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	// Wait until the process is done or the client disconnects
+	proc, exists := h.processManager.GetProcessByIdentifier(identifier)
+	if !exists {
+		return
+	}
+	select {
+	case <-proc.Done:
+	case <-c.Request.Context().Done():
+		h.RemoveLogWriter(identifier, writer)
+		return
+	case <-drain.Signal():
+		h.RemoveLogWriter(identifier, writer)
+		if sw != nil {
+			sw.FlushPending()
+		}
+		if pw != nil {
+			pw.FlushPending()
+		}
+		if jw != nil {
+			jw.FlushPending()
+		}
+		_, _ = writer.Write([]byte(reconnectMessage))
+		return
+	}
+
+	// Wait for tailLogFiles to complete its final reads
+	<-proc.TailDone
+
+	// Detach the writer
+	h.RemoveLogWriter(identifier, writer)
+
+	// For very fast commands, streaming might not have sent anything.
+	// Only re-send from the log file if nothing was streamed, to avoid duplicating output.
+	if !rw.HasSentData() && proc.LogFile != "" {
+		if content, err := os.ReadFile(proc.LogFile); err == nil && len(content) > 0 {
+			if stripANSI {
+				content = []byte(process.StripANSI(string(content)))
+			}
+			if jw != nil {
+				jw.WriteLogFile(string(content))
+			} else if pw != nil {
+				_, _ = pw.Write(content)
+			} else {
+				rw.Write(content)
+			}
+		}
+	}
+	if sw != nil {
+		sw.FlushPending()
+	}
+	if jw != nil {
+		jw.FlushPending()
+	}
+	if pw != nil {
+		pw.FlushPending()
+	}
+}
+
+// HandleGetProcessStatusStream handles GET requests to /process/{identifier}/status/stream
+// @Summary Stream process status changes
+// @Description Streams a newline-delimited JSON event whenever the status of a process changes (running, completed, failed, stopped, killed, paused or resumed), starting with its current status. A restart event is sent when a process with restartOnFailure exits and is restarted. Closes once the process has ended for good or the client disconnects, or after a {"type":"reconnect"} event when the API shuts down or upgrades. Keepalive events are sent every 30 seconds, or every keepaliveSec.
+// @Tags process
+// @Produce application/x-ndjson
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Param keepaliveSec query integer false "Send a keepalive every this many seconds (default 30, min 5, max 600)"
+// @Success 200 {object} process.ProcessStatusEvent "Stream of status events"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Failure 503 {object} ErrorResponse "Too many concurrent streaming connections, retry after Retry-After seconds"
+// @Router /process/{identifier}/status/stream [get]
+func (h *ProcessHandler) HandleGetProcessStatusStream(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	proc, exists := h.processManager.GetProcessByIdentifier(identifier)
+	if !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+		return
+	}
+	keepaliveInterval, err := parseKeepaliveInterval(c, 30*time.Second)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	release, ok := h.acquireStream(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	audit.LogEvent(c, "process_status_stream", logrus.Fields{})
+
+	c.Writer.Header().Set("Content-Type", "application/x-ndjson")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.Flush()
+
+	var mu sync.Mutex
+	write := func(event interface{}) {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = c.Writer.Write(append(data, '\n'))
+		c.Writer.Flush()
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		_ = h.processManager.WatchStatus(ctx, proc.PID, func(event process.ProcessStatusEvent) {
+			write(event)
+		})
+	}()
+
+	keepaliveTicker := time.NewTicker(keepaliveInterval)
+	defer keepaliveTicker.Stop()
+	for {
+		select {
+		case <-watchDone:
+			return
+		case <-drain.Signal():
+			cancel()
+			<-watchDone
+			write(gin.H{"type": "reconnect", "pid": proc.PID})
+			return
+		case <-keepaliveTicker.C:
+			write(gin.H{"type": "keepalive"})
+		}
+	}
+}
+
+// HandleGetMultiProcessLogsStream handles GET requests to /process/logs/stream
+// @Summary Stream logs of several processes in real time
+// @Description Multiplexes the stdout and stderr output of several processes into one stream. Each line is prefixed with the process log tag, or its name when it has none, e.g. '[web] stdout:listening on :3000'. Processes are selected by label or by a comma-separated list of identifiers. Closes when every selected process exits or the client disconnects, or after a [reconnect] line when the API shuts down or upgrades.
+// @Tags process
+// @Produce plain
+// @Param label query string false "Label selector (e.g. app=web)"
+// @Param identifiers query string false "Comma-separated list of process identifiers (PID or name)"
+// @Param flushIntervalMs query integer false "Batch output and flush at most once per interval (max 10000), 0 flushes every write"
+// @Param stripAnsi query boolean false "Remove ANSI escape sequences (colors, cursor moves) from the output"
+// @Param keepaliveSec query integer false "Send a keepalive every this many seconds (default 30, min 5, max 600)"
+// @Success 200 {string} string "Stream of process logs, one line per log (prefixed with [tag] and stdout:/stderr:)"
+// @Failure 400 {object} ErrorResponse "Invalid selection"
+// @Failure 404 {object} ErrorResponse "No matching process"
+// @Failure 503 {object} ErrorResponse "Too many concurrent streaming connections, retry after Retry-After seconds"
+// @Router /process/logs/stream [get]
+func (h *ProcessHandler) HandleGetMultiProcessLogsStream(c *gin.Context) {
+	label := c.Query("label")
+	identifiersParam := c.Query("identifiers")
+	if label == "" && identifiersParam == "" {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("either label or identifiers query parameter is required"))
+		return
+	}
+	flushInterval, err := parseFlushInterval(c)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	keepaliveInterval, err := parseKeepaliveInterval(c, process.DefaultKeepaliveInterval)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var procs []*process.ProcessInfo
+	if label != "" {
+		selector, err := process.ParseLabelSelector(label)
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+		procs = h.processManager.ListProcessesByLabels(selector)
+	}
+	for _, identifier := range strings.Split(identifiersParam, ",") {
+		identifier = strings.TrimSpace(identifier)
+		if identifier == "" {
+			continue
+		}
+		proc, exists := h.processManager.GetProcessByIdentifier(identifier)
+		if !exists {
+			h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+			return
+		}
+		if !slices.Contains(procs, proc) {
+			procs = append(procs, proc)
+		}
+	}
+	if len(procs) == 0 {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("no process matches the selection"))
+		return
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].StartedAt.Before(procs[j].StartedAt) })
+
+	audit.LogEvent(c, "process_logs_stream", logrus.Fields{
+		"processes": len(procs),
+	})
+
+	release, ok := h.acquireStream(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Set headers for streaming
+	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.Flush()
+
+	out := newStreamOutput(c.Writer, flushInterval)
+	defer out.Close()
+	rw := &ResponseWriter{gin: c, out: out}
+
+	// Attach one prefixing writer per process, all sharing the same response
+	stripANSI := c.Query("stripAnsi") == "true"
+	writers := make(map[string]*PrefixedLogWriter, len(procs))
+	attached := make(map[string]io.Writer, len(procs))
+	var wg sync.WaitGroup
+	for _, proc := range procs {
+		pw := NewPrefixedLogWriter(rw, proc.LogPrefix())
+		var writer io.Writer = pw
+		if stripANSI {
+			writer = NewANSIStripWriter(pw)
+		}
+		// Keepalives are sent once for the whole response below, not once per process
+		if err := h.StreamProcessOutputWithKeepalive(proc.PID, writer, 0); err != nil {
+			_, _ = rw.Write([]byte(fmt.Sprintf("[%s] error:%s\n", proc.LogPrefix(), err.Error())))
+			continue
+		}
+		writers[proc.PID] = pw
+		attached[proc.PID] = writer
+
+		wg.Add(1)
+		go func(proc *process.ProcessInfo) {
+			defer wg.Done()
+			select {
+			case <-proc.Done:
+				// Wait for tailLogFiles to complete its final reads
+				<-proc.TailDone
+			case <-c.Request.Context().Done():
+			}
+		}(proc)
+	}
+
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+
+	keepaliveTicker := time.NewTicker(keepaliveInterval)
+	defer keepaliveTicker.Stop()
+
+	drained := false
+wait:
+	for {
+		select {
+		case <-allDone:
+			break wait
+		case <-c.Request.Context().Done():
+			break wait
+		case <-drain.Signal():
+			drained = true
+			break wait
+		case <-keepaliveTicker.C:
+			_, _ = rw.Write([]byte("[keepalive]\n"))
+		}
+	}
+
+	// Detach the writers and emit any trailing partial lines
+	for pid, pw := range writers {
+		h.RemoveLogWriter(pid, attached[pid])
+		pw.FlushPending()
+	}
+	if drained {
+		_, _ = rw.Write([]byte(reconnectMessage))
+	}
+}
+
+// HandleExportProcessLogs handles GET requests to /process/logs/export
+// @Summary Export process logs as an archive
+// @Description Stream a tar.gz archive with one directory per process containing stdout.log, stderr.log and combined.log, plus a manifest.json describing the processes (command, status, exit code, labels...). Processes can be filtered by label and status; without filters every process is exported.
+// @Tags process
+// @Produce application/gzip
+// @Param label query string false "Label selector (e.g. app=web)"
+// @Param status query string false "Comma-separated list of statuses to include (e.g. failed,killed)"
+// @Success 200 {file} file "tar.gz archive of process logs"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Router /process/logs/export [get]
+func (h *ProcessHandler) HandleExportProcessLogs(c *gin.Context) {
+	procs := h.processManager.ListProcesses()
+	if label := c.Query("label"); label != "" {
+		selector, err := process.ParseLabelSelector(label)
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+		procs = h.processManager.ListProcessesByLabels(selector)
+	}
+
+	if statusParam := c.Query("status"); statusParam != "" {
+		statuses := make(map[string]bool)
+		for _, status := range strings.Split(statusParam, ",") {
+			status = strings.TrimSpace(status)
+			switch constants.ProcessStatus(status) {
+			case process.StatusRunning, process.StatusCompleted, process.StatusFailed, process.StatusStopped, process.StatusKilled:
+				statuses[status] = true
+			case "":
+			default:
+				h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid status: %s", status))
+				return
+			}
+		}
+		procs = slices.DeleteFunc(procs, func(p *process.ProcessInfo) bool {
+			return !statuses[string(p.Status)]
+		})
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].StartedAt.Before(procs[j].StartedAt) })
+
+	audit.LogEvent(c, "process_logs_export", logrus.Fields{
+		"processes": len(procs),
+	})
+
+	filename := fmt.Sprintf("process-logs-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	c.Writer.Header().Set("Content-Type", "application/gzip")
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so an error can only be logged
+	if err := h.processManager.WriteLogArchive(c.Writer, procs); err != nil {
+		logrus.WithError(err).Error("Failed to write process log archive")
+	}
+}
+
+// HandleGetProcessExitCode handles GET requests to /process/{identifier}/exit-code
+// @Summary Get the exit code of a process
+// @Description Lightweight alternative to the process details for scripts that only need success or failure. exitCode and exitReason are null while the process is running. With wait=true, block until the process has ended for good (restarts on failure included) or timeoutMs elapses, then respond with its current state.
+// @Tags process
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Param wait query boolean false "Wait for the process to end"
+// @Param timeoutMs query int false "With wait, maximum time to wait in milliseconds (default: 30000, max: 300000)"
+// @Success 200 {object} ProcessExitCodeResponse "Exit status"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Router /process/{identifier}/exit-code [get]
+func (h *ProcessHandler) HandleGetProcessExitCode(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	timeout := defaultExitCodeWait
+	if timeoutMs := c.Query("timeoutMs"); timeoutMs != "" {
+		parsed, err := strconv.Atoi(timeoutMs)
+		if err != nil || parsed < 0 {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid timeoutMs: %s", timeoutMs))
+			return
+		}
+		timeout = time.Duration(parsed) * time.Millisecond
+	}
+	if timeout > maxExitCodeWait {
+		timeout = maxExitCodeWait
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	status, err := h.processManager.GetExitStatus(ctx, identifier, c.Query("wait") == "true")
+	if err != nil {
+		h.SendError(c, http.StatusNotFound, err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, ProcessExitCodeResponse{
+		Status:     string(status.Status),
+		ExitCode:   status.ExitCode,
+		ExitReason: status.ExitReason,
+	})
+}
+
+// HandleProcessPortReady handles GET requests to /process/{identifier}/port-ready
+// @Summary Wait for a port to accept connections
+// @Description Block until the sandbox accepts TCP connections on the given port, the process exits, or the timeout elapses. The response reports whether the port became ready.
+// @Tags process
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Param port query int true "TCP port to probe"
+// @Param timeoutMs query int false "Maximum time to wait in milliseconds (default: 10000, max: 300000)"
+// @Success 200 {object} PortReadyResponse "Readiness result"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Router /process/{identifier}/port-ready [get]
+func (h *ProcessHandler) HandleProcessPortReady(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	port, err := strconv.Atoi(c.Query("port"))
+	if err != nil || port < 1 || port > 65535 {
+		h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid port: %q", c.Query("port")))
+		return
+	}
+
+	timeout := 10 * time.Second
+	if timeoutMs := c.Query("timeoutMs"); timeoutMs != "" {
+		parsed, err := strconv.Atoi(timeoutMs)
+		if err != nil || parsed < 0 {
+			h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid timeoutMs: %s", timeoutMs))
+			return
+		}
+		timeout = time.Duration(parsed) * time.Millisecond
+	}
+	if timeout > maxPortReadyTimeout {
+		timeout = maxPortReadyTimeout
+	}
+
+	proc, exists := h.processManager.GetProcessByIdentifier(identifier)
 	if !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	// Give up early if the process exits before the port opens
+	go func() {
+		select {
+		case <-proc.Done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	start := time.Now()
+	ready := network.WaitForPort(ctx, port, 100*time.Millisecond) == nil
+
+	h.SendJSON(c, http.StatusOK, PortReadyResponse{
+		Port:          port,
+		Ready:         ready,
+		ElapsedMs:     time.Since(start).Milliseconds(),
+		ProcessStatus: string(proc.Status),
+	})
+}
+
+// HandleStopProcess handles DELETE requests to /process/{identifier}
+// @Summary Stop a process
+// @Description Gracefully stop a running process
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Success 200 {object} SuccessResponse "Process stopped"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /process/{identifier} [delete]
+func (h *ProcessHandler) HandleStopProcess(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	audit.LogEvent(c, "process_stop", logrus.Fields{})
+
+	err = h.StopProcess(identifier)
+	if err != nil {
+		h.SendError(c, http.StatusNotFound, err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, gin.H{"message": "Process stopped successfully"})
+}
+
+// HandleKillProcess handles DELETE requests to /process/{identifier}/kill
+// @Summary Kill a process
+// @Description Forcefully kill a running process and its process group. With excludePids, the listed children (OS pids in the process group) and their descendants are kept alive: they are re-parented once the process dies, become orphans that are no longer managed (not listed, no logs captured) and, when they exit, are only collected by POST /process/reap.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Param excludePids query string false "Comma-separated OS pids of children to keep alive"
+// @Success 200 {object} SuccessResponse "Process killed"
+// @Failure 400 {object} ErrorResponse "Invalid excludePids"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /process/{identifier}/kill [delete]
+func (h *ProcessHandler) HandleKillProcess(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var excludePids []int
+	if value := c.Query("excludePids"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			pid, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || pid <= 0 {
+				h.SendError(c, http.StatusBadRequest, fmt.Errorf("invalid pid %q in excludePids", field))
+				return
+			}
+			excludePids = append(excludePids, pid)
+		}
+	}
+
+	audit.LogEvent(c, "process_kill", logrus.Fields{
+		"exclude-pids": excludePids,
+	})
+
+	err = h.KillProcessExcept(identifier, excludePids)
+	if err != nil {
+		if errors.Is(err, process.ErrInvalidExcludePid) {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+		h.SendError(c, http.StatusNotFound, err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, gin.H{"message": "Process killed successfully"})
+}
+
+// HandleMirrorProcessLogs handles POST requests to /process/{identifier}/logs/mirror
+// @Summary Mirror process logs to a file
+// @Description Attach a file sink to the log stream of a running process, alongside any client already streaming it. The file receives the output produced so far followed by the live output, with stdout:/stderr: prefixes, until the process exits. Existing file content is appended to.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Param request body ProcessLogMirrorRequest true "Mirror destination"
+// @Success 200 {object} SuccessResponse "Logs mirrored"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Path outside of the working directory with SANDBOX_FS_CONFINE"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Failure 409 {object} ErrorResponse "Process is not running"
+// @Failure 422 {object} ErrorResponse "File could not be opened"
+// @Router /process/{identifier}/logs/mirror [post]
+func (h *ProcessHandler) HandleMirrorProcessLogs(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var req ProcessLogMirrorRequest
+	if err := h.BindJSON(c, &req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	path, err := lib.FormatPath(req.Path)
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.checkConfinedPath(path, ""); err != nil {
+		h.SendError(c, pathErrorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	proc, exists := h.processManager.GetProcessByIdentifier(identifier)
+	if !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+		return
+	}
+	if proc.Status != process.StatusRunning {
+		h.SendError(c, http.StatusConflict, fmt.Errorf("process with Identifier %s is not running", identifier))
+		return
+	}
+
+	audit.LogEvent(c, "process_logs_mirror", logrus.Fields{
+		"path": path,
+	})
+
+	if err := h.MirrorLogsToFile(identifier, path); err != nil {
+		h.SendError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	h.SendSuccessWithPath(c, path, "Process logs mirrored successfully")
+}
+
+// HandleReattachProcessLogs handles POST requests to /process/{identifier}/logs/reattach
+// @Summary Reattach the log capture of a process
+// @Description Restart the log capture of a running process without restarting the process: the on-disk log files are re-opened and the in-memory output is rebuilt from them. Use it when the logs of a healthy process stopped updating.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Success 200 {object} SuccessResponse "Log capture reattached"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Failure 409 {object} ErrorResponse "Process is not running"
+// @Failure 500 {object} ErrorResponse "Log capture could not be reattached"
+// @Router /process/{identifier}/logs/reattach [post]
+func (h *ProcessHandler) HandleReattachProcessLogs(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	proc, exists := h.processManager.GetProcessByIdentifier(identifier)
+	if !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+		return
+	}
+	if proc.Status != process.StatusRunning {
+		h.SendError(c, http.StatusConflict, fmt.Errorf("process with Identifier %s is not running", identifier))
+		return
+	}
+
+	audit.LogEvent(c, "process_logs_reattach", logrus.Fields{})
+
+	if err := h.ReattachLogs(identifier); err != nil {
+		h.SendError(c, http.StatusInternalServerError, err)
 		return
 	}
-	select {
-	case <-proc.Done:
-	case <-c.Request.Context().Done():
-		h.RemoveLogWriter(identifier, rw)
+
+	h.SendJSON(c, http.StatusOK, gin.H{"message": "Process log capture reattached successfully"})
+}
+
+// HandlePauseProcess handles POST requests to /process/{identifier}/pause
+// @Summary Pause a process
+// @Description Freeze a running process and its children by sending SIGSTOP to its process group. The process keeps its running status and reports paused=true until resumed.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Success 200 {object} SuccessResponse "Process paused"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Failure 409 {object} ErrorResponse "Process is not running or already paused"
+// @Router /process/{identifier}/pause [post]
+func (h *ProcessHandler) HandlePauseProcess(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// Wait for tailLogFiles to complete its final reads
-	<-proc.TailDone
+	if _, exists := h.processManager.GetProcessByIdentifier(identifier); !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+		return
+	}
 
-	// Detach the writer
-	h.RemoveLogWriter(identifier, rw)
+	audit.LogEvent(c, "process_pause", logrus.Fields{})
 
-	// For very fast commands, streaming might not have sent anything.
-	// Only re-send from the log file if nothing was streamed, to avoid duplicating output.
-	if !rw.HasSentData() && proc.LogFile != "" {
-		if content, err := os.ReadFile(proc.LogFile); err == nil && len(content) > 0 {
-			rw.Write(content)
-		}
+	if err := h.PauseProcess(identifier); err != nil {
+		h.SendError(c, http.StatusConflict, err)
+		return
 	}
+
+	h.SendJSON(c, http.StatusOK, gin.H{"message": "Process paused successfully"})
 }
 
-// HandleStopProcess handles DELETE requests to /process/{identifier}
-// @Summary Stop a process
-// @Description Gracefully stop a running process
+// HandleResumeProcess handles POST requests to /process/{identifier}/resume
+// @Summary Resume a process
+// @Description Continue a paused process by sending SIGCONT to its process group
 // @Tags process
 // @Accept json
 // @Produce json
 // @Param identifier path string true "Process identifier (PID or name)"
-// @Success 200 {object} SuccessResponse "Process stopped"
+// @Success 200 {object} SuccessResponse "Process resumed"
 // @Failure 404 {object} ErrorResponse "Process not found"
-// @Failure 422 {object} ErrorResponse "Unprocessable entity"
-// @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /process/{identifier} [delete]
-func (h *ProcessHandler) HandleStopProcess(c *gin.Context) {
+// @Failure 409 {object} ErrorResponse "Process is not running or not paused"
+// @Router /process/{identifier}/resume [post]
+func (h *ProcessHandler) HandleResumeProcess(c *gin.Context) {
 	identifier, err := h.GetPathParam(c, "identifier")
 	if err != nil {
 		h.SendError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	audit.LogEvent(c, "process_stop", logrus.Fields{})
+	if _, exists := h.processManager.GetProcessByIdentifier(identifier); !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+		return
+	}
 
-	err = h.StopProcess(identifier)
+	audit.LogEvent(c, "process_resume", logrus.Fields{})
+
+	if err := h.ResumeProcess(identifier); err != nil {
+		h.SendError(c, http.StatusConflict, err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, gin.H{"message": "Process resumed successfully"})
+}
+
+// HandleRefreshProcess handles POST requests to /process/{identifier}/refresh
+// @Summary Refresh the status of a process
+// @Description Check right away whether a process is still running and return its updated information. Processes adopted after an API restart are otherwise checked every second, so this removes the delay when a client knows the process just exited. Calling it again has no further effect.
+// @Tags process
+// @Accept json
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Success 200 {object} ProcessResponse "Process information"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Router /process/{identifier}/refresh [post]
+func (h *ProcessHandler) HandleRefreshProcess(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
 	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.RefreshProcess(identifier); err != nil {
 		h.SendError(c, http.StatusNotFound, err)
 		return
 	}
 
-	h.SendJSON(c, http.StatusOK, gin.H{"message": "Process stopped successfully"})
+	processInfo, err := h.GetProcess(identifier)
+	if err != nil {
+		h.SendError(c, http.StatusNotFound, err)
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, processInfo)
 }
 
-// HandleKillProcess handles DELETE requests to /process/{identifier}/kill
-// @Summary Kill a process
-// @Description Forcefully kill a running process
+// ProcessUpdateRequest is the request body for updating the restart policy of a running process
+type ProcessUpdateRequest struct {
+	RestartOnFailure *bool `json:"restartOnFailure,omitempty" example:"true"`
+	MaxRestarts      *int  `json:"maxRestarts,omitempty" example:"5"` // Maximum number of restarts on failure, including the restarts already done. Set to a negative value (e.g. -1) for unlimited restarts.
+} // @name ProcessUpdateRequest
+
+// HandleUpdateProcess handles PATCH requests to /process/{identifier}
+// @Summary Update the restart policy of a process
+// @Description Change restartOnFailure and/or maxRestarts of a running process. Omitted fields are left unchanged. The new policy applies to the next failure and is persisted with the process state.
 // @Tags process
 // @Accept json
 // @Produce json
 // @Param identifier path string true "Process identifier (PID or name)"
-// @Success 200 {object} SuccessResponse "Process killed"
+// @Param request body ProcessUpdateRequest true "Restart policy"
+// @Success 200 {object} ProcessResponse "Updated process"
+// @Failure 400 {object} ErrorResponse "Invalid request"
 // @Failure 404 {object} ErrorResponse "Process not found"
-// @Failure 422 {object} ErrorResponse "Unprocessable entity"
+// @Failure 409 {object} ErrorResponse "Process is not running"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /process/{identifier}/kill [delete]
-func (h *ProcessHandler) HandleKillProcess(c *gin.Context) {
+// @Router /process/{identifier} [patch]
+func (h *ProcessHandler) HandleUpdateProcess(c *gin.Context) {
 	identifier, err := h.GetPathParam(c, "identifier")
 	if err != nil {
 		h.SendError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	audit.LogEvent(c, "process_kill", logrus.Fields{})
+	var req ProcessUpdateRequest
+	if err := h.BindJSON(c, &req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.RestartOnFailure == nil && req.MaxRestarts == nil {
+		h.SendError(c, http.StatusBadRequest, process.ErrEmptyRestartPolicy)
+		return
+	}
+
+	if _, exists := h.processManager.GetProcessByIdentifier(identifier); !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process with Identifier %s not found", identifier))
+		return
+	}
+
+	fields := logrus.Fields{}
+	if req.RestartOnFailure != nil {
+		fields["restart_on_failure"] = *req.RestartOnFailure
+	}
+	if req.MaxRestarts != nil {
+		fields["max_restarts"] = *req.MaxRestarts
+	}
+	audit.LogEvent(c, "process_update", fields)
+
+	if err := h.UpdateRestartPolicy(identifier, req.RestartOnFailure, req.MaxRestarts); err != nil {
+		if errors.Is(err, process.ErrProcessNotRunning) {
+			h.SendError(c, http.StatusConflict, err)
+			return
+		}
+		h.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
 
-	err = h.KillProcess(identifier)
+	processInfo, err := h.GetProcess(identifier)
 	if err != nil {
 		h.SendError(c, http.StatusNotFound, err)
 		return
 	}
 
-	h.SendJSON(c, http.StatusOK, gin.H{"message": "Process killed successfully"})
+	h.SendJSON(c, http.StatusOK, processInfo)
 }
 
 // HandleGetProcess handles GET requests to /process/:identifier
@@ -632,9 +2008,104 @@ func (h *ProcessHandler) HandleGetProcess(c *gin.Context) {
 	h.SendJSON(c, http.StatusOK, processInfo)
 }
 
+// ProcessReapResponse lists the zombie processes reaped by POST /process/reap
+type ProcessReapResponse struct {
+	Reaped    int                     `json:"reaped" binding:"required" example:"2"`
+	Processes []process.ReapedProcess `json:"processes" binding:"required"`
+} // @name ProcessReapResponse
+
+// HandleReapZombies handles POST requests to /process/reap
+// @Summary Reap zombie child processes
+// @Description Scans for zombie children of the API process (e.g. orphans of a process that exited without waiting for its children) and reaps them. Returns how many were reaped and their exit code when known. Zombies of running managed processes are left to the process manager.
+// @Tags process
+// @Produce json
+// @Success 200 {object} ProcessReapResponse "Reaped processes"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /process/reap [post]
+func (h *ProcessHandler) HandleReapZombies(c *gin.Context) {
+	reaped, err := h.processManager.ReapZombies()
+	if err != nil {
+		h.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	audit.LogEvent(c, "process_reap", logrus.Fields{
+		"reaped": len(reaped),
+	})
+
+	h.SendJSON(c, http.StatusOK, ProcessReapResponse{Reaped: len(reaped), Processes: reaped})
+}
+
+// processSpec rebuilds the request body that starts a process with the same configuration.
+// Secret env values (see process.IsSecretEnvKey) are replaced with [REDACTED] unless includeSecrets is set.
+func processSpec(p *process.ProcessInfo, includeSecrets bool) ProcessRequest {
+	spec := ProcessRequest{
+		Command:          p.Command,
+		Name:             p.Name,
+		WorkingDir:       p.WorkingDir,
+		RestartOnFailure: p.RestartOnFailure,
+		MaxRestarts:      p.MaxRestarts,
+		KeepAlive:        p.KeepAlive,
+		Labels:           p.Labels,
+		LogTag:           p.LogTag,
+		CgroupLimits:     p.CgroupLimits,
+		StdinFrom:        p.StdinFrom,
+		SaveStateOnExit:  p.SaveStateOnExit,
+		RetainLogs:       p.RetainLogs,
+		NewSession:       p.NewSession,
+		MutexGroup:       p.MutexGroup,
+		MutexPolicy:      p.MutexPolicy,
+	}
+	// A new temp dir is created on each start
+	if p.EphemeralCwd {
+		spec.WorkingDir = ""
+		spec.EphemeralCwd = true
+	}
+	// A keepAlive process without timeout gets the default one, keep 0 (infinite) explicit
+	if p.Timeout != 0 || p.KeepAlive {
+		timeout := p.Timeout
+		spec.Timeout = &timeout
+	}
+	if len(p.Env) > 0 {
+		if includeSecrets {
+			spec.Env = maps.Clone(p.Env)
+		} else {
+			spec.Env = process.RedactEnv(p.Env)
+		}
+	}
+	return spec
+}
+
+// HandleGetProcessSpec handles GET requests to /process/:identifier/spec
+// @Summary Get the start configuration of a process
+// @Description Returns the request body that recreates the process with POST /process: command, working directory, env, restart settings, keepAlive, timeout, labels and cgroup limits. Values of env vars that look like secrets (token, password, key... configurable with SANDBOX_SECRET_ENV_PATTERNS) are replaced with [REDACTED] unless includeSecrets is true.
+// @Tags process
+// @Produce json
+// @Param identifier path string true "Process identifier (PID or name)"
+// @Param includeSecrets query boolean false "Return secret-looking env values as is"
+// @Success 200 {object} ProcessRequest "Process start configuration"
+// @Failure 404 {object} ErrorResponse "Process not found"
+// @Router /process/{identifier}/spec [get]
+func (h *ProcessHandler) HandleGetProcessSpec(c *gin.Context) {
+	identifier, err := h.GetPathParam(c, "identifier")
+	if err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	processInfo, exists := h.processManager.GetProcessByIdentifier(identifier)
+	if !exists {
+		h.SendError(c, http.StatusNotFound, fmt.Errorf("process not found"))
+		return
+	}
+
+	h.SendJSON(c, http.StatusOK, processSpec(processInfo, c.Query("includeSecrets") == "true"))
+}
+
 // ResponseWriter is a custom writer for SSE responses that also flushes after each write
 type ResponseWriter struct {
 	gin      *gin.Context
+	out      *streamOutput // Batches flushes when set, otherwise every write is flushed
 	closed   bool
 	sentData bool // Track if any data was sent
 	mu       sync.Mutex
@@ -662,6 +2133,14 @@ func (w *ResponseWriter) Write(data []byte) (int, error) {
 	}
 
 	// Write data as-is (no SSE wrapping)
+	if w.out != nil {
+		n, err := w.out.Write(data)
+		if err != nil {
+			w.closed = true
+			return 0, err
+		}
+		return n, nil
+	}
 	n, err := w.gin.Writer.Write(data)
 	if err != nil {
 		w.closed = true
@@ -685,6 +2164,288 @@ func (w *ResponseWriter) Close() {
 	w.closed = true
 }
 
+// PrefixedLogWriter receives a process's log events and writes them line by line,
+// prefixed with the process name, to a shared writer. Partial lines are buffered
+// per stream so output of several processes never interleaves mid-line.
+type PrefixedLogWriter struct {
+	out     io.Writer
+	prefix  string
+	pending map[string]string
+	mu      sync.Mutex
+}
+
+// NewPrefixedLogWriter creates a writer prefixing every line with "[name] "
+func NewPrefixedLogWriter(out io.Writer, name string) *PrefixedLogWriter {
+	return &PrefixedLogWriter{
+		out:     out,
+		prefix:  "[" + name + "] ",
+		pending: make(map[string]string),
+	}
+}
+
+// IsJSONStreamWriter makes the process manager send typed events instead of pre-prefixed text
+func (w *PrefixedLogWriter) IsJSONStreamWriter() bool {
+	return true
+}
+
+// WriteEvent buffers data for the given stream and writes every complete line
+func (w *PrefixedLogWriter) WriteEvent(eventType string, data string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buffered := w.pending[eventType] + data
+	lastNewline := strings.LastIndexByte(buffered, '\n')
+	if lastNewline < 0 {
+		w.pending[eventType] = buffered
+		return len(data), nil
+	}
+	w.pending[eventType] = buffered[lastNewline+1:]
+
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(buffered[:lastNewline+1], "\n") {
+		if line != "" {
+			out.WriteString(w.prefix + eventType + ":" + line)
+		}
+	}
+	if _, err := w.out.Write([]byte(out.String())); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Write handles raw messages (restarts, termination notices, keepalives, reconnects)
+func (w *PrefixedLogWriter) Write(data []byte) (int, error) {
+	if string(data) == "[keepalive]\n" || string(data) == reconnectMessage {
+		return w.out.Write(data)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			out.WriteString(w.prefix + strings.TrimSuffix(line, "\n") + "\n")
+		}
+	}
+	if out.Len() == 0 {
+		return len(data), nil
+	}
+	if _, err := w.out.Write([]byte(out.String())); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// FlushPending writes any buffered partial lines. It is deliberately not named
+// Flush: the process manager flushes writers after every event, which would
+// break lines that arrive in several chunks.
+func (w *PrefixedLogWriter) FlushPending() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, eventType := range []string{"stdout", "stderr"} {
+		if line := w.pending[eventType]; line != "" {
+			_, _ = w.out.Write([]byte(w.prefix + eventType + ":" + line + "\n"))
+			w.pending[eventType] = ""
+		}
+	}
+}
+
+// ANSIStripWriter removes ANSI escape sequences from a process's log events before
+// passing them on. A sequence split across two chunks is held back until complete.
+type ANSIStripWriter struct {
+	out     io.Writer
+	pending map[string]string
+	mu      sync.Mutex
+}
+
+// NewANSIStripWriter creates a writer stripping ANSI escape sequences from the output sent to out
+func NewANSIStripWriter(out io.Writer) *ANSIStripWriter {
+	return &ANSIStripWriter{
+		out:     out,
+		pending: make(map[string]string),
+	}
+}
+
+// IsJSONStreamWriter makes the process manager send typed events, so sequences are tracked per stream
+func (w *ANSIStripWriter) IsJSONStreamWriter() bool {
+	return true
+}
+
+// WriteEvent strips the complete escape sequences of data and forwards the result
+func (w *ANSIStripWriter) WriteEvent(eventType string, data string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	complete, rest := process.SplitIncompleteANSI(w.pending[eventType] + data)
+	w.pending[eventType] = rest
+	if complete != "" {
+		if err := w.forward(eventType, process.StripANSI(complete)); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Write strips raw messages (restarts, termination notices, keepalives, reconnects)
+func (w *ANSIStripWriter) Write(data []byte) (int, error) {
+	if _, err := w.out.Write([]byte(process.StripANSI(string(data)))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush flushes the underlying writer when it supports it
+func (w *ANSIStripWriter) Flush() {
+	if f, ok := w.out.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// FlushPending drops the unterminated escape sequences still held back
+func (w *ANSIStripWriter) FlushPending() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for eventType := range w.pending {
+		w.pending[eventType] = ""
+	}
+}
+
+func (w *ANSIStripWriter) forward(eventType string, data string) error {
+	if jw, ok := w.out.(process.JSONStreamWriter); ok {
+		_, err := jw.WriteEvent(eventType, data)
+		return err
+	}
+	_, err := w.out.Write([]byte(eventType + ":" + data))
+	return err
+}
+
+// ParsedLogEvent is a line of a process log stream in parseJsonLines mode
+type ParsedLogEvent struct {
+	Type   string              `json:"type"`
+	Data   *string             `json:"data,omitempty"`
+	Parsed jsoniter.RawMessage `json:"parsed,omitempty"`
+}
+
+// JSONLinesLogWriter receives a process's log events and writes one NDJSON event
+// per line. Stdout lines holding a JSON object or array are embedded as "parsed",
+// every other line is sent as a raw "data" string.
+type JSONLinesLogWriter struct {
+	out     io.Writer
+	pending map[string]string
+	mu      sync.Mutex
+}
+
+// NewJSONLinesLogWriter creates a writer emitting NDJSON events to out
+func NewJSONLinesLogWriter(out io.Writer) *JSONLinesLogWriter {
+	return &JSONLinesLogWriter{
+		out:     out,
+		pending: make(map[string]string),
+	}
+}
+
+// IsJSONStreamWriter makes the process manager send typed events instead of pre-prefixed text
+func (w *JSONLinesLogWriter) IsJSONStreamWriter() bool {
+	return true
+}
+
+// WriteEvent buffers data for the given stream and writes an event for every complete line
+func (w *JSONLinesLogWriter) WriteEvent(eventType string, data string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buffered := w.pending[eventType] + data
+	lastNewline := strings.LastIndexByte(buffered, '\n')
+	if lastNewline < 0 {
+		w.pending[eventType] = buffered
+		return len(data), nil
+	}
+	w.pending[eventType] = buffered[lastNewline+1:]
+
+	if err := w.writeLines(eventType, strings.Split(buffered[:lastNewline], "\n")); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Write handles raw messages (restarts, termination notices, keepalives, reconnects)
+func (w *JSONLinesLogWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if string(data) == "[keepalive]\n" {
+		return len(data), w.writeEvent(ParsedLogEvent{Type: "keepalive"})
+	}
+	if string(data) == reconnectMessage {
+		return len(data), w.writeEvent(ParsedLogEvent{Type: "reconnect"})
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := w.writeLines("info", lines); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// WriteLogFile replays the content of a combined log file, whose lines are
+// prefixed with "stdout:" or "stderr:"
+func (w *JSONLinesLogWriter) WriteLogFile(content string) {
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "stderr:"); ok {
+			_, _ = w.WriteEvent("stderr", rest)
+		} else {
+			_, _ = w.WriteEvent("stdout", strings.TrimPrefix(line, "stdout:"))
+		}
+	}
+}
+
+// FlushPending writes any buffered partial lines
+func (w *JSONLinesLogWriter) FlushPending() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, eventType := range []string{"stdout", "stderr"} {
+		if line := w.pending[eventType]; line != "" {
+			_ = w.writeLines(eventType, []string{line})
+			w.pending[eventType] = ""
+		}
+	}
+}
+
+func (w *JSONLinesLogWriter) writeLines(eventType string, lines []string) error {
+	for _, line := range lines {
+		event := ParsedLogEvent{Type: eventType}
+		trimmed := strings.TrimSpace(line)
+		if eventType == "stdout" && (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+			event.Parsed = jsoniter.RawMessage(trimmed)
+		} else {
+			event.Data = &line
+		}
+		if err := w.writeEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *JSONLinesLogWriter) writeEvent(event ParsedLogEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(append(data, '\n'))
+	return err
+}
+
 // JSONStreamWriter wraps a writer and formats output as JSON events
 // Used by handleExecuteCommandStream for structured streaming output
 type JSONStreamWriter struct {
@@ -0,0 +1,38 @@
+package process
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ansiPattern matches CSI sequences (colors, cursor moves), OSC sequences (titles, hyperlinks)
+// and the remaining two-character escapes (all but ESC [ and ESC ], which start the former)
+var ansiPattern = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[0-Z\\^_\x60-~])`)
+
+// maxPendingANSI bounds how much of a trailing, unterminated escape sequence is held back
+const maxPendingANSI = 256
+
+// StripANSI removes ANSI escape sequences from s
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// SplitIncompleteANSI splits s before a trailing escape sequence that is not terminated yet,
+// so a chunked stream can hold it back until the rest of the sequence arrives
+func SplitIncompleteANSI(s string) (string, string) {
+	idx := strings.LastIndexByte(s, '\x1b')
+	if idx < 0 {
+		return s, ""
+	}
+	tail := s[idx:]
+	if len(tail) > maxPendingANSI || strings.ContainsRune(tail, '\n') {
+		return s, ""
+	}
+	if loc := ansiPattern.FindStringIndex(tail); loc != nil && loc[0] == 0 {
+		return s, ""
+	}
+	return s[:idx], tail
+}
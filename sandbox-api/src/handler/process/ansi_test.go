@@ -0,0 +1,36 @@
+package process
+
+import "testing"
+
+func TestStripANSI(t *testing.T) {
+	for input, expected := range map[string]string{
+		"plain text": "plain text",
+		"\x1b[31mred\x1b[0m and \x1b[1;32mgreen\x1b[m": "red and green",
+		"\x1b[2K\x1b[1Gprogress 50%":                   "progress 50%",
+		"\x1b]0;title\x07done":                         "done",
+		"\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\":     "link",
+		"\x1bcreset": "reset",
+	} {
+		if got := StripANSI(input); got != expected {
+			t.Errorf("StripANSI(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestSplitIncompleteANSI(t *testing.T) {
+	for _, tc := range []struct {
+		input, complete, rest string
+	}{
+		{"no escapes", "no escapes", ""},
+		{"\x1b[31mred", "\x1b[31mred", ""},
+		{"red\x1b[3", "red", "\x1b[3"},
+		{"red\x1b", "red", "\x1b"},
+		{"\x1b]0;title", "", "\x1b]0;title"},
+		{"broken\x1b[3\nnext", "broken\x1b[3\nnext", ""},
+	} {
+		complete, rest := SplitIncompleteANSI(tc.input)
+		if complete != tc.complete || rest != tc.rest {
+			t.Errorf("SplitIncompleteANSI(%q) = %q, %q, expected %q, %q", tc.input, complete, rest, tc.complete, tc.rest)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CgroupLimits are resource limits enforced by placing a process in its own cgroup v2
+type CgroupLimits struct {
+	MemoryMax int64   `json:"memoryMax,omitempty" example:"536870912"` // Hard memory limit in bytes (memory.max), the process is OOM-killed above it
+	CPUMax    float64 `json:"cpuMax,omitempty" example:"0.5"`          // CPU limit in cores (cpu.max), e.g. 0.5 for half a core
+} // @name CgroupLimits
+
+// cpuMaxPeriod is the cpu.max period, in microseconds, used to express CPUMax as a quota
+const cpuMaxPeriod = 100000
+
+// Validate checks that the limits are usable
+func (l *CgroupLimits) Validate() error {
+	if l.MemoryMax < 0 || l.CPUMax < 0 {
+		return errors.New("cgroupLimits values cannot be negative")
+	}
+	if l.MemoryMax == 0 && l.CPUMax == 0 {
+		return errors.New("cgroupLimits requires memoryMax or cpuMax")
+	}
+	if l.CPUMax > 0 && l.CPUMax*cpuMaxPeriod < 1000 {
+		return errors.New("cgroupLimits.cpuMax must be at least 0.01")
+	}
+	return nil
+}
+
+// WithCgroupLimits runs the process in a dedicated cgroup v2 enforcing the given limits
+func WithCgroupLimits(limits *CgroupLimits) ProcessOption {
+	return func(p *ProcessInfo) {
+		if limits == nil {
+			return
+		}
+		copied := *limits
+		p.CgroupLimits = &copied
+	}
+}
+
+// applyCgroupLimits moves a just-started process into a new cgroup enforcing its limits.
+// The process keeps running when the cgroup can't be set up (e.g. no cgroup v2 or not
+// enough privileges): the reason is reported in CgroupWarning.
+func applyCgroupLimits(process *ProcessInfo, pid int) {
+	if process.CgroupLimits == nil {
+		return
+	}
+	process.CgroupWarning = ""
+
+	path, err := createCgroup(process.Name, process.CgroupLimits)
+	if err == nil {
+		if err = addToCgroup(path, pid); err != nil {
+			removeCgroup(path)
+		}
+	}
+	if err != nil {
+		process.CgroupWarning = fmt.Sprintf("cgroup limits were not applied: %v", err)
+		logrus.WithFields(logrus.Fields{
+			"pid":  process.PID,
+			"name": process.Name,
+		}).WithError(err).Warn("Failed to apply cgroup limits, process runs without them")
+		return
+	}
+	process.CgroupPath = path
+}
+
+// releaseCgroup removes the cgroup of a completed process, killing anything left in it
+func releaseCgroup(process *ProcessInfo) {
+	if process.CgroupPath == "" {
+		return
+	}
+	removeCgroup(process.CgroupPath)
+	process.CgroupPath = ""
+}
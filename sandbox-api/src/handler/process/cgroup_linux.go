@@ -0,0 +1,125 @@
+//go:build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cgroupParent is the cgroup v2 directory under which process cgroups are created.
+// Can be configured via SANDBOX_CGROUP_PARENT environment variable.
+var cgroupParent = "/sys/fs/cgroup/sandbox-processes"
+
+var cgroupNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+func init() {
+	if dir := os.Getenv("SANDBOX_CGROUP_PARENT"); dir != "" {
+		cgroupParent = dir
+	}
+}
+
+// createCgroup creates a cgroup for a process and writes its limits
+func createCgroup(name string, limits *CgroupLimits) (string, error) {
+	root := filepath.Dir(cgroupParent)
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroups v2 is not available at %s", root)
+	}
+
+	var controllers []string
+	if limits.MemoryMax > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if limits.CPUMax > 0 {
+		controllers = append(controllers, "cpu")
+	}
+
+	if err := os.MkdirAll(cgroupParent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cgroup %s: %w", cgroupParent, err)
+	}
+	// Controllers must be enabled in every ancestor's subtree to be usable by the process cgroup
+	for _, dir := range []string{root, cgroupParent} {
+		if err := enableCgroupControllers(dir, controllers); err != nil {
+			return "", err
+		}
+	}
+
+	path := filepath.Join(cgroupParent, fmt.Sprintf("%s-%d", cgroupNameSanitizer.ReplaceAllString(name, "_"), time.Now().UnixNano()))
+	if err := os.Mkdir(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cgroup %s: %w", path, err)
+	}
+
+	if limits.MemoryMax > 0 {
+		if err := os.WriteFile(filepath.Join(path, "memory.max"), []byte(strconv.FormatInt(limits.MemoryMax, 10)), 0644); err != nil {
+			_ = os.Remove(path)
+			return "", fmt.Errorf("failed to set memory.max: %w", err)
+		}
+	}
+	if limits.CPUMax > 0 {
+		quota := int64(limits.CPUMax * cpuMaxPeriod)
+		if err := os.WriteFile(filepath.Join(path, "cpu.max"), []byte(fmt.Sprintf("%d %d", quota, cpuMaxPeriod)), 0644); err != nil {
+			_ = os.Remove(path)
+			return "", fmt.Errorf("failed to set cpu.max: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// enableCgroupControllers enables the controllers for the children of a cgroup
+func enableCgroupControllers(dir string, controllers []string) error {
+	enabled, err := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	if err != nil {
+		return fmt.Errorf("failed to read controllers of %s: %w", dir, err)
+	}
+	active := strings.Fields(string(enabled))
+	for _, controller := range controllers {
+		if slices.Contains(active, controller) {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+"+controller), 0644); err != nil {
+			return fmt.Errorf("failed to enable the %s controller in %s: %w", controller, dir, err)
+		}
+	}
+	return nil
+}
+
+// addToCgroup moves a process into a cgroup. Children forked afterwards inherit it.
+func addToCgroup(path string, pid int) error {
+	if err := os.WriteFile(filepath.Join(path, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return fmt.Errorf("failed to move process into cgroup: %w", err)
+	}
+	return nil
+}
+
+// removeCgroup kills the processes left in a cgroup (e.g. background children) and removes it
+func removeCgroup(path string) {
+	// cgroup.kill is only available since Linux 5.14, signal the processes one by one otherwise
+	if err := os.WriteFile(filepath.Join(path, "cgroup.kill"), []byte("1"), 0644); err != nil {
+		if procs, err := os.ReadFile(filepath.Join(path, "cgroup.procs")); err == nil {
+			for _, field := range strings.Fields(string(procs)) {
+				if pid, err := strconv.Atoi(field); err == nil {
+					_ = syscall.Kill(pid, syscall.SIGKILL)
+				}
+			}
+		}
+	}
+
+	var err error
+	for i := 0; i < 20; i++ {
+		if err = os.Remove(path); err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	logrus.WithField("cgroup", path).WithError(err).Warn("Failed to remove process cgroup")
+}
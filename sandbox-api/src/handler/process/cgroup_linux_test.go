@@ -0,0 +1,37 @@
+package process
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCgroupLimitsFallback verifies that a process still runs, with a warning,
+// when no cgroup v2 hierarchy is available
+func TestCgroupLimitsFallback(t *testing.T) {
+	previous := cgroupParent
+	cgroupParent = filepath.Join(t.TempDir(), "sandbox-processes")
+	defer func() { cgroupParent = previous }()
+
+	pm := GetProcessManager()
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("echo limited", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithCgroupLimits(&CgroupLimits{MemoryMax: 64 * 1024 * 1024}))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	if proc.Status != StatusCompleted {
+		t.Errorf("Expected the process to complete, got %s", proc.Status)
+	}
+	if !strings.Contains(proc.CgroupWarning, "cgroups v2 is not available") {
+		t.Errorf("Expected a cgroup warning, got %q", proc.CgroupWarning)
+	}
+	if proc.CgroupLimits == nil || proc.CgroupLimits.MemoryMax != 64*1024*1024 {
+		t.Errorf("Expected the requested limits to be kept, got %+v", proc.CgroupLimits)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package process
+
+import "errors"
+
+// cgroups are only supported on Linux
+var errCgroupsNotSupported = errors.New("cgroups are only supported on Linux")
+
+// createCgroup returns an error on non-Linux platforms
+func createCgroup(name string, limits *CgroupLimits) (string, error) {
+	return "", errCgroupsNotSupported
+}
+
+// addToCgroup returns an error on non-Linux platforms
+func addToCgroup(path string, pid int) error {
+	return errCgroupsNotSupported
+}
+
+// removeCgroup is a no-op on non-Linux platforms
+func removeCgroup(path string) {}
@@ -0,0 +1,30 @@
+package process
+
+import "testing"
+
+func TestCgroupLimitsValidate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		limits    CgroupLimits
+		shouldErr bool
+	}{
+		{"memory only", CgroupLimits{MemoryMax: 256 * 1024 * 1024}, false},
+		{"cpu only", CgroupLimits{CPUMax: 0.5}, false},
+		{"both", CgroupLimits{MemoryMax: 1 << 30, CPUMax: 2}, false},
+		{"empty", CgroupLimits{}, true},
+		{"negative memory", CgroupLimits{MemoryMax: -1}, true},
+		{"cpu too small", CgroupLimits{CPUMax: 0.001}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.limits.Validate()
+			if tc.shouldErr && err == nil {
+				t.Error("Expected an error, got none")
+			}
+			if !tc.shouldErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrWorkingDirNotAllowed is returned when a process would run outside of the allowed working directories
+var ErrWorkingDirNotAllowed = errors.New("working directory is not allowed")
+
+// allowedWorkingDirs are the directories, with their subdirectories, processes can run in.
+// Configured via SANDBOX_ALLOWED_CWD, a comma-separated list of absolute paths. Empty allows
+// every directory. Together with SANDBOX_FS_CONFINE, set it to the filesystem working
+// directory to keep both files and processes inside it.
+var allowedWorkingDirs []string
+
+func init() {
+	allowedWorkingDirs = parseAllowedWorkingDirs(os.Getenv("SANDBOX_ALLOWED_CWD"))
+}
+
+func parseAllowedWorkingDirs(value string) []string {
+	var dirs []string
+	for _, dir := range strings.Split(value, ",") {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			logrus.Warnf("Invalid SANDBOX_ALLOWED_CWD entry %q, expected an absolute path", dir)
+			continue
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs
+}
+
+// CheckWorkingDir returns ErrWorkingDirNotAllowed unless dir, once symlinks are resolved, is one
+// of the allowed working directories or inside one. An empty dir is the API's own working
+// directory, which processes inherit.
+func CheckWorkingDir(dir string) error {
+	if len(allowedWorkingDirs) == 0 {
+		return nil
+	}
+
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("could not resolve the working directory: %w", err)
+		}
+		dir = cwd
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("could not resolve working directory '%s': %w", dir, err)
+	}
+
+	for _, allowed := range allowedWorkingDirs {
+		// Allowed roots are resolved too, so that a root given through a symlink still matches
+		if real, err := filepath.EvalSymlinks(allowed); err == nil {
+			allowed = real
+		}
+		rel, err := filepath.Rel(allowed, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: '%s' is outside of %s", ErrWorkingDirNotAllowed, dir, strings.Join(allowedWorkingDirs, ", "))
+}
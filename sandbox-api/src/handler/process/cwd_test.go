@@ -0,0 +1,65 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAllowedWorkingDirs(t *testing.T) {
+	dirs := parseAllowedWorkingDirs(" /home/user/ , relative, ,/srv")
+	if len(dirs) != 2 || dirs[0] != "/home/user" || dirs[1] != "/srv" {
+		t.Errorf("Unexpected allowed dirs: %v", dirs)
+	}
+}
+
+func TestCheckWorkingDir(t *testing.T) {
+	root := t.TempDir()
+	allowed := filepath.Join(root, "allowed")
+	outside := filepath.Join(root, "outside")
+	sibling := allowed + "-sibling"
+	for _, dir := range []string{filepath.Join(allowed, "project"), outside, sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if err := CheckWorkingDir(outside); err != nil {
+		t.Errorf("Expected every directory to be allowed by default, got %v", err)
+	}
+
+	previous := allowedWorkingDirs
+	allowedWorkingDirs = []string{allowed}
+	defer func() { allowedWorkingDirs = previous }()
+
+	for _, tc := range []struct {
+		dir     string
+		allowed bool
+	}{
+		{allowed, true},
+		{filepath.Join(allowed, "project"), true},
+		{outside, false},
+		{filepath.Join(allowed, "escape"), false},
+		{sibling, false},
+	} {
+		err := CheckWorkingDir(tc.dir)
+		if tc.allowed && err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", tc.dir, err)
+		}
+		if !tc.allowed && !errors.Is(err, ErrWorkingDirNotAllowed) {
+			t.Errorf("Expected ErrWorkingDirNotAllowed for %s, got %v", tc.dir, err)
+		}
+	}
+
+	pm := GetProcessManager()
+	if _, err := pm.StartProcessWithName("true", outside, "", nil, false, 0, false, 0, func(p *ProcessInfo) {}); !errors.Is(err, ErrWorkingDirNotAllowed) {
+		t.Errorf("Expected the process to be rejected, got %v", err)
+	}
+	if _, err := pm.StartProcessWithName("true", "", "", nil, false, 0, false, 0, func(p *ProcessInfo) {}, WithEphemeralWorkingDir(true)); err != nil {
+		t.Errorf("Expected an ephemeral working directory to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ephemeralWorkingDirPrefix prefixes the name of ephemeral working directories
+const ephemeralWorkingDirPrefix = "sandbox-process-"
+
+// ephemeralWorkingDirRoot is where ephemeral working directories are created.
+// Can be configured via SANDBOX_EPHEMERAL_DIR environment variable, defaults to the system temp dir.
+var ephemeralWorkingDirRoot = os.TempDir()
+
+var ephemeralNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// ErrEphemeralWorkingDir is returned when a working dir is given along with an ephemeral one
+var ErrEphemeralWorkingDir = errors.New("workingDir and ephemeralCwd cannot be used together")
+
+func init() {
+	if dir := os.Getenv("SANDBOX_EPHEMERAL_DIR"); dir != "" {
+		ephemeralWorkingDirRoot = dir
+	}
+}
+
+// WithEphemeralWorkingDir runs the process in a new empty temp directory, removed
+// once the process is done for good (completed, stopped or out of restarts)
+func WithEphemeralWorkingDir(enabled bool) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.EphemeralCwd = enabled
+	}
+}
+
+// createEphemeralWorkingDir creates the unique working directory of a process
+func createEphemeralWorkingDir(name string) (string, error) {
+	if err := os.MkdirAll(ephemeralWorkingDirRoot, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(ephemeralWorkingDirRoot, ephemeralWorkingDirPrefix+ephemeralNameSanitizer.ReplaceAllString(name, "_")+"-")
+}
+
+// releaseEphemeralWorkingDir removes the ephemeral working directory of a finished process.
+// WorkingDir is kept so that the process still reports where it ran.
+func releaseEphemeralWorkingDir(process *ProcessInfo) {
+	if !process.EphemeralCwd || process.WorkingDir == "" {
+		return
+	}
+	// Never remove anything we didn't create, even from a tampered state file
+	if !strings.HasPrefix(filepath.Base(process.WorkingDir), ephemeralWorkingDirPrefix) {
+		return
+	}
+	if err := os.RemoveAll(process.WorkingDir); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"pid":         process.PID,
+			"name":        process.Name,
+			"working-dir": process.WorkingDir,
+		}).WithError(err).Warn("Failed to remove ephemeral working directory")
+	}
+}
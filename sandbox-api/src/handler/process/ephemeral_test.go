@@ -0,0 +1,42 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEphemeralWorkingDir(t *testing.T) {
+	original := ephemeralWorkingDirRoot
+	defer func() { ephemeralWorkingDirRoot = original }()
+	ephemeralWorkingDirRoot = t.TempDir()
+
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("pwd; touch scratch.txt", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithEphemeralWorkingDir(true))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+
+	proc, exists := pm.GetProcessByIdentifier(pid)
+	if !exists || !proc.EphemeralCwd || !strings.HasPrefix(proc.WorkingDir, ephemeralWorkingDirRoot) {
+		t.Fatalf("Expected the process to run in an ephemeral dir, got %+v", proc)
+	}
+	output, err := pm.GetProcessOutput(pid)
+	if err != nil || strings.TrimSpace(output.Stdout) != proc.WorkingDir {
+		t.Errorf("Expected the process cwd to be %s, got %q (%v)", proc.WorkingDir, output.Stdout, err)
+	}
+	if _, err := os.Stat(proc.WorkingDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the ephemeral dir to be removed on completion, got %v", err)
+	}
+
+	if _, err := pm.StartProcess("true", "/tmp", nil, false, 0, false, 0, nil, WithEphemeralWorkingDir(true)); !errors.Is(err, ErrEphemeralWorkingDir) {
+		t.Errorf("Expected ErrEphemeralWorkingDir with a working dir, got %v", err)
+	}
+}
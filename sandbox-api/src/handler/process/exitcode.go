@@ -0,0 +1,65 @@
+package process
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler/constants"
+)
+
+// Exit reasons of a process that is no longer running
+const (
+	ExitReasonExited   = "exited"   // Exited with code 0
+	ExitReasonFailed   = "failed"   // Exited with a non-zero code
+	ExitReasonSignaled = "signaled" // Terminated by a signal that was not sent through the API
+	ExitReasonStopped  = "stopped"  // Stopped through the API
+	ExitReasonKilled   = "killed"   // Killed through the API or by its keepAlive timeout
+)
+
+// ExitStatus is the outcome of a process: ExitCode and ExitReason are nil while it is running or queued
+type ExitStatus struct {
+	Status     constants.ProcessStatus
+	ExitCode   *int
+	ExitReason *string
+}
+
+// exitReason explains why a process with the given status and exit code is no longer running
+func exitReason(status constants.ProcessStatus, exitCode int) string {
+	switch status {
+	case StatusStopped:
+		return ExitReasonStopped
+	case StatusKilled:
+		return ExitReasonKilled
+	case StatusCompleted:
+		return ExitReasonExited
+	}
+	// ExitCode is -1 when the process was terminated by a signal
+	if exitCode < 0 {
+		return ExitReasonSignaled
+	}
+	return ExitReasonFailed
+}
+
+// GetExitStatus returns the exit status of a process. When wait is true, it first waits until
+// the process has ended for good (restarts on failure included) or ctx is done, in which case
+// the status of the still running process is returned.
+func (pm *ProcessManager) GetExitStatus(ctx context.Context, identifier string, wait bool) (ExitStatus, error) {
+	p, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return ExitStatus{}, fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+
+	if wait {
+		_ = pm.WatchStatus(ctx, p.PID, func(ProcessStatusEvent) {})
+	}
+
+	snapshot := pm.statusSnapshot(p, StatusEventStatus)
+	result := ExitStatus{Status: snapshot.Status}
+	if snapshot.Status != StatusRunning && snapshot.Status != StatusQueued {
+		exitCode := snapshot.ExitCode
+		reason := exitReason(snapshot.Status, exitCode)
+		result.ExitCode = &exitCode
+		result.ExitReason = &reason
+	}
+	return result, nil
+}
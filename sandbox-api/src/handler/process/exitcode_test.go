@@ -0,0 +1,58 @@
+package process
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler/constants"
+)
+
+func TestExitReason(t *testing.T) {
+	for _, tc := range []struct {
+		status   constants.ProcessStatus
+		exitCode int
+		want     string
+	}{
+		{StatusCompleted, 0, ExitReasonExited},
+		{StatusFailed, 2, ExitReasonFailed},
+		{StatusFailed, -1, ExitReasonSignaled},
+		{StatusStopped, -1, ExitReasonStopped},
+		{StatusKilled, -1, ExitReasonKilled},
+	} {
+		if got := exitReason(tc.status, tc.exitCode); got != tc.want {
+			t.Errorf("exitReason(%s, %d) = %s, want %s", tc.status, tc.exitCode, got, tc.want)
+		}
+	}
+}
+
+func TestGetExitStatus(t *testing.T) {
+	pm := GetProcessManager()
+
+	pid, err := pm.StartProcessWithName("sleep 0.3; exit 3", "", "exit-status-test", nil, false, 0, false, 0, func(p *ProcessInfo) {})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	status, err := pm.GetExitStatus(context.Background(), pid, false)
+	if err != nil {
+		t.Fatalf("Failed to get exit status: %v", err)
+	}
+	if status.Status != StatusRunning || status.ExitCode != nil || status.ExitReason != nil {
+		t.Errorf("Expected a running process without exit code, got %+v", status)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status, err = pm.GetExitStatus(ctx, pid, true)
+	if err != nil {
+		t.Fatalf("Failed to wait for exit status: %v", err)
+	}
+	if status.Status != StatusFailed || status.ExitCode == nil || *status.ExitCode != 3 || status.ExitReason == nil || *status.ExitReason != ExitReasonFailed {
+		t.Errorf("Expected failed with exit code 3, got %+v", status)
+	}
+
+	if _, err := pm.GetExitStatus(ctx, "does-not-exist", false); err == nil {
+		t.Error("Expected error for an unknown process")
+	}
+}
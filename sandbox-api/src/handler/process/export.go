@@ -0,0 +1,143 @@
+package process
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogArchiveEntry describes a process in the manifest of a log archive
+type LogArchiveEntry struct {
+	PID         string            `json:"pid"`
+	Name        string            `json:"name"`
+	Command     string            `json:"command"`
+	Status      string            `json:"status"`
+	StartedAt   time.Time         `json:"startedAt"`
+	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+	ExitCode    int               `json:"exitCode"`
+	WorkingDir  string            `json:"workingDir"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Files       []string          `json:"files"`
+}
+
+// LogArchiveManifest is written as manifest.json at the root of a log archive
+type LogArchiveManifest struct {
+	ExportedAt time.Time         `json:"exportedAt"`
+	Processes  []LogArchiveEntry `json:"processes"`
+}
+
+var unsafeArchiveChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// archiveLogSource is a single log file to add to the archive, read from disk
+// when possible and from the in-memory buffer otherwise
+type archiveLogSource struct {
+	name     string
+	path     string
+	fallback func() string
+}
+
+// WriteLogArchive streams a tar.gz archive to w containing, for each process, a
+// directory with its stdout, stderr and combined logs, plus a manifest.json
+// describing the processes. Log files are copied from disk without being
+// buffered in memory.
+func (pm *ProcessManager) WriteLogArchive(w io.Writer, processes []*ProcessInfo) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	manifest := LogArchiveManifest{ExportedAt: now, Processes: make([]LogArchiveEntry, 0, len(processes))}
+	sources := make(map[string][]archiveLogSource, len(processes))
+	for _, proc := range processes {
+		dir := proc.PID
+		if proc.Name != "" && proc.Name != proc.PID {
+			dir = proc.PID + "-" + strings.Trim(unsafeArchiveChars.ReplaceAllString(proc.Name, "_"), "_")
+		}
+
+		procSources := []archiveLogSource{
+			{name: dir + "/stdout.log", path: proc.StdoutFile, fallback: func() string { return proc.readBuffer(proc.stdout) }},
+			{name: dir + "/stderr.log", path: proc.StderrFile, fallback: func() string { return proc.readBuffer(proc.stderr) }},
+			{name: dir + "/combined.log", path: proc.LogFile, fallback: func() string { return proc.readBuffer(proc.logs) }},
+		}
+		sources[proc.PID] = procSources
+
+		entry := LogArchiveEntry{
+			PID:         proc.PID,
+			Name:        proc.Name,
+			Command:     proc.Command,
+			Status:      string(proc.Status),
+			StartedAt:   proc.StartedAt,
+			CompletedAt: proc.CompletedAt,
+			ExitCode:    proc.ExitCode,
+			WorkingDir:  proc.WorkingDir,
+			Labels:      proc.Labels,
+		}
+		for _, source := range procSources {
+			entry.Files = append(entry.Files, source.name)
+		}
+		manifest.Processes = append(manifest.Processes, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeArchiveBytes(tw, "manifest.json", data, now); err != nil {
+		return err
+	}
+
+	for _, proc := range processes {
+		for _, source := range sources[proc.PID] {
+			if err := writeArchiveLog(tw, source, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readBuffer returns the content of one of the process's in-memory log buffers
+func (p *ProcessInfo) readBuffer(buf *strings.Builder) string {
+	p.logLock.RLock()
+	defer p.logLock.RUnlock()
+	if buf == nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func writeArchiveLog(tw *tar.Writer, source archiveLogSource, modTime time.Time) error {
+	if source.path != "" {
+		if f, err := os.Open(source.path); err == nil {
+			defer func() { _ = f.Close() }()
+			if info, err := f.Stat(); err == nil {
+				// The file may still be growing: only copy what existed when the header was written
+				header := &tar.Header{Name: source.name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+				if err := tw.WriteHeader(header); err != nil {
+					return err
+				}
+				_, err := io.CopyN(tw, f, info.Size())
+				return err
+			}
+		}
+	}
+	return writeArchiveBytes(tw, source.name, []byte(source.fallback()), modTime)
+}
+
+func writeArchiveBytes(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,66 @@
+package process
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWriteLogArchive(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcessWithName("echo out; echo err >&2", "", "export test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	<-proc.TailDone
+
+	var buf bytes.Buffer
+	if err := pm.WriteLogArchive(&buf, []*ProcessInfo{proc}); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Archive is not gzipped: %v", err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		files[header.Name] = string(content)
+	}
+
+	dir := pid + "-export_test"
+	if files[dir+"/stdout.log"] != "out\n" {
+		t.Errorf("Unexpected stdout.log: %q", files[dir+"/stdout.log"])
+	}
+	if files[dir+"/stderr.log"] != "err\n" {
+		t.Errorf("Unexpected stderr.log: %q", files[dir+"/stderr.log"])
+	}
+
+	var manifest LogArchiveManifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	if len(manifest.Processes) != 1 || manifest.Processes[0].PID != pid || len(manifest.Processes[0].Files) != 3 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+}
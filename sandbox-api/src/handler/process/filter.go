@@ -0,0 +1,36 @@
+package process
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CommandFilter reports whether a process command matches a filter
+type CommandFilter func(command string) bool
+
+// ParseCommandFilter builds a filter matching commands that contain pattern,
+// or that match it as a regular expression when regex is set
+func ParseCommandFilter(pattern string, regex bool) (CommandFilter, error) {
+	if !regex {
+		return func(command string) bool {
+			return strings.Contains(command, pattern)
+		}, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command pattern %q: %w", pattern, err)
+	}
+	return re.MatchString, nil
+}
+
+// FilterProcessesByCommand returns the processes whose command matches the filter
+func FilterProcessesByCommand(processes []*ProcessInfo, filter CommandFilter) []*ProcessInfo {
+	result := make([]*ProcessInfo, 0, len(processes))
+	for _, process := range processes {
+		if filter(process.Command) {
+			result = append(result, process)
+		}
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+package process
+
+import "testing"
+
+func TestParseCommandFilter(t *testing.T) {
+	processes := []*ProcessInfo{
+		{Name: "web", Command: "npm run dev"},
+		{Name: "build", Command: "npx tsc --watch"},
+		{Name: "api", Command: "python -m http.server"},
+	}
+
+	filter, err := ParseCommandFilter("npm", false)
+	if err != nil {
+		t.Fatalf("ParseCommandFilter failed: %v", err)
+	}
+	if got := FilterProcessesByCommand(processes, filter); len(got) != 1 || got[0].Name != "web" {
+		t.Errorf("Expected only web to match, got %v", got)
+	}
+
+	filter, err = ParseCommandFilter(`^np[mx]\b`, true)
+	if err != nil {
+		t.Fatalf("ParseCommandFilter failed: %v", err)
+	}
+	if got := FilterProcessesByCommand(processes, filter); len(got) != 2 {
+		t.Errorf("Expected web and build to match, got %v", got)
+	}
+
+	// Regex characters are literal without regex
+	filter, _ = ParseCommandFilter("http.server", false)
+	if got := FilterProcessesByCommand(processes, filter); len(got) != 1 || got[0].Name != "api" {
+		t.Errorf("Expected only api to match, got %v", got)
+	}
+	filter, _ = ParseCommandFilter("^npm", false)
+	if got := FilterProcessesByCommand(processes, filter); len(got) != 0 {
+		t.Errorf("Expected no match for a literal ^, got %v", got)
+	}
+
+	if _, err := ParseCommandFilter("npm(", true); err == nil {
+		t.Errorf("Expected an error for an invalid regex")
+	}
+}
@@ -0,0 +1,72 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ErrProcessIDExists is returned when a client-provided process id is already in use
+var ErrProcessIDExists = errors.New("a process with this id already exists")
+
+// ErrProcessNameExists is returned when a process with the requested name is already running
+var ErrProcessNameExists = errors.New("a process with this name is already running")
+
+var processIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+// ValidateProcessID checks a client-provided process id. Purely numeric ids are rejected
+// so they can't collide with generated PIDs or be mistaken for OS process ids.
+func ValidateProcessID(id string) error {
+	if !processIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid process id %q: use up to 128 letters, digits, '_', '-' or '.', starting with a letter or digit", id)
+	}
+	if _, err := strconv.Atoi(id); err == nil {
+		return fmt.Errorf("invalid process id %q: ids cannot be purely numeric", id)
+	}
+	return nil
+}
+
+// WithID uses a client-provided id as the process PID instead of the generated one,
+// making process creation idempotent: starting a second process with the same id fails
+// with ErrProcessIDExists
+func WithID(id string) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.PID = id
+	}
+}
+
+// ProcessIDExists reports whether a process (running or not) uses the given PID or id
+func (pm *ProcessManager) ProcessIDExists(id string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	_, exists := pm.processes[id]
+	return exists
+}
+
+// ReserveName checks that no running process uses name and reserves it until release is
+// called, once the process is started. Concurrent requests for the same name can't both
+// pass the check. An empty name reserves nothing.
+func (pm *ProcessManager) ReserveName(name string) (release func(), err error) {
+	if name == "" {
+		return func() {}, nil
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if pm.names[name] {
+		return nil, fmt.Errorf("%w: '%s'", ErrProcessNameExists, name)
+	}
+	for _, p := range pm.processes {
+		if (p.Name == name || p.PID == name) && p.Status == StatusRunning {
+			return nil, fmt.Errorf("%w: '%s'", ErrProcessNameExists, name)
+		}
+	}
+	pm.names[name] = true
+
+	return func() {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		delete(pm.names, name)
+	}, nil
+}
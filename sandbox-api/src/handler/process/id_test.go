@@ -0,0 +1,88 @@
+package process
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateProcessID(t *testing.T) {
+	for _, id := range []string{"build-42", "a", "job_1.retry"} {
+		if err := ValidateProcessID(id); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "1234", "-x", "a/b", "with space"} {
+		if err := ValidateProcessID(id); err == nil {
+			t.Errorf("Expected %q to be rejected", id)
+		}
+	}
+}
+
+func TestStartProcessWithID(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcessWithName("sleep 30", "", "custom-id-test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithID("custom-id-test-1"))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = pm.KillProcess(pid) }()
+
+	if pid != "custom-id-test-1" {
+		t.Fatalf("Expected the client id to be used as pid, got %q", pid)
+	}
+	proc, exists := pm.GetProcessByIdentifier("custom-id-test-1")
+	if !exists || proc.ProcessPid <= 0 {
+		t.Fatalf("Expected the process to be found by id with its OS pid, got %+v", proc)
+	}
+
+	_, err = pm.StartProcessWithName("true", "", "custom-id-test-dup", nil, false, 0, false, 0, nil, WithID("custom-id-test-1"))
+	if !errors.Is(err, ErrProcessIDExists) {
+		t.Errorf("Expected ErrProcessIDExists, got %v", err)
+	}
+	if proc, _ := pm.GetProcessByIdentifier("custom-id-test-1"); proc.Name != "custom-id-test" {
+		t.Errorf("The existing process must not be replaced, got %q", proc.Name)
+	}
+
+	if err := pm.KillProcess(pid); err != nil {
+		t.Fatalf("Failed to kill process: %v", err)
+	}
+	<-done
+}
+
+func TestReserveName(t *testing.T) {
+	pm := NewProcessManager()
+
+	release, err := pm.ReserveName("reserve-test")
+	if err != nil {
+		t.Fatalf("Failed to reserve the name: %v", err)
+	}
+	if _, err := pm.ReserveName("reserve-test"); !errors.Is(err, ErrProcessNameExists) {
+		t.Errorf("Expected ErrProcessNameExists while reserved, got %v", err)
+	}
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcessWithName("sleep 30", "", "reserve-test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	release()
+	if _, err := pm.ReserveName("reserve-test"); !errors.Is(err, ErrProcessNameExists) {
+		t.Errorf("Expected ErrProcessNameExists while running, got %v", err)
+	}
+
+	if err := pm.KillProcess(pid); err != nil {
+		t.Fatalf("Failed to kill process: %v", err)
+	}
+	<-done
+	release, err = pm.ReserveName("reserve-test")
+	if err != nil {
+		t.Errorf("Expected the name to be free once the process ended, got %v", err)
+	} else {
+		release()
+	}
+}
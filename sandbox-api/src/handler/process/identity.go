@@ -0,0 +1,62 @@
+package process
+
+import (
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// identityEnvVar is an env var injected in every started process
+type identityEnvVar struct {
+	name   string
+	value  string
+	forced bool // Overrides a custom value of the same name
+}
+
+// identityEnv are env vars injected in every process the API starts, so that downstream tools
+// and logs can attribute their activity to the sandbox. Configured via SANDBOX_PROCESS_ENV, a
+// comma-separated list of NAME=VALUE pairs where values can reference the API's environment
+// (SANDBOX_ID=${BL_NAME}). They yield to env vars of the same name given when starting a process,
+// unless written !NAME=VALUE.
+var identityEnv []identityEnvVar
+
+func init() {
+	identityEnv = parseIdentityEnv(os.Getenv("SANDBOX_PROCESS_ENV"))
+}
+
+func parseIdentityEnv(value string) []identityEnvVar {
+	var vars []identityEnvVar
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(pair, "=")
+		forced := strings.HasPrefix(name, "!")
+		name = strings.TrimSpace(strings.TrimPrefix(name, "!"))
+		if !ok || name == "" {
+			logrus.Warnf("Invalid SANDBOX_PROCESS_ENV entry %q, expected NAME=VALUE", pair)
+			continue
+		}
+		vars = append(vars, identityEnvVar{name: name, value: os.ExpandEnv(val), forced: forced})
+	}
+	return vars
+}
+
+// withIdentityEnv returns custom with the identity env vars added, leaving custom untouched
+func withIdentityEnv(custom map[string]string) map[string]string {
+	if len(identityEnv) == 0 {
+		return custom
+	}
+	merged := make(map[string]string, len(custom)+len(identityEnv))
+	for k, v := range custom {
+		merged[k] = v
+	}
+	for _, v := range identityEnv {
+		if _, exists := custom[v.name]; !exists || v.forced {
+			merged[v.name] = v.value
+		}
+	}
+	return merged
+}
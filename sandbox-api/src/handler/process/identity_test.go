@@ -0,0 +1,31 @@
+package process
+
+import "testing"
+
+func TestIdentityEnv(t *testing.T) {
+	t.Setenv("IDENTITY_TEST_NAME", "sandbox-1")
+	saved := identityEnv
+	defer func() { identityEnv = saved }()
+
+	identityEnv = parseIdentityEnv("SANDBOX_ID=${IDENTITY_TEST_NAME}, SANDBOX_WORKSPACE=acme, !SANDBOX_TENANT=t-1, invalid, =empty")
+	if len(identityEnv) != 3 {
+		t.Fatalf("Expected 3 identity env vars, got %+v", identityEnv)
+	}
+
+	env := PreviewEnv(map[string]string{"SANDBOX_WORKSPACE": "custom", "SANDBOX_TENANT": "custom"})
+	for key, want := range map[string]string{
+		"SANDBOX_ID":        "sandbox-1",
+		"SANDBOX_WORKSPACE": "custom", // Custom values win
+		"SANDBOX_TENANT":    "t-1",    // unless the identity env var is forced
+	} {
+		if got := env[key]; got != want {
+			t.Errorf("env %s: expected %q, got %q", key, want, got)
+		}
+	}
+
+	custom := map[string]string{"APP": "1"}
+	withIdentityEnv(custom)
+	if len(custom) != 1 {
+		t.Errorf("Expected the custom env to be left untouched, got %v", custom)
+	}
+}
@@ -0,0 +1,153 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrInvalidExcludePid is returned when a pid spared from a kill is not a child in the process group
+var ErrInvalidExcludePid = errors.New("invalid excludePids")
+
+// processGroupMembers returns the processes of the process group pgid, mapped to their parent pid
+func processGroupMembers(pgid int) (map[int]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[int]int)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue // The process exited while scanning
+		}
+		// Format: "pid (comm) state ppid pgrp ...", comm may contain spaces and parentheses
+		stat := string(data)
+		end := strings.LastIndex(stat, ")")
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 3 || fields[0] == "Z" {
+			continue
+		}
+		if group, err := strconv.Atoi(fields[2]); err != nil || group != pgid {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			members[pid] = ppid
+		}
+	}
+	return members, nil
+}
+
+// sparedPids checks that the excludePids are members of the process group led by pid and
+// returns them as the initial set of spared processes. The leader can't be excluded.
+func sparedPids(pid int, excludePids []int) (map[int]bool, error) {
+	members, err := processGroupMembers(pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the process group: %w", err)
+	}
+
+	spared := make(map[int]bool, len(excludePids))
+	for _, excluded := range excludePids {
+		if excluded == pid {
+			return nil, fmt.Errorf("%w: %d is the process itself", ErrInvalidExcludePid, excluded)
+		}
+		if _, ok := members[excluded]; !ok {
+			return nil, fmt.Errorf("%w: %d is not running in the process group", ErrInvalidExcludePid, excluded)
+		}
+		spared[excluded] = true
+	}
+	return spared, nil
+}
+
+// killTargets returns the members of the process group led by pid that are not spared:
+// the spared pids and their descendants survive. Descendants are added to spared, so that
+// they stay spared once their parent is gone and they are re-parented.
+func killTargets(pid int, spared map[int]bool) ([]int, error) {
+	members, err := processGroupMembers(pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the process group: %w", err)
+	}
+
+	isSpared := func(member int) bool {
+		for current := member; current != pid; {
+			if spared[current] {
+				return true
+			}
+			parent, ok := members[current]
+			if !ok {
+				return false
+			}
+			current = parent
+		}
+		return false
+	}
+
+	targets := []int{pid}
+	for member := range members {
+		if member == pid {
+			continue
+		}
+		if isSpared(member) {
+			spared[member] = true
+		} else {
+			targets = append(targets, member)
+		}
+	}
+	return targets, nil
+}
+
+// killEach sends SIGKILL to each pid, ignoring the ones that already exited
+func killEach(pids []int) error {
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+	return nil
+}
+
+// killGroupExcept kills the members of the process group led by pid that are not spared.
+// The group is listed again until no new member shows up, so that a child forked while
+// the others were being killed doesn't escape.
+func killGroupExcept(pid int, spared map[int]bool) error {
+	killed := make(map[int]bool)
+	for {
+		targets, err := killTargets(pid, spared)
+		if err != nil {
+			return err
+		}
+		var fresh []int
+		for _, target := range targets {
+			if !killed[target] {
+				killed[target] = true
+				fresh = append(fresh, target)
+			}
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+		if err := killEach(fresh); err != nil {
+			return err
+		}
+	}
+}
+
+// KillProcessExcept forcefully kills a process and its process group, except the
+// excludePids children and their descendants. Once their parent is dead, spared
+// processes are re-parented and are not managed anymore: they are not listed with
+// the other processes and, when they exit, are only collected by ReapZombies.
+func (pm *ProcessManager) KillProcessExcept(identifier string, excludePids []int) error {
+	return pm.killProcess(identifier, excludePids)
+}
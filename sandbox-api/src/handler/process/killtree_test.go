@@ -0,0 +1,128 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestKillProcessExcept(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	name := fmt.Sprintf("kill-except-test-%d", time.Now().UnixNano())
+	pid, err := pm.StartProcessWithName("sleep 30 & echo $!; sleep 30 & wait", "", name, nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = pm.KillProcess(pid) }()
+
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	var daemon int
+	deadline := time.Now().Add(5 * time.Second)
+	for daemon == 0 && time.Now().Before(deadline) {
+		daemon, _ = strconv.Atoi(strings.TrimSpace(proc.readBuffer(proc.stdout)))
+		time.Sleep(20 * time.Millisecond)
+	}
+	if daemon == 0 {
+		t.Fatal("The child pid was not printed")
+	}
+	defer func() { _ = syscall.Kill(daemon, syscall.SIGKILL) }()
+
+	if err := pm.KillProcessExcept(pid, []int{proc.ProcessPid}); !errors.Is(err, ErrInvalidExcludePid) {
+		t.Errorf("Expected ErrInvalidExcludePid when excluding the process itself, got %v", err)
+	}
+	if err := pm.KillProcessExcept(pid, []int{1}); !errors.Is(err, ErrInvalidExcludePid) {
+		t.Errorf("Expected ErrInvalidExcludePid for a pid outside the group, got %v", err)
+	}
+
+	if err := pm.KillProcessExcept(pid, []int{daemon}); err != nil {
+		t.Fatalf("KillProcessExcept failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The process was not killed")
+	}
+
+	if err := syscall.Kill(daemon, 0); err != nil {
+		t.Errorf("Expected the excluded child to survive, got %v", err)
+	}
+	// The other child was killed with the rest of the group
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		members, err := processGroupMembers(proc.ProcessPid)
+		if err != nil {
+			t.Fatalf("Failed to list the process group: %v", err)
+		}
+		if len(members) == 1 && members[daemon] != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected only %d to be left in the group, got %v", daemon, members)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestKillProcessExceptForkingGroup kills a group whose leader keeps forking children
+// and checks that none of them escapes, while the excluded child survives
+func TestKillProcessExceptForkingGroup(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	name := fmt.Sprintf("kill-except-fork-test-%d", time.Now().UnixNano())
+	pid, err := pm.StartProcessWithName("sleep 30 & echo $!; while true; do sleep 30 & sleep 0.005; done", "", name, nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = pm.KillProcess(pid) }()
+
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	var daemon int
+	deadline := time.Now().Add(5 * time.Second)
+	for daemon == 0 && time.Now().Before(deadline) {
+		daemon, _ = strconv.Atoi(strings.TrimSpace(proc.readBuffer(proc.stdout)))
+		time.Sleep(20 * time.Millisecond)
+	}
+	if daemon == 0 {
+		t.Fatal("The child pid was not printed")
+	}
+	defer func() { _ = syscall.Kill(daemon, syscall.SIGKILL) }()
+	time.Sleep(200 * time.Millisecond)
+
+	if err := pm.KillProcessExcept(pid, []int{daemon}); err != nil {
+		t.Fatalf("KillProcessExcept failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The process was not killed")
+	}
+
+	if err := syscall.Kill(daemon, 0); err != nil {
+		t.Errorf("Expected the excluded child to survive, got %v", err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		members, err := processGroupMembers(proc.ProcessPid)
+		if err != nil {
+			t.Fatalf("Failed to list the process group: %v", err)
+		}
+		if len(members) == 1 && members[daemon] != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected only %d to be left in the group, got %d members", daemon, len(members))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
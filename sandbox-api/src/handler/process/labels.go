@@ -0,0 +1,67 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithLabels attaches user-defined labels to a process
+func WithLabels(labels map[string]string) ProcessOption {
+	return func(p *ProcessInfo) {
+		if len(labels) == 0 {
+			return
+		}
+		p.Labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			p.Labels[k] = v
+		}
+	}
+}
+
+// ParseLabelSelector parses a selector of the form "key=value,key2=value2".
+// A bare key (without "=") matches any process that has the label, whatever its value.
+func ParseLabelSelector(selector string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid label selector %q: empty key", part)
+		}
+		result[key] = strings.TrimSpace(value)
+	}
+	return result, nil
+}
+
+// MatchesLabels reports whether labels satisfy every entry of the selector.
+// An empty selector value only requires the key to be present.
+func MatchesLabels(labels map[string]string, selector map[string]string) bool {
+	for key, want := range selector {
+		got, ok := labels[key]
+		if !ok {
+			return false
+		}
+		if want != "" && got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// ListProcessesByLabels returns the processes whose labels match the selector
+func (pm *ProcessManager) ListProcessesByLabels(selector map[string]string) []*ProcessInfo {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	processes := make([]*ProcessInfo, 0)
+	for _, process := range pm.processes {
+		if MatchesLabels(process.Labels, selector) {
+			processes = append(processes, process)
+		}
+	}
+	return processes
+}
@@ -0,0 +1,96 @@
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	testCases := []struct {
+		selector  string
+		expected  map[string]string
+		shouldErr bool
+	}{
+		{"app=web", map[string]string{"app": "web"}, false},
+		{"app=web, tier=api", map[string]string{"app": "web", "tier": "api"}, false},
+		{"app", map[string]string{"app": ""}, false},
+		{"", map[string]string{}, false},
+		{"=web", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := ParseLabelSelector(tc.selector)
+			if tc.shouldErr {
+				if err == nil {
+					t.Errorf("Expected error for selector %q, got none", tc.selector)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error for selector %q: %v", tc.selector, err)
+			}
+			if len(selector) != len(tc.expected) {
+				t.Fatalf("Expected %d entries, got %d (%v)", len(tc.expected), len(selector), selector)
+			}
+			for k, v := range tc.expected {
+				if selector[k] != v {
+					t.Errorf("Expected %s=%s, got %s=%s", k, v, k, selector[k])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesLabels(t *testing.T) {
+	labels := map[string]string{"app": "web", "tier": "frontend"}
+
+	testCases := []struct {
+		name     string
+		selector map[string]string
+		expected bool
+	}{
+		{"empty selector", map[string]string{}, true},
+		{"exact match", map[string]string{"app": "web"}, true},
+		{"multiple keys", map[string]string{"app": "web", "tier": "frontend"}, true},
+		{"key only", map[string]string{"tier": ""}, true},
+		{"value mismatch", map[string]string{"app": "api"}, false},
+		{"missing key", map[string]string{"env": "dev"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := MatchesLabels(labels, tc.selector); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestStartProcessWithLabels(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("echo labelled", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithLabels(map[string]string{"app": "labels-test"}))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+
+	matches := pm.ListProcessesByLabels(map[string]string{"app": "labels-test"})
+	found := false
+	for _, p := range matches {
+		if p.PID == pid {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected process %s to match label selector", pid)
+	}
+
+	if len(pm.ListProcessesByLabels(map[string]string{"app": "other"})) != 0 {
+		t.Error("Expected no process to match a different label value")
+	}
+}
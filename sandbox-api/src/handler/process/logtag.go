@@ -0,0 +1,32 @@
+package process
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var logTagPattern = regexp.MustCompile(`^[^\s\[\]]{1,32}$`)
+
+// ValidateLogTag checks a log tag: up to 32 characters, without whitespace or brackets
+func ValidateLogTag(tag string) error {
+	if !logTagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid log tag %q: use up to 32 characters, without whitespace or brackets", tag)
+	}
+	return nil
+}
+
+// WithLogTag sets the short tag prefixing the process's lines in multiplexed log streams
+func WithLogTag(tag string) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.LogTag = tag
+	}
+}
+
+// LogPrefix returns the tag identifying the process in multiplexed log streams:
+// its log tag, or its name when none was set
+func (p *ProcessInfo) LogPrefix() string {
+	if p.LogTag != "" {
+		return p.LogTag
+	}
+	return p.Name
+}
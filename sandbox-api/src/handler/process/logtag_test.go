@@ -0,0 +1,27 @@
+package process
+
+import "testing"
+
+func TestValidateLogTag(t *testing.T) {
+	for _, tag := range []string{"api", "web-1", "db:primary"} {
+		if err := ValidateLogTag(tag); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "with space", "[api]", "line\nbreak", "a-tag-that-is-way-too-long-to-be-readable"} {
+		if err := ValidateLogTag(tag); err == nil {
+			t.Errorf("Expected %q to be rejected", tag)
+		}
+	}
+}
+
+func TestLogPrefix(t *testing.T) {
+	p := &ProcessInfo{Name: "my-process"}
+	if got := p.LogPrefix(); got != "my-process" {
+		t.Errorf("Expected the name without a tag, got %q", got)
+	}
+	WithLogTag("api")(p)
+	if got := p.LogPrefix(); got != "api" {
+		t.Errorf("Expected the log tag, got %q", got)
+	}
+}
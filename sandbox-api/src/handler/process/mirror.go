@@ -0,0 +1,64 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileLogSink appends a process's log events to a file, with the same "stdout:"/"stderr:"
+// prefixes as the combined log file
+type fileLogSink struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// Write appends data to the file. Keepalives are only meant for network clients and are dropped.
+func (s *fileLogSink) Write(data []byte) (int, error) {
+	if string(data) == "[keepalive]\n" {
+		return len(data), nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Write(data)
+}
+
+// MirrorLogsToFile attaches a file sink to a process's log stream, next to any other
+// subscriber. The file receives the output produced so far, then the live output until
+// the process exits. Existing content of the file is kept and appended to.
+func (pm *ProcessManager) MirrorLogsToFile(identifier string, path string) error {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+	if process.Status != StatusRunning {
+		return fmt.Errorf("process with Identifier %s is not running", identifier)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	sink := &fileLogSink{file: file}
+	if err := pm.StreamProcessOutput(process.PID, sink); err != nil {
+		file.Close()
+		return err
+	}
+
+	go func() {
+		<-process.Done
+		// Wait for tailLogFiles to complete its final reads
+		<-process.TailDone
+		_ = pm.RemoveLogWriter(process.PID, sink)
+		sink.mu.Lock()
+		file.Close()
+		sink.mu.Unlock()
+	}()
+
+	return nil
+}
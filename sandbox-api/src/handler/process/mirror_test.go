@@ -0,0 +1,74 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLateSubscriberGetsBacklog attaches a second writer and a file mirror to a running
+// process and checks that both receive the earlier output followed by the live tail.
+func TestLateSubscriberGetsBacklog(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("echo first; sleep 1; echo second", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	first := &testWriter{}
+	if err := pm.StreamProcessOutput(pid, first); err != nil {
+		t.Fatalf("Failed to attach first writer: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(first.String(), "first") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(first.String(), "first") {
+		t.Fatalf("First writer never received output, got %q", first.String())
+	}
+
+	second := &testWriter{}
+	if err := pm.StreamProcessOutput(pid, second); err != nil {
+		t.Fatalf("Failed to attach second writer: %v", err)
+	}
+	mirrorPath := filepath.Join(t.TempDir(), "logs", "mirror.log")
+	if err := pm.MirrorLogsToFile(pid, mirrorPath); err != nil {
+		t.Fatalf("Failed to mirror logs: %v", err)
+	}
+
+	waitForProcessDone(t, done, 5*time.Second)
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	<-proc.TailDone
+	_ = pm.RemoveLogWriter(pid, first)
+	_ = pm.RemoveLogWriter(pid, second)
+
+	want := "stdout:first\nstdout:second\n"
+	for name, got := range map[string]string{"first": first.String(), "second": second.String()} {
+		if got != want {
+			t.Errorf("%s writer: expected %q, got %q", name, want, got)
+		}
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	var mirrored []byte
+	for time.Now().Before(deadline) {
+		mirrored, _ = os.ReadFile(mirrorPath)
+		if string(mirrored) == want {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if string(mirrored) != want {
+		t.Errorf("mirror file: expected %q, got %q", want, mirrored)
+	}
+
+	if err := pm.MirrorLogsToFile(pid, mirrorPath); err == nil {
+		t.Error("Expected an error when mirroring a finished process")
+	}
+}
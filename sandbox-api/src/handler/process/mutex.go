@@ -0,0 +1,233 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler/constants"
+)
+
+// Policies applied when a process is started while its mutex group is busy
+const (
+	MutexPolicyQueue  = "queue"  // Start it once the other processes of the group ended
+	MutexPolicyReject = "reject" // Fail with ErrMutexGroupBusy
+)
+
+// ErrMutexGroupBusy is returned when a process of the mutex group is already running
+var ErrMutexGroupBusy = errors.New("a process of this mutex group is already running")
+
+// ErrProcessNotQueued is returned when a queued process was started or cancelled meanwhile
+var ErrProcessNotQueued = errors.New("process is no longer queued")
+
+// mutexGroup tracks the process running for a mutex group and the starts waiting for it
+type mutexGroup struct {
+	queue []*queuedStart
+}
+
+// queuedStart is a process waiting for its mutex group
+type queuedStart struct {
+	process  *ProcessInfo // Listed with the queued status until it is started
+	callback func(process *ProcessInfo)
+	start    func(opts ...ProcessOption) (string, error)
+	opts     []ProcessOption
+}
+
+// ValidateMutexPolicy checks the policy of a mutex group, empty meaning queue
+func ValidateMutexPolicy(policy string) error {
+	switch policy {
+	case "", MutexPolicyQueue, MutexPolicyReject:
+		return nil
+	default:
+		return fmt.Errorf("invalid mutexPolicy '%s', must be queue or reject", policy)
+	}
+}
+
+// WithMutexGroup only runs the process once no other process of the group is running. When
+// one is, the process is queued until the processes started before it ended for good, or
+// rejected with ErrMutexGroupBusy with the reject policy.
+func WithMutexGroup(group string, policy string) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.MutexGroup = group
+		p.MutexPolicy = policy
+	}
+}
+
+// startInMutexGroup starts a process with start when its mutex group is free (or it has
+// none), or queues it. A queued process is listed through the queued placeholder, under its
+// client-provided id or a generated one that the process keeps once started.
+func (pm *ProcessManager) startInMutexGroup(queued *ProcessInfo, callback func(process *ProcessInfo), start func(opts ...ProcessOption) (string, error), opts []ProcessOption) (string, error) {
+	for _, opt := range opts {
+		opt(queued)
+	}
+	group := queued.MutexGroup
+	if group == "" {
+		return start(opts...)
+	}
+
+	pm.mutexMu.Lock()
+	if g, busy := pm.mutexGroups[group]; busy {
+		if queued.MutexPolicy == MutexPolicyReject {
+			pm.mutexMu.Unlock()
+			return "", fmt.Errorf("%w: %s", ErrMutexGroupBusy, group)
+		}
+		if queued.PID == "" {
+			queued.PID = GenerateRandomName(8)
+		}
+		pm.mu.Lock()
+		if _, exists := pm.processes[queued.PID]; exists {
+			pm.mu.Unlock()
+			pm.mutexMu.Unlock()
+			return "", fmt.Errorf("%w: %s", ErrProcessIDExists, queued.PID)
+		}
+		queued.Status = StatusQueued
+		queued.StartedAt = time.Now()
+		queued.Done = make(chan struct{})
+		queued.TailDone = make(chan struct{})
+		queued.stdout = &strings.Builder{}
+		queued.stderr = &strings.Builder{}
+		queued.logs = &strings.Builder{}
+		pm.processes[queued.PID] = queued
+		pm.mu.Unlock()
+
+		g.queue = append(g.queue, &queuedStart{
+			process:  queued,
+			callback: callback,
+			start:    start,
+			opts:     append(append([]ProcessOption{}, opts...), WithID(queued.PID)),
+		})
+		pm.mutexMu.Unlock()
+
+		logrus.WithFields(logrus.Fields{
+			"pid":         queued.PID,
+			"name":        queued.Name,
+			"mutex-group": group,
+		}).Info("Process queued until its mutex group is free")
+		return queued.PID, nil
+	}
+	pm.mutexGroups[group] = &mutexGroup{}
+	pm.mutexMu.Unlock()
+
+	pid, err := start(opts...)
+	if err != nil {
+		pm.leaveMutexGroup(group)
+	}
+	return pid, err
+}
+
+// holdMutexGroup marks the mutex group of a process adopted after an API restart as busy
+func (pm *ProcessManager) holdMutexGroup(group string) {
+	if group == "" {
+		return
+	}
+	pm.mutexMu.Lock()
+	defer pm.mutexMu.Unlock()
+	if _, busy := pm.mutexGroups[group]; !busy {
+		pm.mutexGroups[group] = &mutexGroup{}
+	}
+}
+
+// leaveMutexGroup is called once the process holding a mutex group ended for good: the next
+// queued process is started, or the group is freed when none is left
+func (pm *ProcessManager) leaveMutexGroup(group string) {
+	if group == "" {
+		return
+	}
+	for {
+		pm.mutexMu.Lock()
+		g, busy := pm.mutexGroups[group]
+		if !busy {
+			pm.mutexMu.Unlock()
+			return
+		}
+		if len(g.queue) == 0 {
+			delete(pm.mutexGroups, group)
+			pm.mutexMu.Unlock()
+			return
+		}
+		next := g.queue[0]
+		g.queue = g.queue[1:]
+		pm.mutexMu.Unlock()
+
+		// The process is started under the same id and takes the place of the placeholder
+		_, err := next.start(append(next.opts, adoptQueued(next.process))...)
+		if err == nil {
+			return
+		}
+		logrus.WithFields(logrus.Fields{
+			"pid":         next.process.PID,
+			"name":        next.process.Name,
+			"mutex-group": group,
+		}).WithError(err).Warn("Failed to start queued process")
+		failureMsg := fmt.Sprintf("[Failed to start process: %v]\n", err)
+		next.process.logLock.Lock()
+		next.process.stderr.WriteString(failureMsg)
+		next.process.logs.WriteString(failureMsg)
+		next.process.logLock.Unlock()
+		pm.endQueued(next, StatusFailed)
+	}
+}
+
+// adoptQueued makes the started process take the place of the queued placeholder. It shares
+// its channels, so that waits opened while it was queued follow it.
+func adoptQueued(queued *ProcessInfo) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.queued = queued
+		p.Done = queued.Done
+		p.TailDone = queued.TailDone
+	}
+}
+
+// handOver moves the log writers of a queued placeholder to the process started for it. Log
+// streams opened on the placeholder afterwards are redirected through startedAs. The caller
+// must hold pm.mu, under which the process replaces the placeholder.
+func (queued *ProcessInfo) handOver(p *ProcessInfo) {
+	queued.logLock.Lock()
+	defer queued.logLock.Unlock()
+	p.logLock.Lock()
+	defer p.logLock.Unlock()
+
+	p.logWriters = append(p.logWriters, queued.logWriters...)
+	queued.logWriters = nil
+	queued.startedAs = p
+}
+
+// cancelQueued removes a queued process from its mutex group, ending it with status
+func (pm *ProcessManager) cancelQueued(process *ProcessInfo, status constants.ProcessStatus) error {
+	pm.mutexMu.Lock()
+	var cancelled *queuedStart
+	if g, busy := pm.mutexGroups[process.MutexGroup]; busy {
+		for i, q := range g.queue {
+			if q.process == process {
+				cancelled = q
+				g.queue = append(g.queue[:i], g.queue[i+1:]...)
+				break
+			}
+		}
+	}
+	pm.mutexMu.Unlock()
+
+	if cancelled == nil {
+		return fmt.Errorf("%w: %s", ErrProcessNotQueued, process.PID)
+	}
+	pm.endQueued(cancelled, status)
+	return nil
+}
+
+// endQueued records the end of a process that never left the queue
+func (pm *ProcessManager) endQueued(q *queuedStart, status constants.ProcessStatus) {
+	now := time.Now()
+	pm.mu.Lock()
+	q.process.Status = status
+	q.process.CompletedAt = &now
+	q.process.ExitCode = -1
+	pm.processes[q.process.PID] = q.process
+	pm.mu.Unlock()
+
+	close(q.process.Done)
+	close(q.process.TailDone)
+	q.callback(q.process)
+}
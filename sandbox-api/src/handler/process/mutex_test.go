@@ -0,0 +1,204 @@
+package process
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMutexGroup(t *testing.T) {
+	pm := NewProcessManager()
+
+	firstDone := make(chan struct{})
+	first, err := pm.StartProcessWithName("sleep 0.3", "", "mutex-first", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(firstDone)
+	}, WithMutexGroup("install", ""))
+	if err != nil {
+		t.Fatalf("failed to start the first process: %v", err)
+	}
+
+	if _, err := pm.StartProcessWithName("echo rejected", "", "mutex-rejected", nil, false, 0, false, 0, func(p *ProcessInfo) {},
+		WithMutexGroup("install", MutexPolicyReject)); !errors.Is(err, ErrMutexGroupBusy) {
+		t.Fatalf("expected ErrMutexGroupBusy, got %v", err)
+	}
+
+	secondDone := make(chan *ProcessInfo, 1)
+	second, err := pm.StartProcessWithName("echo second", "", "mutex-second", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		secondDone <- p
+	}, WithMutexGroup("install", MutexPolicyQueue))
+	if err != nil {
+		t.Fatalf("failed to queue the second process: %v", err)
+	}
+	proc, exists := pm.GetProcessByIdentifier(second)
+	if !exists || proc.Status != StatusQueued {
+		t.Fatalf("expected the second process to be queued, got %+v", proc)
+	}
+
+	// Another group is not affected
+	otherDone := make(chan struct{})
+	if _, err := pm.StartProcessWithName("true", "", "mutex-other", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(otherDone)
+	}, WithMutexGroup("build", MutexPolicyReject)); err != nil {
+		t.Fatalf("expected another group to be free, got %v", err)
+	}
+	<-otherDone
+
+	select {
+	case <-firstDone:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", first)
+	}
+	select {
+	case p := <-secondDone:
+		if p.PID != second {
+			t.Errorf("expected the queued process to keep id %s, got %s", second, p.PID)
+		}
+		if p.Status != StatusCompleted {
+			t.Errorf("expected the queued process to complete, got %s", p.Status)
+		}
+		if p.ProcessPid == 0 {
+			t.Error("expected the queued process to have been started")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queued process")
+	}
+
+	// The group is free again
+	done := make(chan struct{})
+	if _, err := pm.StartProcessWithName("true", "", "mutex-after", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithMutexGroup("install", MutexPolicyReject)); err != nil {
+		t.Fatalf("expected the group to be free, got %v", err)
+	}
+	<-done
+}
+
+func TestMutexGroupKillQueued(t *testing.T) {
+	pm := NewProcessManager()
+
+	firstDone := make(chan struct{})
+	first, err := pm.StartProcessWithName("sleep 30", "", "mutex-running", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(firstDone)
+	}, WithMutexGroup("install", ""))
+	if err != nil {
+		t.Fatalf("failed to start the first process: %v", err)
+	}
+
+	queuedDone := make(chan *ProcessInfo, 1)
+	queued, err := pm.StartProcessWithName("echo never", "", "mutex-queued", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		queuedDone <- p
+	}, WithID("queued-id"), WithMutexGroup("install", ""))
+	if err != nil {
+		t.Fatalf("failed to queue the process: %v", err)
+	}
+	if queued != "queued-id" {
+		t.Fatalf("expected the client-provided id, got %s", queued)
+	}
+
+	if err := pm.KillProcess(queued); err != nil {
+		t.Fatalf("failed to kill the queued process: %v", err)
+	}
+	select {
+	case p := <-queuedDone:
+		if p.Status != StatusKilled || p.ProcessPid != 0 {
+			t.Errorf("expected the queued process to be killed before starting, got %s (pid %d)", p.Status, p.ProcessPid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queued process callback")
+	}
+
+	if err := pm.KillProcess(first); err != nil {
+		t.Fatalf("failed to kill the running process: %v", err)
+	}
+	<-firstDone
+}
+
+func TestMutexGroupStreamQueued(t *testing.T) {
+	pm := NewProcessManager()
+
+	firstDone := make(chan struct{})
+	if _, err := pm.StartProcessWithName("sleep 0.3", "", "mutex-holder", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(firstDone)
+	}, WithMutexGroup("install", "")); err != nil {
+		t.Fatalf("failed to start the first process: %v", err)
+	}
+
+	queued, err := pm.StartProcessWithName("echo from-queue", "", "mutex-streamed", nil, false, 0, false, 0, func(p *ProcessInfo) {},
+		WithMutexGroup("install", ""))
+	if err != nil {
+		t.Fatalf("failed to queue the process: %v", err)
+	}
+	placeholder, _ := pm.GetProcessByIdentifier(queued)
+	writer := &testWriter{}
+	if err := pm.StreamProcessOutput(queued, writer); err != nil {
+		t.Fatalf("failed to stream the queued process: %v", err)
+	}
+
+	<-firstDone
+	select {
+	case <-placeholder.Done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queued process through the placeholder")
+	}
+	select {
+	case <-placeholder.TailDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queued process logs through the placeholder")
+	}
+	_ = pm.RemoveLogWriter(queued, writer)
+
+	if got := writer.String(); got != "stdout:from-queue\n" {
+		t.Errorf("expected the output of the queued process, got %q", got)
+	}
+}
+
+func TestMutexGroupQueuedAlwaysListed(t *testing.T) {
+	pm := NewProcessManager()
+
+	firstDone := make(chan struct{})
+	if _, err := pm.StartProcessWithName("sleep 0.2", "", "mutex-listed-holder", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(firstDone)
+	}, WithMutexGroup("listed", "")); err != nil {
+		t.Fatalf("failed to start the first process: %v", err)
+	}
+	secondDone := make(chan struct{})
+	queued, err := pm.StartProcessWithName("sleep 0.2", "", "mutex-listed", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(secondDone)
+	}, WithMutexGroup("listed", ""))
+	if err != nil {
+		t.Fatalf("failed to queue the process: %v", err)
+	}
+
+	// The id keeps resolving while the placeholder makes way for the started process
+	placeholder, _ := pm.GetProcessByIdentifier(queued)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		proc, exists := pm.GetProcessByIdentifier(queued)
+		if !exists {
+			t.Fatalf("process %s went missing while leaving the queue", queued)
+		}
+		if proc != placeholder {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the queued process to start")
+		}
+	}
+	<-firstDone
+	select {
+	case <-secondDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queued process")
+	}
+}
+
+func TestValidateMutexPolicy(t *testing.T) {
+	for _, policy := range []string{"", MutexPolicyQueue, MutexPolicyReject} {
+		if err := ValidateMutexPolicy(policy); err != nil {
+			t.Errorf("expected %q to be valid, got %v", policy, err)
+		}
+	}
+	if err := ValidateMutexPolicy("wait"); err == nil {
+		t.Error("expected an invalid policy to be rejected")
+	}
+}
@@ -0,0 +1,70 @@
+package process
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// PauseProcess freezes a running process and its children by sending SIGSTOP to its process group
+func (pm *ProcessManager) PauseProcess(identifier string) error {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+
+	if process.Status != StatusRunning {
+		return fmt.Errorf("process with Identifier %s is not running", identifier)
+	}
+
+	if process.ProcessPid == 0 {
+		return fmt.Errorf("process with Identifier %s has no OS process", identifier)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if process.Paused {
+		return fmt.Errorf("process with Identifier %s is already paused", identifier)
+	}
+
+	if err := signalProcessGroup(process.ProcessPid, syscall.SIGSTOP); err != nil {
+		return fmt.Errorf("failed to pause process with Identifier %s: %w", identifier, err)
+	}
+	process.Paused = true
+
+	return nil
+}
+
+// ResumeProcess continues a paused process by sending SIGCONT to its process group
+func (pm *ProcessManager) ResumeProcess(identifier string) error {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+
+	if process.Status != StatusRunning {
+		return fmt.Errorf("process with Identifier %s is not running", identifier)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !process.Paused {
+		return fmt.Errorf("process with Identifier %s is not paused", identifier)
+	}
+
+	if err := signalProcessGroup(process.ProcessPid, syscall.SIGCONT); err != nil {
+		return fmt.Errorf("failed to resume process with Identifier %s: %w", identifier, err)
+	}
+	process.Paused = false
+
+	return nil
+}
+
+// signalProcessGroup sends sig to the process group led by pid, falling back to the process alone
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(-pid, sig); err != nil {
+		return syscall.Kill(pid, sig)
+	}
+	return nil
+}
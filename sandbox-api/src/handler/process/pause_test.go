@@ -0,0 +1,76 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// processState returns the state letter from /proc/<pid>/stat
+func processState(t *testing.T, pid int) byte {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		t.Skipf("/proc not available: %v", err)
+	}
+	stat := string(data)
+	return stat[strings.LastIndex(stat, ")")+2]
+}
+
+func TestPauseAndResumeProcess(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcessWithName("sleep 30", "", "pause-test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = pm.KillProcess(pid) }()
+
+	proc, _ := pm.GetProcessByIdentifier(pid)
+
+	if err := pm.ResumeProcess(pid); err == nil {
+		t.Error("Expected error when resuming a process that is not paused")
+	}
+
+	if err := pm.PauseProcess(pid); err != nil {
+		t.Fatalf("Failed to pause process: %v", err)
+	}
+	if !proc.Paused {
+		t.Error("Expected process to be marked as paused")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if state := processState(t, proc.ProcessPid); state != 'T' {
+		t.Errorf("Expected stopped state T, got %c", state)
+	}
+	if !isProcessRunning(proc.ProcessPid) {
+		t.Error("Expected paused process to be considered running")
+	}
+	if err := pm.PauseProcess(pid); err == nil {
+		t.Error("Expected error when pausing an already paused process")
+	}
+
+	if err := pm.ResumeProcess(pid); err != nil {
+		t.Fatalf("Failed to resume process: %v", err)
+	}
+	if proc.Paused {
+		t.Error("Expected process to no longer be paused")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if state := processState(t, proc.ProcessPid); state == 'T' {
+		t.Error("Expected process to be continued")
+	}
+
+	// Stopping a paused process must still terminate it
+	if err := pm.PauseProcess(pid); err != nil {
+		t.Fatalf("Failed to pause process: %v", err)
+	}
+	if err := pm.StopProcess(pid); err != nil {
+		t.Fatalf("Failed to stop paused process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+}
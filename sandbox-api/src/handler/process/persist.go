@@ -0,0 +1,23 @@
+package process
+
+import "github.com/sirupsen/logrus"
+
+// WithSaveStateOnExit saves the process state as soon as the process ends for good
+func WithSaveStateOnExit(enabled bool) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.SaveStateOnExit = enabled
+	}
+}
+
+// saveTerminalState persists the state once a process has ended for good, after its logs were
+// fully read. It is always done when the process exhausted its restarts on failure, so that the
+// output of every attempt survives an API restart, and on any end with SaveStateOnExit.
+func (pm *ProcessManager) saveTerminalState(p *ProcessInfo) {
+	exhausted := p.RestartOnFailure && p.RestartCount > 0 && p.Status == StatusFailed
+	if !exhausted && !p.SaveStateOnExit {
+		return
+	}
+	if err := pm.SaveState(); err != nil {
+		logrus.WithError(err).WithField("process_pid", p.PID).Warn("Failed to save state after process exit")
+	}
+}
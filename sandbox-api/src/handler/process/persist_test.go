@@ -0,0 +1,86 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveStateOnRestartExhaustion(t *testing.T) {
+	t.Setenv("SANDBOX_STATE_FILE", filepath.Join(t.TempDir(), "state.json"))
+
+	pm := NewProcessManager()
+	done := make(chan *ProcessInfo, 1)
+	pid, err := pm.StartProcessWithName("echo attempt-output; exit 1", "", "persist-test", nil, true, 1, false, 0, func(p *ProcessInfo) {
+		done <- p
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	var proc *ProcessInfo
+	select {
+	case proc = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the process to exhaust its restarts")
+	}
+	if proc.RestartCount != 1 || proc.Status != StatusFailed {
+		t.Fatalf("Expected a failed process restarted once, got %s with %d restarts", proc.Status, proc.RestartCount)
+	}
+
+	// The state must hold the output on its own, without the log files
+	_ = os.Remove(proc.StdoutFile)
+	_ = os.Remove(proc.StderrFile)
+
+	loaded := NewProcessManager()
+	if err := loaded.LoadState(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	output, err := loaded.GetProcessOutput(pid)
+	if err != nil {
+		t.Fatalf("Expected the process to be recovered: %v", err)
+	}
+	if count := strings.Count(output.Stdout, "attempt-output"); count != 2 {
+		t.Errorf("Expected the output of both attempts, got %q", output.Stdout)
+	}
+	if count := strings.Count(output.Stdout, "Attempting restart 1/1"); count != 1 {
+		t.Errorf("Expected the restart message once, got %q", output.Stdout)
+	}
+}
+
+func TestSaveStateOnExit(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	t.Setenv("SANDBOX_STATE_FILE", stateFile)
+
+	pm := NewProcessManager()
+	done := make(chan struct{})
+	if _, err := pm.StartProcessWithName("echo ok", "", "no-persist-test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	<-done
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no state to be saved without saveStateOnExit, got %v", err)
+	}
+
+	done = make(chan struct{})
+	pid, err := pm.StartProcessWithName("echo ok", "", "persist-on-exit-test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithSaveStateOnExit(true))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	<-done
+
+	loaded := NewProcessManager()
+	if err := loaded.LoadState(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	proc, exists := loaded.GetProcessByIdentifier(pid)
+	if !exists || proc.Status != StatusCompleted || !proc.SaveStateOnExit {
+		t.Errorf("Expected the completed process to be saved, got %+v", proc)
+	}
+}
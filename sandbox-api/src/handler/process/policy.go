@@ -0,0 +1,44 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEmptyRestartPolicy is returned when an update sets neither restartOnFailure nor maxRestarts
+var ErrEmptyRestartPolicy = errors.New("restartOnFailure or maxRestarts is required")
+
+// ErrProcessNotRunning is returned when the restart policy of a process that already exited is updated
+var ErrProcessNotRunning = errors.New("process is not running")
+
+// UpdateRestartPolicy changes the restart policy of a running process and persists it.
+// Nil values are left unchanged. A negative maxRestarts means unlimited restarts,
+// and restarts already done still count against a new maxRestarts.
+func (pm *ProcessManager) UpdateRestartPolicy(identifier string, restartOnFailure *bool, maxRestarts *int) (*ProcessInfo, error) {
+	if restartOnFailure == nil && maxRestarts == nil {
+		return nil, ErrEmptyRestartPolicy
+	}
+
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return nil, fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+
+	pm.mu.Lock()
+	if process.Status != StatusRunning {
+		pm.mu.Unlock()
+		return nil, fmt.Errorf("%w: process with Identifier %s is %s", ErrProcessNotRunning, identifier, process.Status)
+	}
+	if restartOnFailure != nil {
+		process.RestartOnFailure = *restartOnFailure
+	}
+	if maxRestarts != nil {
+		process.MaxRestarts = *maxRestarts
+	}
+	pm.mu.Unlock()
+
+	if err := pm.SaveState(); err != nil {
+		return process, fmt.Errorf("restart policy updated but could not be persisted: %w", err)
+	}
+	return process, nil
+}
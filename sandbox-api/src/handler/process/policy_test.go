@@ -0,0 +1,71 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateRestartPolicy(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	t.Setenv("SANDBOX_STATE_FILE", stateFile)
+
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcessWithName("sleep 0.5; exit 1", "", "policy-test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = pm.KillProcess(pid) }()
+
+	if _, err := pm.UpdateRestartPolicy(pid, nil, nil); !errors.Is(err, ErrEmptyRestartPolicy) {
+		t.Errorf("Expected ErrEmptyRestartPolicy, got %v", err)
+	}
+	if _, err := pm.UpdateRestartPolicy("does-not-exist", nil, new(int)); err == nil {
+		t.Error("Expected error for an unknown process")
+	}
+
+	restart := true
+	maxRestarts := 1
+	proc, err := pm.UpdateRestartPolicy(pid, &restart, &maxRestarts)
+	if err != nil {
+		t.Fatalf("Failed to update restart policy: %v", err)
+	}
+	if !proc.RestartOnFailure || proc.MaxRestarts != 1 {
+		t.Errorf("Expected restartOnFailure=true maxRestarts=1, got %v %d", proc.RestartOnFailure, proc.MaxRestarts)
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatalf("Expected state to be persisted: %v", err)
+	}
+	var state ManagerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Failed to parse state: %v", err)
+	}
+	if saved := state.Processes[proc.PID]; !saved.RestartOnFailure || saved.MaxRestarts != 1 {
+		t.Errorf("Expected persisted restartOnFailure=true maxRestarts=1, got %v %d", saved.RestartOnFailure, saved.MaxRestarts)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the process to exit")
+	}
+	if proc.RestartCount != 1 {
+		t.Fatalf("Expected the updated policy to restart the process once, got %d restarts", proc.RestartCount)
+	}
+	if proc.Status != StatusFailed {
+		t.Fatalf("Expected process to fail after its last restart, got %s", proc.Status)
+	}
+
+	if _, err := pm.UpdateRestartPolicy(pid, &restart, nil); !errors.Is(err, ErrProcessNotRunning) {
+		t.Errorf("Expected ErrProcessNotRunning for an exited process, got %v", err)
+	}
+}
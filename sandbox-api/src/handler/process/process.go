@@ -52,23 +52,28 @@ const (
 	StatusStopped   = constants.ProcessStatusStopped
 	StatusRunning   = constants.ProcessStatusRunning
 	StatusCompleted = constants.ProcessStatusCompleted
+	StatusQueued    = constants.ProcessStatusQueued // Waiting for the other process of its mutex group to end
 )
 
 // ProcessManager manages the running processes
 type ProcessManager struct {
-	processes map[string]*ProcessInfo
-	mu        sync.RWMutex
+	processes   map[string]*ProcessInfo
+	mu          sync.RWMutex
+	mutexGroups map[string]*mutexGroup
+	mutexMu     sync.Mutex      // Protects mutexGroups
+	names       map[string]bool // Names reserved by ReserveName, protected by mu
 }
 
 type ProcessLogs struct {
 	Stdout string `json:"stdout" example:"stdout output" binding:"required"`
 	Stderr string `json:"stderr" example:"stderr output" binding:"required"`
 	Logs   string `json:"logs" example:"logs output" binding:"required"`
+	Cursor int    `json:"cursor" example:"1024"` // Pass back as ?cursor= to only get the output produced after this response
 } // @name ProcessLogs
 
 // ProcessInfo stores information about a running process
 type ProcessInfo struct {
-	PID              string                  `json:"pid"`
+	PID              string                  `json:"pid"` // OS PID at first start, or the client-provided id
 	Name             string                  `json:"name"`
 	Command          string                  `json:"command"`
 	ProcessPid       int                     `json:"-"` // Store the OS process PID for kill/stop operations
@@ -85,10 +90,24 @@ type ProcessInfo struct {
 	MaxRestarts      int                     `json:"maxRestarts"`
 	RestartCount     int                     `json:"restartCount"`
 	KeepAlive        bool                    `json:"keepAlive"`
-	Timeout          int                     `json:"-"` // Internal: timeout in seconds for keepAlive processes
-	LogFile          string                  `json:"-"` // Path to combined log file
-	StdoutFile       string                  `json:"-"` // Path to stdout log file
-	StderrFile       string                  `json:"-"` // Path to stderr log file
+	Paused           bool                    `json:"paused"`
+	Labels           map[string]string       `json:"labels,omitempty"`          // User-defined labels used to select and manage processes together
+	LogTag           string                  `json:"logTag,omitempty"`          // Prefix of the process's lines in multiplexed log streams, defaults to the name
+	CgroupLimits     *CgroupLimits           `json:"cgroupLimits,omitempty"`    // Resource limits enforced through a dedicated cgroup v2
+	CgroupWarning    string                  `json:"cgroupWarning,omitempty"`   // Why cgroupLimits could not be enforced
+	CgroupPath       string                  `json:"-"`                         // Internal: cgroup created for the process, removed on completion
+	EphemeralCwd     bool                    `json:"ephemeralCwd,omitempty"`    // WorkingDir is a temp dir created for the process, removed once it is done
+	Usage            *ResourceUsage          `json:"usage,omitempty"`           // Duration, peak memory and CPU time of the last completed run
+	StdinFrom        *StdinSource            `json:"stdinFrom,omitempty"`       // Where stdin is read from, empty stdin otherwise
+	SaveStateOnExit  bool                    `json:"saveStateOnExit,omitempty"` // Save the state as soon as the process ends for good
+	RetainLogs       *bool                   `json:"retainLogs,omitempty"`      // Whether the log files are kept once the process is done, nil keeps them
+	MutexGroup       string                  `json:"mutexGroup,omitempty"`      // Only one process of the group runs at a time
+	MutexPolicy      string                  `json:"mutexPolicy,omitempty"`     // What to do when the mutex group is busy: queue (default) or reject
+	NewSession       bool                    `json:"newSession,omitempty"`      // Started in its own session, detached from the API's one
+	Timeout          int                     `json:"-"`                         // Internal: timeout in seconds for keepAlive processes
+	LogFile          string                  `json:"-"`                         // Path to combined log file
+	StdoutFile       string                  `json:"-"`                         // Path to stdout log file
+	StderrFile       string                  `json:"-"`                         // Path to stderr log file
 	Done             chan struct{}
 	TailDone         chan struct{} // Closed when tailLogFiles finishes its final reads
 	stdout           *strings.Builder
@@ -96,8 +115,13 @@ type ProcessInfo struct {
 	logs             *strings.Builder
 	logWriters       []io.Writer
 	logLock          sync.RWMutex
-	stopTimeout      chan struct{} // Channel to signal timeout goroutine to stop
-	stopTimeoutOnce  sync.Once    // Protects stopTimeout channel from double-close
+	runStart         logOffsets         // Where the output of the current run starts, moved on each restart
+	queued           *ProcessInfo       // Placeholder listed while the process waited for its mutex group
+	startedAs        *ProcessInfo       // Process started for this queued placeholder, protected by logLock
+	tailer           *logTailer         // Current tailLogFiles goroutine, nil for adopted processes
+	refresh          chan chan struct{} // Asks monitorAdoptedProcess for an immediate check, nil for other processes
+	stopTimeout      chan struct{}      // Channel to signal timeout goroutine to stop
+	stopTimeoutOnce  sync.Once          // Protects stopTimeout channel from double-close
 }
 
 // ProcessLogDir is the directory where process logs are stored
@@ -137,9 +161,23 @@ func restartLimitLabel(maxRestarts int) string {
 // buildProcessEnv merges the current system environment with the caller's
 // custom environment variables, letting custom vars override system ones.
 // The system environment is read fresh from os.Environ() so only the custom
-// vars need to be stored/persisted for a later restart.
+// vars need to be stored/persisted for a later restart. The default timezone,
+// when set, replaces the system TZ but not a custom one, and so do the identity
+// env vars unless forced.
 func buildProcessEnv(custom map[string]string) []string {
 	systemEnv := os.Environ()
+	custom = withIdentityEnv(custom)
+
+	if tz := DefaultTimezone(); tz != "" {
+		if _, ok := custom["TZ"]; !ok {
+			withTZ := make(map[string]string, len(custom)+1)
+			for k, v := range custom {
+				withTZ[k] = v
+			}
+			withTZ["TZ"] = tz
+			custom = withTZ
+		}
+	}
 
 	// Track which keys the custom env overrides.
 	overrides := make(map[string]bool, len(custom))
@@ -166,6 +204,18 @@ func buildProcessEnv(custom map[string]string) []string {
 	return finalEnv
 }
 
+// PreviewEnv returns the environment a process started with the custom env vars
+// would receive, as a map, without starting anything
+func PreviewEnv(custom map[string]string) map[string]string {
+	env := make(map[string]string)
+	for _, envVar := range buildProcessEnv(custom) {
+		if key, value, ok := strings.Cut(envVar, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
 // getLogFilePaths returns the log file paths for a process (stdout, stderr, combined)
 func getLogFilePaths(name string) (stdout, stderr, combined string) {
 	stdout = fmt.Sprintf("%s/%s.stdout.log", ProcessLogDir, name)
@@ -182,7 +232,9 @@ func ensureLogDir() error {
 // NewProcessManager creates a new process manager
 func NewProcessManager() *ProcessManager {
 	return &ProcessManager{
-		processes: make(map[string]*ProcessInfo),
+		processes:   make(map[string]*ProcessInfo),
+		mutexGroups: make(map[string]*mutexGroup),
+		names:       make(map[string]bool),
 	}
 }
 
@@ -200,12 +252,35 @@ func GetProcessManager() *ProcessManager {
 	return processManager
 }
 
-func (pm *ProcessManager) StartProcess(command string, workingDir string, env map[string]string, restartOnFailure bool, maxRestarts int, keepAlive bool, timeout int, callback func(process *ProcessInfo)) (string, error) {
+// ProcessOption customizes a process before it is started.
+// Options only set fields on ProcessInfo so they are also honored on restart.
+type ProcessOption func(p *ProcessInfo)
+
+func (pm *ProcessManager) StartProcess(command string, workingDir string, env map[string]string, restartOnFailure bool, maxRestarts int, keepAlive bool, timeout int, callback func(process *ProcessInfo), opts ...ProcessOption) (string, error) {
 	name := GenerateRandomName(8)
-	return pm.StartProcessWithName(command, workingDir, name, env, restartOnFailure, maxRestarts, keepAlive, timeout, callback)
+	return pm.StartProcessWithName(command, workingDir, name, env, restartOnFailure, maxRestarts, keepAlive, timeout, callback, opts...)
+}
+
+func (pm *ProcessManager) StartProcessWithName(command string, workingDir string, name string, env map[string]string, restartOnFailure bool, maxRestarts int, keepAlive bool, timeout int, callback func(process *ProcessInfo), opts ...ProcessOption) (string, error) {
+	start := func(opts ...ProcessOption) (string, error) {
+		return pm.startProcess(command, workingDir, name, env, restartOnFailure, maxRestarts, keepAlive, timeout, callback, opts...)
+	}
+	// Listed in place of the process while it is queued for its mutex group
+	queued := &ProcessInfo{
+		Name:             name,
+		Command:          command,
+		WorkingDir:       workingDir,
+		Env:              env,
+		RestartOnFailure: restartOnFailure,
+		MaxRestarts:      maxRestarts,
+		KeepAlive:        keepAlive,
+		Timeout:          timeout,
+	}
+	return pm.startInMutexGroup(queued, callback, start, opts)
 }
 
-func (pm *ProcessManager) StartProcessWithName(command string, workingDir string, name string, env map[string]string, restartOnFailure bool, maxRestarts int, keepAlive bool, timeout int, callback func(process *ProcessInfo)) (string, error) {
+// startProcess starts a process right away, see StartProcessWithName
+func (pm *ProcessManager) startProcess(command string, workingDir string, name string, env map[string]string, restartOnFailure bool, maxRestarts int, keepAlive bool, timeout int, callback func(process *ProcessInfo), opts ...ProcessOption) (string, error) {
 	// Always use shell to execute commands
 	// This ensures shell built-ins (cd, export, alias) work properly
 	// Use SHELL and SHELL_ARGS environment variables if set
@@ -238,11 +313,6 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 		cmd.Dir = workingDir
 	}
 
-	// Set up process group to ensure all child processes can be killed together
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-	}
-
 	cmd.Env = buildProcessEnv(env)
 
 	// Ensure log directory exists
@@ -295,6 +365,63 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 		logWriters:       make([]io.Writer, 0),
 		stopTimeout:      make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(process)
+	}
+
+	// Set up process group to ensure all child processes can be killed together
+	cmd.SysProcAttr = processSysProcAttr(process)
+
+	if process.EphemeralCwd {
+		if workingDir != "" {
+			stdoutFile.Close()
+			stderrFile.Close()
+			return "", ErrEphemeralWorkingDir
+		}
+		dir, err := createEphemeralWorkingDir(name)
+		if err != nil {
+			stdoutFile.Close()
+			stderrFile.Close()
+			return "", fmt.Errorf("failed to create ephemeral working directory: %w", err)
+		}
+		process.WorkingDir = dir
+		cmd.Dir = dir
+	} else if err := CheckWorkingDir(workingDir); err != nil {
+		stdoutFile.Close()
+		stderrFile.Close()
+		return "", err
+	}
+
+	stdin, err := pm.openStdin(process, process.WorkingDir)
+	if err != nil {
+		stdoutFile.Close()
+		stderrFile.Close()
+		releaseEphemeralWorkingDir(process)
+		return "", err
+	}
+	if stdin != nil {
+		cmd.Stdin = stdin.file
+	}
+
+	// A client-provided id is reserved before starting, so concurrent requests can't both use it.
+	// A process leaving the queue replaces its placeholder, so its id never goes missing.
+	customID := process.PID
+	if customID != "" {
+		pm.mu.Lock()
+		if existing, exists := pm.processes[customID]; exists && (process.queued == nil || existing != process.queued) {
+			pm.mu.Unlock()
+			stdoutFile.Close()
+			stderrFile.Close()
+			stdin.abort()
+			releaseEphemeralWorkingDir(process)
+			return "", fmt.Errorf("%w: %s", ErrProcessIDExists, customID)
+		}
+		if process.queued != nil {
+			process.queued.handOver(process)
+		}
+		pm.processes[customID] = process
+		pm.mu.Unlock()
+	}
 
 	// Redirect stdout/stderr directly to files
 	// This is crucial - child writes to files, not pipes
@@ -306,17 +433,34 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 	if err := cmd.Start(); err != nil {
 		stdoutFile.Close()
 		stderrFile.Close()
+		stdin.abort()
 		os.Remove(stdoutPath)
 		os.Remove(stderrPath)
+		releaseEphemeralWorkingDir(process)
+		if customID != "" {
+			pm.mu.Lock()
+			if process.queued != nil {
+				pm.processes[customID] = process.queued
+			} else {
+				delete(pm.processes, customID)
+			}
+			pm.mu.Unlock()
+		}
 		return "", err
 	}
 
-	process.PID = fmt.Sprintf("%d", cmd.Process.Pid)
+	if customID == "" {
+		process.PID = fmt.Sprintf("%d", cmd.Process.Pid)
+	}
 	process.ProcessPid = cmd.Process.Pid
+	applyCgroupLimits(process, cmd.Process.Pid)
+	applyDefaultUlimits(process, cmd.Process.Pid)
+	sampler := startUsageSampler(cmd.Process.Pid, process.StartedAt)
 
 	// Close the write handles in parent - child has its own FDs
 	stdoutFile.Close()
 	stderrFile.Close()
+	stdin.started(process)
 
 	// If keepAlive is enabled, disable scale-to-zero and log the event
 	if keepAlive {
@@ -365,6 +509,7 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 	// Monitor process completion
 	go func() {
 		err := cmd.Wait()
+		usage := sampler.finish(cmd.ProcessState)
 
 		// IMPORTANT: Release process resources immediately after Wait() to close pidfd
 		// This must be done right after Wait() completes to prevent FD leaks
@@ -372,6 +517,9 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 			_ = cmd.Process.Release()
 		}
 
+		// Remove the cgroup, killing any background child left in it
+		releaseCgroup(process)
+
 		// Small delay to allow filesystem to sync writes from the child process
 		// This is necessary on macOS where file writes may not be immediately visible
 		// to readers in other goroutines due to filesystem caching
@@ -397,6 +545,8 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 
 		// Update process in memory
 		pm.mu.Lock()
+		process.Paused = false
+		process.Usage = usage
 		pm.processes[process.PID] = process
 		pm.mu.Unlock()
 
@@ -412,16 +562,20 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 				process.ExitCode, process.RestartCount+1, restartLimitLabel(process.MaxRestarts))
 
 			process.logLock.Lock()
-			process.stdout.WriteString(restartMsg)
-			process.logs.WriteString(restartMsg)
-
-			// Append restart message to log files
+			// Append restart message to log files, the tailer then records it with the output.
+			// It is only written to memory directly when there is no log file to go through.
+			appended := false
 			if process.StdoutFile != "" {
 				if f, err := os.OpenFile(process.StdoutFile, os.O_APPEND|os.O_WRONLY, 0644); err == nil {
-					f.WriteString(restartMsg)
+					_, err = f.WriteString(restartMsg)
+					appended = err == nil
 					f.Close()
 				}
 			}
+			if !appended {
+				process.stdout.WriteString(restartMsg)
+				process.logs.WriteString(restartMsg)
+			}
 
 			// Notify log writers about the restart
 			for _, w := range process.logWriters {
@@ -477,6 +631,10 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 				process.logWriters = nil
 				process.logLock.Unlock()
 
+				releaseEphemeralWorkingDir(process)
+				releaseLogFiles(process)
+				pm.saveTerminalState(process)
+				pm.leaveMutexGroup(process.MutexGroup)
 				callback(process)
 			}
 			// If restart succeeds, the callback will be called when that process completes
@@ -507,6 +665,10 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 			process.logWriters = nil
 			process.logLock.Unlock()
 
+			releaseEphemeralWorkingDir(process)
+			releaseLogFiles(process)
+			pm.saveTerminalState(process)
+			pm.leaveMutexGroup(process.MutexGroup)
 			callback(process)
 		}
 	}()
@@ -516,6 +678,18 @@ func (pm *ProcessManager) StartProcessWithName(command string, workingDir string
 
 // tailLogFiles tails the stdout and stderr log files for real-time streaming
 func (pm *ProcessManager) tailLogFiles(proc *ProcessInfo) {
+	tailer := newLogTailer()
+	proc.logLock.Lock()
+	proc.tailer = tailer
+	proc.logLock.Unlock()
+	pm.tailLogFilesFrom(proc, tailer, 0, 0)
+}
+
+// tailLogFilesFrom is tailLogFiles starting at the given offsets of the stdout and stderr
+// files, for a tailer already registered in proc
+func (pm *ProcessManager) tailLogFilesFrom(proc *ProcessInfo, tailer *logTailer, stdoutOffset int64, stderrOffset int64) {
+	defer close(tailer.stopped)
+
 	// Open files for reading
 	stdoutFile, err := os.Open(proc.StdoutFile)
 	if err != nil {
@@ -529,6 +703,13 @@ func (pm *ProcessManager) tailLogFiles(proc *ProcessInfo) {
 	}
 	defer stderrFile.Close()
 
+	if _, err := stdoutFile.Seek(stdoutOffset, io.SeekStart); err != nil {
+		return
+	}
+	if _, err := stderrFile.Seek(stderrOffset, io.SeekStart); err != nil {
+		return
+	}
+
 	// Open combined log file for writing prefixed output (preserves order)
 	var combinedFile *os.File
 	if proc.LogFile != "" {
@@ -557,6 +738,9 @@ func (pm *ProcessManager) tailLogFiles(proc *ProcessInfo) {
 			}
 			close(proc.TailDone)
 			return
+		case <-tailer.detach:
+			// ReattachLogs takes over, a new tailer will close TailDone
+			return
 		default:
 			pm.readAndBroadcast(stdoutFile, stdoutBuf, proc, "stdout", combinedFile)
 			pm.readAndBroadcast(stderrFile, stderrBuf, proc, "stderr", combinedFile)
@@ -660,9 +844,7 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 	}
 
 	// Set up process group to ensure all child processes can be killed together
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-	}
+	cmd.SysProcAttr = processSysProcAttr(oldProcess)
 
 	// Re-merge the custom env vars provided at the original start with the
 	// current system environment. Using os.Environ() alone here would drop any
@@ -685,6 +867,11 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 	cmd.Stdout = stdoutFile
 	cmd.Stderr = stderrFile
 
+	// Output written from now on belongs to the new run
+	oldProcess.logLock.Lock()
+	oldProcess.runStart = currentLogOffsets(oldProcess)
+	oldProcess.logLock.Unlock()
+
 	// Keep the existing process info but reset status
 	oldProcess.Status = StatusRunning
 	oldProcess.StartedAt = time.Now()
@@ -695,28 +882,49 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 	oldProcess.Done = make(chan struct{})
 	oldProcess.TailDone = make(chan struct{})
 
+	stdin, err := pm.openStdin(oldProcess, workingDir)
+	if err != nil {
+		stdoutFile.Close()
+		stderrFile.Close()
+		return "", err
+	}
+	if stdin != nil {
+		cmd.Stdin = stdin.file
+	}
+
 	// Start the process
 	if err := cmd.Start(); err != nil {
 		stdoutFile.Close()
 		stderrFile.Close()
+		stdin.abort()
 		return "", err
 	}
 
 	// Update only the OS process PID for kill/stop operations
 	// Keep the user-facing PID (oldProcess.PID) unchanged for transparency
 	oldProcess.ProcessPid = cmd.Process.Pid
+	applyCgroupLimits(oldProcess, cmd.Process.Pid)
+	applyDefaultUlimits(oldProcess, cmd.Process.Pid)
+	sampler := startUsageSampler(cmd.Process.Pid, oldProcess.StartedAt)
 
 	// Close write handles in parent - child has its own FDs
 	stdoutFile.Close()
 	stderrFile.Close()
+	stdin.started(oldProcess)
 
 	// Update the process in memory (same map key, just updating the entry)
 	pm.mu.Lock()
 	pm.processes[oldProcess.PID] = oldProcess
 	pm.mu.Unlock()
 
-	// Start file tailer for real-time log streaming
-	go pm.tailLogFiles(oldProcess)
+	// Start file tailer for real-time log streaming, after the output of the previous
+	// runs which is already recorded
+	tailer := newLogTailer()
+	oldProcess.logLock.Lock()
+	oldProcess.tailer = tailer
+	stdoutOffset, stderrOffset := int64(oldProcess.runStart.stdoutFile), int64(oldProcess.runStart.stderrFile)
+	oldProcess.logLock.Unlock()
+	go pm.tailLogFilesFrom(oldProcess, tailer, stdoutOffset, stderrOffset)
 	// If keepAlive is enabled, start timeout goroutine for the restarted process
 	pm.mu.RLock()
 	keepAlive := oldProcess.KeepAlive
@@ -743,6 +951,7 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 	// Monitor the restarted process
 	go func() {
 		err := cmd.Wait()
+		usage := sampler.finish(cmd.ProcessState)
 
 		// IMPORTANT: Release process resources immediately after Wait() to close pidfd
 		// This must be done right after Wait() completes to prevent FD leaks
@@ -750,6 +959,9 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 			_ = cmd.Process.Release()
 		}
 
+		// Remove the cgroup, killing any background child left in it
+		releaseCgroup(oldProcess)
+
 		// Small delay to allow filesystem to sync writes from the child process
 		// This is necessary on macOS where file writes may not be immediately visible
 		// to readers in other goroutines due to filesystem caching
@@ -775,6 +987,8 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 
 		// Update process in memory (PID stays the same, just updating the entry)
 		pm.mu.Lock()
+		oldProcess.Paused = false
+		oldProcess.Usage = usage
 		pm.processes[oldProcess.PID] = oldProcess
 		pm.mu.Unlock()
 
@@ -790,16 +1004,20 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 				oldProcess.ExitCode, oldProcess.RestartCount+1, restartLimitLabel(oldProcess.MaxRestarts))
 
 			oldProcess.logLock.Lock()
-			oldProcess.stdout.WriteString(restartMsg)
-			oldProcess.logs.WriteString(restartMsg)
-
-			// Append restart message to log file
+			// Append restart message to log file, the tailer then records it with the output.
+			// It is only written to memory directly when there is no log file to go through.
+			appended := false
 			if oldProcess.StdoutFile != "" {
 				if f, err := os.OpenFile(oldProcess.StdoutFile, os.O_APPEND|os.O_WRONLY, 0644); err == nil {
-					f.WriteString(restartMsg)
+					_, err = f.WriteString(restartMsg)
+					appended = err == nil
 					f.Close()
 				}
 			}
+			if !appended {
+				oldProcess.stdout.WriteString(restartMsg)
+				oldProcess.logs.WriteString(restartMsg)
+			}
 
 			// Notify log writers about the restart
 			for _, w := range oldProcess.logWriters {
@@ -855,6 +1073,10 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 				oldProcess.logWriters = nil
 				oldProcess.logLock.Unlock()
 
+				releaseEphemeralWorkingDir(oldProcess)
+				releaseLogFiles(oldProcess)
+				pm.saveTerminalState(oldProcess)
+				pm.leaveMutexGroup(oldProcess.MutexGroup)
 				callback(oldProcess)
 			}
 			// If restart succeeds, the callback will be called when that process completes
@@ -885,6 +1107,10 @@ func (pm *ProcessManager) restartProcess(oldProcess *ProcessInfo, callback func(
 			oldProcess.logWriters = nil
 			oldProcess.logLock.Unlock()
 
+			releaseEphemeralWorkingDir(oldProcess)
+			releaseLogFiles(oldProcess)
+			pm.saveTerminalState(oldProcess)
+			pm.leaveMutexGroup(oldProcess.MutexGroup)
 			callback(oldProcess)
 		}
 	}()
@@ -897,10 +1123,10 @@ func (pm *ProcessManager) GetProcessByIdentifier(identifier string) (*ProcessInf
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
-	// Try to convert identifier to int (PID)
-	if _, err := strconv.Atoi(identifier); err == nil {
-		// If conversion successful, try to get process by PID
-		process, exists := pm.processes[identifier]
+	// Look the identifier up as a PID (generated or client-provided) first; numeric
+	// identifiers are never names
+	process, exists := pm.processes[identifier]
+	if _, err := strconv.Atoi(identifier); exists || err == nil {
 		if !exists {
 			return nil, false
 		}
@@ -974,6 +1200,19 @@ func (pm *ProcessManager) ListProcesses() []*ProcessInfo {
 	return processes
 }
 
+// HasRunningProcesses reports whether any managed process is still running
+func (pm *ProcessManager) HasRunningProcesses() bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, process := range pm.processes {
+		if process.Status == StatusRunning {
+			return true
+		}
+	}
+	return false
+}
+
 // StopProcess attempts to gracefully stop a process
 func (pm *ProcessManager) StopProcess(identifier string) error {
 	process, exists := pm.GetProcessByIdentifier(identifier)
@@ -981,6 +1220,10 @@ func (pm *ProcessManager) StopProcess(identifier string) error {
 		return fmt.Errorf("process with Identifier %s not found", identifier)
 	}
 
+	if process.Status == StatusQueued {
+		return pm.cancelQueued(process, StatusStopped)
+	}
+
 	if process.Status != StatusRunning {
 		return fmt.Errorf("process with Identifier %s is not running", identifier)
 	}
@@ -1026,6 +1269,14 @@ func (pm *ProcessManager) StopProcess(identifier string) error {
 		}
 	}
 
+	// A paused process group only handles SIGTERM once it is continued
+	pm.mu.Lock()
+	if process.Paused {
+		_ = signalProcessGroup(pid, syscall.SIGCONT)
+		process.Paused = false
+	}
+	pm.mu.Unlock()
+
 	process.Status = StatusStopped
 
 	if wasKeepAlive {
@@ -1049,15 +1300,33 @@ func (pm *ProcessManager) StopProcess(identifier string) error {
 
 // KillProcess forcefully kills a process
 func (pm *ProcessManager) KillProcess(identifier string) error {
+	return pm.killProcess(identifier, nil)
+}
+
+// killProcess kills a process and its process group, sparing excludePids (see KillProcessExcept)
+func (pm *ProcessManager) killProcess(identifier string, excludePids []int) error {
 	process, exists := pm.GetProcessByIdentifier(identifier)
 	if !exists {
 		return fmt.Errorf("process with Identifier %s not found", identifier)
 	}
 
+	if process.Status == StatusQueued {
+		return pm.cancelQueued(process, StatusKilled)
+	}
+
 	if process.ProcessPid == 0 {
 		return fmt.Errorf("process with Identifier %s has no OS process", identifier)
 	}
 
+	// Resolved before anything is signaled, so that an invalid exclusion kills nothing
+	var spared map[int]bool
+	if len(excludePids) > 0 {
+		var err error
+		if spared, err = sparedPids(process.ProcessPid, excludePids); err != nil {
+			return err
+		}
+	}
+
 	// Notify log writers about forceful termination
 	process.logLock.RLock()
 	terminationMsg := []byte("\n[Process is being forcefully killed]\n")
@@ -1083,7 +1352,12 @@ func (pm *ProcessManager) KillProcess(identifier string) error {
 	pid := process.ProcessPid
 
 	// First try to kill the process group (negative PID kills the process group)
-	err := syscall.Kill(-pid, syscall.SIGKILL)
+	var err error
+	if spared != nil {
+		err = killGroupExcept(pid, spared)
+	} else {
+		err = syscall.Kill(-pid, syscall.SIGKILL)
+	}
 	if err != nil {
 		// If process group kill fails, fall back to killing just the process
 		// This might happen if the process didn't create a process group
@@ -1098,6 +1372,10 @@ func (pm *ProcessManager) KillProcess(identifier string) error {
 		}
 	}
 
+	pm.mu.Lock()
+	process.Paused = false
+	pm.mu.Unlock()
+
 	process.Status = StatusKilled
 
 	if wasKeepAlive {
@@ -1127,6 +1405,10 @@ func (pm *ProcessManager) GetProcessOutput(identifier string) (ProcessLogs, erro
 		return ProcessLogs{}, fmt.Errorf("process with PID %s not found", identifier)
 	}
 
+	// Take the cursor before reading so output written meanwhile is returned again
+	// by the next poll rather than skipped
+	cursor := logCursor(process)
+
 	// Try to read from separate log files if available
 	var stdout, stderr, logs string
 
@@ -1159,43 +1441,285 @@ func (pm *ProcessManager) GetProcessOutput(identifier string) (ProcessLogs, erro
 		Stdout: stdout,
 		Stderr: stderr,
 		Logs:   logs,
+		Cursor: cursor,
 	}, nil
 }
 
-func (pm *ProcessManager) StreamProcessOutput(identifier string, w io.Writer) error {
+// GetProcessOutputSince returns the output a process produced after cursor, a byte offset
+// into its combined log file returned by a previous GetProcessOutput or GetProcessOutputSince
+// call. Logs holds the new stdout and stderr output in the order it was produced. A cursor
+// past the end of the file (the log was truncated) reads from the beginning again. Without
+// a combined log file the in-memory output is used, and only Logs is filled.
+func (pm *ProcessManager) GetProcessOutputSince(identifier string, cursor int) (ProcessLogs, error) {
 	process, exists := pm.GetProcessByIdentifier(identifier)
 	if !exists {
-		return fmt.Errorf("process with Identifier %s not found", identifier)
+		return ProcessLogs{}, fmt.Errorf("process with PID %s not found", identifier)
+	}
+	if cursor < 0 {
+		return ProcessLogs{}, fmt.Errorf("cursor must not be negative")
+	}
+
+	content, from, ok := readLogsFrom(process.LogFile, cursor)
+	if !ok {
+		process.logLock.RLock()
+		output := process.logs.String()
+		process.logLock.RUnlock()
+		if cursor > len(output) {
+			cursor = 0
+		}
+		return ProcessLogs{Logs: output[cursor:], Cursor: len(output)}, nil
+	}
+
+	logs := splitCombinedLogs(content)
+	logs.Cursor = from + len(content)
+	return logs, nil
+}
+
+// splitCombinedLogs splits the content of a combined log file into stdout, stderr and the
+// interleaved logs, without the stream prefixes
+func splitCombinedLogs(content []byte) ProcessLogs {
+	var stdout, stderr, logs strings.Builder
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if strings.HasPrefix(line, "stderr:") {
+			line = strings.TrimPrefix(line, "stderr:")
+			stderr.WriteString(line)
+		} else {
+			line = strings.TrimPrefix(line, "stdout:")
+			stdout.WriteString(line)
+		}
+		logs.WriteString(line)
+	}
+	return ProcessLogs{Stdout: stdout.String(), Stderr: stderr.String(), Logs: logs.String()}
+}
+
+// logOffsets locates the start of a run in the stdout, stderr and combined log files, and
+// in the in-memory buffers used when the files cannot be read
+type logOffsets struct {
+	stdoutFile int
+	stderrFile int
+	logFile    int
+	stdout     int
+	stderr     int
+	logs       int
+}
+
+// currentLogOffsets returns the current end of a process's stdout, stderr and combined output.
+// The caller must hold process.logLock.
+func currentLogOffsets(process *ProcessInfo) logOffsets {
+	offsets := logOffsets{stdout: process.stdout.Len(), stderr: process.stderr.Len(), logs: process.logs.Len()}
+	if stat, err := os.Stat(process.StdoutFile); err == nil {
+		offsets.stdoutFile = int(stat.Size())
+	}
+	if stat, err := os.Stat(process.StderrFile); err == nil {
+		offsets.stderrFile = int(stat.Size())
+	}
+	if stat, err := os.Stat(process.LogFile); err == nil {
+		offsets.logFile = int(stat.Size())
+	}
+	return offsets
+}
+
+// GetProcessOutputSinceLastRestart returns the output of the current (most recent) run of a
+// process restarted on failure, without the output of the previous attempts. It is the whole
+// output for a process that was never restarted.
+func (pm *ProcessManager) GetProcessOutputSinceLastRestart(identifier string) (ProcessLogs, error) {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return ProcessLogs{}, fmt.Errorf("process with PID %s not found", identifier)
+	}
+
+	cursor := logCursor(process)
+	process.logLock.RLock()
+	start := process.runStart
+	process.logLock.RUnlock()
+
+	stdout, _, ok := readLogsFrom(process.StdoutFile, start.stdoutFile)
+	if !ok {
+		stdout = []byte(process.readBuffer(process.stdout)[min(start.stdout, process.stdout.Len()):])
+	}
+	stderr, _, ok := readLogsFrom(process.StderrFile, start.stderrFile)
+	if !ok {
+		stderr = []byte(process.readBuffer(process.stderr)[min(start.stderr, process.stderr.Len()):])
+	}
+
+	// The interleaved logs come from the combined log file, like for GetProcessOutputSince
+	var logs string
+	if content, _, ok := readLogsFrom(process.LogFile, start.logFile); ok {
+		logs = splitCombinedLogs(content).Logs
+	} else {
+		logs = process.readBuffer(process.logs)[min(start.logs, process.logs.Len()):]
 	}
 
-	// Write current content first - read from combined log file which has prefixed, ordered content
-	// The combined log file is written by tailLogFiles with "stdout:" and "stderr:" prefixes
+	return ProcessLogs{
+		Stdout: string(stdout),
+		Stderr: string(stderr),
+		Logs:   logs,
+		Cursor: cursor,
+	}, nil
+}
+
+// logCursor returns the current end of a process's combined output: the size of its
+// combined log file, or the length of the in-memory logs when there is no file.
+func logCursor(process *ProcessInfo) int {
 	if process.LogFile != "" {
-		if content, err := os.ReadFile(process.LogFile); err == nil && len(content) > 0 {
-			// Parse prefixed lines and send as proper events
-			// This ensures JSONStreamWriter receives structured stdout/stderr events
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				if strings.HasPrefix(line, "stdout:") {
-					writeToLogWriter(w, "stdout", []byte(strings.TrimPrefix(line, "stdout:")+"\n"))
-				} else if strings.HasPrefix(line, "stderr:") {
-					writeToLogWriter(w, "stderr", []byte(strings.TrimPrefix(line, "stderr:")+"\n"))
-				} else if line != "" {
-					// Fallback for unprefixed lines (shouldn't happen, but handle gracefully)
-					writeToLogWriter(w, "stdout", []byte(line+"\n"))
-				}
+		if stat, err := os.Stat(process.LogFile); err == nil {
+			return int(stat.Size())
+		}
+	}
+	process.logLock.RLock()
+	defer process.logLock.RUnlock()
+	return process.logs.Len()
+}
+
+// GetOutputTail returns the last n bytes of a process's combined output, in the order
+// it was produced. It reads the end of the combined log file and strips the stream
+// prefixes, falling back to the in-memory logs when the file is unavailable.
+func (pm *ProcessManager) GetOutputTail(identifier string, n int) (string, error) {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return "", fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+	if n <= 0 {
+		return "", nil
+	}
+
+	output, ok := readCombinedLogTail(process.LogFile, n)
+	if !ok {
+		process.logLock.RLock()
+		output = process.logs.String()
+		process.logLock.RUnlock()
+	}
+
+	if len(output) > n {
+		output = output[len(output)-n:]
+	}
+	return output, nil
+}
+
+// readCombinedLogTail returns at least the last n bytes of output (when available) from a
+// combined log file, with the stdout:/stderr: prefixes stripped. It reads growing windows
+// from the end of the file so large logs are never loaded entirely.
+func readCombinedLogTail(path string, n int) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", false
+	}
+	size := stat.Size()
+
+	for window := int64(n) * 2; ; window *= 2 {
+		offset := size - window
+		if offset < 0 {
+			offset = 0
+		}
+		buf := make([]byte, size-offset)
+		if _, err := file.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return "", false
+		}
+
+		content := string(buf)
+		if offset > 0 {
+			// Drop the first, partial line so line prefixes stay intact
+			if idx := strings.IndexByte(content, '\n'); idx >= 0 {
+				content = content[idx+1:]
+			} else {
+				content = ""
 			}
 		}
+
+		var b strings.Builder
+		for _, line := range strings.SplitAfter(content, "\n") {
+			line = strings.TrimPrefix(line, "stdout:")
+			line = strings.TrimPrefix(line, "stderr:")
+			b.WriteString(line)
+		}
+		if b.Len() >= n || offset == 0 {
+			return b.String(), true
+		}
 	}
+}
 
-	// Attach writer for future output
+// GetOutputTailLines returns the last n lines of a process's combined output, without
+// their trailing newline. Like GetOutputTail it reads the end of the combined log file,
+// falling back to the in-memory logs when the file is unavailable.
+func (pm *ProcessManager) GetOutputTailLines(identifier string, n int) ([]string, error) {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return nil, fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+	if n <= 0 {
+		return []string{}, nil
+	}
+
+	// Grow the tail until it holds n complete lines or the whole output
+	for size := 4096; ; size *= 2 {
+		output, ok := readCombinedLogTail(process.LogFile, size)
+		if !ok {
+			process.logLock.RLock()
+			output = process.logs.String()
+			process.logLock.RUnlock()
+		}
+		complete := !ok || len(output) < size
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		if output == "" {
+			lines = []string{}
+		}
+		if len(lines) > n {
+			return lines[len(lines)-n:], nil
+		}
+		if complete {
+			return lines, nil
+		}
+	}
+}
+
+// DefaultKeepaliveInterval is how often a [keepalive] line is written to log streams of running processes
+const DefaultKeepaliveInterval = 30 * time.Second
+
+func (pm *ProcessManager) StreamProcessOutput(identifier string, w io.Writer) error {
+	return pm.StreamProcessOutputWithKeepalive(identifier, w, DefaultKeepaliveInterval)
+}
+
+// StreamProcessOutputWithKeepalive is StreamProcessOutput with keepalives written every interval.
+// An interval of 0 disables them, for callers sharing one keepalive across several processes.
+func (pm *ProcessManager) StreamProcessOutputWithKeepalive(identifier string, w io.Writer, interval time.Duration) error {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+
+	// Snapshot the backlog and attach the writer under the log lock: output is broadcast under
+	// the same lock, so a writer joining mid-run gets every line exactly once, without gaps.
+	// The backlog is written outside of the lock, so a slow client doesn't hold up the others.
+	sub := &catchUpWriter{w: w}
 	process.logLock.Lock()
-	process.logWriters = append(process.logWriters, w)
+	for process.startedAs != nil {
+		// A queued placeholder looked up just before its process started
+		next := process.startedAs
+		process.logLock.Unlock()
+		process = next
+		process.logLock.Lock()
+	}
+	backlog := logBacklog(process)
+	process.logWriters = append(process.logWriters, sub)
 	process.logLock.Unlock()
+	sub.catchUp(process, backlog)
+
+	if interval <= 0 {
+		return nil
+	}
 
 	// Start keepalive goroutine to prevent connection timeout
 	go func() {
-		ticker := time.NewTicker(30 * time.Second)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		for range ticker.C {
@@ -1227,6 +1751,9 @@ func (pm *ProcessManager) RemoveLogWriter(identifier string, w io.Writer) error
 	defer process.logLock.Unlock()
 
 	for i, writer := range process.logWriters {
+		if sub, ok := writer.(*catchUpWriter); ok && sub.w == w {
+			writer = w
+		}
 		if writer == w {
 			// Remove this writer
 			process.logWriters = append(process.logWriters[:i], process.logWriters[i+1:]...)
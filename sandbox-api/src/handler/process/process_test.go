@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -938,3 +939,170 @@ func TestLargeOutputStreaming(t *testing.T) {
 		}
 	})
 }
+
+// TestGetOutputTail verifies that the tail of a failed process's output is
+// returned in production order, without stream prefixes, and bounded in size.
+func TestGetOutputTail(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("echo first; echo 'boom' >&2; exit 3", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+
+	tail, err := pm.GetOutputTail(pid, 4096)
+	if err != nil {
+		t.Fatalf("Failed to get output tail: %v", err)
+	}
+	if tail != "first\nboom\n" {
+		t.Errorf("Expected full output without prefixes, got %q", tail)
+	}
+
+	tail, err = pm.GetOutputTail(pid, 5)
+	if err != nil {
+		t.Fatalf("Failed to get output tail: %v", err)
+	}
+	if tail != "boom\n" {
+		t.Errorf("Expected last 5 bytes, got %q", tail)
+	}
+
+	if _, err := pm.GetOutputTail("does-not-exist", 10); err == nil {
+		t.Error("Expected error for unknown process")
+	}
+}
+
+func TestPreviewEnv(t *testing.T) {
+	t.Setenv("PREVIEW_SYSTEM_VAR", "system")
+	t.Setenv("PREVIEW_OVERRIDDEN_VAR", "system")
+
+	env := PreviewEnv(map[string]string{"PREVIEW_OVERRIDDEN_VAR": "custom", "PREVIEW_CUSTOM_VAR": "a=b"})
+	for key, want := range map[string]string{
+		"PREVIEW_SYSTEM_VAR":     "system",
+		"PREVIEW_OVERRIDDEN_VAR": "custom",
+		"PREVIEW_CUSTOM_VAR":     "a=b",
+	} {
+		if got := env[key]; got != want {
+			t.Errorf("env %s: expected %q, got %q", key, want, got)
+		}
+	}
+}
+
+func TestGetOutputTailLines(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("for i in 1 2 3 4 5 6; do echo line$i; done", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+
+	lines, err := pm.GetOutputTailLines(pid, 3)
+	if err != nil {
+		t.Fatalf("Failed to get output tail lines: %v", err)
+	}
+	if strings.Join(lines, ",") != "line4,line5,line6" {
+		t.Errorf("Expected the last 3 lines, got %q", lines)
+	}
+
+	lines, err = pm.GetOutputTailLines(pid, 100)
+	if err != nil || len(lines) != 6 {
+		t.Errorf("Expected every line when asking for more, got %q (%v)", lines, err)
+	}
+}
+
+func TestGetProcessOutputSince(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("echo one; echo two >&2", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+
+	logs, err := pm.GetProcessOutput(pid)
+	if err != nil {
+		t.Fatalf("Failed to get output: %v", err)
+	}
+	if logs.Cursor != len("stdout:one\nstderr:two\n") {
+		t.Fatalf("Expected the cursor at the end of the combined log, got %d", logs.Cursor)
+	}
+
+	since, err := pm.GetProcessOutputSince(pid, logs.Cursor)
+	if err != nil {
+		t.Fatalf("Failed to get output since cursor: %v", err)
+	}
+	if since.Logs != "" || since.Cursor != logs.Cursor {
+		t.Errorf("Expected no new output at the end cursor, got %+v", since)
+	}
+
+	since, err = pm.GetProcessOutputSince(pid, len("stdout:one\n"))
+	if err != nil {
+		t.Fatalf("Failed to get output since cursor: %v", err)
+	}
+	if since.Stdout != "" || since.Stderr != "two\n" || since.Logs != "two\n" || since.Cursor != logs.Cursor {
+		t.Errorf("Expected only the stderr line after the cursor, got %+v", since)
+	}
+
+	// A cursor past the end means the log was truncated, so everything is returned again
+	since, err = pm.GetProcessOutputSince(pid, logs.Cursor+100)
+	if err != nil {
+		t.Fatalf("Failed to get output since cursor: %v", err)
+	}
+	if since.Logs != "one\ntwo\n" || since.Stdout != "one\n" || since.Stderr != "two\n" {
+		t.Errorf("Expected the whole output for a stale cursor, got %+v", since)
+	}
+
+	if _, err := pm.GetProcessOutputSince(pid, -1); err == nil {
+		t.Error("Expected error for a negative cursor")
+	}
+}
+
+func TestGetProcessOutputSinceLastRestart(t *testing.T) {
+	pm := GetProcessManager()
+
+	counter := filepath.Join(t.TempDir(), "runs")
+	command := fmt.Sprintf("n=$(($(cat %[1]s 2>/dev/null || echo 0) + 1)); echo $n > %[1]s; echo run-$n; sleep 0.1; echo err-$n >&2; sleep 0.1; echo end-$n; exit 1", counter)
+	name := fmt.Sprintf("since-last-restart-%d", time.Now().UnixNano())
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcessWithName(command, "", name, nil, true, 2, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	waitForProcessDone(t, done, 10*time.Second)
+
+	all, err := pm.GetProcessOutput(pid)
+	if err != nil {
+		t.Fatalf("Failed to get output: %v", err)
+	}
+	if !strings.Contains(all.Stdout, "run-1") || !strings.Contains(all.Stdout, "run-3") {
+		t.Fatalf("Expected the output of every run, got %q", all.Stdout)
+	}
+
+	logs, err := pm.GetProcessOutputSinceLastRestart(pid)
+	if err != nil {
+		t.Fatalf("Failed to get output since last restart: %v", err)
+	}
+	if logs.Stdout != "run-3\nend-3\n" || logs.Stderr != "err-3\n" {
+		t.Errorf("Expected only the output of the last run, got %+v", logs)
+	}
+	if logs.Logs != "run-3\nerr-3\nend-3\n" {
+		t.Errorf("Expected the logs of the last run interleaved, got %q", logs.Logs)
+	}
+
+	if _, err := pm.GetProcessOutputSinceLastRestart("does-not-exist"); err == nil {
+		t.Error("Expected error for an unknown process")
+	}
+}
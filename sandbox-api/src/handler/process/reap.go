@@ -0,0 +1,126 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReapedProcess describes a zombie child reaped by ReapZombies
+type ReapedProcess struct {
+	PID      int    `json:"pid" example:"4242"`
+	Command  string `json:"command" example:"node"`            // Executable name, from /proc/<pid>/stat
+	ExitCode *int   `json:"exitCode,omitempty" example:"0"`    // Exit code, 128+signal when killed by a signal
+	Signal   string `json:"signal,omitempty" example:"killed"` // Signal that terminated the process, if any
+} // @name ReapedProcess
+
+// zombieReapGracePeriod is how long a zombie must stay unreaped before ReapZombies collects it,
+// so that children about to be waited for by their exec.Cmd are left alone
+var zombieReapGracePeriod = 500 * time.Millisecond
+
+// findZombieChildren returns the zombie children of ppid, mapped to their executable name
+func findZombieChildren(ppid int) (map[int]string, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	zombies := make(map[int]string)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue // The process exited (and was reaped) while scanning
+		}
+		// Format: "pid (comm) state ppid ...", comm may contain spaces and parentheses
+		stat := string(data)
+		open, end := strings.Index(stat, "("), strings.LastIndex(stat, ")")
+		if open < 0 || end < open {
+			continue
+		}
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 2 || fields[0] != "Z" {
+			continue
+		}
+		if parent, err := strconv.Atoi(fields[1]); err != nil || parent != ppid {
+			continue
+		}
+		zombies[pid] = stat[open+1 : end]
+	}
+	return zombies, nil
+}
+
+// ReapZombies waits for the zombie children of the API process and returns them.
+// Children of running managed processes are skipped since their exec.Cmd reaps them,
+// as are zombies that disappear within zombieReapGracePeriod.
+func (pm *ProcessManager) ReapZombies() ([]ReapedProcess, error) {
+	zombies, err := findZombieChildren(os.Getpid())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child processes: %w", err)
+	}
+	reaped := make([]ReapedProcess, 0)
+	if len(zombies) == 0 {
+		return reaped, nil
+	}
+
+	managed := make(map[int]bool)
+	pm.mu.RLock()
+	for _, process := range pm.processes {
+		if process.Status == StatusRunning && process.ProcessPid > 0 {
+			managed[process.ProcessPid] = true
+		}
+	}
+	pm.mu.RUnlock()
+
+	time.Sleep(zombieReapGracePeriod)
+	stillZombies, err := findZombieChildren(os.Getpid())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child processes: %w", err)
+	}
+
+	for pid, command := range zombies {
+		if managed[pid] {
+			continue
+		}
+		if _, ok := stillZombies[pid]; !ok {
+			continue
+		}
+
+		var wstatus syscall.WaitStatus
+		wpid, err := syscall.Wait4(pid, &wstatus, syscall.WNOHANG, nil)
+		if err != nil || wpid != pid {
+			if err != nil && !errors.Is(err, syscall.ECHILD) {
+				logrus.WithField("pid", pid).WithError(err).Warn("Failed to reap zombie process")
+			}
+			continue
+		}
+
+		result := ReapedProcess{PID: pid, Command: command}
+		if wstatus.Exited() {
+			exitCode := wstatus.ExitStatus()
+			result.ExitCode = &exitCode
+		} else if wstatus.Signaled() {
+			exitCode := 128 + int(wstatus.Signal())
+			result.ExitCode = &exitCode
+			result.Signal = wstatus.Signal().String()
+		}
+		reaped = append(reaped, result)
+
+		fields := logrus.Fields{"pid": pid, "command": command}
+		if result.ExitCode != nil {
+			fields["exitCode"] = *result.ExitCode
+		}
+		logrus.WithFields(fields).Info("Reaped zombie process")
+	}
+	return reaped, nil
+}
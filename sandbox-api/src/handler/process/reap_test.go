@@ -0,0 +1,61 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestReapZombies(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skipf("/proc not available: %v", err)
+	}
+	previous := zombieReapGracePeriod
+	zombieReapGracePeriod = 50 * time.Millisecond
+	defer func() { zombieReapGracePeriod = previous }()
+
+	// Started but never waited for: the child stays a zombie once it exits
+	cmd := exec.Command("sh", "-c", "exit 3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	pid := cmd.Process.Pid
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		zombies, err := findZombieChildren(os.Getpid())
+		if err != nil {
+			t.Fatalf("findZombieChildren failed: %v", err)
+		}
+		if zombies[pid] == "sh" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Process %d never became a zombie child: %v", pid, zombies)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	reaped, err := NewProcessManager().ReapZombies()
+	if err != nil {
+		t.Fatalf("ReapZombies failed: %v", err)
+	}
+	var found *ReapedProcess
+	for i := range reaped {
+		if reaped[i].PID == pid {
+			found = &reaped[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected %d to be reaped, got %+v", pid, reaped)
+	}
+	if found.ExitCode == nil || *found.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %v", found.ExitCode)
+	}
+
+	zombies, _ := findZombieChildren(os.Getpid())
+	if _, ok := zombies[pid]; ok {
+		t.Errorf("Process %d is still a zombie", pid)
+	}
+}
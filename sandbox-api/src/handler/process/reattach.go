@@ -0,0 +1,88 @@
+package process
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// reattachTimeout bounds how long ReattachLogs waits for the current log capture to stop
+const reattachTimeout = 5 * time.Second
+
+// logTailer lets ReattachLogs stop a tailLogFiles goroutine without closing TailDone
+type logTailer struct {
+	detach     chan struct{}
+	detachOnce sync.Once
+	stopped    chan struct{}
+}
+
+func newLogTailer() *logTailer {
+	return &logTailer{detach: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+// stop asks the tailer to return and waits for it, at most timeout. It reports whether the
+// tailer stopped.
+func (t *logTailer) stop(timeout time.Duration) bool {
+	t.detachOnce.Do(func() { close(t.detach) })
+	select {
+	case <-t.stopped:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// ReattachLogs restarts the log capture of a running process without touching the process.
+// The current capture is stopped, the in-memory output is rebuilt from the stdout and stderr
+// log files, and the files are re-opened and tailed from their current end. Output written
+// while the capture was broken is part of the rebuilt output but is not replayed to streams.
+// Processes adopted after an API restart have no capture to reattach: only their output is
+// rebuilt, as their logs are read on demand.
+func (pm *ProcessManager) ReattachLogs(identifier string) error {
+	process, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+	if process.Status != StatusRunning {
+		return fmt.Errorf("process with Identifier %s is not running", identifier)
+	}
+	if process.StdoutFile == "" || process.StderrFile == "" {
+		return fmt.Errorf("process with Identifier %s has no log files", identifier)
+	}
+
+	process.logLock.RLock()
+	tailer := process.tailer
+	process.logLock.RUnlock()
+	if tailer != nil && !tailer.stop(reattachTimeout) {
+		return fmt.Errorf("log capture of process with Identifier %s did not stop within %s", identifier, reattachTimeout)
+	}
+
+	process.logLock.Lock()
+	defer process.logLock.Unlock()
+	if process.tailer != tailer {
+		// A concurrent reattach already took over
+		return nil
+	}
+
+	stdout := readLogsSince(process.StdoutFile, 0)
+	stderr := readLogsSince(process.StderrFile, 0)
+	process.stdout.Reset()
+	process.stdout.Write(stdout)
+	process.stderr.Reset()
+	process.stderr.Write(stderr)
+	process.logs.Reset()
+	process.logs.Write(stdout)
+	process.logs.Write(stderr)
+
+	select {
+	case <-process.TailDone:
+		// The process ended and its capture already did the final reads
+		return nil
+	default:
+	}
+	if tailer != nil {
+		process.tailer = newLogTailer()
+		go pm.tailLogFilesFrom(process, process.tailer, int64(len(stdout)), int64(len(stderr)))
+	}
+	return nil
+}
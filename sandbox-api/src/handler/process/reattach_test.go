@@ -0,0 +1,70 @@
+package process
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// waitForOutput waits until the in-memory stdout of a process equals want
+func waitForOutput(t *testing.T, proc *ProcessInfo, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if proc.readBuffer(proc.stdout) == want {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Expected stdout %q, got %q", want, proc.readBuffer(proc.stdout))
+}
+
+func TestReattachLogs(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	name := fmt.Sprintf("reattach-test-%d", time.Now().UnixNano())
+	pid, err := pm.StartProcessWithName("echo one; sleep 1; echo two; sleep 30", "", name, nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = pm.KillProcess(pid) }()
+
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	waitForOutput(t, proc, "one\n")
+
+	// Simulate a capture gone wrong
+	proc.logLock.Lock()
+	proc.stdout.WriteString("garbage")
+	proc.logs.WriteString("garbage")
+	proc.logLock.Unlock()
+
+	if err := pm.ReattachLogs(pid); err != nil {
+		t.Fatalf("Failed to reattach logs: %v", err)
+	}
+	if logs := proc.readBuffer(proc.logs); logs != "one\n" {
+		t.Errorf("Expected the output rebuilt from the log files, got %q", logs)
+	}
+
+	// Capture resumes where the files were read, without duplicating output
+	waitForOutput(t, proc, "one\ntwo\n")
+
+	if err := pm.KillProcess(pid); err != nil {
+		t.Fatalf("Failed to kill process: %v", err)
+	}
+	waitForProcessDone(t, done, 5*time.Second)
+	select {
+	case <-proc.TailDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the reattached capture to finish its final reads")
+	}
+
+	if err := pm.ReattachLogs(pid); err == nil {
+		t.Error("Expected error reattaching a process that is not running")
+	}
+	if err := pm.ReattachLogs("does-not-exist"); err == nil {
+		t.Error("Expected error for an unknown process")
+	}
+}
@@ -0,0 +1,25 @@
+package process
+
+import "fmt"
+
+// RefreshProcess re-evaluates right away whether a process is still running and returns it.
+// Processes adopted after an API restart are otherwise only checked every second. Processes
+// started by this API are waited on and already reflect their exit.
+func (pm *ProcessManager) RefreshProcess(identifier string) (*ProcessInfo, error) {
+	p, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return nil, fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+	if p.refresh == nil {
+		return p, nil
+	}
+
+	reply := make(chan struct{})
+	select {
+	case p.refresh <- reply:
+		<-reply
+	case <-p.Done:
+		// No longer monitored, the process has already ended
+	}
+	return p, nil
+}
@@ -0,0 +1,79 @@
+package process
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRefreshAdoptedProcess(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	t.Setenv("SANDBOX_STATE_FILE", stateFile)
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = cmd.Process.Kill() }()
+
+	state := ManagerState{
+		Version: 1,
+		SavedAt: time.Now(),
+		Processes: map[string]ProcessState{
+			"refresh-test": {
+				PID:        "refresh-test",
+				Name:       "refresh-test",
+				Command:    "sleep 30",
+				ProcessPid: cmd.Process.Pid,
+				StartedAt:  time.Now(),
+				Status:     StatusRunning,
+			},
+		},
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("Failed to marshal state: %v", err)
+	}
+	if err := os.WriteFile(stateFile, data, 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	pm := NewProcessManager()
+	if err := pm.LoadState(); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	proc, err := pm.RefreshProcess("refresh-test")
+	if err != nil {
+		t.Fatalf("Failed to refresh process: %v", err)
+	}
+	if proc.Status != StatusRunning {
+		t.Fatalf("Expected the adopted process to be running, got %s", proc.Status)
+	}
+
+	// Wait for the process to exit without reaping it, like a process the API did not start
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+	for i := 0; i < 100 && isProcessRunning(cmd.Process.Pid); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	proc, err = pm.RefreshProcess("refresh-test")
+	if err != nil {
+		t.Fatalf("Failed to refresh process: %v", err)
+	}
+	if proc.Status == StatusRunning || proc.CompletedAt == nil {
+		t.Errorf("Expected the refresh to record the exit right away, got %s", proc.Status)
+	}
+
+	// Refreshing an ended process is a no-op
+	if _, err := pm.RefreshProcess("refresh-test"); err != nil {
+		t.Errorf("Expected refreshing an ended process to succeed, got %v", err)
+	}
+	if _, err := pm.RefreshProcess("does-not-exist"); err == nil {
+		t.Error("Expected error for an unknown process")
+	}
+}
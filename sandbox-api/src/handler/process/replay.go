@@ -0,0 +1,127 @@
+package process
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// logEvent is a piece of output for a log writer, written with writeToLogWriter, or as is
+// when eventType is empty (restart and termination notices)
+type logEvent struct {
+	eventType string
+	data      []byte
+}
+
+// logBacklog returns the output produced so far. It reads the combined log file, which has
+// prefixed, ordered content written by tailLogFiles with "stdout:" and "stderr:" prefixes,
+// and falls back to the in-memory buffers when there is no log file.
+// The caller must hold process.logLock.
+func logBacklog(process *ProcessInfo) []logEvent {
+	var events []logEvent
+	if process.LogFile == "" {
+		if process.stdout.Len() > 0 {
+			events = append(events, logEvent{"stdout", []byte(process.stdout.String())})
+		}
+		if process.stderr.Len() > 0 {
+			events = append(events, logEvent{"stderr", []byte(process.stderr.String())})
+		}
+		return events
+	}
+
+	content, err := os.ReadFile(process.LogFile)
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	// Parse prefixed lines and send as proper events
+	// This ensures JSONStreamWriter receives structured stdout/stderr events
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "stdout:") {
+			events = append(events, logEvent{"stdout", []byte(strings.TrimPrefix(line, "stdout:") + "\n")})
+		} else if strings.HasPrefix(line, "stderr:") {
+			events = append(events, logEvent{"stderr", []byte(strings.TrimPrefix(line, "stderr:") + "\n")})
+		} else if line != "" {
+			// Fallback for unprefixed lines (shouldn't happen, but handle gracefully)
+			events = append(events, logEvent{"stdout", []byte(line + "\n")})
+		}
+	}
+	return events
+}
+
+var _ JSONStreamWriter = (*catchUpWriter)(nil)
+
+// catchUpWriter stands for a log writer in process.logWriters while its backlog is written
+// outside of the log lock. Output broadcast meanwhile is queued, then written once the
+// backlog is, and the writer itself replaces it once nothing is left to catch up on.
+type catchUpWriter struct {
+	w       io.Writer
+	mu      sync.Mutex
+	pending []logEvent
+	done    bool
+}
+
+// WriteEvent queues output broadcast to the writer while it catches up
+func (c *catchUpWriter) WriteEvent(eventType string, data string) (int, error) {
+	c.mu.Lock()
+	if !c.done {
+		c.pending = append(c.pending, logEvent{eventType, []byte(data)})
+		c.mu.Unlock()
+		return len(data), nil
+	}
+	c.mu.Unlock()
+	writeToLogWriter(c.w, eventType, []byte(data))
+	return len(data), nil
+}
+
+// IsJSONStreamWriter makes the process manager send typed events, written to the writer
+// with writeToLogWriter once caught up
+func (c *catchUpWriter) IsJSONStreamWriter() bool {
+	return true
+}
+
+// Write queues notices broadcast to the writer while it catches up
+func (c *catchUpWriter) Write(data []byte) (int, error) {
+	c.mu.Lock()
+	if !c.done {
+		c.pending = append(c.pending, logEvent{data: append([]byte(nil), data...)})
+		c.mu.Unlock()
+		return len(data), nil
+	}
+	c.mu.Unlock()
+	return c.w.Write(data)
+}
+
+// catchUp writes the backlog, then the output queued meanwhile, and swaps in the writer
+// under the log lock once the queue is empty
+func (c *catchUpWriter) catchUp(process *ProcessInfo, backlog []logEvent) {
+	for {
+		for _, event := range backlog {
+			if event.eventType == "" {
+				_, _ = c.w.Write(event.data)
+				if f, ok := c.w.(interface{ Flush() }); ok {
+					f.Flush()
+				}
+				continue
+			}
+			writeToLogWriter(c.w, event.eventType, event.data)
+		}
+
+		process.logLock.Lock()
+		c.mu.Lock()
+		backlog, c.pending = c.pending, nil
+		if len(backlog) == 0 {
+			c.done = true
+			for i, writer := range process.logWriters {
+				if writer == c {
+					process.logWriters[i] = c.w
+				}
+			}
+		}
+		c.mu.Unlock()
+		process.logLock.Unlock()
+		if len(backlog) == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks every write until its gate is closed.
+type gatedWriter struct {
+	testWriter
+	gate chan struct{}
+}
+
+func (gw *gatedWriter) Write(p []byte) (int, error) {
+	<-gw.gate
+	return gw.testWriter.Write(p)
+}
+
+// TestSlowSubscriberDoesNotBlockOthers attaches a writer that stalls while the backlog
+// is replayed and checks that other writers keep receiving output in the meantime, and
+// that the stalled writer still gets every line exactly once once it catches up.
+func TestSlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	pid, err := pm.StartProcess("for i in $(seq 1 30); do echo line$i; sleep 0.05; done", "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	probe := &testWriter{}
+	if err := pm.StreamProcessOutput(pid, probe); err != nil {
+		t.Fatalf("Failed to attach probe writer: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(probe.String(), "line2\n") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	slow := &gatedWriter{gate: make(chan struct{})}
+	attached := make(chan error, 1)
+	go func() {
+		attached <- pm.StreamProcessOutput(pid, slow)
+	}()
+
+	live := &testWriter{}
+	if err := pm.StreamProcessOutput(pid, live); err != nil {
+		t.Fatalf("Failed to attach live writer: %v", err)
+	}
+	before := len(live.String())
+	deadline = time.Now().Add(5 * time.Second)
+	for len(live.String()) == before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(live.String()) == before {
+		t.Fatal("Live writer stopped receiving output while another writer was replaying")
+	}
+
+	close(slow.gate)
+	if err := <-attached; err != nil {
+		t.Fatalf("Failed to attach slow writer: %v", err)
+	}
+	waitForProcessDone(t, done, 10*time.Second)
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	<-proc.TailDone
+	_ = pm.RemoveLogWriter(pid, probe)
+	_ = pm.RemoveLogWriter(pid, slow)
+	_ = pm.RemoveLogWriter(pid, live)
+
+	var want strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&want, "line%d\n", i)
+	}
+	if got := strings.ReplaceAll(slow.String(), "stdout:", ""); got != want.String() {
+		t.Errorf("slow writer: expected %q, got %q", want.String(), got)
+	}
+}
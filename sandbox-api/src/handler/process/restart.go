@@ -0,0 +1,122 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
+	"github.com/sirupsen/logrus"
+)
+
+// restartHistoryEnv carries the times of the recent restarts to the re-executed binary
+const restartHistoryEnv = "SANDBOX_RESTART_HISTORY"
+
+// MaxRestartsPerWindow is how many restarts are allowed within RestartWindow, to stop restart loops.
+// Can be configured via SANDBOX_MAX_RESTARTS environment variable.
+var MaxRestartsPerWindow = 5
+
+// RestartWindow is the period over which restarts are counted
+const RestartWindow = 10 * time.Minute
+
+// ErrTooManyRestarts is returned when the API already restarted MaxRestartsPerWindow times within RestartWindow
+var ErrTooManyRestarts = errors.New("too many restarts, try again later")
+
+// ErrRestartInProgress is returned when a restart was already requested
+var ErrRestartInProgress = errors.New("a restart is already in progress")
+
+var restarting atomic.Bool
+
+func init() {
+	if value := os.Getenv("SANDBOX_MAX_RESTARTS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			MaxRestartsPerWindow = n
+		}
+	}
+}
+
+// recentRestarts parses a restart history, keeping the restarts within RestartWindow of now
+func recentRestarts(history string, now time.Time) []int64 {
+	var recent []int64
+	for _, field := range strings.Split(history, ",") {
+		ts, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		if now.Sub(time.Unix(ts, 0)) < RestartWindow {
+			recent = append(recent, ts)
+		}
+	}
+	return recent
+}
+
+// recordRestart adds now to a restart history, or fails with ErrTooManyRestarts
+func recordRestart(history string, now time.Time) (string, error) {
+	recent := recentRestarts(history, now)
+	if len(recent) >= MaxRestartsPerWindow {
+		return "", ErrTooManyRestarts
+	}
+	fields := make([]string, 0, len(recent)+1)
+	for _, ts := range recent {
+		fields = append(fields, strconv.FormatInt(ts, 10))
+	}
+	fields = append(fields, strconv.FormatInt(now.Unix(), 10))
+	return strings.Join(fields, ","), nil
+}
+
+// PrepareRestart reserves a restart: it fails when one is already in progress or when
+// the API restarted too often recently. Call TriggerRestart afterwards.
+func PrepareRestart() error {
+	if _, err := recordRestart(os.Getenv(restartHistoryEnv), time.Now()); err != nil {
+		return err
+	}
+	if !restarting.CompareAndSwap(false, true) {
+		return ErrRestartInProgress
+	}
+	return nil
+}
+
+// CancelRestart releases a restart reserved by PrepareRestart that will not be triggered
+func CancelRestart() {
+	restarting.Store(false)
+}
+
+// TriggerRestart re-executes the current binary, so configuration read at startup is
+// applied without downloading a new release. State is saved and streaming clients are
+// asked to reconnect first, so running processes are adopted by the new instance as
+// during an upgrade, and the restart counts in the upgrade count.
+func TriggerRestart() {
+	logger := logrus.WithField("component", "restart")
+	defer restarting.Store(false)
+
+	history, err := recordRestart(os.Getenv(restartHistoryEnv), time.Now())
+	if err != nil {
+		logger.WithError(err).Error("Restart refused")
+		return
+	}
+
+	currentExe, err := os.Executable()
+	if err != nil {
+		logger.WithError(err).Error("Failed to get current executable path")
+		return
+	}
+	if resolved, err := filepath.EvalSymlinks(currentExe); err == nil {
+		currentExe = resolved
+	}
+
+	if err := os.Setenv(restartHistoryEnv, history); err != nil {
+		logger.WithError(err).Error("Failed to record the restart")
+		return
+	}
+	if err := GetProcessManager().SaveState(); err != nil {
+		logger.WithError(err).Warn("Failed to save state before exec, continuing with existing state file")
+	}
+	drain.Drain(StreamDrainTimeout)
+
+	logger.WithField("executable", currentExe).Info("Restarting sandbox-api...")
+	execIntoNewBinary(currentExe)
+}
@@ -0,0 +1,33 @@
+package process
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRecordRestart(t *testing.T) {
+	now := time.Now()
+	old := strconv.FormatInt(now.Add(-2*RestartWindow).Unix(), 10)
+
+	history, err := recordRestart(old+",garbage", now)
+	if err != nil {
+		t.Fatalf("recordRestart failed: %v", err)
+	}
+	if history != strconv.FormatInt(now.Unix(), 10) {
+		t.Errorf("Expected old and invalid entries to be dropped, got %q", history)
+	}
+
+	for i := 1; i < MaxRestartsPerWindow; i++ {
+		if history, err = recordRestart(history, now); err != nil {
+			t.Fatalf("restart %d refused: %v", i+1, err)
+		}
+	}
+	if _, err := recordRestart(history, now); !errors.Is(err, ErrTooManyRestarts) {
+		t.Errorf("Expected ErrTooManyRestarts, got %v", err)
+	}
+	if _, err := recordRestart(history, now.Add(RestartWindow)); err != nil {
+		t.Errorf("Expected restarts to be allowed once the window passed, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package process
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithRetainLogs sets whether the log files of the process are kept once it is done for good.
+// Nil keeps them, the default.
+func WithRetainLogs(retain *bool) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.RetainLogs = retain
+	}
+}
+
+// logsRetained reports whether the log files of a process are kept once it is done
+func logsRetained(process *ProcessInfo) bool {
+	return process.RetainLogs == nil || *process.RetainLogs
+}
+
+// releaseLogFiles removes the log files of a finished process started with retainLogs=false.
+// It must only be called once the files were fully read, the output then being served from
+// memory and saved with the state.
+func releaseLogFiles(process *ProcessInfo) {
+	if logsRetained(process) {
+		return
+	}
+	for _, path := range []string{process.StdoutFile, process.StderrFile, process.LogFile} {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logrus.WithFields(logrus.Fields{
+				"pid":      process.PID,
+				"name":     process.Name,
+				"log-file": path,
+			}).WithError(err).Warn("Failed to remove process log file")
+		}
+	}
+}
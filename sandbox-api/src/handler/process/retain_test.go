@@ -0,0 +1,49 @@
+package process
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRetainLogs(t *testing.T) {
+	pm := NewProcessManager()
+
+	for _, tc := range []struct {
+		name   string
+		retain *bool
+		kept   bool
+	}{
+		{"retain-logs-default", nil, true},
+		{"retain-logs-true", boolPtr(true), true},
+		{"retain-logs-false", boolPtr(false), false},
+	} {
+		done := make(chan *ProcessInfo, 1)
+		pid, err := pm.StartProcessWithName("echo out; echo err >&2", "", tc.name, nil, false, 0, false, 0, func(p *ProcessInfo) {
+			done <- p
+		}, WithRetainLogs(tc.retain))
+		if err != nil {
+			t.Fatalf("%s: failed to start process: %v", tc.name, err)
+		}
+		proc := <-done
+
+		for _, path := range []string{proc.StdoutFile, proc.StderrFile, proc.LogFile} {
+			if _, err := os.Stat(path); (err == nil) != tc.kept {
+				t.Errorf("%s: expected log file %s kept=%v, got %v", tc.name, path, tc.kept, err)
+			}
+		}
+
+		// The output is still available once the files are gone
+		output, err := pm.GetProcessOutput(pid)
+		if err != nil {
+			t.Fatalf("%s: failed to get output: %v", tc.name, err)
+		}
+		if !strings.Contains(output.Stdout, "out") || !strings.Contains(output.Stderr, "err") {
+			t.Errorf("%s: expected the output, got %+v", tc.name, output)
+		}
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
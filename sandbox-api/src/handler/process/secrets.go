@@ -0,0 +1,167 @@
+package process
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RedactedEnvValue replaces the values of secret env vars in API responses and logs
+const RedactedEnvValue = "[REDACTED]"
+
+// encryptedEnvPrefix marks env values encrypted in the state file
+const encryptedEnvPrefix = "enc:v1:"
+
+// secretEnvPatterns are glob patterns, matched case-insensitively, of the env var names
+// considered secrets. Can be configured via SANDBOX_SECRET_ENV_PATTERNS environment
+// variable (comma-separated, replaces the defaults).
+var secretEnvPatterns = []string{
+	"*TOKEN*", "*SECRET*", "*PASSWORD*", "*PASSWD*", "*CREDENTIAL*", "*AUTH*", "*JWT*",
+	"*PRIVATE*", "*SESSION*", "*API_KEY*", "*APIKEY*", "*ACCESS_KEY*",
+	"KEY", "KEY_*", "*_KEY", "*_KEY_*",
+}
+
+// stateEncryptionKey encrypts secret env values in the state file so that processes
+// restarted after an upgrade keep them. It is derived (SHA-256) from the
+// SANDBOX_STATE_ENCRYPTION_KEY environment variable; without it, secret env vars are
+// omitted from the state file.
+var stateEncryptionKey []byte
+
+func init() {
+	if value := os.Getenv("SANDBOX_SECRET_ENV_PATTERNS"); value != "" {
+		var patterns []string
+		for _, pattern := range strings.Split(value, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				patterns = append(patterns, pattern)
+			}
+		}
+		secretEnvPatterns = patterns
+	}
+	if value := os.Getenv("SANDBOX_STATE_ENCRYPTION_KEY"); value != "" {
+		key := sha256.Sum256([]byte(value))
+		stateEncryptionKey = key[:]
+	}
+}
+
+// IsSecretEnvKey reports whether an env var name matches one of the secret patterns
+func IsSecretEnvKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, pattern := range secretEnvPatterns {
+		if matched, _ := filepath.Match(strings.ToUpper(pattern), key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactEnv returns a copy of env with the values of secret env vars replaced by RedactedEnvValue
+func RedactEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(env))
+	for key, value := range env {
+		if IsSecretEnvKey(key) {
+			value = RedactedEnvValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// protectEnvForState returns the env to write to the state file: secret values are
+// encrypted when SANDBOX_STATE_ENCRYPTION_KEY is set, and omitted otherwise
+func protectEnvForState(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	protected := make(map[string]string, len(env))
+	for key, value := range env {
+		if !IsSecretEnvKey(key) {
+			protected[key] = value
+			continue
+		}
+		if stateEncryptionKey == nil {
+			continue
+		}
+		encrypted, err := encryptEnvValue(value)
+		if err != nil {
+			logrus.WithField("key", key).WithError(err).Warn("Failed to encrypt env var, omitting it from the state file")
+			continue
+		}
+		protected[key] = encrypted
+	}
+	return protected
+}
+
+// restoreEnvFromState decrypts the secret env values of a loaded state. Values that
+// can't be decrypted (missing or different key) are dropped.
+func restoreEnvFromState(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	restored := make(map[string]string, len(env))
+	for key, value := range env {
+		if strings.HasPrefix(value, encryptedEnvPrefix) {
+			decrypted, err := decryptEnvValue(value)
+			if err != nil {
+				logrus.WithField("key", key).WithError(err).Warn("Failed to decrypt env var from the state file, dropping it")
+				continue
+			}
+			value = decrypted
+		}
+		restored[key] = value
+	}
+	return restored
+}
+
+func envCipher() (cipher.AEAD, error) {
+	if stateEncryptionKey == nil {
+		return nil, errors.New("SANDBOX_STATE_ENCRYPTION_KEY is not set")
+	}
+	block, err := aes.NewCipher(stateEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptEnvValue(value string) (string, error) {
+	gcm, err := envCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedEnvPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptEnvValue(value string) (string, error) {
+	gcm, err := envCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedEnvPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
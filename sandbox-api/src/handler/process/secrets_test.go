@@ -0,0 +1,75 @@
+package process
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func TestIsSecretEnvKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"GITHUB_TOKEN":          true,
+		"AWS_SECRET_ACCESS_KEY": true,
+		"db_password":           true,
+		"KEY":                   true,
+		"SSH_KEY_PATH":          true,
+		"PORT":                  false,
+		"PWD":                   false,
+		"KEYBOARD":              false,
+		"MONKEY":                false,
+	} {
+		if got := IsSecretEnvKey(key); got != want {
+			t.Errorf("IsSecretEnvKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+
+	original := secretEnvPatterns
+	defer func() { secretEnvPatterns = original }()
+	secretEnvPatterns = []string{"MY_*"}
+	if !IsSecretEnvKey("my_var") || IsSecretEnvKey("GITHUB_TOKEN") {
+		t.Errorf("Expected custom patterns to replace the defaults")
+	}
+}
+
+func TestProtectEnvForState(t *testing.T) {
+	original := stateEncryptionKey
+	defer func() { stateEncryptionKey = original }()
+	env := map[string]string{"PORT": "3000", "API_KEY": "s3cr3t"}
+
+	// Without a key, secrets are omitted
+	stateEncryptionKey = nil
+	protected := protectEnvForState(env)
+	if protected["PORT"] != "3000" {
+		t.Errorf("Expected non-secret env to be kept, got %v", protected)
+	}
+	if _, ok := protected["API_KEY"]; ok {
+		t.Errorf("Expected secret env to be omitted without a key, got %v", protected)
+	}
+
+	// With a key, secrets are encrypted and restored on load
+	key := sha256.Sum256([]byte("test-key"))
+	stateEncryptionKey = key[:]
+	protected = protectEnvForState(env)
+	if !strings.HasPrefix(protected["API_KEY"], encryptedEnvPrefix) || strings.Contains(protected["API_KEY"], "s3cr3t") {
+		t.Errorf("Expected secret env to be encrypted, got %q", protected["API_KEY"])
+	}
+	restored := restoreEnvFromState(protected)
+	if restored["API_KEY"] != "s3cr3t" || restored["PORT"] != "3000" {
+		t.Errorf("Expected env to be restored, got %v", restored)
+	}
+
+	// A different key can't decrypt the values, they are dropped
+	other := sha256.Sum256([]byte("other-key"))
+	stateEncryptionKey = other[:]
+	restored = restoreEnvFromState(protected)
+	if _, ok := restored["API_KEY"]; ok || restored["PORT"] != "3000" {
+		t.Errorf("Expected undecryptable env to be dropped, got %v", restored)
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	redacted := RedactEnv(map[string]string{"PORT": "3000", "GITHUB_TOKEN": "ghp_x"})
+	if redacted["PORT"] != "3000" || redacted["GITHUB_TOKEN"] != RedactedEnvValue {
+		t.Errorf("Unexpected redacted env: %v", redacted)
+	}
+}
@@ -23,6 +23,7 @@ func (pm *ProcessManager) ExecuteProcess(
 	restartOnFailure bool,
 	maxRestarts int,
 	keepAlive bool,
+	opts ...ProcessOption,
 ) (*ProcessInfo, error) {
 	portCh := make(chan int)
 	completionCh := make(chan string)
@@ -78,9 +79,9 @@ func (pm *ProcessManager) ExecuteProcess(
 	var pid string
 	var err error
 	if name != "" {
-		pid, err = pm.StartProcessWithName(command, workingDir, name, env, restartOnFailure, maxRestarts, keepAlive, timeout, callback)
+		pid, err = pm.StartProcessWithName(command, workingDir, name, env, restartOnFailure, maxRestarts, keepAlive, timeout, callback, opts...)
 	} else {
-		pid, err = pm.StartProcess(command, workingDir, env, restartOnFailure, maxRestarts, keepAlive, timeout, callback)
+		pid, err = pm.StartProcess(command, workingDir, env, restartOnFailure, maxRestarts, keepAlive, timeout, callback, opts...)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to start process: %w", err)
@@ -90,7 +91,11 @@ func (pm *ProcessManager) ExecuteProcess(
 	if len(waitForPorts) > 0 {
 		n := network.GetNetwork()
 		ports := make([]int, 0, len(waitForPorts))
+		// pid can be a client-provided id, ports are tracked by OS process id
 		pidInt, _ := strconv.Atoi(pid)
+		if proc, exists := pm.GetProcessByIdentifier(pid); exists {
+			pidInt = proc.ProcessPid
+		}
 		n.RegisterPortOpenCallback(pidInt, func(pid int, port *network.PortInfo) {
 			if slices.Contains(waitForPorts, port.LocalPort) {
 				ports = append(ports, port.LocalPort)
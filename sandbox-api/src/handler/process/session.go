@@ -0,0 +1,52 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// WithNewSession starts the process in its own session (setsid) instead of only its own
+// process group, fully detaching it from the session of the API: it gets no signal sent
+// to that session, e.g. a SIGHUP when its leader exits. A session leader also leads its
+// process group, whose id is the process pid, so stopping or killing the process still
+// signals its whole group through ProcessPid.
+func WithNewSession(enabled bool) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.NewSession = enabled
+	}
+}
+
+// processSysProcAttr puts the process in its own process group so that all its children can
+// be killed together, or in its own session for processes started with newSession
+func processSysProcAttr(process *ProcessInfo) *syscall.SysProcAttr {
+	if process.NewSession {
+		// Setsid also creates the process group, Setpgid would fail once the session exists
+		return &syscall.SysProcAttr{Setsid: true}
+	}
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// isSessionLeader reports whether pid leads its own session, as processes started with
+// newSession do. It is used to tell them apart from an unrelated process reusing their pid.
+// Returns true when /proc can't be read.
+func isSessionLeader(pid int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	// Format: "pid (comm) state ppid pgrp session ...", comm may contain spaces and parentheses
+	stat := string(data)
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return true
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 4 {
+		return true
+	}
+	session, err := strconv.Atoi(fields[3])
+	return err != nil || session == pid
+}
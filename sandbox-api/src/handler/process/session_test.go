@@ -0,0 +1,46 @@
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSession(t *testing.T) {
+	pm := NewProcessManager()
+
+	for _, newSession := range []bool{false, true} {
+		done := make(chan struct{})
+		pid, err := pm.StartProcessWithName("sleep 30 & wait", "", "session-test", nil, false, 0, false, 0, func(p *ProcessInfo) {
+			close(done)
+		}, WithNewSession(newSession))
+		if err != nil {
+			t.Fatalf("newSession=%v: failed to start process: %v", newSession, err)
+		}
+		proc, _ := pm.GetProcessByIdentifier(pid)
+
+		if leader := isSessionLeader(proc.ProcessPid); leader != newSession {
+			t.Errorf("newSession=%v: expected session leader %v, got %v", newSession, newSession, leader)
+		}
+		// The whole group is still killed, the background sleep included
+		members, err := processGroupMembers(proc.ProcessPid)
+		if err != nil {
+			t.Fatalf("newSession=%v: failed to list the process group: %v", newSession, err)
+		}
+		if len(members) == 0 {
+			t.Errorf("newSession=%v: expected the process to lead its group", newSession)
+		}
+		if err := pm.KillProcess(pid); err != nil {
+			t.Fatalf("newSession=%v: failed to kill process: %v", newSession, err)
+		}
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("newSession=%v: timed out waiting for the process to be killed", newSession)
+		}
+		for child := range members {
+			if isProcessRunning(child) {
+				t.Errorf("newSession=%v: expected group member %d to be killed", newSession, child)
+			}
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/blaxel-ai/sandbox-api/src/handler/constants"
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
 	"github.com/sirupsen/logrus"
 )
 
@@ -47,7 +48,18 @@ type ProcessState struct {
 	RestartOnFailure bool                    `json:"restartOnFailure"`
 	MaxRestarts      int                     `json:"maxRestarts"`
 	RestartCount     int                     `json:"restartCount"`
-	Env              map[string]string       `json:"env,omitempty"` // Custom env vars provided at start, reused on restart-on-failure
+	Paused           bool                    `json:"paused,omitempty"`
+	Env              map[string]string       `json:"env,omitempty"` // Custom env vars provided at start, reused on restart-on-failure. Secret values are encrypted or omitted.
+	Labels           map[string]string       `json:"labels,omitempty"`
+	LogTag           string                  `json:"logTag,omitempty"`
+	CgroupLimits     *CgroupLimits           `json:"cgroupLimits,omitempty"`
+	CgroupPath       string                  `json:"cgroupPath,omitempty"`
+	EphemeralCwd     bool                    `json:"ephemeralCwd,omitempty"`
+	SaveStateOnExit  bool                    `json:"saveStateOnExit,omitempty"`
+	RetainLogs       *bool                   `json:"retainLogs,omitempty"`
+	NewSession       bool                    `json:"newSession,omitempty"` // Detached session, whose leader is ProcessPid
+	MutexGroup       string                  `json:"mutexGroup,omitempty"`
+	MutexPolicy      string                  `json:"mutexPolicy,omitempty"`
 }
 
 // ManagerState represents the full state of the process manager
@@ -55,6 +67,7 @@ type ManagerState struct {
 	Version   int                     `json:"version"`
 	SavedAt   time.Time               `json:"savedAt"`
 	Processes map[string]ProcessState `json:"processes"`
+	Timezone  string                  `json:"timezone,omitempty"` // Default TZ of new processes
 }
 
 // GetStateFilePath returns the path to the state file
@@ -74,11 +87,17 @@ func (pm *ProcessManager) SaveState() error {
 		Version:   1,
 		SavedAt:   time.Now(),
 		Processes: make(map[string]ProcessState),
+		Timezone:  DefaultTimezone(),
 	}
 
 	logrus.WithField("totalInMemory", len(pm.processes)).Info("SaveState: starting to save processes")
 
 	for pid, proc := range pm.processes {
+		// Queued processes can't be started after a restart, the request that queued them is gone
+		if proc.Status == StatusQueued {
+			continue
+		}
+
 		// Safely read logs under lock
 		proc.logLock.RLock()
 		var logs, stdout, stderr string
@@ -112,7 +131,18 @@ func (pm *ProcessManager) SaveState() error {
 			RestartOnFailure: proc.RestartOnFailure,
 			MaxRestarts:      proc.MaxRestarts,
 			RestartCount:     proc.RestartCount,
-			Env:              proc.Env,
+			Paused:           proc.Paused,
+			Env:              protectEnvForState(proc.Env),
+			Labels:           proc.Labels,
+			LogTag:           proc.LogTag,
+			CgroupLimits:     proc.CgroupLimits,
+			CgroupPath:       proc.CgroupPath,
+			EphemeralCwd:     proc.EphemeralCwd,
+			SaveStateOnExit:  proc.SaveStateOnExit,
+			RetainLogs:       proc.RetainLogs,
+			NewSession:       proc.NewSession,
+			MutexGroup:       proc.MutexGroup,
+			MutexPolicy:      proc.MutexPolicy,
 		}
 
 		logrus.WithFields(logrus.Fields{
@@ -137,7 +167,7 @@ func (pm *ProcessManager) SaveState() error {
 
 	// Write to temp file first, then rename for atomicity
 	tmpFile := stateFile + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
@@ -186,6 +216,10 @@ func (pm *ProcessManager) LoadState() error {
 		"processCount": len(state.Processes),
 	}).Info("LoadState: state file parsed")
 
+	if err := SetDefaultTimezone(state.Timezone); err != nil {
+		logrus.WithError(err).Warn("LoadState: ignoring saved default timezone")
+	}
+
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
@@ -222,7 +256,18 @@ func (pm *ProcessManager) LoadState() error {
 			RestartOnFailure: procState.RestartOnFailure,
 			MaxRestarts:      procState.MaxRestarts,
 			RestartCount:     procState.RestartCount,
-			Env:              procState.Env,
+			Paused:           procState.Paused && isRunning,
+			Env:              restoreEnvFromState(procState.Env),
+			Labels:           procState.Labels,
+			LogTag:           procState.LogTag,
+			CgroupLimits:     procState.CgroupLimits,
+			CgroupPath:       procState.CgroupPath,
+			EphemeralCwd:     procState.EphemeralCwd,
+			SaveStateOnExit:  procState.SaveStateOnExit,
+			RetainLogs:       procState.RetainLogs,
+			NewSession:       procState.NewSession,
+			MutexGroup:       procState.MutexGroup,
+			MutexPolicy:      procState.MutexPolicy,
 			Done:             make(chan struct{}),
 			TailDone:         make(chan struct{}),
 			stdout:           &strings.Builder{},
@@ -266,9 +311,10 @@ func (pm *ProcessManager) LoadState() error {
 		}
 
 		if isRunning && procState.Status == StatusRunning {
-			// Verify the process command matches what we expect
-			// This prevents adopting arbitrary processes that happen to have the same PID
-			if !verifyProcessCommand(proc.ProcessPid, proc.Command) {
+			// Verify the process command matches what we expect, and that a process started in a
+			// new session still leads it. This prevents adopting arbitrary processes that happen
+			// to have the same PID
+			if !verifyProcessCommand(proc.ProcessPid, proc.Command) || (proc.NewSession && !isSessionLeader(proc.ProcessPid)) {
 				logrus.WithFields(logrus.Fields{
 					"pid":        proc.PID,
 					"name":       proc.Name,
@@ -281,6 +327,7 @@ func (pm *ProcessManager) LoadState() error {
 				proc.ExitCode = -1
 				close(proc.Done)
 				close(proc.TailDone)
+				releaseEphemeralWorkingDir(proc)
 				deadCount++
 				pm.processes[pid] = proc
 				continue
@@ -291,8 +338,9 @@ func (pm *ProcessManager) LoadState() error {
 			recoveredCount++
 
 			// Verify the process is actually responsive (can receive signals)
-			// and check if it's still listening on expected ports
-			if !verifyProcessHealth(proc.ProcessPid) {
+			// and check if it's still listening on expected ports. Paused
+			// processes are expected to be in the stopped state.
+			if !proc.Paused && !verifyProcessHealth(proc.ProcessPid) {
 				logrus.WithFields(logrus.Fields{
 					"pid":     proc.PID,
 					"name":    proc.Name,
@@ -305,6 +353,8 @@ func (pm *ProcessManager) LoadState() error {
 			// the child process writes directly to the log file
 
 			// Start a goroutine to monitor the adopted process
+			proc.refresh = make(chan chan struct{})
+			pm.holdMutexGroup(proc.MutexGroup)
 			go pm.monitorAdoptedProcess(proc)
 
 			logrus.WithFields(logrus.Fields{
@@ -338,6 +388,11 @@ func (pm *ProcessManager) LoadState() error {
 			}
 		}
 
+		// Processes that ended while the API was down leave their ephemeral working dir behind
+		if proc.Status != StatusRunning {
+			releaseEphemeralWorkingDir(proc)
+		}
+
 		pm.processes[pid] = proc
 	}
 
@@ -385,6 +440,7 @@ func isProcessRunning(pid int) bool {
 	state := statStr[closeParenIdx+2]
 
 	// Z = zombie, X = dead - these are not running
+	// T = stopped (e.g. paused with SIGSTOP) is still alive and counts as running
 	if state == 'Z' || state == 'X' {
 		return false
 	}
@@ -436,21 +492,28 @@ func reapZombieProcess(pid int) int {
 // It handles TOCTOU issues by reading the file atomically and validating bounds.
 // Returns nil if the file cannot be read or if offset is invalid.
 func readLogsSince(filePath string, offset int) []byte {
+	content, _, _ := readLogsFrom(filePath, offset)
+	return content
+}
+
+// readLogsFrom is readLogsSince that also returns the offset the content was actually
+// read from (0 when the file was truncated) and whether the file could be read.
+func readLogsFrom(filePath string, offset int) ([]byte, int, bool) {
 	if filePath == "" || offset < 0 {
-		return nil
+		return nil, offset, false
 	}
 
 	// Open file for reading
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil
+		return nil, offset, false
 	}
 	defer file.Close()
 
 	// Get file size atomically with the file handle
 	stat, err := file.Stat()
 	if err != nil {
-		return nil
+		return nil, offset, false
 	}
 
 	fileSize := stat.Size()
@@ -468,21 +531,21 @@ func readLogsSince(filePath string, offset int) []byte {
 
 	// Nothing new to read
 	if int64(offset) >= fileSize {
-		return nil
+		return nil, offset, true
 	}
 
 	// Seek to offset
 	if _, err := file.Seek(int64(offset), 0); err != nil {
-		return nil
+		return nil, offset, false
 	}
 
 	// Read remaining content
 	content, err := io.ReadAll(file)
 	if err != nil {
-		return nil
+		return nil, offset, false
 	}
 
-	return content
+	return content, offset, true
 }
 
 // verifyProcessCommand checks if the running process matches the expected command.
@@ -566,59 +629,21 @@ func (pm *ProcessManager) monitorAdoptedProcess(proc *ProcessInfo) {
 		select {
 		case <-ticker.C:
 			checkCount++
-			isRunning := isProcessRunning(proc.ProcessPid)
-
-			if checkCount <= 3 || checkCount%10 == 0 {
-				logrus.WithFields(logrus.Fields{
-					"pid":        proc.PID,
-					"name":       proc.Name,
-					"process-pid": proc.ProcessPid,
-					"isRunning":  isRunning,
-					"checkCount": checkCount,
-				}).Debug("Monitoring adopted process")
+			if pm.checkAdoptedProcess(proc, checkCount) {
+				return
 			}
-
-			if !isRunning {
-				// Process has exited (or is a zombie)
-				now := time.Now()
-				proc.CompletedAt = &now
-
-				// Try to reap the zombie process to clean it up
-				exitCode := reapZombieProcess(proc.ProcessPid)
-
-				// Update status
-				if proc.Status == StatusRunning {
-					proc.Status = StatusCompleted
-					proc.ExitCode = exitCode
-				}
-
-				// Update process in memory
-				pm.mu.Lock()
-				pm.processes[proc.PID] = proc
-				pm.mu.Unlock()
-
-				// Clean up resources
-				proc.logLock.Lock()
-				proc.logWriters = nil
-				proc.logLock.Unlock()
-
-				// Signal that the process is done
-				close(proc.Done)
-				close(proc.TailDone)
-
-				logrus.WithFields(logrus.Fields{
-					"pid":        proc.PID,
-					"name":       proc.Name,
-					"command":    proc.Command,
-					"process-pid": proc.ProcessPid,
-					"checkCount": checkCount,
-				}).Info("Adopted process completed")
-
+		case reply := <-proc.refresh:
+			// Re-checked right away on request instead of waiting for the next tick
+			checkCount++
+			completed := pm.checkAdoptedProcess(proc, checkCount)
+			close(reply)
+			if completed {
 				return
 			}
 		case <-proc.Done:
 			// Process was killed/stopped through our API
 			close(proc.TailDone)
+			pm.leaveMutexGroup(proc.MutexGroup)
 			logrus.WithFields(logrus.Fields{
 				"pid":  proc.PID,
 				"name": proc.Name,
@@ -628,6 +653,67 @@ func (pm *ProcessManager) monitorAdoptedProcess(proc *ProcessInfo) {
 	}
 }
 
+// checkAdoptedProcess checks whether an adopted process is still running and, once it has
+// exited, records its completion and releases its resources. Returns true when it completed.
+func (pm *ProcessManager) checkAdoptedProcess(proc *ProcessInfo, checkCount int) bool {
+	isRunning := isProcessRunning(proc.ProcessPid)
+
+	if checkCount <= 3 || checkCount%10 == 0 {
+		logrus.WithFields(logrus.Fields{
+			"pid":        proc.PID,
+			"name":       proc.Name,
+			"process-pid": proc.ProcessPid,
+			"isRunning":  isRunning,
+			"checkCount": checkCount,
+		}).Debug("Monitoring adopted process")
+	}
+
+	if !isRunning {
+		// Process has exited (or is a zombie)
+		now := time.Now()
+		proc.CompletedAt = &now
+
+		// Try to reap the zombie process to clean it up
+		exitCode := reapZombieProcess(proc.ProcessPid)
+
+		// Update status
+		if proc.Status == StatusRunning {
+			proc.Status = StatusCompleted
+			proc.ExitCode = exitCode
+		}
+
+		// Update process in memory
+		pm.mu.Lock()
+		proc.Paused = false
+		pm.processes[proc.PID] = proc
+		pm.mu.Unlock()
+
+		// Clean up resources
+		releaseCgroup(proc)
+		releaseEphemeralWorkingDir(proc)
+		proc.logLock.Lock()
+		proc.logWriters = nil
+		proc.logLock.Unlock()
+
+		// Signal that the process is done
+		close(proc.Done)
+		close(proc.TailDone)
+
+		logrus.WithFields(logrus.Fields{
+			"pid":        proc.PID,
+			"name":       proc.Name,
+			"command":    proc.Command,
+			"process-pid": proc.ProcessPid,
+			"checkCount": checkCount,
+		}).Info("Adopted process completed")
+
+		pm.leaveMutexGroup(proc.MutexGroup)
+		return true
+	}
+
+	return false
+}
+
 // ClearState removes the state file from disk
 func ClearState() error {
 	stateFile := GetStateFilePath()
@@ -643,6 +729,9 @@ const (
 	DefaultReleaseURL = "https://github.com/blaxel-ai/sandbox/releases"
 	ValidationPort    = 19999 // Port used for validating the new binary
 
+	// StreamDrainTimeout is how long streaming connections get to send their reconnect event before the exec
+	StreamDrainTimeout = 5 * time.Second
+
 	// Download retry/timeout settings
 	downloadMaxRetries     = 3
 	downloadInitialBackoff = 2 * time.Second
@@ -1296,6 +1385,13 @@ func upgradeWithNewBinary(newBinaryPath string) {
 		logger.WithError(err).Warn("Failed to chmod new binary")
 	}
 
+	// Save the latest state first, then let streaming clients know they must reconnect:
+	// their connections are cut by the exec
+	if err := GetProcessManager().SaveState(); err != nil {
+		logger.WithError(err).Warn("Failed to save state before exec, continuing with existing state file")
+	}
+	drain.Drain(StreamDrainTimeout)
+
 	logger.Info("Executing new binary...")
 
 	// Exec into the new binary (this replaces the current process)
@@ -0,0 +1,111 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler/constants"
+)
+
+// statusPollInterval is how often status changes that do not end the process
+// (stop or kill requested, pause, resume) are checked for
+const statusPollInterval = 100 * time.Millisecond
+
+// Status event types
+const (
+	StatusEventStatus  = "status"  // The status changed (or the initial status)
+	StatusEventRestart = "restart" // The process exited and is being restarted (restartOnFailure)
+)
+
+// ProcessStatusEvent describes a status change of a process
+type ProcessStatusEvent struct {
+	Type         string                  `json:"type" example:"status"` // status or restart
+	PID          string                  `json:"pid" example:"1234"`
+	Status       constants.ProcessStatus `json:"status" example:"completed"`
+	ExitCode     int                     `json:"exitCode" example:"0"`
+	RestartCount int                     `json:"restartCount" example:"0"`
+	Paused       bool                    `json:"paused,omitempty"`
+	Timestamp    time.Time               `json:"timestamp"`
+} // @name ProcessStatusEvent
+
+// statusSnapshot reads the fields of a process reported in status events
+func (pm *ProcessManager) statusSnapshot(p *ProcessInfo, eventType string) ProcessStatusEvent {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return ProcessStatusEvent{
+		Type:         eventType,
+		PID:          p.PID,
+		Status:       p.Status,
+		ExitCode:     p.ExitCode,
+		RestartCount: p.RestartCount,
+		Paused:       p.Paused,
+	}
+}
+
+// doneChannel returns the Done channel of the current run of a process, which is replaced on restart
+func (pm *ProcessManager) doneChannel(p *ProcessInfo) chan struct{} {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return p.Done
+}
+
+// WatchStatus calls emit with the current status of a process, then on every status change.
+// When the process exits and restarts on failure, a restart event is emitted and watching goes
+// on with the new run. It returns once the process has ended for good or ctx is done.
+func (pm *ProcessManager) WatchStatus(ctx context.Context, identifier string, emit func(ProcessStatusEvent)) error {
+	p, exists := pm.GetProcessByIdentifier(identifier)
+	if !exists {
+		return fmt.Errorf("process with Identifier %s not found", identifier)
+	}
+
+	send := func(event ProcessStatusEvent) ProcessStatusEvent {
+		event.Timestamp = time.Now()
+		emit(event)
+		return event
+	}
+	changed := func(a, b ProcessStatusEvent) bool {
+		return a.Status != b.Status || a.ExitCode != b.ExitCode || a.RestartCount != b.RestartCount || a.Paused != b.Paused
+	}
+
+	last := send(pm.statusSnapshot(p, StatusEventStatus))
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+
+	done := pm.doneChannel(p)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if current := pm.statusSnapshot(p, StatusEventStatus); changed(current, last) {
+				last = send(current)
+			}
+			continue
+		case <-done:
+		}
+
+		// The run ended: the restart count is increased before Done is closed when it restarts
+		current := pm.statusSnapshot(p, StatusEventStatus)
+		if current.RestartCount == last.RestartCount {
+			if changed(current, last) {
+				send(current)
+			}
+			return nil
+		}
+		last = send(pm.statusSnapshot(p, StatusEventRestart))
+
+		// Wait for the new run to start, it gets a new Done channel
+		for pm.doneChannel(p) == done {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+		done = pm.doneChannel(p)
+		if current := pm.statusSnapshot(p, StatusEventStatus); changed(current, last) {
+			last = send(current)
+		}
+	}
+}
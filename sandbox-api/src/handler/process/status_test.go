@@ -0,0 +1,76 @@
+package process
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchStatus(t *testing.T) {
+	pm := GetProcessManager()
+
+	t.Run("RestartThenComplete", func(t *testing.T) {
+		counter := t.TempDir() + "/attempts"
+		// Fails on the first attempt, succeeds on the second one
+		command := `if [ -f ` + counter + ` ]; then sleep 0.3; exit 0; fi; touch ` + counter + `; sleep 0.3; exit 3`
+		pid, err := pm.StartProcess(command, "", nil, true, 2, false, 0, func(process *ProcessInfo) {})
+		if err != nil {
+			t.Fatalf("Error starting process: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var events []ProcessStatusEvent
+		if err := pm.WatchStatus(ctx, pid, func(event ProcessStatusEvent) {
+			events = append(events, event)
+		}); err != nil {
+			t.Fatalf("WatchStatus failed: %v", err)
+		}
+
+		var sequence []string
+		for _, event := range events {
+			sequence = append(sequence, event.Type+":"+string(event.Status))
+		}
+		expected := []string{"status:running", "restart:failed", "status:running", "status:completed"}
+		if len(sequence) != len(expected) {
+			t.Fatalf("Expected events %v, got %v", expected, sequence)
+		}
+		for i := range expected {
+			if sequence[i] != expected[i] {
+				t.Fatalf("Expected events %v, got %v", expected, sequence)
+			}
+		}
+		if events[1].ExitCode != 3 || events[1].RestartCount != 1 {
+			t.Errorf("Unexpected restart event: %+v", events[1])
+		}
+	})
+
+	t.Run("Killed", func(t *testing.T) {
+		pid, err := pm.StartProcess("sleep 30", "", nil, false, 0, false, 0, func(process *ProcessInfo) {})
+		if err != nil {
+			t.Fatalf("Error starting process: %v", err)
+		}
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_ = pm.KillProcess(pid)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var last ProcessStatusEvent
+		if err := pm.WatchStatus(ctx, pid, func(event ProcessStatusEvent) {
+			last = event
+		}); err != nil {
+			t.Fatalf("WatchStatus failed: %v", err)
+		}
+		if last.Status != StatusKilled {
+			t.Errorf("Expected the last event to be killed, got %+v", last)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if err := pm.WatchStatus(context.Background(), "does-not-exist", func(ProcessStatusEvent) {}); err == nil {
+			t.Error("Expected an error for an unknown process")
+		}
+	})
+}
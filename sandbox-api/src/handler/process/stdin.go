@@ -0,0 +1,142 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stdinPollInterval is how often the stdout of a source process is checked for new output
+const stdinPollInterval = 50 * time.Millisecond
+
+// ErrInvalidStdinSource is returned when stdinFrom does not name exactly one source
+var ErrInvalidStdinSource = errors.New("stdinFrom needs exactly one of path or process")
+
+// StdinSource connects the stdin of a process to a file or to the stdout of another process
+type StdinSource struct {
+	Path    string `json:"path,omitempty" example:"/data/input.csv"` // File fed to stdin, relative paths resolve from the working directory
+	Process string `json:"process,omitempty" example:"producer"`     // Identifier of a managed process whose stdout, from its start, is fed to stdin until it exits
+} // @name StdinSource
+
+// Validate checks that exactly one source is set
+func (s *StdinSource) Validate() error {
+	if (s.Path == "") == (s.Process == "") {
+		return ErrInvalidStdinSource
+	}
+	return nil
+}
+
+// WithStdinFrom connects the stdin of the process to source. Without it, stdin is empty.
+func WithStdinFrom(source *StdinSource) ProcessOption {
+	return func(p *ProcessInfo) {
+		p.StdinFrom = source
+	}
+}
+
+// stdinConnection is the stdin of a process being started
+type stdinConnection struct {
+	file   *os.File     // Given to the child as stdin
+	pipe   *os.File     // Write end fed with the stdout of source, nil for files
+	source *ProcessInfo // Process whose stdout is fed
+}
+
+// openStdin opens the stdin of a process according to its StdinFrom, nil when it has none
+func (pm *ProcessManager) openStdin(proc *ProcessInfo, workingDir string) (*stdinConnection, error) {
+	from := proc.StdinFrom
+	if from == nil {
+		return nil, nil
+	}
+	if err := from.Validate(); err != nil {
+		return nil, err
+	}
+
+	if from.Path != "" {
+		path := from.Path
+		if !filepath.IsAbs(path) && workingDir != "" {
+			path = filepath.Join(workingDir, path)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open stdin file: %w", err)
+		}
+		return &stdinConnection{file: file}, nil
+	}
+
+	source, exists := pm.GetProcessByIdentifier(from.Process)
+	if !exists {
+		return nil, fmt.Errorf("stdin source process %s not found", from.Process)
+	}
+	if source.StdoutFile == "" {
+		return nil, fmt.Errorf("stdin source process %s has no stdout log file", from.Process)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	return &stdinConnection{file: r, pipe: w, source: source}, nil
+}
+
+// started releases the parent's copy of the child's stdin and starts feeding it
+func (s *stdinConnection) started(proc *ProcessInfo) {
+	if s == nil {
+		return
+	}
+	s.file.Close()
+	if s.pipe != nil {
+		// Channels are read now, a restart replaces them
+		go feedStdin(s.pipe, s.source.StdoutFile, s.source.Done, proc.Done)
+	}
+}
+
+// abort closes the stdin of a process that could not be started
+func (s *stdinConnection) abort() {
+	if s == nil {
+		return
+	}
+	s.file.Close()
+	if s.pipe != nil {
+		s.pipe.Close()
+	}
+}
+
+// feedStdin copies the stdout log of a source process to w, following it until sourceDone is
+// closed, then closes w so the reader gets EOF. It stops early once targetDone is closed.
+func feedStdin(w *os.File, stdoutFile string, sourceDone, targetDone <-chan struct{}) {
+	defer w.Close()
+
+	f, err := os.Open(stdoutFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	finished := false
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				// The process closed its stdin or exited
+				return
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return
+		}
+		if finished {
+			return
+		}
+		select {
+		case <-sourceDone:
+			// Read what was written before the source exited, then stop
+			finished = true
+		case <-targetDone:
+			return
+		case <-time.After(stdinPollInterval):
+		}
+	}
+}
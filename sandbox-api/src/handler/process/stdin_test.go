@@ -0,0 +1,81 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStdinFromFile(t *testing.T) {
+	pm := GetProcessManager()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.txt"), []byte("from file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	name := fmt.Sprintf("stdin-file-test-%d", time.Now().UnixNano())
+	pid, err := pm.StartProcessWithName("cat", dir, name, nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithStdinFrom(&StdinSource{Path: "input.txt"}))
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = pm.KillProcess(pid)
+		t.Fatal("cat did not exit at the end of its stdin")
+	}
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	waitForOutput(t, proc, "from file\n")
+}
+
+func TestStdinFromProcess(t *testing.T) {
+	pm := GetProcessManager()
+
+	producer := fmt.Sprintf("stdin-producer-test-%d", time.Now().UnixNano())
+	producerPid, err := pm.StartProcessWithName("echo hello; sleep 0.3; echo world", "", producer, nil, false, 0, false, 0, func(p *ProcessInfo) {})
+	if err != nil {
+		t.Fatalf("Failed to start producer: %v", err)
+	}
+	defer func() { _ = pm.KillProcess(producerPid) }()
+
+	done := make(chan struct{})
+	name := fmt.Sprintf("stdin-consumer-test-%d", time.Now().UnixNano())
+	pid, err := pm.StartProcessWithName("tr a-z A-Z", "", name, nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	}, WithStdinFrom(&StdinSource{Process: producer}))
+	if err != nil {
+		t.Fatalf("Failed to start consumer: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = pm.KillProcess(pid)
+		t.Fatal("consumer did not exit after the producer")
+	}
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	waitForOutput(t, proc, "HELLO\nWORLD\n")
+}
+
+func TestStdinFromInvalid(t *testing.T) {
+	pm := GetProcessManager()
+
+	for _, source := range []*StdinSource{{}, {Path: "a", Process: "b"}} {
+		_, err := pm.StartProcessWithName("cat", "", "", nil, false, 0, false, 0, func(p *ProcessInfo) {}, WithStdinFrom(source))
+		if !errors.Is(err, ErrInvalidStdinSource) {
+			t.Errorf("Expected ErrInvalidStdinSource for %+v, got %v", source, err)
+		}
+	}
+
+	if _, err := pm.StartProcessWithName("cat", "", "", nil, false, 0, false, 0, func(p *ProcessInfo) {}, WithStdinFrom(&StdinSource{Process: "no-such-process"})); err == nil {
+		t.Error("Expected an error for an unknown source process")
+	}
+}
@@ -0,0 +1,39 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ErrInvalidCommandTemplate is returned when a command template cannot be parsed or rendered,
+// including when it references a variable missing from the args
+var ErrInvalidCommandTemplate = errors.New("invalid command template")
+
+// RenderCommand renders a command template, replacing {{.Var}} placeholders with the values of
+// args. Values are single-quoted for the shell, so each one is passed as a single word whatever
+// it contains: placeholders must not be quoted in the template. Referencing a variable missing
+// from args is an error.
+func RenderCommand(command string, args map[string]string) (string, error) {
+	tmpl, err := template.New("command").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCommandTemplate, err)
+	}
+
+	quoted := make(map[string]string, len(args))
+	for key, value := range args {
+		quoted[key] = shellQuote(value)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, quoted); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCommandTemplate, err)
+	}
+	return b.String(), nil
+}
+
+// shellQuote wraps s in single quotes, escaping the single quotes it contains
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,47 @@
+package process
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRenderCommand(t *testing.T) {
+	command, err := RenderCommand("python {{.script}} --out {{.out}}", map[string]string{
+		"script": "main.py",
+		"out":    "result dir/it's.json",
+	})
+	if err != nil {
+		t.Fatalf("RenderCommand failed: %v", err)
+	}
+	if command != `python 'main.py' --out 'result dir/it'\''s.json'` {
+		t.Errorf("Unexpected command: %s", command)
+	}
+
+	// Values are passed as single words, never interpreted by the shell
+	command, err = RenderCommand("printf '%s|' {{.value}}", map[string]string{"value": "a b; echo injected $(id)"})
+	if err != nil {
+		t.Fatalf("RenderCommand failed: %v", err)
+	}
+	output, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		t.Fatalf("Failed to run command: %v", err)
+	}
+	if string(output) != "a b; echo injected $(id)|" {
+		t.Errorf("Expected the value as a single argument, got %q", output)
+	}
+}
+
+func TestRenderCommandErrors(t *testing.T) {
+	_, err := RenderCommand("cat {{.file}} {{.other}}", map[string]string{"file": "a.txt"})
+	if !errors.Is(err, ErrInvalidCommandTemplate) || !strings.Contains(err.Error(), "other") {
+		t.Errorf("Expected an error naming the missing variable, got %v", err)
+	}
+	if _, err := RenderCommand("echo {{.file", map[string]string{"file": "a.txt"}); !errors.Is(err, ErrInvalidCommandTemplate) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+	if _, err := RenderCommand("echo {{.file}}", nil); !errors.Is(err, ErrInvalidCommandTemplate) {
+		t.Errorf("Expected an error without args, got %v", err)
+	}
+}
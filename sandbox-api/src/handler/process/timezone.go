@@ -0,0 +1,44 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	defaultTimezone   string
+	defaultTimezoneMu sync.RWMutex
+)
+
+// DefaultTimezone returns the TZ given to new processes, empty when the system one is used
+func DefaultTimezone() string {
+	defaultTimezoneMu.RLock()
+	defer defaultTimezoneMu.RUnlock()
+	return defaultTimezone
+}
+
+// EffectiveTimezone returns the TZ new processes receive: the default one when set,
+// the API's own TZ otherwise (empty meaning the system local time)
+func EffectiveTimezone() string {
+	if tz := DefaultTimezone(); tz != "" {
+		return tz
+	}
+	return os.Getenv("TZ")
+}
+
+// SetDefaultTimezone sets the TZ of processes started from now on, unless their env sets
+// TZ itself. The name must exist in the tz database (e.g. "Europe/Paris", "UTC");
+// an empty name goes back to the system timezone.
+func SetDefaultTimezone(tz string) error {
+	if tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", tz, err)
+		}
+	}
+	defaultTimezoneMu.Lock()
+	defer defaultTimezoneMu.Unlock()
+	defaultTimezone = tz
+	return nil
+}
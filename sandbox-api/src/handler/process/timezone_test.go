@@ -0,0 +1,32 @@
+package process
+
+import "testing"
+
+func TestDefaultTimezone(t *testing.T) {
+	defer func() { _ = SetDefaultTimezone("") }()
+
+	if err := SetDefaultTimezone("Not/A_Zone"); err == nil {
+		t.Error("Expected an unknown timezone to be rejected")
+	}
+	if err := SetDefaultTimezone("Europe/Paris"); err != nil {
+		t.Fatalf("Failed to set timezone: %v", err)
+	}
+	if got := EffectiveTimezone(); got != "Europe/Paris" {
+		t.Errorf("Expected Europe/Paris, got %q", got)
+	}
+
+	if env := PreviewEnv(nil); env["TZ"] != "Europe/Paris" {
+		t.Errorf("Expected new processes to get the default TZ, got %q", env["TZ"])
+	}
+	if env := PreviewEnv(map[string]string{"TZ": "UTC"}); env["TZ"] != "UTC" {
+		t.Errorf("Expected a custom TZ to take priority, got %q", env["TZ"])
+	}
+
+	t.Setenv("TZ", "Asia/Tokyo")
+	if err := SetDefaultTimezone(""); err != nil {
+		t.Fatalf("Failed to reset timezone: %v", err)
+	}
+	if got := EffectiveTimezone(); got != "Asia/Tokyo" {
+		t.Errorf("Expected the API TZ once reset, got %q", got)
+	}
+}
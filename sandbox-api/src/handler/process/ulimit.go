@@ -0,0 +1,68 @@
+package process
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Ulimits are soft resource limits given to new processes, 0 keeping the one inherited from the API
+type Ulimits struct {
+	NoFile uint64 `json:"nofile,omitempty" example:"65536"` // Maximum number of open files (RLIMIT_NOFILE)
+	NProc  uint64 `json:"nproc,omitempty" example:"4096"`   // Maximum number of processes of the user (RLIMIT_NPROC)
+} // @name Ulimits
+
+var (
+	defaultUlimits   Ulimits
+	defaultUlimitsMu sync.RWMutex
+)
+
+// DefaultUlimits returns the limits applied to new processes
+func DefaultUlimits() Ulimits {
+	defaultUlimitsMu.RLock()
+	defer defaultUlimitsMu.RUnlock()
+	return defaultUlimits
+}
+
+// HardUlimits returns the hard limits of the API process, the highest values SetDefaultUlimits accepts
+func HardUlimits() (Ulimits, error) {
+	return hardUlimits()
+}
+
+// SetDefaultUlimits sets the soft limits of processes started from now on. Each limit must
+// not exceed the API's hard limit; 0 goes back to the inherited limit. Running processes
+// are not affected.
+func SetDefaultUlimits(limits Ulimits) error {
+	hard, err := hardUlimits()
+	if err != nil {
+		return err
+	}
+	if limits.NoFile > hard.NoFile {
+		return fmt.Errorf("nofile %d exceeds the hard limit of %d", limits.NoFile, hard.NoFile)
+	}
+	if limits.NProc > hard.NProc {
+		return fmt.Errorf("nproc %d exceeds the hard limit of %d", limits.NProc, hard.NProc)
+	}
+
+	defaultUlimitsMu.Lock()
+	defer defaultUlimitsMu.Unlock()
+	defaultUlimits = limits
+	return nil
+}
+
+// applyDefaultUlimits sets the default limits on a just-started process. Like cgroup limits,
+// they are applied right after the start, so children forked before that keep the inherited
+// ones. The process keeps running when they can't be applied.
+func applyDefaultUlimits(process *ProcessInfo, pid int) {
+	limits := DefaultUlimits()
+	if limits == (Ulimits{}) {
+		return
+	}
+	if err := setProcessUlimits(pid, limits); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"pid":  process.PID,
+			"name": process.Name,
+		}).WithError(err).Warn("Failed to apply default ulimits, process runs with the inherited ones")
+	}
+}
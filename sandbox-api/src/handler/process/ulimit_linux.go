@@ -0,0 +1,45 @@
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// hardUlimits reads the hard limits of the API process
+func hardUlimits() (Ulimits, error) {
+	var nofile, nproc unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &nofile); err != nil {
+		return Ulimits{}, fmt.Errorf("failed to read RLIMIT_NOFILE: %w", err)
+	}
+	if err := unix.Getrlimit(unix.RLIMIT_NPROC, &nproc); err != nil {
+		return Ulimits{}, fmt.Errorf("failed to read RLIMIT_NPROC: %w", err)
+	}
+	return Ulimits{NoFile: nofile.Max, NProc: nproc.Max}, nil
+}
+
+// setProcessUlimits sets the soft limits of pid, keeping its hard limits
+func setProcessUlimits(pid int, limits Ulimits) error {
+	for _, limit := range []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_NOFILE, limits.NoFile},
+		{unix.RLIMIT_NPROC, limits.NProc},
+	} {
+		if limit.value == 0 {
+			continue
+		}
+		var current unix.Rlimit
+		if err := unix.Prlimit(pid, limit.resource, nil, &current); err != nil {
+			return err
+		}
+		current.Cur = min(limit.value, current.Max)
+		if err := unix.Prlimit(pid, limit.resource, &current, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultUlimits(t *testing.T) {
+	defer func() { _ = SetDefaultUlimits(Ulimits{}) }()
+
+	hard, err := HardUlimits()
+	if err != nil {
+		t.Fatalf("HardUlimits failed: %v", err)
+	}
+	if hard.NoFile < 256 {
+		t.Skipf("Hard RLIMIT_NOFILE %d is too low", hard.NoFile)
+	}
+	if hard.NoFile < ^uint64(0) {
+		if err := SetDefaultUlimits(Ulimits{NoFile: hard.NoFile + 1}); err == nil {
+			t.Error("Expected a limit above the hard limit to be rejected")
+		}
+	}
+	if err := SetDefaultUlimits(Ulimits{NoFile: 256}); err != nil {
+		t.Fatalf("SetDefaultUlimits failed: %v", err)
+	}
+	if got := DefaultUlimits(); got != (Ulimits{NoFile: 256}) {
+		t.Errorf("Expected nofile 256, got %+v", got)
+	}
+
+	pm := GetProcessManager()
+	done := make(chan struct{})
+	name := fmt.Sprintf("ulimit-test-%d", time.Now().UnixNano())
+	// The builtin runs once the limits were applied
+	pid, err := pm.StartProcessWithName("sleep 0.2; ulimit -n", "", name, nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = pm.KillProcess(pid)
+		t.Fatal("Process did not complete")
+	}
+	proc, _ := pm.GetProcessByIdentifier(pid)
+	if out := strings.TrimSpace(proc.readBuffer(proc.stdout)); out != "256" {
+		t.Errorf("Expected the process to get nofile 256, got %q", out)
+	}
+}
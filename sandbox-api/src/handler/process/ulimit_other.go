@@ -0,0 +1,18 @@
+//go:build !linux
+
+package process
+
+import "errors"
+
+// ulimits are only supported on Linux
+var errUlimitsNotSupported = errors.New("ulimits are only supported on Linux")
+
+// hardUlimits returns an error on non-Linux platforms
+func hardUlimits() (Ulimits, error) {
+	return Ulimits{}, errUlimitsNotSupported
+}
+
+// setProcessUlimits returns an error on non-Linux platforms
+func setProcessUlimits(pid int, limits Ulimits) error {
+	return errUlimitsNotSupported
+}
@@ -0,0 +1,68 @@
+package process
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// usageSampleInterval is how often the memory of a running process tree is sampled. Peaks
+// shorter than the interval are still caught by the rusage of the waited-for processes.
+const usageSampleInterval = 10 * time.Millisecond
+
+// ResourceUsage is the wall-clock time, peak memory and CPU time of a process run
+type ResourceUsage struct {
+	DurationMs  int64 `json:"durationMs" example:"1520"`      // Wall-clock time from start to exit
+	MaxRssBytes int64 `json:"maxRssBytes" example:"52428800"` // Peak resident memory of the process and its children (0 when unavailable)
+	CPUTimeMs   int64 `json:"cpuTimeMs" example:"1380"`       // User and system CPU time of the process and its waited-for children
+} // @name ResourceUsage
+
+// usageSampler tracks the peak memory of a process tree while it runs
+type usageSampler struct {
+	startedAt time.Time
+	stop      chan struct{}
+	done      sync.WaitGroup
+	peak      int64
+}
+
+// startUsageSampler starts sampling the resident memory of pid and its descendants. The
+// duration is measured from startedAt, taken before the process was started.
+func startUsageSampler(pid int, startedAt time.Time) *usageSampler {
+	s := &usageSampler{startedAt: startedAt, stop: make(chan struct{})}
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(usageSampleInterval)
+		defer ticker.Stop()
+		for {
+			if rss := treeRSS(pid); rss > s.peak {
+				s.peak = rss
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return s
+}
+
+// finish stops sampling once the process has been waited for and returns its usage,
+// combining the sampled peak with the rusage reported by the kernel
+func (s *usageSampler) finish(state *os.ProcessState) *ResourceUsage {
+	close(s.stop)
+	s.done.Wait()
+
+	usage := &ResourceUsage{
+		DurationMs:  time.Since(s.startedAt).Milliseconds(),
+		MaxRssBytes: s.peak,
+	}
+	if state != nil {
+		usage.CPUTimeMs = (state.UserTime() + state.SystemTime()).Milliseconds()
+		if rss := processMaxRSS(state); rss > usage.MaxRssBytes {
+			usage.MaxRssBytes = rss
+		}
+	}
+	return usage
+}
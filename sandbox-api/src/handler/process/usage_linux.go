@@ -0,0 +1,75 @@
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// treeRSS returns the resident memory, in bytes, of pid and all its descendants
+func treeRSS(pid int) int64 {
+	var total int64
+	pending := []int{pid}
+	seen := map[int]bool{}
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		total += processRSS(current)
+		pending = append(pending, processChildren(current)...)
+	}
+	return total
+}
+
+// processRSS reads the resident memory of a single process from /proc/[pid]/statm
+func processRSS(pid int) int64 {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * int64(os.Getpagesize())
+}
+
+// processChildren lists the direct children of every thread of pid
+func processChildren(pid int) []int {
+	files, _ := filepath.Glob(fmt.Sprintf("/proc/%d/task/*/children", pid))
+	var children []int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, field := range strings.Fields(string(data)) {
+			if child, err := strconv.Atoi(field); err == nil {
+				children = append(children, child)
+			}
+		}
+	}
+	return children
+}
+
+// processMaxRSS returns the peak resident memory reported by wait4, which covers the process
+// and the largest of its waited-for descendants. Linux reports it in kilobytes.
+func processMaxRSS(state *os.ProcessState) int64 {
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok && rusage != nil {
+		return rusage.Maxrss * 1024
+	}
+	return 0
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package process
+
+import "os"
+
+// treeRSS is not sampled outside of Linux, which has no /proc
+func treeRSS(pid int) int64 {
+	return 0
+}
+
+// processMaxRSS is only reported on Linux, other platforms use different units
+func processMaxRSS(state *os.ProcessState) int64 {
+	return 0
+}
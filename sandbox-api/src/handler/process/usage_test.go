@@ -0,0 +1,38 @@
+package process
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestProcessResourceUsage(t *testing.T) {
+	pm := GetProcessManager()
+
+	done := make(chan struct{})
+	// Hold about 20MB in a shell variable, then burn a little CPU
+	pid, err := pm.StartProcess(`x=$(head -c 20000000 /dev/zero | tr '\0' a); sleep 0.2; i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done`, "", nil, false, 0, false, 0, func(p *ProcessInfo) {
+		close(done)
+	})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	if proc, _ := pm.GetProcessByIdentifier(pid); proc.Usage != nil {
+		t.Error("Expected no usage while the process is running")
+	}
+	waitForProcessDone(t, done, 10*time.Second)
+
+	proc, exists := pm.GetProcessByIdentifier(pid)
+	if !exists || proc.Usage == nil {
+		t.Fatal("Expected the usage of the completed process")
+	}
+	if proc.Usage.DurationMs < 200 {
+		t.Errorf("Expected a duration of at least 200ms, got %d", proc.Usage.DurationMs)
+	}
+	if proc.Usage.CPUTimeMs <= 0 {
+		t.Errorf("Expected some CPU time, got %d", proc.Usage.CPUTimeMs)
+	}
+	if runtime.GOOS == "linux" && proc.Usage.MaxRssBytes < 20000000 {
+		t.Errorf("Expected a peak memory of at least 20MB, got %d", proc.Usage.MaxRssBytes)
+	}
+}
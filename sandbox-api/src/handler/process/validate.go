@@ -0,0 +1,79 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// syntaxCheckTimeout bounds the time a shell may spend parsing a script
+const syntaxCheckTimeout = 10 * time.Second
+
+// syntaxCheckShells are the shells known to support the POSIX "-n" (noexec) option
+var syntaxCheckShells = map[string]bool{
+	"sh":   true,
+	"bash": true,
+	"dash": true,
+	"ash":  true,
+	"ksh":  true,
+	"mksh": true,
+	"zsh":  true,
+}
+
+// ErrUnsupportedShell is returned when the requested shell has no syntax check mode
+var ErrUnsupportedShell = errors.New("unsupported shell")
+
+// SyntaxCheckResult is the outcome of a shell syntax check
+type SyntaxCheckResult struct {
+	Valid  bool
+	Shell  string
+	Output string
+}
+
+// CheckSyntax parses command with the shell's "-n" option, which reads the script without
+// executing any of it. An empty shell uses $SHELL, like process execution does, and falls back to sh.
+func CheckSyntax(command string, shell string) (*SyntaxCheckResult, error) {
+	if shell == "" {
+		shell = os.Getenv("SHELL")
+		if shell == "" || !syntaxCheckShells[filepath.Base(shell)] {
+			shell = "sh"
+		}
+	}
+	if !syntaxCheckShells[filepath.Base(shell)] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedShell, shell)
+	}
+
+	shellPath, err := exec.LookPath(shell)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s not found", ErrUnsupportedShell, shell)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syntaxCheckTimeout)
+	defer cancel()
+
+	// The script is passed on stdin rather than with -c so that it is never interpreted as options
+	cmd := exec.CommandContext(ctx, shellPath, "-n")
+	cmd.Stdin = strings.NewReader(command)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	result := &SyntaxCheckResult{Shell: shell}
+	err = cmd.Run()
+	result.Output = strings.TrimSpace(output.String())
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("syntax check timed out after %s", syntaxCheckTimeout)
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	result.Valid = err == nil
+	return result, nil
+}
@@ -0,0 +1,38 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSyntax(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "executed")
+
+	result, err := CheckSyntax("touch "+marker+"\nfor i in 1 2; do echo $i; done", "sh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("Expected a valid script, got output %q", result.Output)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Syntax check must not execute the script")
+	}
+
+	result, err = CheckSyntax("if true; then echo missing fi", "sh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Valid {
+		t.Error("Expected an invalid script")
+	}
+	if result.Output == "" {
+		t.Error("Expected the shell's error output")
+	}
+
+	if _, err := CheckSyntax("print('hi')", "python3"); !errors.Is(err, ErrUnsupportedShell) {
+		t.Errorf("Expected ErrUnsupportedShell, got %v", err)
+	}
+}
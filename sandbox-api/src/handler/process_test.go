@@ -0,0 +1,413 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/blaxel-ai/sandbox-api/src/handler/filesystem"
+	"github.com/blaxel-ai/sandbox-api/src/handler/process"
+)
+
+// TestPrefixedLogWriter verifies that multiplexed log lines are prefixed with
+// the process name and that partial lines are only emitted once complete.
+func TestPrefixedLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewPrefixedLogWriter(&out, "web")
+
+	_, _ = w.WriteEvent("stdout", "hello\nwor")
+	_, _ = w.WriteEvent("stderr", "oops\n")
+	_, _ = w.WriteEvent("stdout", "ld\n")
+	_, _ = w.Write([]byte("[keepalive]\n"))
+	_, _ = w.Write([]byte("\n[Process is being gracefully terminated]\n"))
+	_, _ = w.WriteEvent("stdout", "tail")
+	w.FlushPending()
+
+	want := "[web] stdout:hello\n" +
+		"[web] stderr:oops\n" +
+		"[web] stdout:world\n" +
+		"[keepalive]\n" +
+		"[web] [Process is being gracefully terminated]\n" +
+		"[web] stdout:tail\n"
+	if got := out.String(); got != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestJSONLinesLogWriter verifies that JSON stdout lines are embedded as parsed
+// values while every other line is kept as a raw string.
+func TestJSONLinesLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewJSONLinesLogWriter(&out)
+
+	_, _ = w.WriteEvent("stdout", "{\"level\":\"info\",\"n\":1}\nplain ")
+	_, _ = w.WriteEvent("stdout", "text\n")
+	_, _ = w.WriteEvent("stderr", "{\"not\":\"parsed\"}\n")
+	_, _ = w.Write([]byte("[keepalive]\n"))
+	_, _ = w.WriteEvent("stdout", "[1,2")
+	w.FlushPending()
+
+	want := "{\"type\":\"stdout\",\"parsed\":{\"level\":\"info\",\"n\":1}}\n" +
+		"{\"type\":\"stdout\",\"data\":\"plain text\"}\n" +
+		"{\"type\":\"stderr\",\"data\":\"{\\\"not\\\":\\\"parsed\\\"}\"}\n" +
+		"{\"type\":\"keepalive\"}\n" +
+		"{\"type\":\"stdout\",\"data\":\"[1,2\"}\n"
+	if got := out.String(); got != want {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestANSIStripWriter verifies that escape sequences are removed, including the ones
+// split across chunks, both on plain and typed writers.
+func TestANSIStripWriter(t *testing.T) {
+	var plain bytes.Buffer
+	w := NewANSIStripWriter(&plain)
+	_, _ = w.WriteEvent("stdout", "\x1b[32mok\x1b[")
+	_, _ = w.WriteEvent("stderr", "\x1b[31merr\x1b[0m\n")
+	_, _ = w.WriteEvent("stdout", "0m done\n")
+	_, _ = w.Write([]byte("[keepalive]\n"))
+	want := "stdout:ok" + "stderr:err\n" + "stdout: done\n" + "[keepalive]\n"
+	if got := plain.String(); got != want {
+		t.Errorf("unexpected output:\ngot:\n%q\nwant:\n%q", got, want)
+	}
+
+	var prefixed bytes.Buffer
+	pw := NewPrefixedLogWriter(&prefixed, "web")
+	w = NewANSIStripWriter(pw)
+	_, _ = w.WriteEvent("stdout", "\x1b[1mbold\x1b")
+	_, _ = w.WriteEvent("stdout", "[22m line\n")
+	w.FlushPending()
+	pw.FlushPending()
+	if got := prefixed.String(); got != "[web] stdout:bold line\n" {
+		t.Errorf("unexpected prefixed output: %q", got)
+	}
+}
+
+func TestProcessSpec(t *testing.T) {
+	info := &process.ProcessInfo{
+		Command:          "npm run dev",
+		Name:             "web",
+		WorkingDir:       "/app",
+		Env:              map[string]string{"PORT": "3000", "PWD": "/app", "GITHUB_TOKEN": "ghp_x", "AWS_SECRET_ACCESS_KEY": "s", "KEYBOARD": "us"},
+		RestartOnFailure: true,
+		MaxRestarts:      3,
+		KeepAlive:        true,
+		Labels:           map[string]string{"app": "web"},
+	}
+
+	spec := processSpec(info, false)
+	if spec.Command != "npm run dev" || spec.Name != "web" || spec.WorkingDir != "/app" || !spec.RestartOnFailure || spec.MaxRestarts != 3 || spec.Labels["app"] != "web" {
+		t.Errorf("unexpected spec: %+v", spec)
+	}
+	// An infinite keepAlive timeout must stay explicit, otherwise the default one would apply
+	if spec.Timeout == nil || *spec.Timeout != 0 {
+		t.Errorf("expected an explicit 0 timeout, got %v", spec.Timeout)
+	}
+	for key, want := range map[string]string{"PORT": "3000", "PWD": "/app", "KEYBOARD": "us", "GITHUB_TOKEN": process.RedactedEnvValue, "AWS_SECRET_ACCESS_KEY": process.RedactedEnvValue} {
+		if got := spec.Env[key]; got != want {
+			t.Errorf("env %s: expected %q, got %q", key, want, got)
+		}
+	}
+
+	spec = processSpec(info, true)
+	if spec.Env["GITHUB_TOKEN"] != "ghp_x" {
+		t.Errorf("expected secrets to be included, got %q", spec.Env["GITHUB_TOKEN"])
+	}
+	if info.Env["GITHUB_TOKEN"] != "ghp_x" {
+		t.Errorf("redaction must not modify the process env")
+	}
+}
+
+// TestExpectExitCode verifies that a synchronous run exiting with another code than
+// expectExitCode is reported as a failure, with the process output
+func TestExpectExitCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProcessHandler()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+	}{
+		{`{"command": "echo boom; exit 3", "waitForCompletion": true, "expectExitCode": 0}`, http.StatusExpectationFailed},
+		{`{"command": "echo boom; exit 3", "waitForCompletion": true, "expectExitCode": 3}`, http.StatusOK},
+		{`{"command": "true", "expectExitCode": 0}`, http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/process", strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		h.HandleExecuteCommand(c)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.body, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status == http.StatusExpectationFailed {
+			var resp ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.ExitCode != 3 || resp.TailOutput == nil || !strings.Contains(*resp.TailOutput, "boom") {
+				t.Errorf("Expected the process with its output, got %s (%v)", w.Body.String(), err)
+			}
+		}
+	}
+}
+
+// TestExecuteCommandArgs verifies that commands with args are rendered as templates before running
+func TestExecuteCommandArgs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProcessHandler()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+		stdout string
+	}{
+		{`{"command": "echo {{.greeting}} {{.name}}", "args": {"greeting": "hello", "name": "a b"}, "waitForCompletion": true}`, http.StatusOK, "hello a b\n"},
+		{`{"command": "echo {{.greeting}} {{.name}}", "args": {"greeting": "hello"}, "waitForCompletion": true}`, http.StatusBadRequest, ""},
+		{`{"command": "echo {{.greeting}}", "waitForCompletion": true}`, http.StatusOK, "{{.greeting}}\n"},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/process", strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		h.HandleExecuteCommand(c)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.body, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status == http.StatusOK {
+			var resp ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Stdout == nil || *resp.Stdout != tc.stdout {
+				t.Errorf("%s: expected stdout %q, got %s (%v)", tc.body, tc.stdout, w.Body.String(), err)
+			}
+		}
+	}
+}
+
+// TestProcessFilesConfined verifies that the files a process request reads or writes are
+// rejected with 403 outside of the working directory when confinement is enabled
+func TestProcessFilesConfined(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	h := NewProcessHandler()
+	h.fs = filesystem.NewFilesystemWithWorkingDir("/", root)
+	h.fs.Confine = true
+
+	for _, tc := range []struct {
+		url, body string
+		params    gin.Params
+		handle    gin.HandlerFunc
+	}{
+		{"/process", `{"command": "cat", "stdinFrom": {"path": "` + outside + `/input"}}`, nil, h.HandleExecuteCommand},
+		{"/process", `{"command": "cat", "workingDir": "` + root + `", "stdinFrom": {"path": "escape/input"}}`, nil, h.HandleExecuteCommand},
+		{"/process/any/logs/mirror", `{"path": "` + root + `/escape/mirror.log"}`, gin.Params{{Key: "identifier", Value: "any"}}, h.HandleMirrorProcessLogs},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = tc.params
+		tc.handle(c)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d (%s)", tc.body, w.Code, w.Body.String())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "mirror.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside of the working directory, got %v", err)
+	}
+}
+
+// TestExecuteCommandUsage verifies that synchronous runs report their duration, peak memory and CPU time
+func TestExecuteCommandUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProcessHandler()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/process", strings.NewReader(`{"command": "sleep 0.1", "waitForCompletion": true}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	h.HandleExecuteCommand(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if duration, ok := resp["durationMs"].(float64); !ok || duration < 100 {
+		t.Errorf("Expected durationMs of at least 100, got %s", w.Body.String())
+	}
+	for _, key := range []string{"maxRssBytes", "cpuTimeMs"} {
+		if _, ok := resp[key]; !ok {
+			t.Errorf("Expected %s in the response, got %s", key, w.Body.String())
+		}
+	}
+}
+
+func TestExecuteBatchParallelSameName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProcessHandler()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	entry := `{"command": "sleep 5", "name": "batch-same-name"}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/process/batch", strings.NewReader(`{"parallel": true, "processes": [`+strings.Repeat(entry+",", 4)+entry+`]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	h.HandleExecuteBatch(c)
+
+	var resp ProcessBatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	started := 0
+	for _, result := range resp.Results {
+		if result.Error == "" {
+			started++
+			defer func(pid string) { _ = h.processManager.KillProcess(pid) }(result.PID)
+		}
+	}
+	if started != 1 {
+		t.Errorf("Expected exactly one process named batch-same-name to start, got %d: %s", started, w.Body.String())
+	}
+}
+
+func TestProcessStatusStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProcessHandler()
+
+	resp, err := h.ExecuteProcess("sleep 0.3; exit 2", "", "", nil, false, 0, nil, false, 0, false)
+	if err != nil {
+		t.Fatalf("Error starting process: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/process/"+resp.PID+"/status/stream", nil)
+	c.Params = gin.Params{{Key: "identifier", Value: resp.PID}}
+	h.HandleGetProcessStatusStream(c)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	var first, last process.ProcessStatusEvent
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.Status != process.StatusRunning {
+		t.Errorf("Expected a running event first, got %s (%v)", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || last.Status != process.StatusFailed || last.ExitCode != 2 {
+		t.Errorf("Expected a failed event last, got %s (%v)", lines[len(lines)-1], err)
+	}
+}
+
+// TestUpdateProcess verifies that PATCH /process/{identifier} updates the restart policy of a running process
+func TestUpdateProcess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SANDBOX_STATE_FILE", t.TempDir()+"/state.json")
+	h := NewProcessHandler()
+
+	pid, err := h.processManager.StartProcessWithName("sleep 30", "", "update-test", nil, false, 0, false, 0, func(p *process.ProcessInfo) {})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = h.KillProcess(pid) }()
+
+	for _, tc := range []struct {
+		identifier string
+		body       string
+		status     int
+	}{
+		{pid, `{}`, http.StatusBadRequest},
+		{pid, `{"maxRestarts": "3"}`, http.StatusBadRequest},
+		{"does-not-exist", `{"maxRestarts": 3}`, http.StatusNotFound},
+		{pid, `{"restartOnFailure": true, "maxRestarts": 3}`, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "identifier", Value: tc.identifier}}
+		c.Request = httptest.NewRequest(http.MethodPatch, "/process/"+tc.identifier, strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		h.HandleUpdateProcess(c)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.body, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status == http.StatusOK {
+			var resp ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !resp.RestartOnFailure || resp.MaxRestarts != 3 {
+				t.Errorf("Expected the updated restart policy, got %s (%v)", w.Body.String(), err)
+			}
+		}
+	}
+}
+
+func TestRefreshProcess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProcessHandler()
+
+	pid, err := h.processManager.StartProcessWithName("sleep 30", "", "refresh-test", nil, false, 0, false, 0, func(p *process.ProcessInfo) {})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = h.KillProcess(pid) }()
+
+	for _, tc := range []struct {
+		identifier string
+		status     int
+	}{
+		{"does-not-exist", http.StatusNotFound},
+		{pid, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "identifier", Value: tc.identifier}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/process/"+tc.identifier+"/refresh", nil)
+		h.HandleRefreshProcess(c)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.identifier, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.status == http.StatusOK {
+			var resp ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.PID != pid || resp.Status != string(process.StatusRunning) {
+				t.Errorf("Expected the running process, got %s (%v)", w.Body.String(), err)
+			}
+		}
+	}
+}
+
+// TestGetProcessExitCode verifies that the exit code is null while running and set once the process ended
+func TestGetProcessExitCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProcessHandler()
+
+	pid, err := h.processManager.StartProcessWithName("sleep 0.3", "", "exit-code-test", nil, false, 0, false, 0, func(p *process.ProcessInfo) {})
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	for _, tc := range []struct {
+		query  string
+		status int
+		body   string
+	}{
+		{"", http.StatusOK, `{"status":"running","exitCode":null,"exitReason":null}`},
+		{"?wait=true&timeoutMs=abc", http.StatusBadRequest, ""},
+		{"?wait=true", http.StatusOK, `{"status":"completed","exitCode":0,"exitReason":"exited"}`},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "identifier", Value: pid}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/process/"+pid+"/exit-code"+tc.query, nil)
+		h.HandleGetProcessExitCode(c)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tc.query, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		if tc.body != "" && w.Body.String() != tc.body {
+			t.Errorf("%s: expected %s, got %s", tc.query, tc.body, w.Body.String())
+		}
+	}
+}
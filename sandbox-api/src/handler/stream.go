@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
+	"github.com/gin-gonic/gin"
+)
+
+// reconnectMessage is the last line of a plain text stream ended because the API is
+// shutting down or upgrading: the client should reconnect
+const reconnectMessage = "[reconnect]\n"
+
+// streamRetryAfter is the Retry-After, in seconds, of streams refused because too many are open
+const streamRetryAfter = "5"
+
+// acquireStream registers a streaming connection, counted for drain.Drain and the health
+// endpoint until the returned function is called. When drain.MaxStreams streams are already
+// open it responds 503 with Retry-After and returns false.
+func (h *BaseHandler) acquireStream(c *gin.Context) (func(), bool) {
+	release, err := drain.TryTrack()
+	if err != nil {
+		c.Header("Retry-After", streamRetryAfter)
+		h.SendError(c, http.StatusServiceUnavailable, err)
+		return nil, false
+	}
+	return release, true
+}
+
+// MaxFlushInterval is the largest flushIntervalMs accepted by streaming endpoints
+const MaxFlushInterval = 10 * time.Second
+
+// parseFlushInterval reads the optional flushIntervalMs query parameter of a streaming endpoint.
+// It returns 0 (flush on every write) when the parameter is absent.
+func parseFlushInterval(c *gin.Context) (time.Duration, error) {
+	value := c.Query("flushIntervalMs")
+	if value == "" {
+		return 0, nil
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		return 0, fmt.Errorf("flushIntervalMs must be a non-negative integer")
+	}
+	interval := time.Duration(ms) * time.Millisecond
+	if interval > MaxFlushInterval {
+		return 0, fmt.Errorf("flushIntervalMs cannot exceed %d", MaxFlushInterval.Milliseconds())
+	}
+	return interval, nil
+}
+
+// Bounds of the keepaliveSec accepted by streaming endpoints, the minimum keeping clients
+// from turning keepalives into a flood
+const (
+	MinKeepaliveInterval = 5 * time.Second
+	MaxKeepaliveInterval = 10 * time.Minute
+)
+
+// parseKeepaliveInterval reads the optional keepaliveSec query parameter of a streaming endpoint.
+// It returns defaultInterval when the parameter is absent.
+func parseKeepaliveInterval(c *gin.Context, defaultInterval time.Duration) (time.Duration, error) {
+	value := c.Query("keepaliveSec")
+	if value == "" {
+		return defaultInterval, nil
+	}
+	sec, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("keepaliveSec must be an integer")
+	}
+	interval := time.Duration(sec) * time.Second
+	if interval < MinKeepaliveInterval || interval > MaxKeepaliveInterval {
+		return 0, fmt.Errorf("keepaliveSec must be between %d and %d", int(MinKeepaliveInterval.Seconds()), int(MaxKeepaliveInterval.Seconds()))
+	}
+	return interval, nil
+}
+
+// errStreamClosed is returned by writes to a stream output that was closed: its response
+// writer may already serve another request
+var errStreamClosed = errors.New("stream is closed")
+
+// streamOutput writes to a streaming response and batches flushes. A write is flushed
+// right away when nothing was flushed during the last interval, so sparse output isn't
+// delayed; bursts of writes are flushed together at most once per interval.
+type streamOutput struct {
+	w         gin.ResponseWriter
+	interval  time.Duration
+	lastFlush time.Time
+	pending   bool
+	closed    bool
+	done      chan struct{}
+	mu        sync.Mutex
+}
+
+// newStreamOutput creates a stream output; an interval of 0 flushes after every write
+func newStreamOutput(w gin.ResponseWriter, interval time.Duration) *streamOutput {
+	// Streams stay open far longer than the server WriteTimeout meant for regular requests
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	s := &streamOutput{
+		w:        w,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+	if interval > 0 {
+		go s.flushLoop()
+	}
+	return s
+}
+
+// Write writes data to the response and flushes it according to the interval
+func (s *streamOutput) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, errStreamClosed
+	}
+	n, err := s.w.Write(data)
+	if err != nil {
+		return n, err
+	}
+	if s.interval == 0 || time.Since(s.lastFlush) >= s.interval {
+		s.flushLocked()
+	} else {
+		s.pending = true
+	}
+	return n, nil
+}
+
+// Close flushes any pending data and stops the periodic flush
+func (s *streamOutput) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+	if s.pending {
+		s.flushLocked()
+	}
+}
+
+func (s *streamOutput) flushLoop() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.pending {
+				s.flushLocked()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *streamOutput) flushLocked() {
+	s.w.Flush()
+	s.pending = false
+	s.lastFlush = time.Now()
+}
+
+// MaxSettleWindow is the largest settleMs accepted by the watch endpoint
+const MaxSettleWindow = time.Minute
+
+// parseSettleWindow reads the optional settleMs query parameter of the watch endpoint.
+// It returns 0 (emit every event) when the parameter is absent.
+func parseSettleWindow(c *gin.Context) (time.Duration, error) {
+	value := c.Query("settleMs")
+	if value == "" {
+		return 0, nil
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		return 0, fmt.Errorf("settleMs must be a non-negative integer")
+	}
+	window := time.Duration(ms) * time.Millisecond
+	if window > MaxSettleWindow {
+		return 0, fmt.Errorf("settleMs cannot exceed %d", MaxSettleWindow.Milliseconds())
+	}
+	return window, nil
+}
+
+// eventSettler collects the paths of a burst of events and passes them to emit, once, after
+// no event happened for the settle window. The timer is reset on each event. Emitting is done
+// under the lock, so nothing is emitted once Stop returned.
+type eventSettler struct {
+	window  time.Duration
+	emit    func(paths []string)
+	timer   *time.Timer
+	paths   []string
+	seen    map[string]bool
+	stopped bool
+	mu      sync.Mutex
+}
+
+func newEventSettler(window time.Duration, emit func(paths []string)) *eventSettler {
+	return &eventSettler{window: window, emit: emit, seen: map[string]bool{}}
+}
+
+// Add records a changed path and restarts the settle window
+func (s *eventSettler) Add(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	if !s.seen[path] {
+		s.seen[path] = true
+		s.paths = append(s.paths, path)
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.window, s.settle)
+	} else {
+		s.timer.Reset(s.window)
+	}
+}
+
+// Stop drops the pending paths without emitting them
+func (s *eventSettler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.paths = nil
+}
+
+func (s *eventSettler) settle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped || len(s.paths) == 0 {
+		return
+	}
+	paths := s.paths
+	s.paths = nil
+	s.seen = map[string]bool{}
+	s.emit(paths)
+}
+
+// DefaultBatchInterval groups the events of batched watch streams without flushIntervalMs
+const DefaultBatchInterval = 100 * time.Millisecond
+
+// eventBatcher numbers watch events and passes them to emit in batches, once per interval
+// after the first event of a batch. Emitting is done under the lock, so batches and
+// keepalives are written in seq order.
+type eventBatcher struct {
+	interval time.Duration
+	emit     func(FileEventBatch)
+	timer    *time.Timer
+	pending  []FileEvent
+	seq      uint64
+	stopped  bool
+	mu       sync.Mutex
+}
+
+func newEventBatcher(interval time.Duration, emit func(FileEventBatch)) *eventBatcher {
+	return &eventBatcher{interval: interval, emit: emit}
+}
+
+// Add numbers an event and adds it to the current batch
+func (b *eventBatcher) Add(event FileEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return
+	}
+	b.seq++
+	event.Seq = b.seq
+	b.pending = append(b.pending, event)
+	if len(b.pending) > 1 {
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, b.Flush)
+	} else {
+		b.timer.Reset(b.interval)
+	}
+}
+
+// Flush emits the current batch, if any
+func (b *eventBatcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) > 0 {
+		b.emitLocked()
+	}
+}
+
+// Keepalive emits the current batch, or an empty one carrying the last seq
+func (b *eventBatcher) Keepalive() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.emitLocked()
+}
+
+// Stop drops the pending events without emitting them
+func (b *eventBatcher) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopped = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.pending = nil
+}
+
+func (b *eventBatcher) emitLocked() {
+	if b.stopped {
+		return
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	events := b.pending
+	if events == nil {
+		events = []FileEvent{}
+	}
+	b.pending = nil
+	b.emit(FileEventBatch{Events: events, LastSeq: b.seq})
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
+	"github.com/gin-gonic/gin"
+)
+
+// countingWriter counts the flushes of a gin response writer
+type countingWriter struct {
+	gin.ResponseWriter
+	flushes atomic.Int32
+}
+
+func (w *countingWriter) Flush() {
+	w.flushes.Add(1)
+	w.ResponseWriter.Flush()
+}
+
+func TestStreamOutputBatchesFlushes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	w := &countingWriter{ResponseWriter: c.Writer}
+
+	out := newStreamOutput(w, 200*time.Millisecond)
+	// The first write after an idle period is flushed right away
+	_, _ = out.Write([]byte("first\n"))
+	if got := w.flushes.Load(); got != 1 {
+		t.Fatalf("Expected an immediate flush, got %d flushes", got)
+	}
+	// A burst is batched until the next tick
+	for i := 0; i < 100; i++ {
+		_, _ = out.Write([]byte("line\n"))
+	}
+	if got := w.flushes.Load(); got != 1 {
+		t.Errorf("Expected the burst to be batched, got %d flushes", got)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for w.flushes.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := w.flushes.Load(); got != 2 {
+		t.Errorf("Expected the burst to be flushed by the ticker, got %d flushes", got)
+	}
+	out.Close()
+
+	// Writes after the handler returned never reach the response
+	if _, err := out.Write([]byte("late\n")); !errors.Is(err, errStreamClosed) {
+		t.Errorf("Expected errStreamClosed after close, got %v", err)
+	}
+	if got := recorder.Body.Len(); got != len("first\n")+100*len("line\n") {
+		t.Errorf("Unexpected body length %d", got)
+	}
+
+	unbatched := newStreamOutput(w, 0)
+	defer unbatched.Close()
+	before := w.flushes.Load()
+	_, _ = unbatched.Write([]byte("a\n"))
+	_, _ = unbatched.Write([]byte("b\n"))
+	if got := w.flushes.Load() - before; got != 2 {
+		t.Errorf("Expected a flush per write without interval, got %d", got)
+	}
+}
+
+func TestEventSettler(t *testing.T) {
+	settled := make(chan []string, 10)
+	s := newEventSettler(100*time.Millisecond, func(paths []string) { settled <- paths })
+	defer s.Stop()
+
+	// A burst longer than the window is emitted once, after it is over
+	for _, path := range []string{"a", "b", "a", "c"} {
+		s.Add(path)
+		time.Sleep(60 * time.Millisecond)
+	}
+	select {
+	case paths := <-settled:
+		if len(paths) != 3 || paths[0] != "a" || paths[1] != "b" || paths[2] != "c" {
+			t.Errorf("Expected each changed path once, got %v", paths)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the settled paths")
+	}
+	select {
+	case paths := <-settled:
+		t.Errorf("Expected a single emission per burst, got %v", paths)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The next burst starts from an empty list
+	s.Add("d")
+	select {
+	case paths := <-settled:
+		if len(paths) != 1 || paths[0] != "d" {
+			t.Errorf("Expected only the new path, got %v", paths)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the second burst")
+	}
+
+	// Pending paths are dropped on stop, and later ones are ignored
+	s.Add("e")
+	s.Stop()
+	s.Add("f")
+	select {
+	case paths := <-settled:
+		t.Errorf("Expected nothing after stop, got %v", paths)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestEventSettlerStopWaitsForEmit(t *testing.T) {
+	emitting := make(chan struct{})
+	release := make(chan struct{})
+	var emitted atomic.Bool
+	s := newEventSettler(10*time.Millisecond, func(paths []string) {
+		close(emitting)
+		<-release
+		emitted.Store(true)
+	})
+
+	s.Add("a")
+	select {
+	case <-emitting:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the settled paths")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	s.Stop()
+	if !emitted.Load() {
+		t.Error("Expected Stop to wait for the emission in flight")
+	}
+}
+
+func TestStreamLimit(t *testing.T) {
+	defer func(max int) { drain.MaxStreams = max }(drain.MaxStreams)
+	drain.MaxStreams = drain.Active() + 1
+
+	// Hold the last slot
+	release, err := drain.TryTrack()
+	if err != nil {
+		t.Fatalf("TryTrack failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	h := NewFileSystemHandler()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/watch/filesystem/"+strings.ReplaceAll(dir, "/", "%2F"), nil)
+	c.Params = gin.Params{{Key: "path", Value: dir}}
+	h.HandleWatchDirectory(c)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 503 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/health", nil)
+	NewSystemHandler().HandleHealth(c)
+	var health HealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Failed to decode health: %v", err)
+	}
+	if health.ActiveStreams != drain.MaxStreams || health.MaxStreams != drain.MaxStreams {
+		t.Errorf("Expected %d active streams out of %d, got %+v", drain.MaxStreams, drain.MaxStreams, health)
+	}
+	release()
+}
+
+func TestEventBatcher(t *testing.T) {
+	batches := make(chan FileEventBatch, 10)
+	b := newEventBatcher(100*time.Millisecond, func(batch FileEventBatch) { batches <- batch })
+	defer b.Stop()
+
+	b.Keepalive()
+	if batch := <-batches; len(batch.Events) != 0 || batch.LastSeq != 0 {
+		t.Errorf("Expected an empty keepalive batch before any event, got %+v", batch)
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		b.Add(FileEvent{Op: "CREATE", Name: name})
+	}
+	select {
+	case batch := <-batches:
+		if len(batch.Events) != 3 || batch.LastSeq != 3 {
+			t.Fatalf("Expected the 3 events in one batch, got %+v", batch)
+		}
+		for i, event := range batch.Events {
+			if event.Seq != uint64(i+1) {
+				t.Errorf("Expected event %d to have seq %d, got %d", i, i+1, event.Seq)
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the batch")
+	}
+
+	b.Add(FileEvent{Op: "WRITE", Name: "d"})
+	b.Keepalive()
+	if batch := <-batches; len(batch.Events) != 1 || batch.Events[0].Seq != 4 || batch.LastSeq != 4 {
+		t.Errorf("Expected a keepalive to deliver the pending event, got %+v", batch)
+	}
+	b.Keepalive()
+	if batch := <-batches; len(batch.Events) != 0 || batch.LastSeq != 4 {
+		t.Errorf("Expected an empty keepalive batch with the last seq, got %+v", batch)
+	}
+	select {
+	case batch := <-batches:
+		t.Errorf("Expected no batch once the pending events were delivered, got %+v", batch)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestParseKeepaliveInterval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		query    string
+		interval time.Duration
+		valid    bool
+	}{
+		{"", 30 * time.Second, true},
+		{"?keepaliveSec=5", 5 * time.Second, true},
+		{"?keepaliveSec=600", 10 * time.Minute, true},
+		{"?keepaliveSec=1", 0, false},
+		{"?keepaliveSec=601", 0, false},
+		{"?keepaliveSec=abc", 0, false},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/watch/filesystem/tmp"+tc.query, nil)
+		interval, err := parseKeepaliveInterval(c, 30*time.Second)
+		if (err == nil) != tc.valid {
+			t.Errorf("%q: expected valid=%v, got %v", tc.query, tc.valid, err)
+			continue
+		}
+		if tc.valid && interval != tc.interval {
+			t.Errorf("%q: expected %v, got %v", tc.query, tc.interval, interval)
+		}
+	}
+
+	// Rejected before the stream starts
+	dir := t.TempDir()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/watch/filesystem/"+strings.ReplaceAll(dir, "/", "%2F")+"?keepaliveSec=1", nil)
+	c.Params = gin.Params{{Key: "path", Value: dir}}
+	NewFileSystemHandler().HandleWatchDirectory(c)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a keepaliveSec below the minimum, got %d", w.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"runtime"
@@ -8,8 +9,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 
 	"github.com/blaxel-ai/sandbox-api/src/handler/process"
+	"github.com/blaxel-ai/sandbox-api/src/lib/audit"
+	"github.com/blaxel-ai/sandbox-api/src/lib/drain"
 )
 
 // Build information - set via ldflags at build time
@@ -62,6 +66,9 @@ type HealthResponse struct {
 	UpgradeCount  int                   `json:"upgradeCount" binding:"required" example:"0"`
 	StartedAt     string                `json:"startedAt" binding:"required" example:"2026-01-29T18:45:49Z"`
 	LastUpgrade   process.UpgradeStatus `json:"lastUpgrade" binding:"required"`
+	ActiveStreams int                   `json:"activeStreams" binding:"required" example:"3"` // Open streaming connections (watch, logs, follow...)
+	MaxStreams    int                   `json:"maxStreams" binding:"required" example:"256"`  // Maximum number of concurrent streaming connections, 0 for no limit
+	Resources     SystemResources       `json:"resources" binding:"required"`                 // Resource usage of the API process, as returned by GET /system/resources
 } // @name HealthResponse
 
 // HandleHealth handles GET requests to /health
@@ -88,6 +95,9 @@ func (h *SystemHandler) HandleHealth(c *gin.Context) {
 		UpgradeCount:  upgradeCount,
 		StartedAt:     startTime.Format(time.RFC3339),
 		LastUpgrade:   process.GetLastUpgradeStatus(),
+		ActiveStreams: drain.Active(),
+		MaxStreams:    drain.MaxStreams,
+		Resources:     collectSystemResources(),
 	})
 }
 
@@ -166,3 +176,160 @@ func (h *SystemHandler) HandleUpgrade(c *gin.Context) {
 		process.TriggerUpgrade(version, baseURL)
 	}()
 }
+
+// HandleRestart handles POST requests to /restart
+// @Summary Restart the sandbox-api
+// @Description Re-executes the current sandbox-api binary to apply configuration changes, without downloading a release. Returns 200 immediately before restarting.
+// @Description Process state is saved first and running processes are preserved, as during an upgrade. To stop restart loops, at most SANDBOX_MAX_RESTARTS (default 5) restarts are allowed within 10 minutes.
+// @Tags system
+// @Produce json
+// @Success 200 {object} SuccessResponse "Restart initiated"
+// @Failure 409 {object} ErrorResponse "A restart is already in progress"
+// @Failure 429 {object} ErrorResponse "Too many restarts"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /restart [post]
+func (h *SystemHandler) HandleRestart(c *gin.Context) {
+	if err := process.PrepareRestart(); err != nil {
+		if errors.Is(err, process.ErrTooManyRestarts) {
+			h.SendError(c, http.StatusTooManyRequests, err)
+		} else {
+			h.SendError(c, http.StatusConflict, err)
+		}
+		return
+	}
+
+	// Save state before responding
+	if err := h.processManager.SaveState(); err != nil {
+		process.CancelRestart()
+		h.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	audit.LogEvent(c, "restart", logrus.Fields{})
+
+	h.SendJSON(c, http.StatusOK, gin.H{
+		"message": "Restart initiated. Process state saved. The server will restart shortly.",
+	})
+
+	// Flush the response to ensure the client receives it
+	if flusher, ok := c.Writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	// Restart after a short delay to give time for the response to be sent
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		process.TriggerRestart()
+	}()
+}
+
+// TimezoneRequest is the request body for setting the default timezone of processes
+type TimezoneRequest struct {
+	Timezone string `json:"timezone" example:"Europe/Paris"` // Name from the tz database, empty to use the system timezone
+} // @name TimezoneRequest
+
+// TimezoneResponse describes the timezone given to new processes
+type TimezoneResponse struct {
+	Timezone   string `json:"timezone" binding:"required" example:"Europe/Paris"` // TZ new processes receive, empty for the system local time
+	Configured bool   `json:"configured" binding:"required" example:"true"`       // Whether it was set with PUT /config/timezone
+} // @name TimezoneResponse
+
+func timezoneResponse() TimezoneResponse {
+	return TimezoneResponse{
+		Timezone:   process.EffectiveTimezone(),
+		Configured: process.DefaultTimezone() != "",
+	}
+}
+
+// HandleGetTimezone handles GET requests to /config/timezone
+// @Summary Get the default timezone of processes
+// @Description Returns the TZ given to newly started processes, either set with PUT /config/timezone or inherited from the API environment
+// @Tags system
+// @Produce json
+// @Success 200 {object} TimezoneResponse "Process timezone"
+// @Router /config/timezone [get]
+func (h *SystemHandler) HandleGetTimezone(c *gin.Context) {
+	h.SendJSON(c, http.StatusOK, timezoneResponse())
+}
+
+// HandleSetTimezone handles PUT requests to /config/timezone
+// @Summary Set the default timezone of processes
+// @Description Sets the TZ env var of processes started from now on, unless their env sets TZ itself. The timezone must exist in the tz database (e.g. Europe/Paris, UTC). An empty timezone goes back to the API environment's TZ. Running processes are not affected.
+// @Tags system
+// @Accept json
+// @Produce json
+// @Param request body TimezoneRequest true "Timezone"
+// @Success 200 {object} TimezoneResponse "Process timezone"
+// @Failure 400 {object} ErrorResponse "Invalid timezone"
+// @Router /config/timezone [put]
+func (h *SystemHandler) HandleSetTimezone(c *gin.Context) {
+	var req TimezoneRequest
+	if err := h.BindJSON(c, &req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := process.SetDefaultTimezone(req.Timezone); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	audit.LogEvent(c, "config_timezone", logrus.Fields{
+		"timezone": req.Timezone,
+	})
+
+	h.SendJSON(c, http.StatusOK, timezoneResponse())
+}
+
+// UlimitsResponse describes the resource limits given to new processes
+type UlimitsResponse struct {
+	Defaults process.Ulimits `json:"defaults" binding:"required"` // Soft limits set on new processes, omitted ones are inherited from the API
+	Hard     process.Ulimits `json:"hard" binding:"required"`     // Hard limits of the API, the highest accepted defaults
+} // @name UlimitsResponse
+
+// HandleGetUlimits handles GET requests to /config/ulimits
+// @Summary Get the default ulimits of processes
+// @Description Returns the RLIMIT_NOFILE and RLIMIT_NPROC soft limits set on newly started processes with PUT /config/ulimits, and the hard limits they can't exceed
+// @Tags system
+// @Produce json
+// @Success 200 {object} UlimitsResponse "Process ulimits"
+// @Failure 500 {object} ErrorResponse "Limits could not be read"
+// @Router /config/ulimits [get]
+func (h *SystemHandler) HandleGetUlimits(c *gin.Context) {
+	hard, err := process.HardUlimits()
+	if err != nil {
+		h.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.SendJSON(c, http.StatusOK, UlimitsResponse{Defaults: process.DefaultUlimits(), Hard: hard})
+}
+
+// HandleSetUlimits handles PUT requests to /config/ulimits
+// @Summary Set the default ulimits of processes
+// @Description Sets the RLIMIT_NOFILE (nofile) and RLIMIT_NPROC (nproc) soft limits of processes started from now on, so commands don't need a ulimit prefix. Limits can't exceed the hard limits of the API; 0 or an omitted limit goes back to the inherited one. Limits are applied right after a process starts: children it forks immediately may keep the inherited ones. Running processes are not affected. Linux only.
+// @Tags system
+// @Accept json
+// @Produce json
+// @Param request body process.Ulimits true "Soft limits"
+// @Success 200 {object} UlimitsResponse "Process ulimits"
+// @Failure 400 {object} ErrorResponse "Limit above the hard limit"
+// @Router /config/ulimits [put]
+func (h *SystemHandler) HandleSetUlimits(c *gin.Context) {
+	var req process.Ulimits
+	if err := h.BindJSON(c, &req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := process.SetDefaultUlimits(req); err != nil {
+		h.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	audit.LogEvent(c, "config_ulimits", logrus.Fields{
+		"nofile": req.NoFile,
+		"nproc":  req.NProc,
+	})
+
+	h.HandleGetUlimits(c)
+}
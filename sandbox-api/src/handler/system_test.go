@@ -1,6 +1,11 @@
 package handler
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
 
 // TestResolveUpgradeVersion verifies the default upgrade version logic.
 // Regression test for ENG-2974: an empty version must resolve to "latest"
@@ -27,3 +32,58 @@ func TestResolveUpgradeVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestProbeTool(t *testing.T) {
+	info := probeTool("go")
+	if !info.Available || info.Path == "" {
+		t.Fatalf("Expected go to be available, got %+v", info)
+	}
+	if info.Version == "" || !strings.Contains(info.Output, info.Version) {
+		t.Errorf("Expected a version parsed from %q, got %q", info.Output, info.Version)
+	}
+
+	missing := probeTool("definitely-not-an-installed-tool")
+	if missing.Available || missing.Path != "" || missing.Version != "" {
+		t.Errorf("Expected a missing tool, got %+v", missing)
+	}
+}
+
+func TestParseToolList(t *testing.T) {
+	tools, err := parseToolList("node, python3,,node")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tools) != 2 || tools[0] != "node" || tools[1] != "python3" {
+		t.Errorf("Unexpected tools %v", tools)
+	}
+	if _, err := parseToolList("/bin/sh"); err == nil {
+		t.Error("Expected an error for a path")
+	}
+}
+
+func TestCollectSystemResources(t *testing.T) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(t.TempDir()); err != nil {
+		t.Fatalf("Failed to watch directory: %v", err)
+	}
+
+	resources := collectSystemResources()
+	if resources.OpenFiles == 0 || resources.MaxOpenFiles == 0 {
+		t.Errorf("Expected open file counts, got %+v", resources)
+	}
+	if resources.InotifyInstances < 1 || resources.InotifyWatches < 1 {
+		t.Errorf("Expected the watcher to be counted, got %+v", resources)
+	}
+	if resources.Goroutines == 0 || resources.HeapAllocBytes == 0 || resources.RSSBytes == 0 {
+		t.Errorf("Expected goroutine and memory usage, got %+v", resources)
+	}
+
+	watcher.Close()
+	if after := collectSystemResources(); after.InotifyInstances >= resources.InotifyInstances {
+		t.Errorf("Expected the closed watcher to no longer be counted, got %d instances", after.InotifyInstances)
+	}
+}
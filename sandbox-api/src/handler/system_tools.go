@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultProbedTools are the executables reported by GET /system/tools unless
+// SANDBOX_TOOLS or the tools query parameter select others
+var defaultProbedTools = []string{
+	"node", "npm", "pnpm", "yarn", "bun", "deno",
+	"python", "python3", "pip", "pip3", "uv",
+	"go", "rustc", "cargo", "java", "ruby", "php",
+	"git", "make", "gcc", "docker", "curl",
+}
+
+// toolVersionArgs lists the tools that don't support "--version"
+var toolVersionArgs = map[string][]string{
+	"go":   {"version"},
+	"java": {"-version"},
+}
+
+// MaxProbedTools is the maximum number of tools probed in a single request
+const MaxProbedTools = 50
+
+const (
+	toolProbeTimeout = 5 * time.Second
+	toolCacheTTL     = 60 * time.Second
+)
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+([-+.][0-9A-Za-z.]+)?`)
+
+// ToolInfo describes an executable available (or not) in the sandbox
+type ToolInfo struct {
+	Name      string `json:"name" binding:"required" example:"node"`
+	Available bool   `json:"available" binding:"required" example:"true"`
+	Path      string `json:"path,omitempty" example:"/usr/local/bin/node"`
+	Version   string `json:"version,omitempty" example:"22.11.0"`
+	Output    string `json:"output,omitempty" example:"v22.11.0"` // First line printed by the version command
+	Error     string `json:"error,omitempty" example:"version command timed out"`
+} // @name ToolInfo
+
+// SystemToolsResponse is the response body for the tools endpoint
+type SystemToolsResponse struct {
+	Tools []ToolInfo `json:"tools" binding:"required"`
+} // @name SystemToolsResponse
+
+type cachedTool struct {
+	info      ToolInfo
+	checkedAt time.Time
+}
+
+// toolCache keeps probe results for a short time, so agents can query the endpoint freely
+var toolCache = struct {
+	entries map[string]cachedTool
+	mu      sync.Mutex
+}{entries: make(map[string]cachedTool)}
+
+// probeTool looks an executable up in PATH and runs its version command
+func probeTool(name string) ToolInfo {
+	info := ToolInfo{Name: name}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return info
+	}
+	info.Available = true
+	info.Path = path
+
+	args, ok := toolVersionArgs[name]
+	if !ok {
+		args = []string{"--version"}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), toolProbeTimeout)
+	defer cancel()
+	// Some tools (java) print their version on stderr
+	output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if ctx.Err() != nil {
+		info.Error = "version command timed out"
+		return info
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			info.Output = line
+			break
+		}
+	}
+	info.Version = versionPattern.FindString(info.Output)
+	if err != nil && info.Output == "" {
+		info.Error = err.Error()
+	}
+	return info
+}
+
+// getToolInfo returns the cached probe result of a tool, probing it when missing or stale
+func getToolInfo(name string, refresh bool) ToolInfo {
+	toolCache.mu.Lock()
+	cached, ok := toolCache.entries[name]
+	toolCache.mu.Unlock()
+	if ok && !refresh && time.Since(cached.checkedAt) < toolCacheTTL {
+		return cached.info
+	}
+
+	info := probeTool(name)
+	toolCache.mu.Lock()
+	toolCache.entries[name] = cachedTool{info: info, checkedAt: time.Now()}
+	toolCache.mu.Unlock()
+	return info
+}
+
+// parseToolList splits a comma-separated list of executable names
+func parseToolList(value string) ([]string, error) {
+	var tools []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if strings.ContainsAny(name, "/\\") {
+			return nil, fmt.Errorf("invalid tool name %q: expected an executable name, not a path", name)
+		}
+		seen[name] = true
+		tools = append(tools, name)
+	}
+	if len(tools) > MaxProbedTools {
+		return nil, fmt.Errorf("too many tools: %d (max %d)", len(tools), MaxProbedTools)
+	}
+	return tools, nil
+}
+
+// HandleListTools handles GET requests to /system/tools
+// @Summary List installed runtimes and tools
+// @Description Probes common executables (node, python, go...) with their version command and reports their path and version, or that they are missing. The probed set defaults to SANDBOX_TOOLS (comma-separated) or a built-in list. Results are cached for 60 seconds.
+// @Tags system
+// @Produce json
+// @Param tools query string false "Comma-separated list of executables to probe instead of the default set"
+// @Param refresh query boolean false "Ignore cached results"
+// @Success 200 {object} SystemToolsResponse "Tool availability and versions"
+// @Failure 400 {object} ErrorResponse "Invalid tool list"
+// @Router /system/tools [get]
+func (h *SystemHandler) HandleListTools(c *gin.Context) {
+	list := c.Query("tools")
+	if list == "" {
+		list = os.Getenv("SANDBOX_TOOLS")
+	}
+	names := defaultProbedTools
+	if list != "" {
+		var err error
+		names, err = parseToolList(list)
+		if err != nil {
+			h.SendError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	refresh := c.Query("refresh") == "true"
+
+	tools := make([]ToolInfo, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			tools[i] = getToolInfo(name, refresh)
+		}(i, name)
+	}
+	wg.Wait()
+
+	h.SendJSON(c, http.StatusOK, SystemToolsResponse{Tools: tools})
+}
@@ -0,0 +1,126 @@
+// Package auth implements optional capability-scoped tokens. A token carries the
+// scopes it grants (e.g. "filesystem:read") and is signed with a secret shared by
+// the operator and the API, so it can be checked without any token store.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Secret signs and verifies tokens. Token checks are disabled while it is empty.
+// Can be configured via SANDBOX_TOKEN_SECRET environment variable, empty by default.
+var Secret = ""
+
+// Scope actions
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+)
+
+// AllScopes grants every scope
+const AllScopes = "*"
+
+var (
+	// ErrInvalidToken is returned for malformed tokens or tokens with a wrong signature
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned for tokens past their expiry
+	ErrTokenExpired = errors.New("token expired")
+)
+
+var scopePattern = regexp.MustCompile(`^(\*|[a-z]+:(read|write|\*))$`)
+
+func init() {
+	Secret = os.Getenv("SANDBOX_TOKEN_SECRET")
+}
+
+// Enabled reports whether requests must carry a token
+func Enabled() bool {
+	return Secret != ""
+}
+
+// Claims is the payload of a token
+type Claims struct {
+	Scopes    []string `json:"scopes"`
+	ExpiresAt int64    `json:"exp,omitempty"` // Unix time, 0 never expires
+}
+
+// Allows reports whether the claims grant action on area. "area:*" grants both
+// actions on the area, "*" grants everything; write does not imply read.
+func (c *Claims) Allows(area, action string) bool {
+	return slices.Contains(c.Scopes, AllScopes) ||
+		slices.Contains(c.Scopes, area+":*") ||
+		slices.Contains(c.Scopes, area+":"+action)
+}
+
+// ValidateScope checks a scope: "*", or "area:read", "area:write" or "area:*"
+func ValidateScope(scope string) error {
+	if !scopePattern.MatchString(scope) {
+		return fmt.Errorf("invalid scope %q: use *, or area:read, area:write or area:*", scope)
+	}
+	return nil
+}
+
+// Issue returns a token granting scopes, valid for ttl (0 never expires)
+func Issue(secret string, scopes []string, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", errors.New("no secret to sign the token with")
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("a token needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if err := ValidateScope(scope); err != nil {
+			return "", err
+		}
+	}
+	claims := Claims{Scopes: scopes}
+	if ttl > 0 {
+		claims.ExpiresAt = time.Now().Add(ttl).Unix()
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(secret, encoded), nil
+}
+
+// Parse verifies a token's signature and expiry and returns its claims
+func Parse(secret, token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(sign(secret, encoded))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.ExpiresAt != 0 && time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	return &claims, nil
+}
+
+// sign returns the HMAC-SHA256 signature of an encoded payload
+func sign(secret, encoded string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIssueAndParse(t *testing.T) {
+	token, err := Issue("secret", []string{"filesystem:read", "process:*"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	claims, err := Parse("secret", token)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	for _, tc := range []struct {
+		area, action string
+		allowed      bool
+	}{
+		{"filesystem", ActionRead, true},
+		{"filesystem", ActionWrite, false},
+		{"process", ActionWrite, true},
+		{"terminal", ActionRead, false},
+	} {
+		if got := claims.Allows(tc.area, tc.action); got != tc.allowed {
+			t.Errorf("Allows(%s, %s) = %v, expected %v", tc.area, tc.action, got, tc.allowed)
+		}
+	}
+
+	if _, err := Parse("other", token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken with another secret, got %v", err)
+	}
+	if _, err := Parse("secret", token+"x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for a tampered token, got %v", err)
+	}
+
+	admin, _ := Issue("secret", []string{"*"}, 0)
+	if claims, err := Parse("secret", admin); err != nil || claims.ExpiresAt != 0 || !claims.Allows("system", ActionWrite) {
+		t.Errorf("Expected a token without expiry granting everything, got %+v (%v)", claims, err)
+	}
+	if _, err := Issue("secret", []string{"filesystem:delete"}, 0); err == nil {
+		t.Errorf("Expected an invalid scope to be rejected")
+	}
+}
+
+func TestParseExpired(t *testing.T) {
+	token, err := Issue("secret", []string{"*"}, time.Second)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := Parse("secret", token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package drain lets streaming handlers end cleanly before the API stops or
+// execs into an upgraded binary, so that clients get an explicit signal to
+// reconnect instead of a connection reset.
+package drain
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MaxStreams is the maximum number of concurrent streams accepted by TryTrack, 0 for no limit.
+// Set with SANDBOX_MAX_STREAMS.
+var MaxStreams = 256
+
+// ErrTooManyStreams is returned by TryTrack when MaxStreams streams are already active
+var ErrTooManyStreams = errors.New("too many concurrent streaming connections, try again later")
+
+var (
+	draining  = make(chan struct{})
+	drainOnce sync.Once
+	streams   sync.WaitGroup
+	active    atomic.Int64
+)
+
+func init() {
+	if value := os.Getenv("SANDBOX_MAX_STREAMS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			MaxStreams = n
+		} else {
+			logrus.Warnf("Invalid SANDBOX_MAX_STREAMS %q, using %d", value, MaxStreams)
+		}
+	}
+}
+
+// Signal returns a channel closed when streams must send a final reconnect
+// event and return
+func Signal() <-chan struct{} {
+	return draining
+}
+
+// Track registers an active stream. The returned function must be called
+// once the stream has ended.
+func Track() func() {
+	active.Add(1)
+	streams.Add(1)
+	return untrack()
+}
+
+// TryTrack registers an active stream like Track, unless MaxStreams streams are already active
+func TryTrack() (func(), error) {
+	for {
+		n := active.Load()
+		if MaxStreams > 0 && n >= int64(MaxStreams) {
+			return nil, ErrTooManyStreams
+		}
+		if active.CompareAndSwap(n, n+1) {
+			break
+		}
+	}
+	streams.Add(1)
+	return untrack(), nil
+}
+
+// untrack returns the function ending a tracked stream, safe to call several times
+func untrack() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			active.Add(-1)
+			streams.Done()
+		})
+	}
+}
+
+// Active returns the number of active streams
+func Active() int {
+	return int(active.Load())
+}
+
+// Drain signals every stream to end and waits for them, at most timeout
+func Drain(timeout time.Duration) {
+	drainOnce.Do(func() { close(draining) })
+
+	done := make(chan struct{})
+	go func() {
+		streams.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		logrus.Info("Streaming connections drained")
+	case <-time.After(timeout):
+		logrus.WithField("timeout", timeout).Warn("Timed out draining streaming connections")
+	}
+}
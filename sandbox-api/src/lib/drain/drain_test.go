@@ -0,0 +1,65 @@
+package drain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	ended := make(chan struct{})
+	release := Track()
+	go func() {
+		<-Signal()
+		close(ended)
+		release()
+	}()
+
+	start := time.Now()
+	Drain(5 * time.Second)
+	select {
+	case <-ended:
+	default:
+		t.Fatal("Expected the stream to be signaled before Drain returns")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected Drain to return once the stream ended, took %s", time.Since(start))
+	}
+
+	// A stream that never ends doesn't block past the timeout
+	_ = Track()
+	start = time.Now()
+	Drain(100 * time.Millisecond)
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected Drain to time out, took %s", time.Since(start))
+	}
+}
+
+func TestTryTrack(t *testing.T) {
+	defer func(max int) { MaxStreams = max }(MaxStreams)
+	MaxStreams = Active() + 2
+
+	first, err := TryTrack()
+	if err != nil {
+		t.Fatalf("TryTrack failed: %v", err)
+	}
+	second, err := TryTrack()
+	if err != nil {
+		t.Fatalf("TryTrack failed: %v", err)
+	}
+	if _, err := TryTrack(); err != ErrTooManyStreams {
+		t.Fatalf("Expected ErrTooManyStreams, got %v", err)
+	}
+
+	// Releasing twice frees a single slot
+	first()
+	first()
+	third, err := TryTrack()
+	if err != nil {
+		t.Fatalf("Expected a slot once a stream ended, got %v", err)
+	}
+	if _, err := TryTrack(); err != ErrTooManyStreams {
+		t.Fatalf("Expected ErrTooManyStreams, got %v", err)
+	}
+	second()
+	third()
+}
@@ -148,6 +148,23 @@ func (c *WireGuardConfig) Validate() error {
 	return nil
 }
 
+// RequiresRecreate reports whether switching from c to next needs the WireGuard device
+// to be torn down and recreated. Only changes to the peer (public key, endpoint,
+// allowed IPs, keepalive) can be applied in place; interface settings and keys cannot.
+// With RouteAll, the peer endpoint is also pinned in the routing table, so changing it
+// requires a recreate as well.
+func (c *WireGuardConfig) RequiresRecreate(next *WireGuardConfig) bool {
+	if c.LocalIP != next.LocalIP ||
+		c.PrivateKey != next.PrivateKey ||
+		c.MTU != next.MTU ||
+		c.ListenPort != next.ListenPort ||
+		c.InterfaceName != next.InterfaceName ||
+		c.RouteAll != next.RouteAll {
+		return true
+	}
+	return c.RouteAll && c.PeerEndpoint != next.PeerEndpoint
+}
+
 // validateWireGuardKey checks that a key is valid base64-encoded 32-byte value
 func validateWireGuardKey(key string) error {
 	decoded, err := base64.StdEncoding.DecodeString(key)
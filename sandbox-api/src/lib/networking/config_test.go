@@ -280,3 +280,49 @@ func TestToJSON_DoesNotMutateOriginal(t *testing.T) {
 		t.Error("ToJSON mutated the original config's PrivateKey")
 	}
 }
+
+func TestRequiresRecreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *WireGuardConfig)
+		want   bool
+	}{
+		{"identical", func(c *WireGuardConfig) {}, false},
+		{"allowed IPs changed", func(c *WireGuardConfig) { c.AllowedIPs = []string{"10.0.0.0/8"} }, false},
+		{"peer key changed", func(c *WireGuardConfig) { c.PeerPublicKey = testKey2() }, false},
+		{"peer endpoint changed", func(c *WireGuardConfig) { c.PeerEndpoint = "5.6.7.8:51820" }, false},
+		{"keepalive changed", func(c *WireGuardConfig) { k := 10; c.PersistentKeepalive = &k }, false},
+		{"local IP changed", func(c *WireGuardConfig) { c.LocalIP = "10.0.0.2/32" }, true},
+		{"private key changed", func(c *WireGuardConfig) { c.PrivateKey = testKey() }, true},
+		{"MTU changed", func(c *WireGuardConfig) { c.MTU = 1280 }, true},
+		{"listen port changed", func(c *WireGuardConfig) { c.ListenPort = 51821 }, true},
+		{"interface changed", func(c *WireGuardConfig) { c.InterfaceName = "wg1" }, true},
+		{"route all changed", func(c *WireGuardConfig) { c.RouteAll = true }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := testConfig()
+			current.ApplyDefaults()
+			next := testConfig()
+			next.ApplyDefaults()
+			tt.modify(&next)
+
+			if got := current.RequiresRecreate(&next); got != tt.want {
+				t.Errorf("RequiresRecreate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiresRecreate_RouteAllEndpointChange(t *testing.T) {
+	current := testConfig()
+	current.RouteAll = true
+	current.ApplyDefaults()
+	next := current
+	next.PeerEndpoint = "5.6.7.8:51820"
+
+	if !current.RequiresRecreate(&next) {
+		t.Error("changing the peer endpoint with route_all should require a recreate")
+	}
+}
@@ -4,8 +4,10 @@ package networking
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -110,12 +112,25 @@ func StopWireGuard() error {
 	return nil
 }
 
-// UpdateWireGuardConfig stops the current WireGuard client (if any) and starts a new one
-// with the provided configuration.
+// UpdateWireGuardConfig applies a new configuration. When only the peer changed, the
+// update is applied in place on the running device so existing flows stay alive.
+// Otherwise the current WireGuard client (if any) is stopped and a new one is started.
 func UpdateWireGuardConfig(config *WireGuardConfig) error {
 	wgMutex.Lock()
 	defer wgMutex.Unlock()
 
+	if wgClient != nil && wgClient.canUpdateInPlace(config) {
+		err := wgClient.applyPeerUpdate(config)
+		if err == nil {
+			logrus.WithFields(logrus.Fields{
+				"peer_endpoint": config.PeerEndpoint,
+				"allowed_ips":   config.AllowedIPs,
+			}).Info("WireGuard peer configuration updated in place")
+			return nil
+		}
+		logrus.WithError(err).Warn("In-place WireGuard update failed, recreating the device")
+	}
+
 	// Stop existing client if running
 	if wgClient != nil {
 		logrus.Info("Stopping existing WireGuard client for config update")
@@ -307,6 +322,63 @@ func (w *WireGuardClient) buildIPCConfig(privateKey string) (string, error) {
 	return config.String(), nil
 }
 
+// canUpdateInPlace reports whether config can be applied to the running device without recreating it
+func (w *WireGuardClient) canUpdateInPlace(config *WireGuardConfig) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.running && w.device != nil && !w.config.RequiresRecreate(config)
+}
+
+// applyPeerUpdate reconfigures the peer of the running device via IpcSet, keeping the TUN device up
+func (w *WireGuardClient) applyPeerUpdate(config *WireGuardConfig) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	ipcConfig, err := buildPeerIPCConfig(w.config, config)
+	if err != nil {
+		return fmt.Errorf("failed to build peer IPC config: %w", err)
+	}
+	if err := w.device.IpcSet(ipcConfig); err != nil {
+		return fmt.Errorf("failed to update WireGuard peer: %w", err)
+	}
+
+	w.config = config
+	return nil
+}
+
+// buildPeerIPCConfig creates an IPC configuration string that only updates the peer.
+// When the peer key is unchanged the peer is updated in place (keeping its session);
+// otherwise the peer set is replaced.
+func buildPeerIPCConfig(current *WireGuardConfig, next *WireGuardConfig) (string, error) {
+	var config strings.Builder
+
+	if current.PeerPublicKey != next.PeerPublicKey {
+		config.WriteString("replace_peers=true\n")
+	}
+
+	pubHex, err := hexEncode(next.PeerPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode peer public key: %w", err)
+	}
+	config.WriteString(fmt.Sprintf("public_key=%s\n", pubHex))
+	config.WriteString(fmt.Sprintf("endpoint=%s\n", next.PeerEndpoint))
+
+	// Allowed IPs replace the previous set
+	config.WriteString("replace_allowed_ips=true\n")
+	for _, allowedIP := range next.AllowedIPs {
+		config.WriteString(fmt.Sprintf("allowed_ip=%s\n", allowedIP))
+	}
+
+	// Persistent keepalive (explicit 0 disables a previously set interval)
+	keepalive := 0
+	if next.PersistentKeepalive != nil && *next.PersistentKeepalive > 0 {
+		keepalive = *next.PersistentKeepalive
+	}
+	config.WriteString(fmt.Sprintf("persistent_keepalive_interval=%d\n", keepalive))
+
+	return config.String(), nil
+}
+
 // configureNetwork sets up the IP address and routing for the WireGuard interface using netlink
 func (w *WireGuardClient) configureNetwork(interfaceName string) error {
 	// Get the link by name
@@ -608,6 +680,7 @@ func (w *WireGuardClient) GetStatus() map[string]interface{} {
 			for k, v := range parseIPCStats(ipcOutput) {
 				status[k] = v
 			}
+			status["peers"] = parsePeerStats(ipcOutput)
 		}
 	}
 
@@ -629,3 +702,67 @@ func parseIPCStats(ipcOutput string) map[string]string {
 	}
 	return stats
 }
+
+// PeerStats holds the operational metrics of one WireGuard peer
+type PeerStats struct {
+	// PublicKey is the redacted base64 public key of the peer, enough to tell peers apart
+	PublicKey                   string   `json:"public_key"`
+	Endpoint                    string   `json:"endpoint,omitempty"`
+	AllowedIPs                  []string `json:"allowed_ips,omitempty"`
+	RxBytes                     uint64   `json:"rx_bytes"`
+	TxBytes                     uint64   `json:"tx_bytes"`
+	LastHandshakeTimeSec        int64    `json:"last_handshake_time_sec"` // 0 when no handshake happened yet
+	LastHandshakeTimeNsec       int64    `json:"last_handshake_time_nsec"`
+	PersistentKeepaliveInterval int      `json:"persistent_keepalive_interval"`
+}
+
+// parsePeerStats extracts the metrics of each peer from WireGuard IPC output, where every
+// public_key line starts a new peer and the lines before the first one describe the interface
+func parsePeerStats(ipcOutput string) []PeerStats {
+	peers := []PeerStats{}
+	var peer *PeerStats
+	for _, line := range strings.Split(ipcOutput, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if key == "public_key" {
+			peers = append(peers, PeerStats{PublicKey: redactHexKey(value)})
+			peer = &peers[len(peers)-1]
+			continue
+		}
+		if peer == nil {
+			continue
+		}
+		switch key {
+		case "endpoint":
+			peer.Endpoint = value
+		case "allowed_ip":
+			peer.AllowedIPs = append(peer.AllowedIPs, value)
+		case "rx_bytes":
+			peer.RxBytes, _ = strconv.ParseUint(value, 10, 64)
+		case "tx_bytes":
+			peer.TxBytes, _ = strconv.ParseUint(value, 10, 64)
+		case "last_handshake_time_sec":
+			peer.LastHandshakeTimeSec, _ = strconv.ParseInt(value, 10, 64)
+		case "last_handshake_time_nsec":
+			peer.LastHandshakeTimeNsec, _ = strconv.ParseInt(value, 10, 64)
+		case "persistent_keepalive_interval":
+			peer.PersistentKeepaliveInterval, _ = strconv.Atoi(value)
+		}
+	}
+	return peers
+}
+
+// redactHexKey converts a hex key from WireGuard IPC to base64, only keeping its first characters
+func redactHexKey(hexKey string) string {
+	keyBytes, err := hex.DecodeString(hexKey)
+	if err != nil || len(keyBytes) == 0 {
+		return "[REDACTED]"
+	}
+	encoded := base64.StdEncoding.EncodeToString(keyBytes)
+	if len(encoded) > 8 {
+		encoded = encoded[:8]
+	}
+	return encoded + "..."
+}
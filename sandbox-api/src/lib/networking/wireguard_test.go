@@ -177,6 +177,66 @@ func TestParseIPCStats_Empty(t *testing.T) {
 	}
 }
 
+func TestParsePeerStats(t *testing.T) {
+	key1, _ := hexEncode(testKey())
+	key2, _ := hexEncode(testKey2())
+	ipcOutput := `private_key=abcdef0123456789
+listen_port=51820
+public_key=` + key1 + `
+endpoint=1.2.3.4:51820
+allowed_ip=10.0.0.0/8
+allowed_ip=192.168.0.0/16
+last_handshake_time_sec=1234567890
+last_handshake_time_nsec=123456789
+rx_bytes=1024
+tx_bytes=2048
+persistent_keepalive_interval=25
+public_key=` + key2 + `
+last_handshake_time_sec=0
+last_handshake_time_nsec=0
+rx_bytes=0
+tx_bytes=0
+persistent_keepalive_interval=0`
+
+	peers := parsePeerStats(ipcOutput)
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(peers))
+	}
+
+	first := peers[0]
+	if first.Endpoint != "1.2.3.4:51820" || first.RxBytes != 1024 || first.TxBytes != 2048 {
+		t.Errorf("unexpected first peer stats: %+v", first)
+	}
+	if first.LastHandshakeTimeSec != 1234567890 || first.LastHandshakeTimeNsec != 123456789 {
+		t.Errorf("unexpected first peer handshake: %+v", first)
+	}
+	if len(first.AllowedIPs) != 2 || first.PersistentKeepaliveInterval != 25 {
+		t.Errorf("unexpected first peer config: %+v", first)
+	}
+
+	second := peers[1]
+	if second.Endpoint != "" || second.RxBytes != 0 || second.LastHandshakeTimeSec != 0 {
+		t.Errorf("unexpected second peer stats: %+v", second)
+	}
+
+	// Keys are redacted to a short prefix of their base64 encoding
+	for i, key := range []string{testKey(), testKey2()} {
+		if peers[i].PublicKey != key[:8]+"..." {
+			t.Errorf("expected peer %d public key %s..., got %s", i, key[:8], peers[i].PublicKey)
+		}
+		if contains(peers[i].PublicKey, key) {
+			t.Errorf("peer %d public key should be redacted", i)
+		}
+	}
+}
+
+func TestParsePeerStats_NoPeers(t *testing.T) {
+	peers := parsePeerStats("private_key=abcdef0123456789\nlisten_port=51820")
+	if peers == nil || len(peers) != 0 {
+		t.Errorf("expected an empty peer list, got %v", peers)
+	}
+}
+
 func TestBuildIPCConfig(t *testing.T) {
 	keepalive := 25
 	cfg := &WireGuardConfig{
@@ -568,6 +628,68 @@ func TestBuildIPCConfig_DifferentConfigs(t *testing.T) {
 	}
 }
 
+// ==================== buildPeerIPCConfig (in-place update) ====================
+
+func TestBuildPeerIPCConfig_SamePeerKey(t *testing.T) {
+	current := testConfig()
+	current.ApplyDefaults()
+	next := current
+	next.AllowedIPs = []string{"10.0.0.0/8", "192.168.0.0/16"}
+
+	ipc, err := buildPeerIPCConfig(&current, &next)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if contains(ipc, "replace_peers=true") {
+		t.Error("peer set should not be replaced when the peer key is unchanged")
+	}
+	if contains(ipc, "private_key=") || contains(ipc, "listen_port=") {
+		t.Error("peer update should not touch interface settings")
+	}
+	if !contains(ipc, "replace_allowed_ips=true") {
+		t.Error("IPC config missing replace_allowed_ips")
+	}
+	if !contains(ipc, "allowed_ip=10.0.0.0/8") || !contains(ipc, "allowed_ip=192.168.0.0/16") {
+		t.Error("IPC config missing new allowed IPs")
+	}
+	if !contains(ipc, "persistent_keepalive_interval=25") {
+		t.Error("IPC config missing persistent_keepalive_interval")
+	}
+}
+
+func TestBuildPeerIPCConfig_NewPeerKey(t *testing.T) {
+	current := testConfig()
+	current.ApplyDefaults()
+	next := current
+	next.PeerPublicKey = testKey2()
+	keepalive := 0
+	next.PersistentKeepalive = &keepalive
+
+	ipc, err := buildPeerIPCConfig(&current, &next)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !contains(ipc, "replace_peers=true") {
+		t.Error("peer set should be replaced when the peer key changes")
+	}
+	if !contains(ipc, "persistent_keepalive_interval=0") {
+		t.Error("keepalive should be explicitly disabled")
+	}
+}
+
+func TestUpdateWireGuardConfig_NoDeviceFallsBackToRecreate(t *testing.T) {
+	cfg := testConfig()
+	cfg.ApplyDefaults()
+	existingClient, _ := NewWireGuardClient(&cfg)
+	existingClient.running = true
+
+	if existingClient.canUpdateInPlace(&cfg) {
+		t.Error("a client without a device must not be updated in place")
+	}
+}
+
 // helpers
 
 func testIPv4() net.IP {
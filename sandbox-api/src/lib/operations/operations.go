@@ -0,0 +1,119 @@
+// Package operations keeps a registry of the long-running operations in flight
+// (searches, archive extractions...) so that they can be listed and cancelled
+// from a single place, whatever the subsystem running them.
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotFound is returned when cancelling an operation that is not running
+var ErrNotFound = errors.New("operation not found")
+
+// Operation describes a long-running operation in flight
+type Operation struct {
+	ID        string    `json:"id" binding:"required" example:"op-12"`
+	Type      string    `json:"type" binding:"required" example:"find"`
+	Target    string    `json:"target" binding:"required" example:"/app"`
+	Progress  int64     `json:"progress" binding:"required" example:"1500"` // Units processed so far
+	Unit      string    `json:"unit" binding:"required" example:"entries"`  // What progress counts: entries, files or bytes
+	StartedAt time.Time `json:"startedAt" binding:"required"`
+} // @name Operation
+
+// Handle is held by the code running a registered operation
+type Handle struct {
+	id        string
+	kind      string
+	target    string
+	unit      string
+	startedAt time.Time
+	progress  atomic.Int64
+	cancel    context.CancelFunc
+}
+
+var (
+	mu      sync.Mutex
+	running = map[string]*Handle{}
+	lastID  atomic.Int64
+)
+
+// Start registers an operation of the given type on target. The returned context is
+// cancelled when ctx is or when the operation is cancelled through Cancel; the
+// operation must stop once it is done. Done must be called when the operation ends.
+func Start(ctx context.Context, kind string, target string, unit string) (context.Context, *Handle) {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &Handle{
+		id:        fmt.Sprintf("op-%d", lastID.Add(1)),
+		kind:      kind,
+		target:    target,
+		unit:      unit,
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+	mu.Lock()
+	running[h.id] = h
+	mu.Unlock()
+	return ctx, h
+}
+
+// ID returns the identifier of the operation
+func (h *Handle) ID() string {
+	return h.id
+}
+
+// Add records n more units processed
+func (h *Handle) Add(n int64) {
+	h.progress.Add(n)
+}
+
+// Done removes the operation from the registry and releases its context
+func (h *Handle) Done() {
+	mu.Lock()
+	delete(running, h.id)
+	mu.Unlock()
+	h.cancel()
+}
+
+// List returns the operations in flight, oldest first
+func List() []Operation {
+	mu.Lock()
+	ops := make([]Operation, 0, len(running))
+	for _, h := range running {
+		ops = append(ops, Operation{
+			ID:        h.id,
+			Type:      h.kind,
+			Target:    h.target,
+			Progress:  h.progress.Load(),
+			Unit:      h.unit,
+			StartedAt: h.startedAt,
+		})
+	}
+	mu.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool {
+		if !ops[i].StartedAt.Equal(ops[j].StartedAt) {
+			return ops[i].StartedAt.Before(ops[j].StartedAt)
+		}
+		return ops[i].ID < ops[j].ID
+	})
+	return ops
+}
+
+// Cancel cancels the context of a running operation. The operation is listed until
+// it has stopped.
+func Cancel(id string) error {
+	mu.Lock()
+	h, ok := running[id]
+	mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	h.cancel()
+	return nil
+}
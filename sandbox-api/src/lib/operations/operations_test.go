@@ -0,0 +1,46 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOperations(t *testing.T) {
+	ctx, first := Start(context.Background(), "find", "/app", "entries")
+	_, second := Start(context.Background(), "untar", "/data", "bytes")
+	defer second.Done()
+
+	first.Add(3)
+	first.Add(2)
+
+	ops := List()
+	if len(ops) != 2 {
+		t.Fatalf("Expected 2 operations, got %+v", ops)
+	}
+	if ops[0].ID != first.ID() || ops[0].Type != "find" || ops[0].Target != "/app" || ops[0].Progress != 5 || ops[0].Unit != "entries" {
+		t.Errorf("Unexpected first operation: %+v", ops[0])
+	}
+	if ops[1].ID != second.ID() {
+		t.Errorf("Expected operations oldest first, got %+v", ops)
+	}
+
+	if err := Cancel(first.ID()); err != nil {
+		t.Fatalf("Failed to cancel: %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the operation context to be cancelled")
+	}
+	// Still listed until the operation stops
+	if len(List()) != 2 {
+		t.Error("Expected the cancelled operation to be listed until done")
+	}
+
+	first.Done()
+	if ops := List(); len(ops) != 1 || ops[0].ID != second.ID() {
+		t.Errorf("Expected only the second operation, got %+v", ops)
+	}
+	if err := Cancel(first.ID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a finished operation, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Package throttle limits the rate of data transfers, so that one large
+// transfer does not starve the others sharing the link.
+package throttle
+
+import (
+	"io"
+	"time"
+)
+
+// limiter paces a transfer to rate bytes per second on average since its start
+type limiter struct {
+	rate  int64
+	burst int
+	start time.Time
+	total int64
+}
+
+func newLimiter(rate int64) *limiter {
+	// Transfer in chunks of at most a tenth of a second of data, so the pace stays even
+	burst := int(min(max(rate/10, 1), 64*1024))
+	return &limiter{rate: rate, burst: burst, start: time.Now()}
+}
+
+// wait records n bytes transferred and sleeps until the rate allows them
+func (l *limiter) wait(n int) {
+	l.total += int64(n)
+	due := time.Duration(float64(l.total) / float64(l.rate) * float64(time.Second))
+	if d := due - time.Since(l.start); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+type reader struct {
+	r io.Reader
+	l *limiter
+}
+
+// NewReader returns a reader reading from r at most bytesPerSec bytes per second.
+// r is returned as is when bytesPerSec is 0 or less.
+func NewReader(r io.Reader, bytesPerSec int64) io.Reader {
+	if bytesPerSec <= 0 {
+		return r
+	}
+	return &reader{r: r, l: newLimiter(bytesPerSec)}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > r.l.burst {
+		p = p[:r.l.burst]
+	}
+	n, err := r.r.Read(p)
+	r.l.wait(n)
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// NewReadCloser is NewReader for request bodies and other readers that must be closed
+func NewReadCloser(r io.ReadCloser, bytesPerSec int64) io.ReadCloser {
+	if bytesPerSec <= 0 {
+		return r
+	}
+	return readCloser{Reader: NewReader(r, bytesPerSec), Closer: r}
+}
+
+type writer struct {
+	w io.Writer
+	l *limiter
+}
+
+// NewWriter returns a writer writing to w at most bytesPerSec bytes per second.
+// w is returned as is when bytesPerSec is 0 or less.
+func NewWriter(w io.Writer, bytesPerSec int64) io.Writer {
+	if bytesPerSec <= 0 {
+		return w
+	}
+	return &writer{w: w, l: newLimiter(bytesPerSec)}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), w.l.burst)]
+		n, err := w.w.Write(chunk)
+		written += n
+		w.l.wait(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
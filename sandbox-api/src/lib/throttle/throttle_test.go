@@ -0,0 +1,47 @@
+package throttle
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReaderRate(t *testing.T) {
+	data := make([]byte, 20*1024)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, NewReader(bytes.NewReader(data), 40*1024))
+	elapsed := time.Since(start)
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("Expected %d bytes, got %d (%v)", len(data), n, err)
+	}
+	// 20KiB at 40KiB/s takes half a second
+	if elapsed < 450*time.Millisecond || elapsed > 900*time.Millisecond {
+		t.Errorf("Expected about 500ms, took %s", elapsed)
+	}
+}
+
+func TestWriterRate(t *testing.T) {
+	var out bytes.Buffer
+	data := bytes.Repeat([]byte("x"), 20*1024)
+	start := time.Now()
+	n, err := NewWriter(&out, 40*1024).Write(data)
+	elapsed := time.Since(start)
+	if err != nil || n != len(data) || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("Expected all the data written, got %d bytes (%v)", n, err)
+	}
+	if elapsed < 450*time.Millisecond || elapsed > 900*time.Millisecond {
+		t.Errorf("Expected about 500ms, took %s", elapsed)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	r := bytes.NewReader(nil)
+	if NewReader(r, 0) != io.Reader(r) {
+		t.Error("Expected the reader unchanged without a rate")
+	}
+	var w bytes.Buffer
+	if NewWriter(&w, 0) != io.Writer(&w) {
+		t.Error("Expected the writer unchanged without a rate")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"path"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,7 +18,7 @@ import (
 type Server struct {
 	mcpServer *mcp.Server
 	handlers  *Handlers
-	engine    *gin.Engine
+	engine    *gin.RouterGroup
 }
 
 // Handlers contains all the handlers used by the MCP server
@@ -27,8 +28,9 @@ type Handlers struct {
 	Network    *handler.NetworkHandler
 }
 
-// NewServer creates a new MCP server using the official SDK
-func NewServer(ginEngine *gin.Engine) (*Server, error) {
+// NewServer creates a new MCP server using the official SDK.
+// Its endpoints are registered on ginEngine, which can be a route group.
+func NewServer(ginEngine *gin.RouterGroup) (*Server, error) {
 	logrus.Info("Creating MCP server")
 
 	// Create MCP server with the official SDK
@@ -95,7 +97,7 @@ func (s *Server) setupHTTPEndpoints() {
 		})
 	})
 
-	logrus.Info("MCP HTTP endpoints configured at /mcp (stateless, JSON responses)")
+	logrus.Infof("MCP HTTP endpoints configured at %s (stateless, JSON responses)", path.Join(s.engine.BasePath(), "mcp"))
 }
 
 // registerTools registers all the tools with the MCP server